@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"math/rand/v2"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// AccessRule suppresses or samples access-log entries of matching requests.
+//
+// A request matches when every non-empty criterion matches. Rules are evaluated in order
+// and the first matching rule decides; requests matching no rule are always logged.
+//
+// Example:
+//
+//	rules := []logger.AccessRule{
+//	    // Drop successful health checks.
+//	    {Path: "/healthz", Statuses: []int{http.StatusOK}},
+//	    // Keep 1% of static asset requests.
+//	    {Path: "/static/*", Methods: []string{http.MethodGet}, SampleRate: 0.01},
+//	}
+type AccessRule struct {
+	// Path is a path.Match pattern matched against the request path. A trailing "/*"
+	// also matches nested paths. Empty matches any path.
+	Path string
+	// Methods lists the matching request methods. Empty matches any method.
+	Methods []string
+	// Statuses lists the matching response status codes. Empty matches any status; list
+	// the success codes explicitly to keep failures of noisy endpoints visible.
+	Statuses []int
+	// SampleRate is the fraction of matching requests that are logged, between 0 (drop
+	// all) and 1 (log all).
+	SampleRate float64
+}
+
+// matches reports whether the rule applies to the request and response status.
+func (rule AccessRule) matches(r *http.Request, status int) bool {
+	if rule.Path != "" && !matchPath(rule.Path, r.URL.Path) {
+		return false
+	}
+	if len(rule.Methods) > 0 && !slices.ContainsFunc(rule.Methods, func(m string) bool {
+		return strings.EqualFold(m, r.Method)
+	}) {
+		return false
+	}
+	if len(rule.Statuses) > 0 && !slices.Contains(rule.Statuses, status) {
+		return false
+	}
+	return true
+}
+
+// sampled reports whether a matching request is logged.
+func (rule AccessRule) sampled() bool {
+	switch {
+	case rule.SampleRate <= 0:
+		return false
+	case rule.SampleRate >= 1:
+		return true
+	default:
+		return rand.Float64() < rule.SampleRate
+	}
+}
+
+// matchPath matches p against pattern, treating a trailing "/*" as a prefix match.
+func matchPath(pattern, p string) bool {
+	if ok, _ := path.Match(pattern, p); ok {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(p, prefix)
+	}
+	return false
+}
+
+// shouldLog applies the first rule matching the request, trying each rule set in order.
+func shouldLog(r *http.Request, status int, ruleSets ...[]AccessRule) bool {
+	for _, rules := range ruleSets {
+		for _, rule := range rules {
+			if rule.matches(r, status) {
+				return rule.sampled()
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Anonymization is a privacy transform applied to the value of a field.
+type Anonymization string
+
+const (
+	// AnonymizeHash replaces the value with a keyed hash (HMAC-SHA256, truncated to 16
+	// hex characters). Equal values yield equal pseudonyms, so entries can still be
+	// correlated without revealing the original value.
+	AnonymizeHash Anonymization = "hash"
+	// AnonymizeTruncate zeroes the host part of IP addresses (IPv4 /24 and IPv6 /48 by
+	// default) and drops the first label of hostnames ("db1.eu.example.com" becomes
+	// "eu.example.com").
+	AnonymizeTruncate Anonymization = "truncate"
+)
+
+// Default prefix lengths kept by AnonymizeTruncate.
+const (
+	defaultIPv4Prefix = 24
+	defaultIPv6Prefix = 48
+)
+
+// AnonymizationConfig pseudonymizes IP addresses and hostnames before entries are
+// emitted, e.g. to meet GDPR requirements for access logs.
+//
+// Transforms apply to string fields (and fields added with zap.Stringer, such as
+// netip.Addr values) whose key is listed in Fields, in every output.
+//
+// Example:
+//
+//	Anonymization: &logger.AnonymizationConfig{
+//	    Fields: map[string]logger.Anonymization{
+//	        "remote_ip": logger.AnonymizeTruncate,
+//	        "hostname":  logger.AnonymizeHash,
+//	    },
+//	    Key: []byte(os.Getenv("LOG_PSEUDONYM_KEY")),
+//	}
+type AnonymizationConfig struct {
+	// Fields selects the transform of each field.
+	Fields map[string]Anonymization
+	// Key is the secret used by AnonymizeHash. A random key is generated when empty, in
+	// which case pseudonyms are only stable for the lifetime of the process.
+	Key []byte
+	// IPv4Prefix is the number of leading bits kept by AnonymizeTruncate. Defaults to 24.
+	IPv4Prefix int
+	// IPv6Prefix is the number of leading bits kept by AnonymizeTruncate. Defaults to 48.
+	IPv6Prefix int
+}
+
+// fieldNames returns the sorted names of the anonymized fields.
+func (c *AnonymizationConfig) fieldNames() []string {
+	if c == nil {
+		return nil
+	}
+	names := make([]string, 0, len(c.Fields))
+	for name := range c.Fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// anonymizer is the validated form of an AnonymizationConfig.
+type anonymizer struct {
+	fields     map[string]Anonymization
+	key        []byte
+	ipv4Prefix int
+	ipv6Prefix int
+}
+
+// newAnonymizer validates the configuration and applies its defaults.
+func newAnonymizer(cfg *AnonymizationConfig) (*anonymizer, error) {
+	a := &anonymizer{fields: cfg.Fields, key: cfg.Key, ipv4Prefix: cfg.IPv4Prefix, ipv6Prefix: cfg.IPv6Prefix}
+	for name, mode := range cfg.Fields {
+		if mode != AnonymizeHash && mode != AnonymizeTruncate {
+			return nil, fmt.Errorf("anonymization: unknown transform %q for field %q", mode, name)
+		}
+	}
+	if a.ipv4Prefix == 0 {
+		a.ipv4Prefix = defaultIPv4Prefix
+	}
+	if a.ipv6Prefix == 0 {
+		a.ipv6Prefix = defaultIPv6Prefix
+	}
+	if a.ipv4Prefix < 0 || a.ipv4Prefix > 32 || a.ipv6Prefix < 0 || a.ipv6Prefix > 128 {
+		return nil, fmt.Errorf("anonymization: invalid prefix lengths /%d and /%d", a.ipv4Prefix, a.ipv6Prefix)
+	}
+	if len(a.key) == 0 {
+		a.key = make([]byte, 32)
+		if _, err := rand.Read(a.key); err != nil {
+			return nil, fmt.Errorf("anonymization: %w", err)
+		}
+	}
+	return a, nil
+}
+
+// apply returns the fields with the configured transforms applied.
+//
+// The input slice is never modified; a copy is made only when a field is changed.
+func (a *anonymizer) apply(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		mode, ok := a.fields[f.Key]
+		if ok {
+			var value string
+			switch f.Type {
+			case zapcore.StringType:
+				value = f.String
+			case zapcore.StringerType:
+				value = f.Interface.(fmt.Stringer).String()
+			default:
+				ok = false
+			}
+			if ok {
+				if out == nil {
+					out = make([]zapcore.Field, i, len(fields))
+					copy(out, fields[:i])
+				}
+				out = append(out, zap.String(f.Key, a.transform(mode, value)))
+				continue
+			}
+		}
+		if out != nil {
+			out = append(out, f)
+		}
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// transform applies mode to a single value.
+func (a *anonymizer) transform(mode Anonymization, value string) string {
+	if value == "" {
+		return value
+	}
+	if mode == AnonymizeHash {
+		mac := hmac.New(sha256.New, a.key)
+		_, _ = mac.Write([]byte(value))
+		return hex.EncodeToString(mac.Sum(nil)[:8])
+	}
+	if addr, err := netip.ParseAddr(value); err == nil {
+		bits := a.ipv6Prefix
+		if addr.Unmap().Is4() {
+			addr, bits = addr.Unmap(), a.ipv4Prefix
+		}
+		prefix, _ := addr.WithZone("").Prefix(bits)
+		return prefix.Addr().String()
+	}
+	if _, rest, ok := strings.Cut(value, "."); ok && strings.Contains(rest, ".") {
+		return rest
+	}
+	return value
+}
+
+// anonymizationCore wraps a core and anonymizes the configured fields.
+type anonymizationCore struct {
+	zapcore.Core
+	a *anonymizer
+}
+
+// newAnonymizationCore wraps core with the transforms described by cfg.
+func newAnonymizationCore(core zapcore.Core, cfg *AnonymizationConfig) (zapcore.Core, error) {
+	a, err := newAnonymizer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &anonymizationCore{Core: core, a: a}, nil
+}
+
+// With anonymizes contextual fields before passing them down.
+func (c *anonymizationCore) With(fields []zapcore.Field) zapcore.Core {
+	return &anonymizationCore{Core: c.Core.With(c.a.apply(fields)), a: c.a}
+}
+
+// Check registers this core so that Write sees every entry.
+func (c *anonymizationCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write anonymizes the entry's fields and writes it to the wrapped core.
+func (c *anonymizationCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return writeChecked(c.Core, ent, c.a.apply(fields))
+}
@@ -0,0 +1,415 @@
+package logger
+
+import (
+	"fmt"
+	"hash/maphash"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of the async mode.
+const (
+	defaultAsyncQueueSize         = 4096
+	defaultAsyncPriorityQueueSize = 1024
+	defaultAsyncFlushInterval     = time.Second
+	asyncDropReportInterval       = 10 * time.Second
+	// asyncProducerSlots is the number of counters tracking the queued entries of each
+	// producer. Producers sharing a counter share their allowance.
+	asyncProducerSlots = 256
+	// asyncWarnReserve is the fraction (1/n) of the regular lane reserved for WARN entries.
+	asyncWarnReserve = 8
+)
+
+// OverflowPolicy selects what happens to regular entries when the regular lane of the
+// async queue is full.
+type OverflowPolicy string
+
+// Supported overflow policies.
+const (
+	// OverflowDrop sheds the entries that do not fit, the default.
+	OverflowDrop OverflowPolicy = "drop"
+	// OverflowDropOldest drops the oldest queued regular entry to make room, so that the
+	// most recent entries are kept.
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowBlock makes logging calls wait for room: no entry is dropped, at the cost
+	// of blocking callers while the outputs are slow.
+	OverflowBlock OverflowPolicy = "block"
+)
+
+// validate reports an unknown policy.
+func (p OverflowPolicy) validate() error {
+	switch p {
+	case "", OverflowDrop, OverflowDropOldest, OverflowBlock:
+		return nil
+	default:
+		return fmt.Errorf("unknown overflow policy %q", p)
+	}
+}
+
+// ingestDelayKey is the field recording how long an entry waited in the async queue.
+const ingestDelayKey = "ingest_delay_ms"
+
+// AsyncConfig makes the outputs be written by a background goroutine, so that logging
+// calls do not wait on disk or network writes.
+//
+// Entries are queued in two lanes. ERROR and above, as well as audit entries, go to the
+// priority lane, which the writer always drains first and which never drops entries:
+// when it is full, logging calls wait. Other entries go to the regular lane; when it is
+// full, Overflow decides whether new entries are shed, the oldest ones are dropped or
+// logging calls wait, so that by default low-priority traffic is dropped first under
+// backpressure and critical entries still get through promptly. Dropped entries are
+// reported on stderr and passed to OnDropped.
+//
+// With OverflowDrop, a single producer (a logging call site, such as a goroutine logging
+// in a tight loop) cannot fill the regular lane on its own: once it has ProducerQueueSize
+// entries queued, its further entries are shed while the other producers' still get
+// through. The last eighth of the lane is reserved for WARN entries, so that a flood of
+// DEBUG and INFO entries does not crowd them out. The other policies use the regular lane
+// as a plain ring buffer.
+//
+// The outputs are flushed whenever the queue is empty, and at least every FlushInterval
+// while it is not, so that entries are not held in memory for long under sustained load.
+//
+// Entries keep the time of the logging call as their timestamp and carry an
+// ingest_delay_ms field with the time spent in the queue.
+//
+// Entries are encoded by the background goroutine: values passed as fields (slices,
+// maps, objects) must not be modified after the logging call. DPANIC, PANIC and FATAL
+// entries are written synchronously after draining the queue, as the process may stop
+// right after them. Sync waits until the queue is drained.
+type AsyncConfig struct {
+	// QueueSize is the capacity of the regular lane. Defaults to 4096 entries.
+	QueueSize int
+	// PriorityQueueSize is the capacity of the priority lane. Defaults to 1024 entries.
+	PriorityQueueSize int
+	// ProducerQueueSize is the maximum number of entries of a single producer in the
+	// regular lane with OverflowDrop. Defaults to a quarter of QueueSize.
+	ProducerQueueSize int
+	// Overflow selects what happens to regular entries when their lane is full. Defaults
+	// to OverflowDrop.
+	Overflow OverflowPolicy
+	// FlushInterval is the maximum time between two flushes of the outputs while the
+	// queue is not empty. Defaults to 1s.
+	FlushInterval time.Duration
+	// OnDropped, when set, is called for every entry dropped from the queue, e.g. to count
+	// drops in a metric. It must be fast and must not log through the same logger.
+	OnDropped func(zapcore.Entry)
+}
+
+// asyncEntry is a queued entry together with the core it was checked against.
+type asyncEntry struct {
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []zapcore.Field
+	// producer is the producer counter of a regular entry, or -1 for priority entries.
+	producer int
+}
+
+// asyncQueue holds the lanes and the background writer shared by all derived cores.
+type asyncQueue struct {
+	high chan asyncEntry
+	low  chan asyncEntry
+
+	// flush writes the batches of the outputs, once the queue is empty or every
+	// flushInterval.
+	flush         func()
+	flushInterval time.Duration
+
+	overflow  OverflowPolicy
+	onDropped func(zapcore.Entry)
+
+	// pending counts queued entries not yet written.
+	pending atomic.Int64
+	dropped atomic.Uint64
+
+	// producers counts the regular entries queued by each producer, by hash.
+	producers   [asyncProducerSlots]atomic.Int32
+	producerMax int32
+	seed        maphash.Seed
+
+	// mu is held for reading while an entry is sent to a lane, and for writing by close
+	// to set closed, so that no entry is sent once the writer may have stopped.
+	mu        sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// newAsyncQueue starts the background writer, which calls flush whenever it has written
+// every queued entry and at least every FlushInterval.
+func newAsyncQueue(cfg *AsyncConfig, flush func()) *asyncQueue {
+	size, prioritySize := cfg.QueueSize, cfg.PriorityQueueSize
+	if size <= 0 {
+		size = defaultAsyncQueueSize
+	}
+	if prioritySize <= 0 {
+		prioritySize = defaultAsyncPriorityQueueSize
+	}
+	producerSize := cfg.ProducerQueueSize
+	if producerSize <= 0 {
+		producerSize = max(size/4, 1)
+	}
+	flushInterval := cfg.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultAsyncFlushInterval
+	}
+	q := &asyncQueue{
+		high:          make(chan asyncEntry, prioritySize),
+		low:           make(chan asyncEntry, size),
+		flush:         flush,
+		flushInterval: flushInterval,
+		overflow:      cfg.Overflow,
+		onDropped:     cfg.OnDropped,
+		producerMax:   int32(producerSize),
+		seed:          maphash.MakeSeed(),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// run writes queued entries, always draining the priority lane first. The outputs are
+// flushed whenever both lanes are empty, so that a burst of entries is written as a
+// single batch, and at least every flushInterval under sustained load.
+func (q *asyncQueue) run() {
+	defer close(q.stopped)
+	labelGoroutine("async")
+	report := time.NewTicker(asyncDropReportInterval)
+	defer report.Stop()
+	flushed := time.Now()
+	for {
+		if time.Since(flushed) >= q.flushInterval {
+			q.flush()
+			flushed = time.Now()
+		}
+		select {
+		case e := <-q.high:
+			q.write(e)
+			continue
+		default:
+		}
+		if len(q.low) == 0 {
+			q.flush()
+			flushed = time.Now()
+		}
+		select {
+		case e := <-q.high:
+			q.write(e)
+		case e := <-q.low:
+			q.write(e)
+		case <-report.C:
+			q.reportDrops()
+		case <-q.done:
+			q.flush()
+			q.reportDrops()
+			return
+		}
+	}
+}
+
+// write writes a single entry through the core it was queued from.
+//
+// The entry keeps its original timestamp; the time it spent queued is recorded as
+// ingest_delay_ms, so consumers can detect buffering delays during backpressure.
+func (q *asyncQueue) write(e asyncEntry) {
+	defer q.pending.Add(-1)
+	if e.producer >= 0 {
+		q.producers[e.producer].Add(-1)
+	}
+	// Check again so that per-output levels apply.
+	if ce := e.core.Check(e.ent, nil); ce != nil {
+		ce.Write(append(e.fields, DurationMS(ingestDelayKey, time.Since(e.ent.Time)))...)
+	}
+}
+
+// enqueue queues an entry, waiting for room in the priority lane and applying the
+// overflow policy to regular entries: by default, they are shed when their lane is full,
+// when their producer has used up its share of the lane, or when only the room reserved
+// for WARN entries is left.
+func (q *asyncQueue) enqueue(e asyncEntry, priority bool) {
+	e.producer = -1
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		// The pipeline is closing: write directly rather than losing the entry.
+		q.pending.Add(1)
+		q.write(e)
+		return
+	}
+	if priority {
+		q.pending.Add(1)
+		q.high <- e
+		return
+	}
+	switch q.overflow {
+	case OverflowBlock:
+		q.pending.Add(1)
+		q.low <- e
+		return
+	case OverflowDropOldest:
+		q.pending.Add(1)
+		for {
+			select {
+			case q.low <- e:
+				return
+			default:
+			}
+			// Make room by dropping the oldest entry, unless the writer took it first.
+			select {
+			case old := <-q.low:
+				q.pending.Add(-1)
+				q.drop(old.ent)
+			default:
+			}
+		}
+	}
+	if e.ent.Level < zapcore.WarnLevel && len(q.low) >= cap(q.low)-cap(q.low)/asyncWarnReserve {
+		q.drop(e.ent)
+		return
+	}
+	producer := q.producer(e.ent)
+	if q.producers[producer].Add(1) > q.producerMax {
+		q.producers[producer].Add(-1)
+		q.drop(e.ent)
+		return
+	}
+	e.producer = producer
+	q.pending.Add(1)
+	select {
+	case q.low <- e:
+	default:
+		q.pending.Add(-1)
+		q.producers[producer].Add(-1)
+		q.drop(e.ent)
+	}
+}
+
+// drop counts a dropped entry and reports it to the OnDropped callback.
+func (q *asyncQueue) drop(ent zapcore.Entry) {
+	q.dropped.Add(1)
+	if q.onDropped != nil {
+		q.onDropped(ent)
+	}
+}
+
+// producer returns the producer counter of an entry, identified by its logger and call
+// site, or by its message when the caller is not recorded.
+func (q *asyncQueue) producer(ent zapcore.Entry) int {
+	var h maphash.Hash
+	h.SetSeed(q.seed)
+	h.WriteString(ent.LoggerName)
+	if ent.Caller.Defined {
+		var pc [8]byte
+		for i := range pc {
+			pc[i] = byte(ent.Caller.PC >> (8 * i))
+		}
+		h.Write(pc[:])
+	} else {
+		h.WriteString(ent.Message)
+	}
+	return int(h.Sum64() % asyncProducerSlots)
+}
+
+// drain waits until every queued entry has been written.
+func (q *asyncQueue) drain() {
+	for q.pending.Load() > 0 {
+		select {
+		case <-q.stopped:
+			return
+		default:
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// reportDrops reports the entries shed since the last report on stderr.
+func (q *asyncQueue) reportDrops() {
+	if n := q.dropped.Swap(0); n > 0 {
+		fmt.Fprintf(os.Stderr, "logger: async queue full, dropped %d entries\n", n)
+	}
+}
+
+// close drains the queue and stops the background writer. Entries logged afterwards are
+// written directly.
+func (q *asyncQueue) close() {
+	q.closeOnce.Do(func() {
+		// Wait for the entries being sent; the writer keeps draining the lanes meanwhile.
+		q.mu.Lock()
+		q.closed = true
+		q.mu.Unlock()
+		q.drain()
+		close(q.done)
+		<-q.stopped
+	})
+}
+
+// asyncCore queues entries for the background writer of its queue.
+type asyncCore struct {
+	core zapcore.Core
+	q    *asyncQueue
+	// priority is set when contextual fields mark every entry as high priority.
+	priority bool
+}
+
+// newAsyncCore wraps core with an async queue and returns the function stopping it. The
+// background writer calls flush to write the batches of the outputs.
+func newAsyncCore(core zapcore.Core, cfg *AsyncConfig, flush func()) (zapcore.Core, func()) {
+	q := newAsyncQueue(cfg, flush)
+	return &asyncCore{core: core, q: q}, q.close
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *asyncCore) Enabled(level zapcore.Level) bool {
+	return c.core.Enabled(level)
+}
+
+// With implements zapcore.Core.
+func (c *asyncCore) With(fields []zapcore.Field) zapcore.Core {
+	return &asyncCore{core: c.core.With(fields), q: c.q, priority: c.priority || isAuditEntry(fields)}
+}
+
+// Check implements zapcore.Core.
+func (c *asyncCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *asyncCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level > zapcore.ErrorLevel {
+		c.q.drain()
+		if ce := c.core.Check(ent, nil); ce != nil {
+			ce.Write(fields...)
+		}
+		return nil
+	}
+	priority := c.priority || ent.Level >= zapcore.ErrorLevel || isAuditEntry(fields)
+	// The caller may reuse the slice once the logging call returns.
+	fields = append([]zapcore.Field(nil), fields...)
+	c.q.enqueue(asyncEntry{core: c.core, ent: ent, fields: fields}, priority)
+	return nil
+}
+
+// Sync waits until the queue is drained and syncs the outputs.
+func (c *asyncCore) Sync() error {
+	c.q.drain()
+	return c.core.Sync()
+}
+
+// isAuditEntry reports whether fields tag the entry with the audit retention class.
+func isAuditEntry(fields []zapcore.Field) bool {
+	for _, f := range fields {
+		if f.Key == retentionKey && f.Type == zapcore.StringType && f.String == string(RetentionAudit) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// attachmentsKey is the context key of the fields attached with Attach.
+type attachmentsKey struct{}
+
+// attachments holds the fields attached to a request, by key in attachment order.
+type attachments struct {
+	mu     sync.Mutex
+	fields []zap.Field
+	// start is the time the collection was installed.
+	start time.Time
+	// timers holds the accumulated durations of the timer fields, by key.
+	timers map[string]time.Duration
+	// summarized is set once LogSummary wrote the summary entry.
+	summarized bool
+}
+
+// set adds a field, replacing the one with the same key. It must be called with mu held.
+func (a *attachments) set(f zap.Field) {
+	if i := a.index(f.Key); i >= 0 {
+		a.fields[i] = f
+	} else {
+		a.fields = append(a.fields, f)
+	}
+}
+
+// index returns the position of the field with the given key, or -1. It must be called
+// with mu held.
+func (a *attachments) index(key string) int {
+	return slices.IndexFunc(a.fields, func(f zap.Field) bool { return f.Key == key })
+}
+
+// WithAttachments returns a copy of ctx collecting the fields attached with Attach, for
+// code logging its own summary entries. The HTTP middleware and the gRPC interceptors
+// install it for every request. ctx is returned unchanged if it already collects them.
+func WithAttachments(ctx context.Context) context.Context {
+	if attachmentsFrom(ctx) != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, attachmentsKey{}, &attachments{start: time.Now()})
+}
+
+// attachmentsFrom returns the attachments collected by ctx, or nil.
+func attachmentsFrom(ctx context.Context) *attachments {
+	a, _ := ctx.Value(attachmentsKey{}).(*attachments)
+	return a
+}
+
+// Attach adds fields to the summary entry of the request carried by ctx: the access-log
+// entry of the HTTP middleware, the entry of a gRPC server call, or the entry of a
+// recovered panic. Deep call sites can then contribute context, such as query counts or
+// cache hits, without passing loggers around. A field replaces the one attached earlier
+// with the same key. Attach is safe for concurrent use, and is a no-op when ctx does not
+// collect attachments (see WithAttachments).
+//
+// Example:
+//
+//	func (r *Repo) Find(ctx context.Context, id string) (Order, error) {
+//	    if o, ok := r.cache.Get(id); ok {
+//	        logger.Attach(ctx, zap.Bool("cache_hit", true))
+//	        return o, nil
+//	    }
+//	    ...
+//	}
+func Attach(ctx context.Context, fields ...zap.Field) {
+	a := attachmentsFrom(ctx)
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, f := range fields {
+		a.set(f)
+	}
+}
+
+// Attachments returns the fields attached to ctx with Attach, to be merged into a summary
+// entry.
+func Attachments(ctx context.Context) []zap.Field {
+	a := attachmentsFrom(ctx)
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]zap.Field(nil), a.fields...)
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Built-in backends (see Config.Backend).
+const (
+	// BackendZap encodes and writes entries with zap, the default.
+	BackendZap = "zap"
+	// BackendSlog encodes and writes entries with the handlers of log/slog.
+	BackendSlog = "slog"
+)
+
+// BackendOutput is an output handed to a backend: its opened destination, its level and
+// its encoding.
+type BackendOutput struct {
+	// Writer is the destination, with the fsync policy, buffering, encryption and budget
+	// of the output applied.
+	Writer zapcore.WriteSyncer
+	// Level is the minimum level of the output.
+	Level zapcore.LevelEnabler
+	// Encoding is the encoding of the output: backends write "json" and "ecs" outputs as
+	// JSON and the other ones in their human-readable format.
+	Encoding string
+}
+
+// BackendFactory returns the core encoding and writing the entries of an output with
+// another logging library. The logger applies the level of the output before the core
+// and syncs the writer after it.
+type BackendFactory func(out BackendOutput) (zapcore.Core, error)
+
+// backendFactories holds the factories registered by name.
+var (
+	backendFactoriesMu sync.RWMutex
+	backendFactories   = map[string]BackendFactory{BackendSlog: newSlogBackend}
+)
+
+// RegisterBackend makes the backend with the given name available to Config.Backend.
+// Shim packages call it from an init function, so importing them is enough to enable
+// their backend. It fails when the name is already registered.
+func RegisterBackend(name string, factory BackendFactory) error {
+	backendFactoriesMu.Lock()
+	defer backendFactoriesMu.Unlock()
+	if _, ok := backendFactories[name]; ok || name == BackendZap || name == "" {
+		return fmt.Errorf("backend %q already registered", name)
+	}
+	backendFactories[name] = factory
+	return nil
+}
+
+// backendFactory returns the factory of the configured backend, nil for zap.
+func (c Config) backendFactory() (BackendFactory, error) {
+	if c.Backend == "" || c.Backend == BackendZap {
+		return nil, nil
+	}
+	backendFactoriesMu.RLock()
+	defer backendFactoriesMu.RUnlock()
+	factory, ok := backendFactories[c.Backend]
+	if !ok {
+		return nil, fmt.Errorf("unknown backend %q", c.Backend)
+	}
+	return factory, nil
+}
+
+// newBackendCore returns the core writing the entries of an output with factory.
+func newBackendCore(factory BackendFactory, out BackendOutput) (zapcore.Core, error) {
+	core, err := factory(out)
+	if err != nil {
+		return nil, err
+	}
+	return &backendCore{Core: core, level: out.Level, out: out.Writer}, nil
+}
+
+// newSlogBackend writes the entries of an output with a slog JSON or text handler.
+func newSlogBackend(out BackendOutput) (zapcore.Core, error) {
+	opts := &slog.HandlerOptions{AddSource: true, Level: slog.LevelDebug}
+	var h slog.Handler
+	switch out.Encoding {
+	case EncodingJSON, EncodingECS:
+		h = slog.NewJSONHandler(out.Writer, opts)
+	default:
+		h = slog.NewTextHandler(out.Writer, opts)
+	}
+	return &slogCore{h: h}, nil
+}
+
+// backendCore applies the level of an output to the core of a backend and syncs the
+// writer of the output.
+type backendCore struct {
+	zapcore.Core
+	level zapcore.LevelEnabler
+	out   zapcore.WriteSyncer
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *backendCore) Enabled(level zapcore.Level) bool {
+	return c.level.Enabled(level)
+}
+
+// With implements zapcore.Core.
+func (c *backendCore) With(fields []zapcore.Field) zapcore.Core {
+	return &backendCore{Core: c.Core.With(fields), level: c.level, out: c.out}
+}
+
+// Check implements zapcore.Core.
+func (c *backendCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return c.Core.Check(ent, ce)
+	}
+	return ce
+}
+
+// writeChecked implements checkedWriter.
+func (c *backendCore) writeChecked(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+	return writeChecked(c.Core, ent, fields)
+}
+
+// Sync implements zapcore.Core.
+func (c *backendCore) Sync() error {
+	if err := c.Core.Sync(); err != nil {
+		return err
+	}
+	return c.out.Sync()
+}
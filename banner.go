@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// logBanner emits a single self-describing "logger initialized" entry summarizing the
+// effective configuration and build information.
+func (l *Logger) logBanner(cfg Config) {
+	l.WithOptions(zap.WithCaller(false)).Info("logger initialized",
+		zap.String("min_level", parseLevel(cfg.Level).CapitalString()),
+		zap.Array("outputs", bannerOutputs(cfg)),
+		zap.Dict("enrichment",
+			zap.String("field_validation", string(cfg.FieldValidation)),
+			zap.Bool("caller_trim_module", cfg.Caller.TrimModule),
+			zap.Strings("caller_trim_prefixes", cfg.Caller.TrimPrefixes),
+			zap.Bool("cgroup", cfg.CgroupEnrichment),
+			zap.Strings("anonymized_fields", cfg.Anonymization.fieldNames()),
+			zap.Strings("redacted_keys", cfg.Redaction.keys()),
+		),
+		zap.Object("build", buildInfo{}),
+	)
+}
+
+// bannerOutputs describes the configured outputs for the startup banner.
+func bannerOutputs(cfg Config) zapcore.ArrayMarshaler {
+	return zapcore.ArrayMarshalerFunc(func(enc zapcore.ArrayEncoder) error {
+		for _, out := range cfg.outputs() {
+			out := out
+			err := enc.AppendObject(zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+				enc.AddString("path", out.displayPath())
+				enc.AddString("encoding", out.encoding(cfg))
+				if out.Level != "" {
+					enc.AddString("min_level", parseLevel(out.Level).CapitalString())
+				}
+				enc.AddBool("mapped", out.Mapping != nil)
+				return nil
+			}))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// buildInfo marshals the Go toolchain and main module build information.
+type buildInfo struct{}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (buildInfo) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("go_version", runtime.Version())
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	enc.AddString("module", info.Main.Path)
+	enc.AddString("version", info.Main.Version)
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			enc.AddString("revision", setting.Value)
+		case "vcs.time":
+			enc.AddString("revision_time", setting.Value)
+		case "vcs.modified":
+			enc.AddString("dirty", setting.Value)
+		}
+	}
+	return nil
+}
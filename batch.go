@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// FsyncPolicy selects when a file output is flushed to stable storage.
+type FsyncPolicy string
+
+// Supported fsync policies.
+const (
+	// FsyncNever leaves flushing to the operating system, except on Sync.
+	FsyncNever FsyncPolicy = "never"
+	// FsyncInterval flushes after a write at most once per OutputConfig.FsyncInterval.
+	FsyncInterval FsyncPolicy = "interval"
+	// FsyncBatch flushes after every write: after every batch in async mode, after every
+	// entry otherwise.
+	FsyncBatch FsyncPolicy = "batch"
+)
+
+// Defaults of batched writes.
+const (
+	defaultFsyncInterval = time.Second
+	// maxBatchBytes bounds the size of a batch: larger batches are written as soon as
+	// they reach it.
+	maxBatchBytes = 256 << 10
+)
+
+// validate reports an unknown policy.
+func (p FsyncPolicy) validate() error {
+	switch p {
+	case "", FsyncNever, FsyncInterval, FsyncBatch:
+		return nil
+	default:
+		return fmt.Errorf("unknown fsync policy %q", p)
+	}
+}
+
+// batchWriter writes the entries of an output in batches and applies its fsync policy.
+//
+// Entries are buffered only when the writer belongs to a batchGroup, whose flush is
+// called by the async writer whenever its queue is empty: a burst of entries then costs
+// a single write system call.
+type batchWriter struct {
+	name     string
+	ws       zapcore.WriteSyncer
+	policy   FsyncPolicy
+	interval time.Duration
+	buffered bool
+
+	mu       sync.Mutex
+	buf      []byte
+	lastSync time.Time
+}
+
+// newBatchWriter wraps ws with the fsync policy of out.
+func newBatchWriter(ws zapcore.WriteSyncer, out OutputConfig, buffered bool) *batchWriter {
+	w := &batchWriter{name: out.path(), ws: ws, policy: out.Fsync, interval: out.FsyncInterval, buffered: buffered, lastSync: time.Now()}
+	if w.interval <= 0 {
+		w.interval = defaultFsyncInterval
+	}
+	return w
+}
+
+// Write implements zapcore.WriteSyncer.
+func (w *batchWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.buffered {
+		n, err := w.ws.Write(p)
+		if err != nil {
+			return n, err
+		}
+		return n, w.fsyncLocked()
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= maxBatchBytes {
+		return len(p), w.flushLocked()
+	}
+	return len(p), nil
+}
+
+// flush writes the buffered batch.
+func (w *batchWriter) flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flushLocked()
+}
+
+// flushLocked writes the buffered batch with a single write. The caller holds mu.
+func (w *batchWriter) flushLocked() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := w.ws.Write(w.buf)
+	if len(w.buf) > maxBatchBytes {
+		// Do not keep an oversized buffer around after a burst.
+		w.buf = nil
+	} else {
+		w.buf = w.buf[:0]
+	}
+	if err != nil {
+		return err
+	}
+	return w.fsyncLocked()
+}
+
+// fsyncLocked applies the fsync policy after a write. The caller holds mu.
+func (w *batchWriter) fsyncLocked() error {
+	switch w.policy {
+	case FsyncBatch:
+		return w.ws.Sync()
+	case FsyncInterval:
+		if now := time.Now(); now.Sub(w.lastSync) >= w.interval {
+			w.lastSync = now
+			return w.ws.Sync()
+		}
+	}
+	return nil
+}
+
+// Sync implements zapcore.WriteSyncer. It writes the buffered batch before syncing.
+func (w *batchWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return errors.Join(w.flushLocked(), w.ws.Sync())
+}
+
+// batchGroup holds the batched writers of a pipeline, flushed together by its async
+// writer. A nil group does not batch.
+type batchGroup struct {
+	writers []*batchWriter
+}
+
+// wrap returns ws wrapped with the batching and fsync policy of out.
+func (g *batchGroup) wrap(ws zapcore.WriteSyncer, out OutputConfig) zapcore.WriteSyncer {
+	if g == nil {
+		if out.Fsync == "" || out.Fsync == FsyncNever {
+			return ws
+		}
+		return newBatchWriter(ws, out, false)
+	}
+	w := newBatchWriter(ws, out, true)
+	g.writers = append(g.writers, w)
+	return w
+}
+
+// flush writes the buffered batches, reporting failures on stderr.
+func (g *batchGroup) flush() {
+	for _, w := range g.writers {
+		if err := w.flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: failed to write batch to %s: %v\n", w.name, err)
+		}
+	}
+}
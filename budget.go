@@ -0,0 +1,287 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// budgetAlertQueueSize bounds the budget alerts waiting to be logged. Alerts are rare (at
+// most four per output and hour), so a full queue only drops alerts of a misbehaving
+// pipeline.
+const budgetAlertQueueSize = 64
+
+// BudgetConfig sets volume budgets on an output, to catch runaway logging before it shows
+// up on the bill of the log backend.
+//
+// The entries and bytes written to the output are counted per hour and per day, in UTC.
+// The first time a count crosses its budget within a period, a WARN "log budget exceeded"
+// entry is logged through the logger, and OnExceeded is called. Entries keep being
+// written: budgets alert, they do not drop.
+//
+// Bytes are counted as encoded, before compression or encryption. Budgets apply to files,
+// standard streams, custom writers and sinks, but not to the built-in Loki and syslog
+// outputs. A zero budget is not enforced. On an output restricted to some categories
+// (OutputConfig.Categories), the budget applies to the entries of these categories.
+//
+// Example:
+//
+//	Budget: &logger.BudgetConfig{
+//	    HourlyBytes: 512 << 20, // 512 MiB
+//	    DailyBytes:  8 << 30,   // 8 GiB
+//	    OnExceeded: func(a logger.BudgetAlert) {
+//	        budgetExceeded.WithLabelValues(a.Output, a.Period, a.Unit).Inc()
+//	    },
+//	}
+type BudgetConfig struct {
+	// HourlyEntries is the maximum number of entries written per hour.
+	HourlyEntries int64
+	// DailyEntries is the maximum number of entries written per day.
+	DailyEntries int64
+	// HourlyBytes is the maximum number of bytes written per hour.
+	HourlyBytes int64
+	// DailyBytes is the maximum number of bytes written per day.
+	DailyBytes int64
+	// OnExceeded, when set, is called when a budget is exceeded, e.g. to count it in a
+	// metric. It must be fast and must not log through the same logger.
+	OnExceeded func(BudgetAlert)
+}
+
+// BudgetAlert describes a budget exceeded by an output.
+type BudgetAlert struct {
+	// Output is the destination of the output, e.g. its path.
+	Output string
+	// Period is "hour" or "day".
+	Period string
+	// Unit is "entries" or "bytes".
+	Unit string
+	// Limit is the budget of the period.
+	Limit int64
+	// Used is the count when the budget was exceeded.
+	Used int64
+	// Start is the start of the period, in UTC.
+	Start time.Time
+}
+
+// budgetPeriod counts the entries and bytes written during an hour or a day.
+type budgetPeriod struct {
+	name    string
+	length  time.Duration
+	entries int64
+	bytes   int64
+	limits  [2]int64
+	start   time.Time
+	alerted [2]bool
+}
+
+// Indices of the counts of a budget period.
+const (
+	budgetEntries = iota
+	budgetBytes
+)
+
+// budgetUnits names the counts of a budget period.
+var budgetUnits = [2]string{"entries", "bytes"}
+
+// roll starts a new period if now is past the current one.
+func (p *budgetPeriod) roll(now time.Time) {
+	start := now.UTC().Truncate(p.length)
+	if start.Equal(p.start) {
+		return
+	}
+	p.start, p.entries, p.bytes, p.alerted = start, 0, 0, [2]bool{}
+}
+
+// add counts an entry of n bytes and appends the alerts for the budgets it exceeds.
+func (p *budgetPeriod) add(n int, output string, alerts []BudgetAlert) []BudgetAlert {
+	p.entries++
+	p.bytes += int64(n)
+	for unit, used := range [2]int64{p.entries, p.bytes} {
+		limit := p.limits[unit]
+		if limit <= 0 || used <= limit || p.alerted[unit] {
+			continue
+		}
+		p.alerted[unit] = true
+		alerts = append(alerts, BudgetAlert{
+			Output: output,
+			Period: p.name,
+			Unit:   budgetUnits[unit],
+			Limit:  limit,
+			Used:   used,
+			Start:  p.start,
+		})
+	}
+	return alerts
+}
+
+// budgetTracker counts the writes to an output against its budgets.
+type budgetTracker struct {
+	output     string
+	onExceeded func(BudgetAlert)
+	reporter   *budgetReporter
+
+	mu   sync.Mutex
+	hour budgetPeriod
+	day  budgetPeriod
+}
+
+// record counts an entry of n bytes and reports the budgets it exceeds.
+func (t *budgetTracker) record(n int) {
+	var buf [4]BudgetAlert
+	now := time.Now()
+	t.mu.Lock()
+	t.hour.roll(now)
+	t.day.roll(now)
+	alerts := t.hour.add(n, t.output, buf[:0])
+	alerts = t.day.add(n, t.output, alerts)
+	t.mu.Unlock()
+	for _, alert := range alerts {
+		if t.onExceeded != nil {
+			t.onExceeded(alert)
+		}
+		t.reporter.report(alert)
+	}
+}
+
+// budgetWriter counts the entries written to a destination. Each write is an entry.
+type budgetWriter struct {
+	zapcore.WriteSyncer
+	tracker *budgetTracker
+}
+
+// Write implements zapcore.WriteSyncer.
+func (w *budgetWriter) Write(p []byte) (int, error) {
+	n, err := w.WriteSyncer.Write(p)
+	w.tracker.record(n)
+	return n, err
+}
+
+// budgetSink counts the entries written to a sink.
+type budgetSink struct {
+	Sink
+	tracker *budgetTracker
+}
+
+// Write implements Sink.
+func (s *budgetSink) Write(e Entry, encoded []byte) error {
+	err := s.Sink.Write(e, encoded)
+	s.tracker.record(len(encoded))
+	return err
+}
+
+// budgetReporter logs the budget alerts of the outputs of a pipeline.
+//
+// Alerts are raised while writing to an output, possibly holding its lock, so they are
+// logged from a separate goroutine.
+type budgetReporter struct {
+	alerts  chan BudgetAlert
+	core    zapcore.Core
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// newBudgetReporter returns a reporter if any of the outputs has a budget, nil otherwise.
+func newBudgetReporter(outputs []OutputConfig) *budgetReporter {
+	for _, out := range outputs {
+		if out.Budget != nil {
+			return &budgetReporter{
+				alerts:  make(chan BudgetAlert, budgetAlertQueueSize),
+				done:    make(chan struct{}),
+				stopped: make(chan struct{}),
+			}
+		}
+	}
+	return nil
+}
+
+// tracker returns the tracker of the budgets of out, or nil if it has none.
+func (r *budgetReporter) tracker(out OutputConfig, output string) *budgetTracker {
+	if r == nil || out.Budget == nil {
+		return nil
+	}
+	b := out.Budget
+	return &budgetTracker{
+		output:     output,
+		onExceeded: b.OnExceeded,
+		reporter:   r,
+		hour:       budgetPeriod{name: "hour", length: time.Hour, limits: [2]int64{b.HourlyEntries, b.HourlyBytes}},
+		day:        budgetPeriod{name: "day", length: 24 * time.Hour, limits: [2]int64{b.DailyEntries, b.DailyBytes}},
+	}
+}
+
+// wrap returns ws counting the writes against the budgets of out.
+func (r *budgetReporter) wrap(ws zapcore.WriteSyncer, out OutputConfig) zapcore.WriteSyncer {
+	if t := r.tracker(out, out.displayPath()); t != nil {
+		return &budgetWriter{WriteSyncer: ws, tracker: t}
+	}
+	return ws
+}
+
+// wrapSink returns sink counting the writes against the budgets of out.
+func (r *budgetReporter) wrapSink(sink Sink, out OutputConfig) Sink {
+	if t := r.tracker(out, maskURL(sink.String())); t != nil {
+		return &budgetSink{Sink: sink, tracker: t}
+	}
+	return sink
+}
+
+// report queues an alert to be logged, dropping it if the queue is full.
+func (r *budgetReporter) report(alert BudgetAlert) {
+	select {
+	case r.alerts <- alert:
+	default:
+		fmt.Fprintf(os.Stderr, "logger: %s budget of %s exceeded (%d %s)\n", alert.Period, alert.Output, alert.Limit, alert.Unit)
+	}
+}
+
+// start logs the alerts through core until close is called.
+func (r *budgetReporter) start(core zapcore.Core) {
+	r.core = core
+	go r.run()
+}
+
+// run logs the alerts as they are raised.
+func (r *budgetReporter) run() {
+	defer close(r.stopped)
+	labelGoroutine("budget")
+	for {
+		select {
+		case alert := <-r.alerts:
+			r.log(alert)
+		case <-r.done:
+			for {
+				select {
+				case alert := <-r.alerts:
+					r.log(alert)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// log writes the entry of an alert.
+func (r *budgetReporter) log(alert BudgetAlert) {
+	writeReport(r.core, zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Now(), Message: "log budget exceeded"}, []zapcore.Field{
+		zap.String("output", alert.Output),
+		zap.String("budget_period", alert.Period),
+		zap.String("budget_unit", alert.Unit),
+		zap.Int64("budget_limit", alert.Limit),
+		zap.Int64("budget_used", alert.Used),
+		zap.Time("budget_period_start", alert.Start),
+	})
+}
+
+// close logs the pending alerts and stops the reporter.
+func (r *budgetReporter) close() {
+	r.once.Do(func() {
+		close(r.done)
+		<-r.stopped
+	})
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// CallerConfig controls how caller locations are rendered.
+//
+// By default zap's short form ("package/file.go:42") is used, which is ambiguous in
+// monorepos with many identically named files. Trimming to the module root yields
+// unambiguous yet compact locations such as "internal/billing/util/util.go:42".
+type CallerConfig struct {
+	// TrimModule renders caller paths relative to the root of the module that
+	// contains them.
+	TrimModule bool
+	// TrimPrefixes lists path prefixes stripped from caller paths. The first
+	// matching prefix wins and takes precedence over TrimModule.
+	TrimPrefixes []string
+}
+
+// enabled reports whether any trimming is configured.
+func (c CallerConfig) enabled() bool {
+	return c.TrimModule || len(c.TrimPrefixes) > 0
+}
+
+// encoder returns a caller encoder implementing the configured trimming.
+func (c CallerConfig) encoder() zapcore.CallerEncoder {
+	trimmer := &callerTrimmer{prefixes: c.TrimPrefixes, module: c.TrimModule}
+	if c.TrimModule {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Path != "" {
+			trimmer.modulePath = info.Main.Path + "/"
+		}
+	}
+	return func(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+		if !caller.Defined {
+			enc.AppendString("undefined")
+			return
+		}
+		enc.AppendString(trimmer.trim(caller))
+	}
+}
+
+// callerTrimmer renders caller paths relative to configured prefixes or module roots.
+type callerTrimmer struct {
+	prefixes   []string
+	module     bool
+	modulePath string
+	// roots caches the module root of each source directory ("" when unknown).
+	roots sync.Map
+}
+
+// trim returns the rendered "path:line" location of the caller.
+func (t *callerTrimmer) trim(caller zapcore.EntryCaller) string {
+	file := caller.File
+	for _, prefix := range t.prefixes {
+		if strings.HasPrefix(file, prefix) {
+			return location(strings.TrimPrefix(strings.TrimPrefix(file, prefix), "/"), caller.Line)
+		}
+	}
+	if t.module {
+		// Binaries built with -trimpath report import paths rather than file system paths.
+		if t.modulePath != "" && strings.HasPrefix(file, t.modulePath) {
+			return location(strings.TrimPrefix(file, t.modulePath), caller.Line)
+		}
+		if root := t.moduleRoot(filepath.Dir(file)); root != "" {
+			return location(strings.TrimPrefix(file, root+"/"), caller.Line)
+		}
+	}
+	return caller.TrimmedPath()
+}
+
+// moduleRoot returns the closest ancestor of dir containing a go.mod file.
+//
+// Lookups hit the file system once per directory; results are cached.
+func (t *callerTrimmer) moduleRoot(dir string) string {
+	if root, ok := t.roots.Load(dir); ok {
+		return root.(string)
+	}
+	root := ""
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			root = d
+			break
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			break
+		}
+		d = parent
+	}
+	t.roots.Store(dir, root)
+	return root
+}
+
+// location formats a file and line the way zap's caller encoders do.
+func location(file string, line int) string {
+	return file + ":" + strconv.Itoa(line)
+}
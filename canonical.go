@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Count adds n to the counter key of the summary entry of the request carried by ctx,
+// e.g. the number of database queries or of retried calls. Counters start at zero and
+// are written as integers. Like Attach, Count is safe for concurrent use and is a no-op
+// when ctx does not collect attachments.
+//
+// Example:
+//
+//	logger.Count(ctx, "db_queries", 1)
+func Count(ctx context.Context, key string, n int64) {
+	a := attachmentsFrom(ctx)
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if i := a.index(key); i >= 0 && a.fields[i].Type == zapcore.Int64Type {
+		n += a.fields[i].Integer
+	}
+	a.set(zap.Int64(key, n))
+}
+
+// AddDuration adds d to the timer key of the summary entry of the request carried by ctx.
+// Timers accumulate across calls and are written as float milliseconds, like DurationMS,
+// so keys should end in _ms. AddDuration is a no-op when ctx does not collect attachments.
+//
+// Example:
+//
+//	logger.AddDuration(ctx, "db_ms", time.Since(start))
+func AddDuration(ctx context.Context, key string, d time.Duration) {
+	a := attachmentsFrom(ctx)
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.timers == nil {
+		a.timers = make(map[string]time.Duration)
+	}
+	a.timers[key] += d
+	a.set(DurationMS(key, a.timers[key]))
+}
+
+// StartTimer starts timing an operation for the timer key of the summary entry of the
+// request carried by ctx. The returned function stops the timer and adds the elapsed time
+// with AddDuration; it is meant to be deferred.
+//
+// Example:
+//
+//	defer logger.StartTimer(ctx, "render_ms")()
+func StartTimer(ctx context.Context, key string) (stop func()) {
+	start := time.Now()
+	return func() { AddDuration(ctx, key, time.Since(start)) }
+}
+
+// LogSummary writes the canonical log line of the unit of work carried by ctx: one INFO
+// entry with msg, the given fields, the fields, counters and timers collected since
+// WithAttachments, and the elapsed time in duration_ms unless a field already carries
+// it. It uses the logger carried by ctx. Only the first call for a collection writes an
+// entry, so that an explicit call and a deferred one can coexist.
+//
+// Requests served through HTTPMiddleware and the loggrpc interceptors already have their
+// summary: the access-log entry. LogSummary is for the other units of work, such as jobs
+// or queue messages.
+//
+// Example:
+//
+//	func (w *Worker) Handle(ctx context.Context, msg Message) error {
+//	    ctx = logger.WithAttachments(ctx)
+//	    defer logger.LogSummary(ctx, "message handled", zap.String("message_id", msg.ID))
+//	    ...
+//	}
+func LogSummary(ctx context.Context, msg string, fields ...zap.Field) {
+	a := attachmentsFrom(ctx)
+	if a != nil {
+		a.mu.Lock()
+		if a.summarized {
+			a.mu.Unlock()
+			return
+		}
+		a.summarized = true
+		a.mu.Unlock()
+	}
+	fields = append(fields, Attachments(ctx)...)
+	if a != nil && !slices.ContainsFunc(fields, func(f zap.Field) bool { return f.Key == "duration_ms" }) {
+		fields = append(fields, Latency(time.Since(a.start)))
+	}
+	FromContext(ctx).skipped().Info(msg, fields...)
+}
@@ -0,0 +1,194 @@
+package logger
+
+import (
+	"bytes"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// defaultContinuation matches indented lines, which covers most stack traces
+// (Java "\tat ...", Python "  File ...", Go "\t/path/file.go:42").
+var defaultContinuation = regexp.MustCompile(`^[ \t]`)
+
+// defaultMaxFoldedLines bounds the number of lines folded into a single entry.
+const defaultMaxFoldedLines = 200
+
+// CaptureOptions configures how external output is turned into log entries.
+//
+// Lines are folded into a single entry until a new entry starts. A new entry starts on a
+// line matching StartPattern when it is set; otherwise, on any line that does not match
+// ContinuationPattern.
+type CaptureOptions struct {
+	// Level is the level of the emitted entries. Defaults to INFO.
+	Level LogLevel
+	// StartPattern matches the first line of a new entry (e.g. `^\d{4}-\d{2}-\d{2}`).
+	StartPattern *regexp.Regexp
+	// ContinuationPattern matches lines belonging to the previous entry.
+	// Defaults to indented lines. Ignored when StartPattern is set.
+	ContinuationPattern *regexp.Regexp
+	// MaxLines bounds the number of lines folded into one entry. Defaults to 200.
+	MaxLines int
+	// FlushAfter emits a pending entry after this much idle time. Zero waits for the
+	// next entry or Close.
+	FlushAfter time.Duration
+	// Fields are attached to every emitted entry.
+	Fields []zap.Field
+}
+
+// CaptureWriter is an io.WriteCloser turning captured output into structured entries.
+//
+// The first line of each folded entry becomes the message; subsequent lines are attached
+// as the `detail` field, so stack traces and multi-line SQL arrive as one entry instead
+// of one broken entry per line.
+type CaptureWriter struct {
+	log  *zap.Logger
+	opts CaptureOptions
+
+	mu      sync.Mutex
+	partial []byte
+	pending []string
+	timer   *time.Timer
+}
+
+// CaptureWriter returns a writer logging captured output through l.
+//
+// Example:
+//
+//	w := log.CaptureWriter(logger.CaptureOptions{Level: logger.LevelWarn})
+//	defer w.Close()
+//	legacyLib.SetOutput(w)
+func (l *Logger) CaptureWriter(opts CaptureOptions) *CaptureWriter {
+	if opts.ContinuationPattern == nil {
+		opts.ContinuationPattern = defaultContinuation
+	}
+	if opts.MaxLines <= 0 {
+		opts.MaxLines = defaultMaxFoldedLines
+	}
+	return &CaptureWriter{log: l.Logger.WithOptions(zap.WithCaller(false)), opts: opts}
+}
+
+// Write splits p into lines and folds them into entries.
+func (w *CaptureWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.addLine(strings.TrimSuffix(string(w.partial[:i]), "\r"))
+		w.partial = w.partial[i+1:]
+	}
+	w.armTimer()
+	return len(p), nil
+}
+
+// Close emits any pending entry, including an unterminated last line.
+func (w *CaptureWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.addLine(string(w.partial))
+		w.partial = nil
+	}
+	w.flush()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	return nil
+}
+
+// addLine folds a line into the pending entry or starts a new one.
+func (w *CaptureWriter) addLine(line string) {
+	if len(w.pending) > 0 && !w.startsEntry(line) && len(w.pending) < w.opts.MaxLines {
+		w.pending = append(w.pending, line)
+		return
+	}
+	w.flush()
+	if strings.TrimSpace(line) != "" {
+		w.pending = append(w.pending, line)
+	}
+}
+
+// startsEntry reports whether line is the first line of a new entry.
+func (w *CaptureWriter) startsEntry(line string) bool {
+	if w.opts.StartPattern != nil {
+		return w.opts.StartPattern.MatchString(line)
+	}
+	return !w.opts.ContinuationPattern.MatchString(line)
+}
+
+// flush emits the pending entry, if any.
+func (w *CaptureWriter) flush() {
+	if len(w.pending) == 0 {
+		return
+	}
+	ce := w.log.Check(parseLevel(w.opts.Level), w.pending[0])
+	if ce != nil {
+		fields := w.opts.Fields
+		if len(w.pending) > 1 {
+			fields = append(fields[:len(fields):len(fields)],
+				zap.String("detail", strings.Join(w.pending[1:], "\n")),
+				zap.Int("line_count", len(w.pending)),
+			)
+		}
+		ce.Write(fields...)
+	}
+	w.pending = w.pending[:0]
+}
+
+// armTimer schedules an idle flush of the pending entry.
+func (w *CaptureWriter) armTimer() {
+	if w.opts.FlushAfter <= 0 || len(w.pending) == 0 {
+		return
+	}
+	if w.timer == nil {
+		w.timer = time.AfterFunc(w.opts.FlushAfter, func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			w.flush()
+		})
+		return
+	}
+	w.timer.Reset(w.opts.FlushAfter)
+}
+
+// CaptureCommand routes the standard output and error of cmd through l.
+//
+// Standard output is logged at opts.Level and standard error at WARN (or opts.Level if
+// higher); both carry a `stream` field. The returned function must be called after the
+// command exits to emit any pending entries.
+//
+// Example:
+//
+//	cmd := exec.Command("pg_dump", "app")
+//	done := log.CaptureCommand(cmd, logger.CaptureOptions{})
+//	err := cmd.Run()
+//	done()
+func (l *Logger) CaptureCommand(cmd *exec.Cmd, opts CaptureOptions) (done func()) {
+	stdoutOpts := opts
+	stdoutOpts.Fields = append(opts.Fields[:len(opts.Fields):len(opts.Fields)], zap.String("stream", "stdout"))
+	stdout := l.CaptureWriter(stdoutOpts)
+
+	stderrOpts := opts
+	stderrOpts.Fields = append(opts.Fields[:len(opts.Fields):len(opts.Fields)], zap.String("stream", "stderr"))
+	if parseLevel(opts.Level) < zapcore.WarnLevel {
+		stderrOpts.Level = LevelWarn
+	}
+	stderr := l.CaptureWriter(stderrOpts)
+
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	return func() {
+		_ = stdout.Close()
+		_ = stderr.Close()
+	}
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Correlation keys written by Inject and read by Extract. They are lowercase and made of
+// characters valid in HTTP headers, gRPC metadata, Kafka record headers and SQS message
+// attribute names alike, and follow the conventions of other languages' libraries (W3C
+// Trace Context and the X-Request-ID header), so that the correlation of a request
+// survives any sequence of transport hops.
+const (
+	CarrierRequestID   = "x-request-id"
+	CarrierTraceparent = "traceparent"
+	CarrierTracestate  = "tracestate"
+)
+
+// Carrier is a set of string key/value pairs sent along with a request or message: HTTP
+// headers, gRPC metadata, Kafka record headers, SQS message attributes, ...
+type Carrier interface {
+	// Get returns the value of key, or "" when it is not set.
+	Get(key string) string
+	// Set sets the value of key.
+	Set(key, value string)
+}
+
+// HeaderCarrier adapts HTTP headers to Carrier.
+type HeaderCarrier http.Header
+
+// Get implements Carrier.
+func (c HeaderCarrier) Get(key string) string {
+	return http.Header(c).Get(key)
+}
+
+// Set implements Carrier.
+func (c HeaderCarrier) Set(key, value string) {
+	http.Header(c).Set(key, value)
+}
+
+// MapCarrier adapts a map to Carrier, for transports whose headers are converted from and
+// to a map, such as Kafka record headers and SQS message attributes. Get falls back to a
+// case-insensitive match, as producers in other languages may not use lowercase keys.
+type MapCarrier map[string]string
+
+// Get implements Carrier.
+func (c MapCarrier) Get(key string) string {
+	if v, ok := c[key]; ok {
+		return v
+	}
+	for k, v := range c {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Set implements Carrier.
+func (c MapCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+// Inject writes the correlation of ctx to c: its request ID (see RequestID) and the W3C
+// trace context of its OpenTelemetry span. Keys without a value in ctx are left unset.
+//
+// Example (Kafka):
+//
+//	headers := logger.MapCarrier{}
+//	logger.Inject(ctx, headers)
+//	for k, v := range headers {
+//	    record.Headers = append(record.Headers, kgo.RecordHeader{Key: k, Value: []byte(v)})
+//	}
+func Inject(ctx context.Context, c Carrier) {
+	if id := RequestID(ctx); id != "" {
+		c.Set(CarrierRequestID, id)
+	}
+	if tp := traceparent(ctx); tp != "" {
+		c.Set(CarrierTraceparent, tp)
+		if ts := trace.SpanContextFromContext(ctx).TraceState().String(); ts != "" {
+			c.Set(CarrierTracestate, ts)
+		}
+	}
+}
+
+// Extract returns a copy of ctx carrying the correlation read from c, as written by
+// Inject or by another language's library using the same keys: the request ID, returned
+// by RequestID and attached to the logger of the context as request_id, and the remote
+// span of the trace context, unless ctx already carries a span. Invalid values are
+// ignored.
+//
+// Example (SQS):
+//
+//	attrs := logger.MapCarrier{}
+//	for k, v := range msg.MessageAttributes {
+//	    attrs[k] = aws.ToString(v.StringValue)
+//	}
+//	ctx = logger.Extract(ctx, attrs)
+//	logger.FromContext(ctx).Info("processing message")
+func Extract(ctx context.Context, c Carrier) context.Context {
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		if sc, ok := parseTraceparent(c.Get(CarrierTraceparent)); ok {
+			if ts, err := trace.ParseTraceState(c.Get(CarrierTracestate)); err == nil {
+				sc = sc.WithTraceState(ts)
+			}
+			ctx = trace.ContextWithRemoteSpanContext(ctx, sc)
+		}
+	}
+	if id := c.Get(CarrierRequestID); validRequestID(id) {
+		ctx = context.WithValue(ctx, requestIDKey{}, id)
+		ctx = NewContext(ctx, zap.String("request_id", id))
+	}
+	return ctx
+}
+
+// requestIDKey is the context key of a request ID read by Extract.
+type requestIDKey struct{}
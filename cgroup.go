@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// cgroupRoot is the mount point of the cgroup file system.
+const cgroupRoot = "/sys/fs/cgroup"
+
+// CgroupStats describes the resource limits and usage of the current container.
+//
+// Values that are unlimited or unavailable are zero.
+type CgroupStats struct {
+	// MemoryLimit is the memory limit in bytes.
+	MemoryLimit int64
+	// MemoryUsage is the current memory usage in bytes.
+	MemoryUsage int64
+	// CPUQuota is the CPU limit expressed in cores (e.g. 1.5).
+	CPUQuota float64
+}
+
+// ReadCgroup reads the resource limits and usage of the current cgroup.
+//
+// Both cgroup v2 (unified) and v1 hierarchies are supported. The second return value
+// is false when no cgroup information is available, e.g. outside Linux containers.
+func ReadCgroup() (CgroupStats, bool) {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil {
+		return readCgroupV2()
+	}
+	return readCgroupV1()
+}
+
+// readCgroupV2 reads statistics from the unified hierarchy.
+func readCgroupV2() (CgroupStats, bool) {
+	var stats CgroupStats
+	limit, okLimit := readCgroupInt(filepath.Join(cgroupRoot, "memory.max"))
+	usage, okUsage := readCgroupInt(filepath.Join(cgroupRoot, "memory.current"))
+	stats.MemoryLimit, stats.MemoryUsage = limit, usage
+
+	okCPU := false
+	if raw, err := os.ReadFile(filepath.Join(cgroupRoot, "cpu.max")); err == nil {
+		parts := strings.Fields(string(raw))
+		if len(parts) == 2 && parts[0] != "max" {
+			quota, errQuota := strconv.ParseFloat(parts[0], 64)
+			period, errPeriod := strconv.ParseFloat(parts[1], 64)
+			if errQuota == nil && errPeriod == nil && period > 0 {
+				stats.CPUQuota = quota / period
+			}
+		}
+		okCPU = true
+	}
+	return stats, okLimit || okUsage || okCPU
+}
+
+// readCgroupV1 reads statistics from the legacy per-controller hierarchies.
+func readCgroupV1() (CgroupStats, bool) {
+	var stats CgroupStats
+	limit, okLimit := readCgroupInt(filepath.Join(cgroupRoot, "memory", "memory.limit_in_bytes"))
+	usage, okUsage := readCgroupInt(filepath.Join(cgroupRoot, "memory", "memory.usage_in_bytes"))
+	// cgroup v1 reports "no limit" as a very large page-aligned number.
+	if limit >= 1<<62 {
+		limit = 0
+	}
+	stats.MemoryLimit, stats.MemoryUsage = limit, usage
+
+	quota, okQuota := readCgroupInt(filepath.Join(cgroupRoot, "cpu", "cpu.cfs_quota_us"))
+	period, okPeriod := readCgroupInt(filepath.Join(cgroupRoot, "cpu", "cpu.cfs_period_us"))
+	if okQuota && okPeriod && quota > 0 && period > 0 {
+		stats.CPUQuota = float64(quota) / float64(period)
+	}
+	return stats, okLimit || okUsage || okQuota
+}
+
+// readCgroupInt reads a single integer value from a cgroup file.
+//
+// The literal "max" is reported as zero (unlimited) and considered valid.
+func readCgroupInt(path string) (int64, bool) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	value := strings.TrimSpace(string(raw))
+	if value == "max" {
+		return 0, true
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (s CgroupStats) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddInt64("memory_limit_bytes", s.MemoryLimit)
+	enc.AddInt64("memory_usage_bytes", s.MemoryUsage)
+	enc.AddFloat64("cpu_quota_cores", s.CPUQuota)
+	return nil
+}
+
+// cgroupLimitsField returns a field describing the static container limits,
+// or false when no cgroup information is available.
+func cgroupLimitsField() (zap.Field, bool) {
+	stats, ok := ReadCgroup()
+	if !ok {
+		return zap.Skip(), false
+	}
+	return zap.Dict("cgroup",
+		zap.Int64("memory_limit_bytes", stats.MemoryLimit),
+		zap.Float64("cpu_quota_cores", stats.CPUQuota),
+	), true
+}
+
+// StartHeartbeat logs a periodic "heartbeat" entry until ctx is cancelled.
+//
+// Each entry carries the process uptime and, when available, the current cgroup limits
+// and usage, so memory growth towards an OOM kill is visible directly in the log stream.
+//
+// Example:
+//
+//	go logger.Get().StartHeartbeat(ctx, time.Minute)
+func (l *Logger) StartHeartbeat(ctx context.Context, interval time.Duration) {
+	start := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fields := []zap.Field{DurationMS("uptime_ms", time.Since(start))}
+			if stats, ok := ReadCgroup(); ok {
+				fields = append(fields, zap.Object("cgroup_usage", stats))
+			}
+			l.Info("heartbeat", fields...)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Forwarding headers understood by the client IP resolver.
+const (
+	HeaderForwardedFor = "X-Forwarded-For"
+	HeaderRealIP       = "X-Real-IP"
+)
+
+// ClientIPConfig configures how the HTTP middleware determines the client address.
+//
+// Forwarding headers are only honored when the request comes from a trusted proxy;
+// otherwise the peer address is used, so clients cannot spoof their address by sending
+// the headers themselves. X-Forwarded-For is walked from right to left, skipping trusted
+// proxies, and the first untrusted address is taken as the client.
+type ClientIPConfig struct {
+	// TrustedProxies lists the networks of proxies and load balancers whose forwarding
+	// headers are trusted. When empty, forwarding headers are ignored.
+	TrustedProxies []netip.Prefix
+	// Headers lists the forwarding headers to consult, in order of preference.
+	// Defaults to X-Forwarded-For, then X-Real-IP.
+	Headers []string
+}
+
+// ParseTrustedProxies parses CIDR notations (or single addresses) into prefixes suitable
+// for ClientIPConfig.TrustedProxies.
+//
+// Example:
+//
+//	proxies, err := logger.ParseTrustedProxies("10.0.0.0/8", "192.168.1.10")
+func ParseTrustedProxies(cidrs ...string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			addr, err := netip.ParseAddr(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", cidr, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// ClientIP returns the client address of r according to the configuration.
+func (c ClientIPConfig) ClientIP(r *http.Request) string {
+	peer := remoteIP(r)
+	if !c.trusted(peer) {
+		return peer
+	}
+
+	headers := c.Headers
+	if len(headers) == 0 {
+		headers = []string{HeaderForwardedFor, HeaderRealIP}
+	}
+	for _, header := range headers {
+		var ip string
+		if http.CanonicalHeaderKey(header) == HeaderForwardedFor {
+			ip = c.fromForwardedFor(r.Header.Values(HeaderForwardedFor))
+		} else {
+			ip = parseIP(r.Header.Get(header))
+		}
+		if ip != "" {
+			return ip
+		}
+	}
+	return peer
+}
+
+// fromForwardedFor returns the rightmost untrusted address of the X-Forwarded-For chain,
+// or the leftmost valid address when every hop is trusted.
+func (c ClientIPConfig) fromForwardedFor(values []string) string {
+	var hops []string
+	for _, value := range values {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	leftmost := ""
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := parseIP(hops[i])
+		if ip == "" {
+			// A malformed hop breaks the chain of trust.
+			return ""
+		}
+		if !c.trusted(ip) {
+			return ip
+		}
+		leftmost = ip
+	}
+	return leftmost
+}
+
+// trusted reports whether ip belongs to a trusted proxy network.
+func (c ClientIPConfig) trusted(ip string) bool {
+	if len(c.TrustedProxies) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range c.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIP normalizes a header value to an IP address, or returns "" if it is not one.
+func parseIP(value string) string {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(value, "[]"))
+	if err != nil {
+		return ""
+	}
+	return addr.Unmap().String()
+}
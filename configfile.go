@@ -0,0 +1,407 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FromFile reads a Config from a YAML (.yaml, .yml) or JSON (.json) file, for
+// configurations richer than FromEnv can express: several outputs, per-name levels,
+// sampling, redaction rules, field mappings, ...
+//
+// Keys are the names of the Config fields in snake_case ("service_name",
+// "outputs", "index_prefix"); camelCase and the Go names are accepted as well. Durations
+// are strings such as "10s", the severity of an output is a map or the name of a built-in
+// profile ("syslog", "gcp" or "pagerduty"), and the anonymization key is a string. An
+// empty mapping enables a feature with its defaults, e.g. "sampling: {}".
+//
+// Unknown keys, values of the wrong type and invalid values (levels, encodings, policies,
+// filters, redaction patterns, ...) are errors naming the offending key, all reported at
+// once. Fields holding code, such as Writer, Sink, Processors and callbacks, cannot be
+// set in a file: set them on the returned Config.
+//
+// Example (logger.yaml):
+//
+//	level: INFO
+//	environment: production
+//	service_name: orders
+//	outputs:
+//	  - path: stdout
+//	  - path: /var/log/orders/orders.log
+//	    level: WARN
+//	    rotation: {max_size_mb: 100, max_backups: 5}
+//	    mapping:
+//	      rename: {message: msg, timestamp: ts}
+//	levels: {db: DEBUG}
+//	sampling: {initial: 100, thereafter: 10, tick: 1s}
+//	redaction:
+//	  keys: [iban, ssn]
+//
+// And in main:
+//
+//	cfg, err := logger.FromFile("logger.yaml")
+//	if err != nil {
+//	    panic(err)
+//	}
+//	log, err := logger.New(cfg)
+func FromFile(path string) (Config, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".yaml" && ext != ".yml" && ext != ".json" {
+		return Config{}, fmt.Errorf("config %s: unknown format %q, expected .yaml, .yml or .json", path, ext)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+	var doc any
+	if ext == ".json" {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("config %s: %w", path, err)
+	}
+	var cfg Config
+	d := &configDecoder{}
+	if doc != nil {
+		d.decode(reflect.ValueOf(&cfg).Elem(), doc, "")
+	}
+	cfg.validateFile(d)
+	if len(d.errs) > 0 {
+		return Config{}, fmt.Errorf("config %s: %w", path, errors.Join(d.errs...))
+	}
+	return cfg, nil
+}
+
+var (
+	durationType = reflect.TypeFor[time.Duration]()
+	severityType = reflect.TypeFor[SeverityProfile]()
+	packagePath  = reflect.TypeFor[Config]().PkgPath()
+)
+
+// configDecoder decodes a configuration document into a Config, collecting the errors.
+type configDecoder struct {
+	errs []error
+}
+
+// errorf records an error about the value at path.
+func (d *configDecoder) errorf(path, format string, args ...any) {
+	if path == "" {
+		path = "config"
+	}
+	d.errs = append(d.errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+// decode stores data, a value of a YAML or JSON document, in v. A null value leaves v
+// unchanged.
+func (d *configDecoder) decode(v reflect.Value, data any, path string) {
+	if data == nil {
+		return
+	}
+	switch v.Type() {
+	case durationType:
+		s, ok := data.(string)
+		if !ok {
+			d.errorf(path, "expected a duration such as \"10s\", got %s", describe(data))
+			return
+		}
+		dur, err := time.ParseDuration(s)
+		if err != nil {
+			d.errorf(path, "invalid duration %q", s)
+			return
+		}
+		v.SetInt(int64(dur))
+		return
+	case severityType:
+		if name, ok := data.(string); ok {
+			p, ok := SeverityProfileByName(name)
+			if !ok {
+				d.errorf(path, "unknown severity profile %q, expected syslog, gcp or pagerduty", name)
+				return
+			}
+			v.Set(reflect.ValueOf(p))
+			return
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		d.decode(elem.Elem(), data, path)
+		v.Set(elem)
+	case reflect.Struct:
+		d.decodeStruct(v, data, path)
+	case reflect.Slice:
+		if s, ok := data.(string); ok && v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(s))
+			return
+		}
+		list, ok := data.([]any)
+		if !ok {
+			d.errorf(path, "expected a list, got %s", describe(data))
+			return
+		}
+		s := reflect.MakeSlice(v.Type(), len(list), len(list))
+		for i, item := range list {
+			d.decode(s.Index(i), item, fmt.Sprintf("%s[%d]", path, i))
+		}
+		v.Set(s)
+	case reflect.Map:
+		m, ok := data.(map[string]any)
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			d.errorf(path, "expected a mapping, got %s", describe(data))
+			return
+		}
+		out := reflect.MakeMapWithSize(v.Type(), len(m))
+		for _, k := range sortedKeys(m) {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			d.decode(elem, m[k], joinPath(path, k))
+			out.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), elem)
+		}
+		v.Set(out)
+	case reflect.Interface:
+		if v.NumMethod() > 0 {
+			d.errorf(path, "cannot be set in a file, set it in code")
+			return
+		}
+		v.Set(reflect.ValueOf(plainValue(data)))
+	case reflect.String:
+		s, ok := data.(string)
+		if !ok {
+			d.errorf(path, "expected a string, got %s", describe(data))
+			return
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := data.(bool)
+		if !ok {
+			d.errorf(path, "expected true or false, got %s", describe(data))
+			return
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := integer(data)
+		if !ok || v.OverflowInt(n) {
+			d.errorf(path, "expected an integer, got %s", describe(data))
+			return
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := integer(data)
+		if !ok || n < 0 || v.OverflowUint(uint64(n)) {
+			d.errorf(path, "expected a non-negative integer, got %s", describe(data))
+			return
+		}
+		v.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, ok := number(data)
+		if !ok {
+			d.errorf(path, "expected a number, got %s", describe(data))
+			return
+		}
+		v.SetFloat(f)
+	default:
+		d.errorf(path, "cannot be set in a file, set it in code")
+	}
+}
+
+// decodeStruct stores a mapping in the fields of a struct of this package. Keys match
+// field names regardless of case, underscores and dashes.
+func (d *configDecoder) decodeStruct(v reflect.Value, data any, path string) {
+	if v.Type().PkgPath() != packagePath {
+		d.errorf(path, "cannot be set in a file, set it in code")
+		return
+	}
+	m, ok := data.(map[string]any)
+	if !ok {
+		d.errorf(path, "expected a mapping, got %s", describe(data))
+		return
+	}
+	for _, k := range sortedKeys(m) {
+		field, ok := v.Type().FieldByNameFunc(func(name string) bool {
+			return strings.EqualFold(name, strings.NewReplacer("_", "", "-", "").Replace(k))
+		})
+		if !ok || !field.IsExported() {
+			d.errorf(joinPath(path, k), "unknown key")
+			continue
+		}
+		d.decode(v.FieldByIndex(field.Index), m[k], joinPath(path, k))
+	}
+}
+
+// validateFile records the invalid values of a configuration read by FromFile, which
+// New would otherwise replace with defaults or only report when building the outputs.
+func (c Config) validateFile(d *configDecoder) {
+	checkLevel(d, "level", c.Level)
+	for name, level := range c.Levels {
+		checkLevel(d, joinPath("levels", name), level)
+	}
+	checkOneOf(d, "field_validation", c.FieldValidation, ValidationOff, ValidationWarn, ValidationStrict)
+	checkOneOf(d, "retention", c.Retention, "", RetentionHot, RetentionWarm, RetentionAudit)
+	checkOneOf(d, "secret_check", c.SecretCheck, SecretCheckReject, SecretCheckMask, SecretCheckOff)
+	if c.Diagnostics != nil {
+		checkLevel(d, "diagnostics.stacktrace_level", c.Diagnostics.StacktraceLevel)
+		checkOneOf(d, "diagnostics.dpanic", c.Diagnostics.DPanic, DPanicDefault, DPanicPanic, DPanicLog)
+	}
+	if c.Anonymization != nil {
+		for name, a := range c.Anonymization.Fields {
+			checkOneOf(d, joinPath("anonymization.fields", name), a, AnonymizeHash, AnonymizeTruncate)
+		}
+	}
+	if c.Redaction != nil {
+		for i, pattern := range c.Redaction.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				d.errorf(fmt.Sprintf("redaction.patterns[%d]", i), "%v", err)
+			}
+		}
+	}
+	if c.Async != nil {
+		if err := c.Async.Overflow.validate(); err != nil {
+			d.errorf("async.overflow", "%v", err)
+		}
+	}
+	for i, o := range c.Outputs {
+		path := fmt.Sprintf("outputs[%d]", i)
+		checkLevel(d, path+".level", o.Level)
+		checkOneOf(d, path+".encoding", o.Encoding, "", EncodingJSON, EncodingECS, EncodingGCP, EncodingConsole, EncodingLogfmt, EncodingPlain)
+		if err := o.Fsync.validate(); err != nil {
+			d.errorf(path+".fsync", "%v", err)
+		}
+		if o.Filter != "" {
+			if _, err := compileFilter(o.Filter); err != nil {
+				d.errorf(path+".filter", "%v", err)
+			}
+		}
+	}
+}
+
+// checkLevel records an error when level is set and is not a level name.
+func checkLevel(d *configDecoder, path string, level LogLevel) {
+	if level == "" {
+		return
+	}
+	levels := []LogLevel{LevelDebug, LevelInfo, LevelWarn, LevelError}
+	if !slices.Contains(levels, LogLevel(strings.ToUpper(string(level)))) {
+		d.errorf(path, "unknown level %q, expected DEBUG, INFO, WARN or ERROR", level)
+	}
+}
+
+// checkOneOf records an error when value is not one of the allowed values.
+func checkOneOf[T ~string](d *configDecoder, path string, value T, allowed ...T) {
+	if slices.Contains(allowed, value) {
+		return
+	}
+	var names []string
+	for _, a := range allowed {
+		if a != "" {
+			names = append(names, fmt.Sprintf("%q", a))
+		}
+	}
+	d.errorf(path, "unknown value %q, expected one of %s", value, strings.Join(names, ", "))
+}
+
+// joinPath returns the path of a key of the mapping at path.
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// sortedKeys returns the keys of a mapping in order, so that errors are reported in a
+// stable order.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// integer returns the value of an integral number of a document.
+func integer(data any) (int64, bool) {
+	switch n := data.(type) {
+	case int:
+		return int64(n), true
+	case int64:
+		return n, true
+	case uint64:
+		return int64(n), n <= 1<<63-1
+	case float64:
+		return int64(n), n == float64(int64(n))
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// number returns the value of a number of a document.
+func number(data any) (float64, bool) {
+	switch n := data.(type) {
+	case int, int64, uint64:
+		i, _ := integer(n)
+		return float64(i), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// plainValue converts the JSON numbers of a value to int64 or float64, for fields of
+// type any such as the fields added by a mapping.
+func plainValue(data any) any {
+	switch v := data.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i := range v {
+			v[i] = plainValue(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = plainValue(v[k])
+		}
+	}
+	return data
+}
+
+// describe names the type of a value of a document in errors.
+func describe(data any) string {
+	switch v := data.(type) {
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case bool:
+		return fmt.Sprintf("%t", v)
+	case int, int64, uint64, float64, json.Number:
+		return fmt.Sprintf("number %v", v)
+	case []any:
+		return "a list"
+	case map[string]any:
+		return "a mapping"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
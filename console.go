@@ -0,0 +1,147 @@
+package logger
+
+import (
+	"os"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/term"
+)
+
+// Console alignment settings.
+const (
+	// alignedConsoleWidth is the terminal width from which console columns are aligned.
+	alignedConsoleWidth = 100
+	// consoleLevelWidth and consoleCallerWidth are the widths of the aligned columns.
+	consoleLevelWidth  = 5
+	consoleCallerWidth = 28
+)
+
+// consoleLevelColors maps levels to ANSI color codes, as zap's colored level encoder.
+var consoleLevelColors = map[zapcore.Level]string{
+	zapcore.DebugLevel:  "\x1b[35m",
+	zapcore.InfoLevel:   "\x1b[34m",
+	zapcore.WarnLevel:   "\x1b[33m",
+	zapcore.ErrorLevel:  "\x1b[31m",
+	zapcore.DPanicLevel: "\x1b[31m",
+	zapcore.PanicLevel:  "\x1b[31m",
+	zapcore.FatalLevel:  "\x1b[31m",
+}
+
+// consoleCapabilities describes what the destination of a console output can display.
+type consoleCapabilities struct {
+	// color is set when ANSI colors are rendered.
+	color bool
+	// width is the width of the terminal in columns, or 0 when it is not a terminal.
+	width int
+}
+
+// detectConsole returns the capabilities of the destination of out.
+//
+// Colors are only used on terminals, except on dumb ones and when NO_COLOR is set; on
+// Windows, virtual terminal processing must also be enabled on the console. FORCE_COLOR
+// or CLICOLOR_FORCE enable them on the standard streams even when they are not terminals,
+// e.g. in IDE run consoles that render ANSI colors. Files and other writers never get
+// colors.
+func detectConsole(out OutputConfig) consoleCapabilities {
+	f := out.consoleFile()
+	if f == nil {
+		return consoleCapabilities{}
+	}
+	var caps consoleCapabilities
+	terminal := term.IsTerminal(int(f.Fd()))
+	if terminal {
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil {
+			caps.width = width
+		}
+	}
+	switch {
+	case os.Getenv("NO_COLOR") != "":
+	case envEnabled("FORCE_COLOR") || envEnabled("CLICOLOR_FORCE"):
+		caps.color = true
+	case !terminal || os.Getenv("TERM") == "dumb":
+	default:
+		caps.color = enableVirtualTerminal(f)
+	}
+	return caps
+}
+
+// envEnabled reports whether the environment variable key is set to a value other than
+// "0" or "false".
+func envEnabled(key string) bool {
+	value := os.Getenv(key)
+	return value != "" && value != "0" && !strings.EqualFold(value, "false")
+}
+
+// consoleFile returns the standard stream or file written by the output, or nil when it
+// does not write to one.
+func (o OutputConfig) consoleFile() *os.File {
+	if o.Writer != nil {
+		f, _ := o.Writer.(*os.File)
+		return f
+	}
+	if o.Loki != nil || o.Syslog != nil || o.Sink != nil {
+		return nil
+	}
+	switch o.path() {
+	case "stdout":
+		return os.Stdout
+	case "stderr":
+		return os.Stderr
+	default:
+		return nil
+	}
+}
+
+// encoderConfig adapts a console encoder configuration to the capabilities: colored
+// levels on color terminals only, and aligned columns on wide terminals.
+func (c consoleCapabilities) encoderConfig(base zapcore.EncoderConfig) zapcore.EncoderConfig {
+	aligned := c.width >= alignedConsoleWidth
+	switch {
+	case aligned:
+		base.EncodeLevel = alignedLevelEncoder(c.color)
+		base.EncodeCaller = alignedCallerEncoder
+	case c.color:
+		base.EncodeLevel = zapcore.CapitalColorLevelEncoder
+	default:
+		base.EncodeLevel = zapcore.CapitalLevelEncoder
+	}
+	return base
+}
+
+// alignedLevelEncoder encodes levels padded to a fixed width, colored if color is set.
+func alignedLevelEncoder(color bool) zapcore.LevelEncoder {
+	return func(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		s := padRight(level.CapitalString(), consoleLevelWidth)
+		if color {
+			s = consoleLevelColors[level] + s + "\x1b[0m"
+		}
+		enc.AppendString(s)
+	}
+}
+
+// alignedCallerEncoder encodes callers in short form, padded to a fixed width.
+func alignedCallerEncoder(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(padRight(caller.TrimmedPath(), consoleCallerWidth))
+}
+
+// key distinguishes the console encodings of outputs with different capabilities, which
+// cannot share encoded entries.
+func (c consoleCapabilities) key() string {
+	key := EncodingConsole
+	if c.color {
+		key += "+color"
+	}
+	if c.width >= alignedConsoleWidth {
+		key += "+aligned"
+	}
+	return key
+}
+
+// padRight pads s with spaces to width.
+func padRight(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package logger
+
+import "os"
+
+// enableVirtualTerminal reports that terminals render ANSI escape sequences, as they do
+// on every platform but Windows.
+func enableVirtualTerminal(*os.File) bool {
+	return true
+}
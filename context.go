@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// loggerKey is the context key of the request-scoped logger.
+type loggerKey struct{}
+
+// NewContext returns a copy of ctx carrying a logger enriched with fields.
+//
+// The logger is derived from the one already carried by ctx, or from the global logger,
+// so fields accumulate as the context is passed down through handler, service and
+// repository layers.
+//
+// Example:
+//
+//	ctx := logger.NewContext(r.Context(), zap.String("request_id", id))
+//	svc.CreateOrder(ctx, order)
+func NewContext(ctx context.Context, fields ...zap.Field) context.Context {
+	return context.WithValue(ctx, loggerKey{}, contextLogger(ctx).WithContext(fields...))
+}
+
+// ContextWithLogger returns a copy of ctx carrying l, which FromContext then returns and
+// NewContext derives from. Middlewares use it to install a request-scoped logger built
+// from a configured one.
+func ContextWithLogger(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger carried by ctx, or the global logger if there is none.
+//
+// When ctx carries an OpenTelemetry span, the returned logger is enriched with its
+// trace_id and span_id (see WithTrace), so entries can be correlated with traces.
+//
+// Example:
+//
+//	func (r *Repo) Save(ctx context.Context, o Order) error {
+//	    logger.FromContext(ctx).Debug("saving order", zap.String("order_id", o.ID))
+//	    ...
+//	}
+func FromContext(ctx context.Context) *Logger {
+	return contextLogger(ctx).WithTrace(ctx)
+}
+
+// contextLogger returns the logger stored in ctx, without trace enrichment.
+//
+// Trace fields are applied on retrieval rather than stored, so a span started after
+// NewContext is reflected without duplicating the trace keys.
+func contextLogger(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+		return l
+	}
+	return Get()
+}
+
+// WithTrace returns a derived logger enriched with the OpenTelemetry span carried by ctx.
+//
+// Fields follow the OpenTelemetry log data model: trace_id and span_id as lowercase hex
+// and trace_flags as a two-digit hex string. The logger is returned unchanged when ctx
+// carries no valid span context.
+//
+// Example:
+//
+//	ctx, span := tracer.Start(ctx, "checkout")
+//	defer span.End()
+//	log.WithTrace(ctx).Info("payment authorized")
+func (l *Logger) WithTrace(ctx context.Context) *Logger {
+	fields := TraceFields(ctx)
+	if len(fields) == 0 {
+		return l
+	}
+	return l.WithContext(fields...)
+}
+
+// TraceFields returns the trace_id, span_id and trace_flags fields of the OpenTelemetry
+// span carried by ctx, or of the trace context generated by the HTTP middleware for a
+// request without one, or nil when there is neither.
+func TraceFields(ctx context.Context) []zap.Field {
+	sc := logSpanContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return []zap.Field{
+		zap.String("trace_id", sc.TraceID().String()),
+		zap.String("span_id", sc.SpanID().String()),
+		zap.String("trace_flags", sc.TraceFlags().String()),
+	}
+}
@@ -0,0 +1,138 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Default correlation headers.
+const (
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceparent = "traceparent"
+)
+
+// newRequestID returns a random 128-bit request ID in lowercase hex.
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// maxRequestIDLength bounds the length of request IDs accepted from callers.
+const maxRequestIDLength = 128
+
+// validRequestID reports whether a request ID received from a caller can be reused: it
+// must be non-empty, bounded and made of letters, digits and "-_.:" only, so that it
+// cannot inject content into logs or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		case c == '-' || c == '_' || c == '.' || c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID returns the ID assigned to the request by the HTTP middleware, or read from a
+// carrier by Extract, or "" when there is none.
+func RequestID(ctx context.Context) string {
+	if state, ok := ctx.Value(requestStateKey{}).(*requestState); ok {
+		return state.id
+	}
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// traceparent formats the span context carried by ctx as a W3C traceparent value, or
+// returns "" when there is none.
+func traceparent(ctx context.Context) string {
+	return formatTraceparent(trace.SpanContextFromContext(ctx))
+}
+
+// formatTraceparent formats a span context as a W3C traceparent value, or returns "" when
+// it is invalid.
+func formatTraceparent(sc trace.SpanContext) string {
+	if !sc.IsValid() {
+		return ""
+	}
+	return "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-" + sc.TraceFlags().String()
+}
+
+// generatedTraceKey is the context key of the trace context generated by the HTTP
+// middleware for requests without one. It only feeds the log fields and the response
+// header: as an OpenTelemetry span context, it would be taken for the remote parent of
+// the spans of the request, and ParentBased samplers would drop their traces.
+type generatedTraceKey struct{}
+
+// extractTraceContext returns ctx carrying the trace context of a request: the span
+// already in ctx, the remote span parsed from the traceparent header, or a new trace
+// used for correlation only (see logSpanContext).
+func extractTraceContext(ctx context.Context, header string) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	if sc, ok := parseTraceparent(header); ok {
+		return trace.ContextWithRemoteSpanContext(ctx, sc)
+	}
+	return context.WithValue(ctx, generatedTraceKey{}, newTraceContext())
+}
+
+// logSpanContext returns the span context carried by ctx, or else the trace context
+// generated by the HTTP middleware, for log fields and response headers.
+func logSpanContext(ctx context.Context) trace.SpanContext {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		return sc
+	}
+	sc, _ := ctx.Value(generatedTraceKey{}).(trace.SpanContext)
+	return sc
+}
+
+// parseTraceparent parses a W3C traceparent header value. Versions above 00 are accepted
+// as long as they start with the version 00 fields, as the specification requires.
+func parseTraceparent(value string) (trace.SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return trace.SpanContext{}, false
+	}
+	traceID, err := trace.TraceIDFromHex(parts[1])
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	spanID, err := trace.SpanIDFromHex(parts[2])
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil || len(flags) != 1 {
+		return trace.SpanContext{}, false
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.TraceFlags(flags[0]),
+		Remote:     true,
+	})
+	return sc, sc.IsValid()
+}
+
+// newTraceContext returns the context of a new, unsampled trace, local to the process.
+func newTraceContext() trace.SpanContext {
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	_, _ = rand.Read(traceID[:])
+	_, _ = rand.Read(spanID[:])
+	return trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+}
@@ -0,0 +1,242 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of the deduplication configuration.
+const (
+	defaultDedupWindow = 10 * time.Second
+	// maxDedupKeys bounds the number of distinct messages tracked at once. Entries with
+	// new messages are not deduplicated while the limit is reached.
+	maxDedupKeys = 10000
+	// minDedupSweepInterval bounds how often expired windows are summarized.
+	minDedupSweepInterval = 100 * time.Millisecond
+)
+
+// repeatedKey is the field carrying the number of suppressed repetitions in a summary.
+const repeatedKey = "repeated"
+
+// DedupConfig suppresses identical entries (same level and message) repeated within a
+// window, so a crash loop in a dependency cannot flood the outputs.
+//
+// The first entry of a window is logged; identical entries logged during the window are
+// suppressed. When the window ends, a summary entry with the same level and logger name
+// reports how many entries were suppressed, e.g. "repeated 4212 times in 10s: connection
+// refused", with the count in the repeated field. The next identical entry starts a new
+// window.
+//
+// Entries exempt from volume reduction (see SamplingExemptions) are never suppressed.
+// DPANIC, PANIC and FATAL entries are never deduplicated.
+//
+// Example:
+//
+//	Dedup: &logger.DedupConfig{Window: 30 * time.Second}
+type DedupConfig struct {
+	// Window is the period during which identical entries are suppressed. Defaults to 10s.
+	Window time.Duration
+	// OnSuppressed, when set, is called for every suppressed entry, e.g. to count them in
+	// a metric. It must be fast and must not log through the same logger.
+	OnSuppressed func(zapcore.Entry)
+}
+
+// dedupFromEnv returns the deduplication configured by LOG_DEDUP_WINDOW, or nil when it is
+// unset or invalid.
+func dedupFromEnv() *DedupConfig {
+	window, err := time.ParseDuration(getEnv("LOG_DEDUP_WINDOW", ""))
+	if err != nil || window <= 0 {
+		return nil
+	}
+	return &DedupConfig{Window: window}
+}
+
+// dedupKey identifies a group of identical entries.
+type dedupKey struct {
+	level   zapcore.Level
+	message string
+}
+
+// dedupWindow tracks the entries of a group within the current window.
+type dedupWindow struct {
+	start time.Time
+	// ent and core are those of the first entry, used to write the summary.
+	ent        zapcore.Entry
+	core       zapcore.Core
+	suppressed int
+}
+
+// deduper holds the windows shared by all cores derived from a deduplicating core.
+type deduper struct {
+	window       time.Duration
+	onSuppressed func(zapcore.Entry)
+
+	mu      sync.Mutex
+	windows map[dedupKey]*dedupWindow
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// newDeduper applies the configuration defaults and starts summarizing expired windows.
+func newDeduper(cfg *DedupConfig) *deduper {
+	d := &deduper{
+		window:       defaultDedupWindow,
+		onSuppressed: cfg.OnSuppressed,
+		windows:      make(map[dedupKey]*dedupWindow),
+		done:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+	if cfg.Window > 0 {
+		d.window = cfg.Window
+	}
+	go d.run()
+	return d
+}
+
+// run summarizes the windows as they expire.
+func (d *deduper) run() {
+	defer close(d.stopped)
+	labelGoroutine("dedup")
+	ticker := time.NewTicker(max(d.window/10, minDedupSweepInterval))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			d.sweep(func(w *dedupWindow) bool { return now.Sub(w.start) >= d.window })
+		case <-d.done:
+			d.sweep(func(*dedupWindow) bool { return true })
+			return
+		}
+	}
+}
+
+// sweep removes the windows matching expired and writes their summaries.
+func (d *deduper) sweep(expired func(*dedupWindow) bool) {
+	var ended []*dedupWindow
+	d.mu.Lock()
+	for key, w := range d.windows {
+		if expired(w) {
+			delete(d.windows, key)
+			ended = append(ended, w)
+		}
+	}
+	d.mu.Unlock()
+	for _, w := range ended {
+		d.summarize(w)
+	}
+}
+
+// summarize writes the summary of a window in which entries were suppressed.
+func (d *deduper) summarize(w *dedupWindow) {
+	if w.suppressed == 0 {
+		return
+	}
+	ent := w.ent
+	ent.Time = time.Now()
+	ent.Message = fmt.Sprintf("repeated %d times in %s: %s", w.suppressed, d.window, w.ent.Message)
+	ent.Caller = zapcore.EntryCaller{}
+	ent.Stack = ""
+	// Check again so that per-output levels and in-flight tracking apply.
+	if ce := w.core.Check(ent, nil); ce != nil {
+		ce.Write(zap.Int(repeatedKey, w.suppressed))
+	}
+}
+
+// admit reports whether the entry starts a new window. When it does not, the entry is a
+// repetition of an entry logged during the current window.
+func (d *deduper) admit(ent zapcore.Entry, core zapcore.Core) bool {
+	key := dedupKey{level: ent.Level, message: ent.Message}
+	d.mu.Lock()
+	w, ok := d.windows[key]
+	if ok && ent.Time.Sub(w.start) < d.window {
+		d.mu.Unlock()
+		return false
+	}
+	if ok || len(d.windows) < maxDedupKeys {
+		d.windows[key] = &dedupWindow{start: ent.Time, ent: ent, core: core}
+	}
+	d.mu.Unlock()
+	if ok {
+		// The window ended before the sweeper noticed: summarize it first.
+		d.summarize(w)
+	}
+	return true
+}
+
+// suppress counts a repetition that was not exempt from volume reduction.
+func (d *deduper) suppress(ent zapcore.Entry) {
+	d.mu.Lock()
+	if w, ok := d.windows[dedupKey{level: ent.Level, message: ent.Message}]; ok {
+		w.suppressed++
+	}
+	d.mu.Unlock()
+	if d.onSuppressed != nil {
+		d.onSuppressed(ent)
+	}
+}
+
+// close summarizes the pending windows and stops the sweeper.
+func (d *deduper) close() {
+	d.closeOnce.Do(func() {
+		close(d.done)
+		<-d.stopped
+	})
+}
+
+// dedupCore suppresses repeated entries, except those exempt from volume reduction.
+type dedupCore struct {
+	zapcore.Core
+	d *deduper
+	// suppressed writes the exempt entries among the repetitions.
+	suppressed *suppressedCore
+}
+
+// newDedupCore wraps core with the deduplication described by cfg and returns the function
+// writing the pending summaries.
+func newDedupCore(core zapcore.Core, cfg *DedupConfig, set *exemptionSet) (zapcore.Core, func()) {
+	d := newDeduper(cfg)
+	return &dedupCore{
+		Core:       core,
+		d:          d,
+		suppressed: &suppressedCore{Core: core, set: set, onDrop: d.suppress},
+	}, d.close
+}
+
+// With implements zapcore.Core. Derived cores share the windows.
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	core := c.Core.With(fields)
+	return &dedupCore{
+		Core:       core,
+		d:          c.d,
+		suppressed: &suppressedCore{Core: core, set: c.suppressed.set, onDrop: c.suppressed.onDrop},
+	}
+}
+
+// Check implements zapcore.Core.
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if ent.Level > zapcore.ErrorLevel || c.d.admit(ent, c.Core) {
+		return c.Core.Check(ent, ce)
+	}
+	// Exemptions may depend on the fields, which are only known when writing.
+	return ce.AddCore(ent, c.suppressed)
+}
+
+// writeChecked implements checkedWriter.
+func (c *dedupCore) writeChecked(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+	if ent.Level > zapcore.ErrorLevel || c.d.admit(ent, c.Core) {
+		return writeChecked(c.Core, ent, fields)
+	}
+	return c.suppressed.Write(ent, fields)
+}
@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"runtime"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// deprecatedSites records the call sites already reported by Deprecated.
+var deprecatedSites sync.Map
+
+// Deprecated logs a warning that a deprecated API was called, once per unique call site.
+//
+// It is meant to be called from inside the deprecated function itself; the reported caller
+// is the code calling that function. The entry carries a structured `deprecation` field so
+// migration progress can be tracked in log aggregators.
+//
+// Example:
+//
+//	func OldFunc() {
+//	    logger.Deprecated("OldFunc", "NewFunc")
+//	    NewFunc()
+//	}
+func Deprecated(api, replacement string) {
+	pc, file, line, ok := runtime.Caller(2)
+	if !ok {
+		return
+	}
+	site := zapcore.NewEntryCaller(pc, file, line, ok).TrimmedPath()
+	if _, loaded := deprecatedSites.LoadOrStore(api+"\x00"+site, struct{}{}); loaded {
+		return
+	}
+
+	Get().WithOptions(zap.AddCallerSkip(2)).Warn("deprecated API called",
+		zap.Dict("deprecation",
+			zap.String("api", api),
+			zap.String("replacement", replacement),
+			zap.String("call_site", site),
+		),
+	)
+}
@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DPanicMode controls what happens when a DPANIC entry is logged.
+type DPanicMode string
+
+const (
+	// DPanicDefault panics outside of the production environment (default).
+	DPanicDefault DPanicMode = ""
+	// DPanicPanic always panics after writing a DPANIC entry, to surface "impossible"
+	// conditions early.
+	DPanicPanic DPanicMode = "panic"
+	// DPanicLog only writes DPANIC entries, as in production.
+	DPanicLog DPanicMode = "log"
+)
+
+// functionKey is the key of the function name in entries with Diagnostics.FunctionNames.
+const functionKey = "function"
+
+// DiagnosticsConfig tunes the diagnostic information attached to entries independently of
+// Environment, e.g. to get development-grade diagnostics in a staging environment using
+// the production encoding. Zero fields keep the defaults.
+//
+// Caller, stack trace and DPANIC settings are fixed when the logger is built, like the
+// Options: Reconfigure does not change them.
+//
+// Example (staging):
+//
+//	Diagnostics: &logger.DiagnosticsConfig{
+//	    StacktraceLevel: logger.LevelWarn,
+//	    FunctionNames:   true,
+//	    DPanic:          logger.DPanicPanic,
+//	}
+type DiagnosticsConfig struct {
+	// DisableCaller omits the caller location from entries.
+	DisableCaller bool
+	// StacktraceLevel is the minimum level of the entries carrying a stack trace. It takes
+	// precedence over WithStacktraceLevel. Defaults to ERROR.
+	StacktraceLevel LogLevel
+	// DisableStacktrace omits stack traces from entries at every level.
+	DisableStacktrace bool
+	// FunctionNames adds the name of the calling function to entries, in the function
+	// field.
+	FunctionNames bool
+	// DPanic selects whether DPANIC entries panic. Defaults to panicking outside of the
+	// production environment.
+	DPanic DPanicMode
+}
+
+// diagnosticsFromEnv returns the diagnostics configured by LOG_CALLER,
+// LOG_STACKTRACE_LEVEL, LOG_FUNCTION_NAMES and LOG_DPANIC, or nil when none is set.
+func diagnosticsFromEnv() *DiagnosticsConfig {
+	d := &DiagnosticsConfig{
+		DisableCaller:   os.Getenv("LOG_CALLER") == "false",
+		StacktraceLevel: LogLevel(os.Getenv("LOG_STACKTRACE_LEVEL")),
+		FunctionNames:   os.Getenv("LOG_FUNCTION_NAMES") == "true",
+		DPanic:          DPanicMode(os.Getenv("LOG_DPANIC")),
+	}
+	if d.StacktraceLevel == "NONE" {
+		d.StacktraceLevel, d.DisableStacktrace = "", true
+	}
+	if *d == (DiagnosticsConfig{}) {
+		return nil
+	}
+	return d
+}
+
+// development reports whether DPANIC entries panic with the configuration.
+func development(cfg Config) bool {
+	if cfg.Diagnostics != nil {
+		switch cfg.Diagnostics.DPanic {
+		case DPanicPanic:
+			return true
+		case DPanicLog:
+			return false
+		}
+	}
+	return !isProduction(cfg)
+}
+
+// zap returns the zap options implementing the diagnostics, applied after the Options.
+func (d *DiagnosticsConfig) zap() []zap.Option {
+	if d == nil {
+		return nil
+	}
+	var opts []zap.Option
+	if d.DisableCaller {
+		opts = append(opts, zap.WithCaller(false))
+	}
+	switch {
+	case d.DisableStacktrace:
+		opts = append(opts, zap.AddStacktrace(zapcore.InvalidLevel))
+	case d.StacktraceLevel != "":
+		opts = append(opts, zap.AddStacktrace(parseLevel(d.StacktraceLevel)))
+	}
+	return opts
+}
+
+// encoderConfig adds the function key to an encoder configuration if requested.
+func (d *DiagnosticsConfig) encoderConfig(cfg zapcore.EncoderConfig) zapcore.EncoderConfig {
+	if d != nil && d.FunctionNames && (cfg.FunctionKey == "" || cfg.FunctionKey == zapcore.OmitKey) {
+		cfg.FunctionKey = functionKey
+	}
+	return cfg
+}
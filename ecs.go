@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// EncodingECS emits one JSON object per line with the field names of the Elastic Common
+// Schema (@timestamp, log.level, message, service.name, trace.id, ...), so that entries
+// shipped to Elasticsearch match the ECS dashboards and ingest pipelines.
+const EncodingECS = "ecs"
+
+// ecsVersion is the version of the Elastic Common Schema the entries conform to.
+const ecsVersion = "8.11.0"
+
+// ecsFieldNames maps the fields attached by the logger and its integrations to their ECS
+// names.
+var ecsFieldNames = map[string]string{
+	"service":     "service.name",
+	"environment": "service.environment",
+	"trace_id":    "trace.id",
+	"span_id":     "span.id",
+	"request_id":  "http.request.id",
+	"error":       "error.message",
+	"pid":         "process.pid",
+	"function":    "log.origin.function",
+}
+
+// ecsEncoderConfig returns the encoder settings of ECS outputs.
+func ecsEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "@timestamp",
+		LevelKey:       "log.level",
+		NameKey:        "log.logger",
+		CallerKey:      "log.origin.file.name",
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "message",
+		StacktraceKey:  "error.stack_trace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeTime:     ecsTimeEncoder,
+		EncodeDuration: zapcore.MillisDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+}
+
+// ecsTimeEncoder encodes @timestamp in UTC with millisecond precision, the format
+// Elasticsearch date fields accept by default.
+func ecsTimeEncoder(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(t.UTC().Format("2006-01-02T15:04:05.000Z07:00"))
+}
+
+// ecsMapping returns the field mapping of ECS outputs: the fields are renamed to their
+// ECS names and ecs.version is attached. The output's own mapping, if any, is applied on
+// top: its renames take precedence over the ECS names.
+func ecsMapping(m *FieldMapping) *FieldMapping {
+	mapping := &FieldMapping{
+		Rename: make(map[string]string, len(ecsFieldNames)),
+		Add:    map[string]any{"ecs.version": ecsVersion},
+	}
+	for key, name := range ecsFieldNames {
+		mapping.Rename[key] = name
+	}
+	if m == nil {
+		return mapping
+	}
+	for key, name := range m.Rename {
+		mapping.Rename[key] = name
+	}
+	for key, value := range m.Add {
+		mapping.Add[key] = value
+	}
+	mapping.Drop = m.Drop
+	return mapping
+}
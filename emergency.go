@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Rate limit of the emergency lines written to stderr.
+const (
+	emergencyInterval = time.Second
+	emergencyBurst    = 10
+)
+
+// emergencyCore writes entries to the cores of the outputs, like a tee. When every output
+// fails to write an entry at ERROR or above, it writes a compact line with the message and
+// the failures to stderr, so that a pipeline whose destinations are all broken is not
+// silent about the entries that matter most.
+//
+// Lines are rate-limited to 10 per second: the lines suppressed meanwhile are counted in
+// the next one. Outputs that write in the background, such as async mode and Loki, only
+// fail here when their queue rejects the entry; they report later failures themselves.
+type emergencyCore struct {
+	cores   []zapcore.Core
+	limiter *emergencyLimiter
+}
+
+// newEmergencyCore returns a core writing entries to every core.
+func newEmergencyCore(cores []zapcore.Core) *emergencyCore {
+	return &emergencyCore{cores: cores, limiter: &emergencyLimiter{}}
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *emergencyCore) Enabled(level zapcore.Level) bool {
+	for _, core := range c.cores {
+		if core.Enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// With implements zapcore.Core.
+func (c *emergencyCore) With(fields []zapcore.Field) zapcore.Core {
+	cores := make([]zapcore.Core, len(c.cores))
+	for i, core := range c.cores {
+		cores[i] = core.With(fields)
+	}
+	return &emergencyCore{cores: cores, limiter: c.limiter}
+}
+
+// Check implements zapcore.Core. Entries below ERROR are checked by each core, as with a
+// tee; the others are written by Write, which needs the outcome of every output.
+func (c *emergencyCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level < zapcore.ErrorLevel {
+		for _, core := range c.cores {
+			ce = core.Check(ent, ce)
+		}
+		return ce
+	}
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core, writing the entry to every core that accepts it. The
+// failures of some outputs are returned; the failure of all of them is written to stderr
+// for entries at ERROR or above.
+func (c *emergencyCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var accepted int
+	var failures []error
+	for _, core := range c.cores {
+		if fanout, ok := core.(*fanoutCore); ok {
+			// Each destination of a shared encoding is an output of its own.
+			if fanout.Enabled(ent.Level) {
+				n, errs := fanout.writeDestinations(ent, fields)
+				accepted += n
+				failures = append(failures, errs...)
+			}
+			continue
+		}
+		if !core.Enabled(ent.Level) {
+			continue
+		}
+		accepted++
+		if err := writeChecked(core, ent, fields); err != nil {
+			failures = append(failures, err)
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	if len(failures) < accepted || ent.Level < zapcore.ErrorLevel {
+		return errors.Join(failures...)
+	}
+	if suppressed, ok := c.limiter.allow(); ok {
+		reason := strings.ReplaceAll(errors.Join(failures...).Error(), "\n", "; ")
+		note := ""
+		if suppressed > 0 {
+			note = fmt.Sprintf(" (%d similar lines suppressed)", suppressed)
+		}
+		fmt.Fprintf(os.Stderr, "logger: every output failed, %s %s %q: %s%s\n",
+			ent.Time.UTC().Format(time.RFC3339Nano), ent.Level.CapitalString(), ent.Message, reason, note)
+	}
+	return nil
+}
+
+// writeChecked implements checkedWriter: entries below ERROR are checked by each core,
+// which Write does.
+func (c *emergencyCore) writeChecked(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+	return c.Write(ent, fields)
+}
+
+// Sync implements zapcore.Core.
+func (c *emergencyCore) Sync() error {
+	var errs []error
+	for _, core := range c.cores {
+		if err := core.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// emergencyLimiter bounds the emergency lines to emergencyBurst per emergencyInterval.
+type emergencyLimiter struct {
+	mu         sync.Mutex
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// allow reports whether a line can be written, with the number of lines suppressed
+// since the previous one.
+func (l *emergencyLimiter) allow() (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if now.Sub(l.start) >= emergencyInterval {
+		l.start, l.count = now, 0
+	}
+	if l.count >= emergencyBurst {
+		l.suppressed++
+		return 0, false
+	}
+	l.count++
+	suppressed := l.suppressed
+	l.suppressed = 0
+	return suppressed, true
+}
@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// encryptedLinePrefix marks a line written by the encrypting writer and versions its format.
+const encryptedLinePrefix = "enc:v1:"
+
+// EncryptionConfig enables encryption at rest for a local output.
+//
+// Each entry is sealed with AES-GCM and written as a single line of the form
+// "enc:v1:<base64(nonce|ciphertext)>", so files stay line-oriented, partially written
+// lines are detected, and buffered-but-unshipped logs on a compromised host don't leak.
+// Use NewDecryptReader to read the plaintext back.
+type EncryptionConfig struct {
+	// Key is the base64-encoded AES key (16, 24 or 32 bytes).
+	Key string
+	// KeyEnv names an environment variable holding the base64-encoded key.
+	// It is used when Key is empty, keeping the key out of configuration files.
+	KeyEnv string
+}
+
+// key resolves and decodes the configured key.
+func (c *EncryptionConfig) key() ([]byte, error) {
+	encoded := c.Key
+	if encoded == "" && c.KeyEnv != "" {
+		encoded = os.Getenv(c.KeyEnv)
+	}
+	if encoded == "" {
+		return nil, errors.New("encryption key is not configured")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return key, nil
+}
+
+// newAEAD returns an AES-GCM cipher for the given key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// aead resolves the configured key into an AES-GCM cipher.
+func (c *EncryptionConfig) aead() (cipher.AEAD, error) {
+	key, err := c.key()
+	if err != nil {
+		return nil, err
+	}
+	return newAEAD(key)
+}
+
+// seal encrypts plain under a random nonce and appends nonce|ciphertext to dst.
+func seal(aead cipher.AEAD, dst, plain []byte) ([]byte, error) {
+	start := len(dst)
+	dst = append(dst, make([]byte, aead.NonceSize())...)
+	if _, err := rand.Read(dst[start:]); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(dst, dst[start:], plain, nil), nil
+}
+
+// open decrypts a nonce|ciphertext record sealed by seal.
+func open(aead cipher.AEAD, sealed []byte) ([]byte, error) {
+	nonceSize := aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("truncated record")
+	}
+	return aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+}
+
+// encryptingWriter seals every write into a self-contained encrypted line.
+//
+// zap writes exactly one encoded entry per Write call, so each entry maps to one line.
+type encryptingWriter struct {
+	mu   sync.Mutex
+	out  zapcore.WriteSyncer
+	aead cipher.AEAD
+	buf  []byte
+}
+
+// newEncryptingWriter wraps out so that all data written to it is encrypted with cfg's key.
+func newEncryptingWriter(out zapcore.WriteSyncer, cfg *EncryptionConfig) (zapcore.WriteSyncer, error) {
+	aead, err := cfg.aead()
+	if err != nil {
+		return nil, err
+	}
+	return &encryptingWriter{out: out, aead: aead}, nil
+}
+
+// Write encrypts p and writes it to the underlying writer as a single line.
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	plain := bytes.TrimSuffix(p, []byte("\n"))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	sealed, err := seal(w.aead, nil, plain)
+	if err != nil {
+		return 0, err
+	}
+
+	w.buf = append(w.buf[:0], encryptedLinePrefix...)
+	w.buf = base64.StdEncoding.AppendEncode(w.buf, sealed)
+	w.buf = append(w.buf, '\n')
+	if _, err := w.out.Write(w.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync flushes the underlying writer.
+func (w *encryptingWriter) Sync() error {
+	return w.out.Sync()
+}
+
+// NewDecryptReader returns a reader yielding the plaintext lines of an encrypted log stream
+// written with the given key.
+//
+// Lines that are not encrypted are passed through unchanged, which makes it possible to
+// read files that were only partially encrypted after enabling encryption. A line that
+// fails authentication (tampering, truncation, wrong key) results in an error.
+func NewDecryptReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(decryptLines(r, pw, aead))
+	}()
+	return pr, nil
+}
+
+// decryptLines decrypts every line of r into w.
+func decryptLines(r io.Reader, w io.Writer, aead cipher.AEAD) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		raw := scanner.Bytes()
+		plain := raw
+		if bytes.HasPrefix(raw, []byte(encryptedLinePrefix)) {
+			sealed, err := base64.StdEncoding.DecodeString(string(raw[len(encryptedLinePrefix):]))
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			plain, err = open(aead, sealed)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+		if _, err := w.Write(append(plain, '\n')); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,198 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// maxErrorChain bounds the number of errors reported in error_chain.
+const maxErrorChain = 32
+
+// StackTracer is implemented by errors recording the stack where they were created, as
+// program counters (see runtime.Callers). Errors of github.com/pkg/errors, whose
+// StackTrace method returns frames rather than program counters, are supported as well.
+type StackTracer interface {
+	StackTrace() []uintptr
+}
+
+// ErrorE logs a message at the ERROR level with err as its cause (see Logger.ErrorE),
+// using the global logger.
+func ErrorE(msg string, err error, fields ...zap.Field) {
+	packageLogger().ErrorE(msg, err, fields...)
+}
+
+// WarnE logs a message at the WARN level with err as its cause (see Logger.ErrorE),
+// using the global logger.
+func WarnE(msg string, err error, fields ...zap.Field) {
+	packageLogger().WarnE(msg, err, fields...)
+}
+
+// FatalE logs a message at the FATAL level with err as its cause (see Logger.ErrorE),
+// using the global logger, and terminates the application.
+func FatalE(msg string, err error, fields ...zap.Field) {
+	packageLogger().FatalE(msg, err, fields...)
+}
+
+// ErrorE logs a message at the ERROR level with err as its cause, so that it cannot be
+// forgotten: the entry carries the error field, an error_chain field listing the type
+// and message of each wrapped error when err wraps others, and the stack trace recorded
+// by the innermost error implementing StackTracer, instead of the stack of the logging
+// call. A nil err is logged without them.
+//
+// Example:
+//
+//	if err := repo.Save(ctx, order); err != nil {
+//	    log.ErrorE("failed to save order", err, zap.String("order_id", order.ID))
+//	}
+func (l *Logger) ErrorE(msg string, err error, fields ...zap.Field) {
+	if l.Core().Enabled(zapcore.ErrorLevel) {
+		if ce := l.skipped().Check(zapcore.ErrorLevel, msg); ce != nil {
+			writeError(ce, err, fields)
+		}
+	}
+}
+
+// WarnE logs a message at the WARN level with err as its cause (see ErrorE).
+func (l *Logger) WarnE(msg string, err error, fields ...zap.Field) {
+	if l.Core().Enabled(zapcore.WarnLevel) {
+		if ce := l.skipped().Check(zapcore.WarnLevel, msg); ce != nil {
+			writeError(ce, err, fields)
+		}
+	}
+}
+
+// FatalE logs a message at the FATAL level with err as its cause (see ErrorE), then
+// terminates the application.
+func (l *Logger) FatalE(msg string, err error, fields ...zap.Field) {
+	if ce := l.skipped().Check(zapcore.FatalLevel, msg); ce != nil {
+		writeError(ce, err, fields)
+	}
+}
+
+// writeError writes a checked entry with the error fields of err.
+func writeError(ce *zapcore.CheckedEntry, err error, fields []zap.Field) {
+	if err == nil {
+		ce.Write(fields...)
+		return
+	}
+	all := make([]zap.Field, 0, len(fields)+2)
+	all = append(all, zap.Error(err))
+	if chain := errorChain(err); len(chain) > 1 {
+		all = append(all, zap.Array("error_chain", chain))
+	}
+	if pcs := errorStack(err); len(pcs) > 0 {
+		// Where the error occurred tells more than where it is logged.
+		ce.Stack = formatStack(pcs)
+	}
+	ce.Write(append(all, fields...)...)
+}
+
+// errorLink is an error of a chain, encoded as its type and message.
+type errorLink struct {
+	err error
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (e errorLink) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("type", fmt.Sprintf("%T", e.err))
+	enc.AddString("message", e.err.Error())
+	return nil
+}
+
+// errorChainArray is the error_chain field of an error.
+type errorChainArray []errorLink
+
+// MarshalLogArray implements zapcore.ArrayMarshaler.
+func (c errorChainArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, link := range c {
+		if err := enc.AppendObject(link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errorChain returns err and the errors it wraps, depth first.
+func errorChain(err error) errorChainArray {
+	var chain errorChainArray
+	walkErrors(err, func(e error) {
+		if len(chain) < maxErrorChain {
+			chain = append(chain, errorLink{err: e})
+		}
+	})
+	return chain
+}
+
+// walkErrors calls fn for err and every error it wraps, depth first.
+func walkErrors(err error, fn func(error)) {
+	for err != nil {
+		fn(err)
+		switch u := err.(type) {
+		case interface{ Unwrap() []error }:
+			for _, e := range u.Unwrap() {
+				walkErrors(e, fn)
+			}
+			return
+		case interface{ Unwrap() error }:
+			err = u.Unwrap()
+		default:
+			return
+		}
+	}
+}
+
+// errorStack returns the stack recorded by the innermost error of err's chain that
+// records one, which is closest to where the failure occurred.
+func errorStack(err error) []uintptr {
+	var pcs []uintptr
+	walkErrors(err, func(e error) {
+		if stack := stackOf(e); len(stack) > 0 {
+			pcs = stack
+		}
+	})
+	return pcs
+}
+
+// stackOf returns the stack recorded by err itself, if any.
+func stackOf(err error) []uintptr {
+	if st, ok := err.(StackTracer); ok {
+		return st.StackTrace()
+	}
+	// github.com/pkg/errors: StackTrace() errors.StackTrace, a slice of uintptr-based
+	// frames holding the program counters returned by runtime.Callers.
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return nil
+	}
+	out := m.Type().Out(0)
+	if out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+	frames := m.Call(nil)[0]
+	pcs := make([]uintptr, frames.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(frames.Index(i).Uint())
+	}
+	return pcs
+}
+
+// formatStack formats program counters like zap's stack traces.
+func formatStack(pcs []uintptr) string {
+	var b strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "%s\n\t%s:%d", frame.Function, frame.File, frame.Line)
+		if !more {
+			return b.String()
+		}
+	}
+}
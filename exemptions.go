@@ -0,0 +1,183 @@
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SamplingExemptions declares entries that must never be dropped by a volume-reduction
+// layer: Once, EveryN, access log rules and sampling.
+//
+// Entries tagged with the audit retention class are always exempt.
+//
+// Example:
+//
+//	SamplingExemptions: &logger.SamplingExemptions{
+//	    Messages: []string{"payment failed"},
+//	    Values:   map[string][]string{"event_code": {"PAYMENT_DECLINED", "CHARGEBACK"}},
+//	}
+type SamplingExemptions struct {
+	// Messages lists exempt entry messages (exact match).
+	Messages []string
+	// Fields lists field keys whose presence makes an entry exempt.
+	Fields []string
+	// Values lists, per field key, the string values that make an entry exempt, e.g.
+	// event codes.
+	Values map[string][]string
+}
+
+// exemptionSet is the lookup-friendly form of SamplingExemptions.
+type exemptionSet struct {
+	messages map[string]struct{}
+	fields   map[string]struct{}
+	values   map[string]map[string]struct{}
+}
+
+// compile converts the exemptions into their lookup-friendly form.
+func (e *SamplingExemptions) compile() *exemptionSet {
+	s := &exemptionSet{
+		messages: make(map[string]struct{}, len(e.Messages)),
+		fields:   make(map[string]struct{}, len(e.Fields)),
+		values:   make(map[string]map[string]struct{}, len(e.Values)),
+	}
+	for _, msg := range e.Messages {
+		s.messages[msg] = struct{}{}
+	}
+	for _, key := range e.Fields {
+		s.fields[key] = struct{}{}
+	}
+	for key, values := range e.Values {
+		set := make(map[string]struct{}, len(values))
+		for _, v := range values {
+			set[v] = struct{}{}
+		}
+		s.values[key] = set
+	}
+	return s
+}
+
+// exempt reports whether the entry must bypass volume reduction.
+//
+// Only the fields of the entry itself are inspected, not those attached with With.
+func (s *exemptionSet) exempt(ent zapcore.Entry, fields []zapcore.Field) bool {
+	if _, ok := s.messages[ent.Message]; ok {
+		return true
+	}
+	for _, f := range fields {
+		if _, ok := s.fields[f.Key]; ok {
+			return true
+		}
+		if f.Type != zapcore.StringType {
+			continue
+		}
+		if f.Key == retentionKey && f.String == string(RetentionAudit) {
+			return true
+		}
+		if _, ok := s.values[f.Key][f.String]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultExemptions only exempts audit entries.
+var defaultExemptions = (&SamplingExemptions{}).compile()
+
+// exemptions returns the compiled exemptions of the configuration.
+func (c Config) exemptions() *exemptionSet {
+	if c.SamplingExemptions == nil {
+		return defaultExemptions
+	}
+	return c.SamplingExemptions.compile()
+}
+
+// exemptionSource is implemented by cores that know the exemptions of their pipeline.
+type exemptionSource interface {
+	exemptions() *exemptionSet
+	suppressionReports() *suppressionReporter
+}
+
+// exemptionsOf returns the exemptions of the pipeline behind core, or nil when unknown.
+func exemptionsOf(core zapcore.Core) *exemptionSet {
+	if src, ok := core.(exemptionSource); ok {
+		return src.exemptions()
+	}
+	return nil
+}
+
+// exemptionCore carries the exemptions and the suppression reporter of a pipeline built
+// from a Config.
+type exemptionCore struct {
+	zapcore.Core
+	set     *exemptionSet
+	reports *suppressionReporter
+}
+
+// With implements zapcore.Core.
+func (c *exemptionCore) With(fields []zapcore.Field) zapcore.Core {
+	return &exemptionCore{Core: c.Core.With(fields), set: c.set, reports: c.reports}
+}
+
+// exemptions implements exemptionSource.
+func (c *exemptionCore) exemptions() *exemptionSet { return c.set }
+
+// suppressionReports implements exemptionSource.
+func (c *exemptionCore) suppressionReports() *suppressionReporter { return c.reports }
+
+// suppressedCore only writes the entries that are exempt from volume reduction. It
+// replaces the core of a logger whose entries a volume-reduction layer decided to drop.
+type suppressedCore struct {
+	zapcore.Core
+	set *exemptionSet
+	// onDrop, when set, is called for every entry dropped.
+	onDrop func(zapcore.Entry)
+}
+
+// suppressed returns a core writing only the exempt entries of core and reporting the
+// others as suppressed for reason, or nil when the exemptions of its pipeline are
+// unknown.
+func suppressed(core zapcore.Core, reason string) zapcore.Core {
+	src, ok := core.(exemptionSource)
+	if !ok {
+		return nil
+	}
+	return &suppressedCore{Core: core, set: src.exemptions(), onDrop: src.suppressionReports().hook(reason, nil)}
+}
+
+// With implements zapcore.Core.
+func (c *suppressedCore) With(fields []zapcore.Field) zapcore.Core {
+	return &suppressedCore{Core: c.Core.With(fields), set: c.set, onDrop: c.onDrop}
+}
+
+// Check registers this core so that Write sees the fields of the entry.
+func (c *suppressedCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write writes exempt entries through the wrapped core and drops the others.
+func (c *suppressedCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.set.exempt(ent, fields) {
+		if c.onDrop != nil {
+			c.onDrop(ent)
+		}
+		return nil
+	}
+	// Check again so that per-output levels and in-flight tracking apply.
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+// suppressedLogger returns the logger used in place of l when Once or EveryN drops its
+// next entry: it only writes exempt entries.
+func (l *Logger) suppressedLogger() *Logger {
+	core := suppressed(l.Core(), suppressionRateLimit)
+	if core == nil {
+		return nopLogger
+	}
+	return &Logger{Logger: l.WithOptions(zap.WrapCore(func(zapcore.Core) zapcore.Core { return core })), shutdown: l.shutdown}
+}
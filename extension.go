@@ -0,0 +1,250 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// Entry is a log entry as seen by extensions: encoders, sinks and processors implemented
+// outside this package. Unlike zapcore.Entry, it carries all of its fields, already
+// resolved to plain Go values, so extensions do not need to know zap's field types.
+//
+// Entry, EntryEncoder, Sink and Processor are the stable extension interface of the
+// logger and follow semantic versioning: within a major version, fields may be added to
+// Entry but none are removed or change meaning, and no method is added to the interfaces.
+type Entry struct {
+	// Time is the time the entry was logged.
+	Time time.Time
+	// Level is the level of the entry: DEBUG, INFO, WARN, ERROR, DPANIC, PANIC or FATAL.
+	Level LogLevel
+	// Logger is the name of the logger (see Named), empty for the root logger.
+	Logger string
+	// Message is the log message.
+	Message string
+	// Caller is the file:line location of the logging call, empty when not recorded.
+	Caller string
+	// Stack is the stack trace of the logging call, empty when not recorded.
+	Stack string
+	// Fields holds the fields of the entry, attached with With or passed to the logging
+	// call, by key. Values are strings, booleans, numbers, time.Time, time.Duration,
+	// []any and map[string]any for nested objects, or other values passed to zap.Any.
+	Fields map[string]any
+}
+
+// EntryEncoder encodes entries for an output (see OutputConfig.Encoder), e.g. in a
+// vendor-specific format.
+type EntryEncoder interface {
+	// EncodeEntry returns the encoding of a single entry. A newline is appended when it
+	// does not end with one. It must be safe for concurrent use.
+	EncodeEntry(e Entry) ([]byte, error)
+}
+
+// Processor transforms or drops entries before they are written (see Config.Processors),
+// e.g. to attach fields computed from the others or to filter entries.
+type Processor interface {
+	// Process modifies e in place and returns false to drop it. Changes to Time, Level,
+	// Logger, Message and Fields are written; Caller and Stack are read-only. It must be
+	// safe for concurrent use and must not log through the same logger.
+	Process(e *Entry) bool
+}
+
+// newEntry returns the extension view of an entry, with the fields of context followed
+// by fields.
+func newEntry(ent zapcore.Entry, context *zapcore.MapObjectEncoder, fields []zapcore.Field) Entry {
+	enc := zapcore.NewMapObjectEncoder()
+	if context != nil {
+		for k, v := range context.Fields {
+			enc.Fields[k] = v
+		}
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	e := Entry{
+		Time:    ent.Time,
+		Level:   LogLevel(ent.Level.CapitalString()),
+		Logger:  ent.LoggerName,
+		Message: ent.Message,
+		Stack:   ent.Stack,
+		Fields:  enc.Fields,
+	}
+	if ent.Caller.Defined {
+		e.Caller = ent.Caller.TrimmedPath()
+	}
+	return e
+}
+
+// zapEntry returns ent updated with the changes made to e.
+func (e Entry) zapEntry(ent zapcore.Entry) zapcore.Entry {
+	ent.Time = e.Time
+	if level, err := zapcore.ParseLevel(strings.ToLower(string(e.Level))); err == nil {
+		ent.Level = level
+	}
+	ent.LoggerName = e.Logger
+	ent.Message = e.Message
+	return ent
+}
+
+// zapFields returns the fields of e, in key order.
+func (e Entry) zapFields() []zapcore.Field {
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	fields := make([]zapcore.Field, len(keys))
+	for i, k := range keys {
+		fields[i] = zap.Any(k, e.Fields[k])
+	}
+	return fields
+}
+
+// cloneContext returns a copy of context with fields added.
+func cloneContext(context *zapcore.MapObjectEncoder, fields []zapcore.Field) *zapcore.MapObjectEncoder {
+	enc := zapcore.NewMapObjectEncoder()
+	for k, v := range context.Fields {
+		enc.Fields[k] = v
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return enc
+}
+
+// entryBuffers holds the buffers returned by entryEncoder.
+var entryBuffers = buffer.NewPool()
+
+// entryEncoder adapts an EntryEncoder to zapcore.Encoder. The embedded map encoder
+// collects the fields attached with With.
+type entryEncoder struct {
+	*zapcore.MapObjectEncoder
+	enc EntryEncoder
+}
+
+// newEntryEncoder returns the zap encoder of an EntryEncoder.
+func newEntryEncoder(enc EntryEncoder) *entryEncoder {
+	return &entryEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), enc: enc}
+}
+
+// Clone implements zapcore.Encoder.
+func (e *entryEncoder) Clone() zapcore.Encoder {
+	return &entryEncoder{MapObjectEncoder: cloneContext(e.MapObjectEncoder, nil), enc: e.enc}
+}
+
+// EncodeEntry implements zapcore.Encoder.
+func (e *entryEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	p, err := e.enc.EncodeEntry(newEntry(ent, e.MapObjectEncoder, fields))
+	if err != nil {
+		return nil, err
+	}
+	buf := entryBuffers.Get()
+	_, _ = buf.Write(p)
+	if len(p) == 0 || p[len(p)-1] != '\n' {
+		buf.AppendByte('\n')
+	}
+	return buf, nil
+}
+
+// processorCore runs the configured processors on every entry before writing it.
+//
+// The fields attached with With are kept in context rather than encoded by the wrapped
+// core, since processors may change them.
+type processorCore struct {
+	zapcore.Core
+	processors []Processor
+	context    *zapcore.MapObjectEncoder
+}
+
+// newProcessorCore wraps core with processors.
+func newProcessorCore(core zapcore.Core, processors []Processor) *processorCore {
+	return &processorCore{Core: core, processors: processors, context: zapcore.NewMapObjectEncoder()}
+}
+
+// With implements zapcore.Core.
+func (c *processorCore) With(fields []zapcore.Field) zapcore.Core {
+	return &processorCore{Core: c.Core, processors: c.processors, context: cloneContext(c.context, fields)}
+}
+
+// Check implements zapcore.Core.
+func (c *processorCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write runs the processors, then writes the entry unless one of them dropped it.
+func (c *processorCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	e := newEntry(ent, c.context, fields)
+	for _, p := range c.processors {
+		if !p.Process(&e) {
+			return nil
+		}
+	}
+	return writeChecked(c.Core, e.zapEntry(ent), e.zapFields())
+}
+
+// sinkCore writes entries to a Sink.
+type sinkCore struct {
+	zapcore.LevelEnabler
+	enc     zapcore.Encoder
+	context *zapcore.MapObjectEncoder
+	sink    Sink
+}
+
+// newSinkCore returns the core writing entries encoded by enc to sink, and a function
+// closing the sink.
+func newSinkCore(sink Sink, enc zapcore.Encoder, level zapcore.LevelEnabler) (zapcore.Core, func()) {
+	core := &sinkCore{LevelEnabler: level, enc: enc, context: zapcore.NewMapObjectEncoder(), sink: sink}
+	return core, func() {
+		if err := sink.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: failed to close %s: %v\n", sink, err)
+		}
+	}
+}
+
+// With implements zapcore.Core.
+func (c *sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &sinkCore{LevelEnabler: c.LevelEnabler, enc: enc, context: cloneContext(c.context, fields), sink: c.sink}
+}
+
+// Check implements zapcore.Core.
+func (c *sinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *sinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	if err := c.sink.Write(newEntry(ent, c.context, fields), buf.Bytes()); err != nil {
+		return err
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// Like zapcore.NewCore, sync before a likely crash.
+		_ = c.Sync()
+	}
+	return nil
+}
+
+// Sync implements zapcore.Core.
+func (c *sinkCore) Sync() error {
+	return c.sink.Sync()
+}
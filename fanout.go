@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// fanoutDestination is one of the writers of a fanoutCore, with its own minimum level.
+type fanoutDestination struct {
+	out   zapcore.WriteSyncer
+	level zapcore.LevelEnabler
+}
+
+// fanoutCore encodes each entry once into a pooled buffer and writes the same bytes to
+// several destinations.
+//
+// It replaces a tee of cores that only differ by their destination and level: with one
+// core per output, a tee encodes every entry once per output.
+type fanoutCore struct {
+	enc          zapcore.Encoder
+	destinations []fanoutDestination
+}
+
+// newFanoutCore returns a core writing entries encoded by enc to every destination.
+func newFanoutCore(enc zapcore.Encoder, destinations []fanoutDestination) *fanoutCore {
+	return &fanoutCore{enc: enc, destinations: destinations}
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *fanoutCore) Enabled(level zapcore.Level) bool {
+	for _, d := range c.destinations {
+		if d.level.Enabled(level) {
+			return true
+		}
+	}
+	return false
+}
+
+// With implements zapcore.Core. The fields are encoded once, for every destination.
+func (c *fanoutCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &fanoutCore{enc: enc, destinations: c.destinations}
+}
+
+// Check implements zapcore.Core.
+func (c *fanoutCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *fanoutCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	_, errs := c.writeDestinations(ent, fields)
+	return errors.Join(errs...)
+}
+
+// writeDestinations writes the entry to the destinations enabled for its level, and
+// returns their number with the errors of those that failed.
+func (c *fanoutCore) writeDestinations(ent zapcore.Entry, fields []zapcore.Field) (int, []error) {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return 1, []error{err}
+	}
+	defer buf.Free()
+	var written int
+	var errs []error
+	for _, d := range c.destinations {
+		if !d.level.Enabled(ent.Level) {
+			continue
+		}
+		written++
+		if _, err := d.out.Write(buf.Bytes()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if ent.Level > zapcore.ErrorLevel {
+		// Like zapcore.NewCore, sync before a likely crash.
+		_ = c.Sync()
+	}
+	return written, errs
+}
+
+// Sync implements zapcore.Core.
+func (c *fanoutCore) Sync() error {
+	var errs []error
+	for _, d := range c.destinations {
+		if err := d.out.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,206 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of the crash loop detection.
+const (
+	defaultFatalMinInterval = time.Minute
+	defaultFatalMaxBackoff  = 10 * time.Minute
+)
+
+// FatalLoopConfig tells crash loops of supervised processes apart from isolated crashes.
+//
+// Every FATAL entry records its reason (a fingerprint of the logger name, caller and
+// message) in a state file that survives restarts. When the process dies again with the
+// same fingerprint less than MinInterval after the previous FATAL entry, the restart is
+// part of a loop: an ERROR "crash loop detected" entry is logged first, and the FATAL
+// entry carries crash_loop, fatal_repeats and a restart_backoff_ms hint doubling with
+// every repetition, up to MaxBackoff. Every FATAL entry carries fatal_fingerprint.
+//
+// Example (for a service restarted by systemd):
+//
+//	FatalLoop: &logger.FatalLoopConfig{StatePath: "/var/lib/orders/fatal.json"}
+type FatalLoopConfig struct {
+	// StatePath is the file recording the last FATAL entry. It must persist across
+	// restarts of the process, and not be shared with other processes.
+	StatePath string
+	// MinInterval is the interval under which identical FATAL entries are considered a
+	// crash loop. Defaults to 1m.
+	MinInterval time.Duration
+	// MaxBackoff caps the restart backoff hint. Defaults to 10m.
+	MaxBackoff time.Duration
+}
+
+// fatalLoopFromEnv returns the crash loop detection configured by LOG_FATAL_STATE, or nil
+// when it is unset.
+func fatalLoopFromEnv() *FatalLoopConfig {
+	path := os.Getenv("LOG_FATAL_STATE")
+	if path == "" {
+		return nil
+	}
+	return &FatalLoopConfig{StatePath: path}
+}
+
+// fatalState is the content of the state file.
+type fatalState struct {
+	Fingerprint string    `json:"fingerprint"`
+	Message     string    `json:"message"`
+	Time        time.Time `json:"time"`
+	Repeats     int       `json:"repeats"`
+}
+
+// fatalLoop detects repeated FATAL entries through the state file.
+type fatalLoop struct {
+	path        string
+	minInterval time.Duration
+	maxBackoff  time.Duration
+}
+
+// newFatalLoop applies the configuration defaults.
+func newFatalLoop(cfg *FatalLoopConfig) (*fatalLoop, error) {
+	if cfg.StatePath == "" {
+		return nil, fmt.Errorf("fatal loop detection requires a StatePath")
+	}
+	l := &fatalLoop{path: cfg.StatePath, minInterval: defaultFatalMinInterval, maxBackoff: defaultFatalMaxBackoff}
+	if cfg.MinInterval > 0 {
+		l.minInterval = cfg.MinInterval
+	}
+	if cfg.MaxBackoff > 0 {
+		l.maxBackoff = cfg.MaxBackoff
+	}
+	return l, nil
+}
+
+// fatalFingerprint returns a short stable identifier of the reason of a FATAL entry.
+func fatalFingerprint(ent zapcore.Entry) string {
+	h := fnv.New64a()
+	h.Write([]byte(ent.LoggerName))
+	h.Write([]byte{0})
+	if ent.Caller.Defined {
+		h.Write([]byte(ent.Caller.TrimmedPath()))
+	}
+	h.Write([]byte{0})
+	h.Write([]byte(ent.Message))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// record stores the entry in the state file and returns the new state and the previous
+// one. The state is best-effort: a missing or unreadable file starts a new count.
+func (l *fatalLoop) record(ent zapcore.Entry) (current, previous fatalState) {
+	current = fatalState{Fingerprint: fatalFingerprint(ent), Message: ent.Message, Time: ent.Time, Repeats: 1}
+	if data, err := os.ReadFile(l.path); err == nil && json.Unmarshal(data, &previous) == nil {
+		if previous.Fingerprint == current.Fingerprint && ent.Time.Sub(previous.Time) < l.minInterval {
+			current.Repeats = previous.Repeats + 1
+		}
+	}
+	if err := l.store(current); err != nil {
+		fmt.Fprintf(os.Stderr, "logger: failed to record fatal state: %v\n", err)
+	}
+	return current, previous
+}
+
+// store writes the state file atomically.
+func (l *fatalLoop) store(state fatalState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), l.path)
+}
+
+// backoff returns the restart backoff hint after the given number of repeats.
+func (l *fatalLoop) backoff(repeats int) time.Duration {
+	d := l.minInterval
+	for i := 2; i < repeats && d < l.maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, l.maxBackoff)
+}
+
+// fatalLoopCore records FATAL entries and annotates those repeated in a crash loop.
+type fatalLoopCore struct {
+	zapcore.Core
+	loop *fatalLoop
+}
+
+// newFatalLoopCore wraps core with the crash loop detection described by cfg.
+func newFatalLoopCore(core zapcore.Core, cfg *FatalLoopConfig) (zapcore.Core, error) {
+	loop, err := newFatalLoop(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &fatalLoopCore{Core: core, loop: loop}, nil
+}
+
+// With implements zapcore.Core.
+func (c *fatalLoopCore) With(fields []zapcore.Field) zapcore.Core {
+	return &fatalLoopCore{Core: c.Core.With(fields), loop: c.loop}
+}
+
+// Check registers this core for FATAL entries, and defers to the wrapped core otherwise.
+func (c *fatalLoopCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level != zapcore.FatalLevel {
+		return c.Core.Check(ent, ce)
+	}
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// writeChecked implements checkedWriter.
+func (c *fatalLoopCore) writeChecked(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Level != zapcore.FatalLevel {
+		return writeChecked(c.Core, ent, fields)
+	}
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+	return c.Write(ent, fields)
+}
+
+// Write records the FATAL entry and, in a crash loop, logs the alert before it.
+func (c *fatalLoopCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	current, previous := c.loop.record(ent)
+	annotated := make([]zapcore.Field, 0, len(fields)+4)
+	annotated = append(annotated, fields...)
+	annotated = append(annotated, zap.String("fatal_fingerprint", current.Fingerprint))
+	var alertErr error
+	if current.Repeats > 1 {
+		backoff := DurationMS("restart_backoff_ms", c.loop.backoff(current.Repeats))
+		alert := zapcore.Entry{LoggerName: ent.LoggerName, Time: ent.Time, Level: zapcore.ErrorLevel, Message: "crash loop detected"}
+		alertErr = writeChecked(c.Core, alert, []zapcore.Field{
+			zap.String("fatal_fingerprint", current.Fingerprint),
+			zap.String("fatal_message", current.Message),
+			zap.Int("fatal_repeats", current.Repeats),
+			zap.Time("previous_fatal_time", previous.Time),
+			backoff,
+		})
+		annotated = append(annotated, zap.Bool("crash_loop", true), zap.Int("fatal_repeats", current.Repeats), backoff)
+	}
+	return errors.Join(alertErr, writeChecked(c.Core, ent, annotated))
+}
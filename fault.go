@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrInjectedFault is the error returned by injected failures by default.
+var ErrInjectedFault = errors.New("logger: injected fault")
+
+// FaultConfig describes the faults injected into the writes of a destination, to verify
+// in tests how an application, and the logger's async and backpressure policies, behave
+// when the logging backend misbehaves.
+//
+// Example:
+//
+//	cfg.Outputs = []logger.OutputConfig{{
+//	    Path:   "/tmp/app.log",
+//	    Faults: &logger.FaultConfig{Latency: 50 * time.Millisecond, ErrorRate: 0.1},
+//	}}
+type FaultConfig struct {
+	// Latency delays every write.
+	Latency time.Duration
+	// Jitter adds a random delay of up to Jitter to every write.
+	Jitter time.Duration
+	// ErrorRate is the fraction of writes, from 0 to 1, failing without writing anything.
+	ErrorRate float64
+	// PartialWriteRate is the fraction of writes, from 0 to 1, writing only part of the
+	// entry before failing with io.ErrShortWrite.
+	PartialWriteRate float64
+	// SyncErrorRate is the fraction of syncs, from 0 to 1, failing.
+	SyncErrorRate float64
+	// Err is the error of failed writes and syncs. Defaults to ErrInjectedFault.
+	Err error
+	// Seed makes the injected faults reproducible. A random seed is used when zero.
+	Seed uint64
+}
+
+// faultInjector decides the faults of each operation.
+type faultInjector struct {
+	cfg FaultConfig
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// newFaultInjector returns the injector of the faults described by cfg.
+func newFaultInjector(cfg FaultConfig) *faultInjector {
+	if cfg.Err == nil {
+		cfg.Err = ErrInjectedFault
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	return &faultInjector{cfg: cfg, rnd: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// fault is the outcome drawn for a write.
+type fault int
+
+const (
+	faultNone fault = iota
+	faultError
+	faultPartial
+)
+
+// write waits for the injected latency and returns the fault of the next write.
+func (f *faultInjector) write() fault {
+	f.mu.Lock()
+	delay := f.cfg.Latency
+	if f.cfg.Jitter > 0 {
+		delay += time.Duration(f.rnd.Int64N(int64(f.cfg.Jitter)))
+	}
+	r := f.rnd.Float64()
+	f.mu.Unlock()
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	switch {
+	case r < f.cfg.ErrorRate:
+		return faultError
+	case r < f.cfg.ErrorRate+f.cfg.PartialWriteRate:
+		return faultPartial
+	default:
+		return faultNone
+	}
+}
+
+// sync reports whether the next sync fails.
+func (f *faultInjector) sync() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rnd.Float64() < f.cfg.SyncErrorRate
+}
+
+// faultyWriter injects faults into the writes of a WriteSyncer.
+type faultyWriter struct {
+	ws zapcore.WriteSyncer
+	f  *faultInjector
+}
+
+// NewFaultyWriter returns ws with the faults described by cfg injected into its writes,
+// e.g. around a MemoryBuffer set as OutputConfig.Writer.
+func NewFaultyWriter(ws zapcore.WriteSyncer, cfg FaultConfig) zapcore.WriteSyncer {
+	return &faultyWriter{ws: ws, f: newFaultInjector(cfg)}
+}
+
+// Write implements zapcore.WriteSyncer.
+func (w *faultyWriter) Write(p []byte) (int, error) {
+	switch w.f.write() {
+	case faultError:
+		return 0, w.f.cfg.Err
+	case faultPartial:
+		n, err := w.ws.Write(p[:len(p)/2])
+		if err != nil {
+			return n, err
+		}
+		return n, io.ErrShortWrite
+	default:
+		return w.ws.Write(p)
+	}
+}
+
+// Sync implements zapcore.WriteSyncer.
+func (w *faultyWriter) Sync() error {
+	if w.f.sync() {
+		return w.f.cfg.Err
+	}
+	return w.ws.Sync()
+}
+
+// faultySink injects faults into the writes of a Sink.
+type faultySink struct {
+	Sink
+	f *faultInjector
+}
+
+// NewFaultySink returns s with the faults described by cfg injected into its writes.
+// Partial writes pass the first half of the encoded entry.
+func NewFaultySink(s Sink, cfg FaultConfig) Sink {
+	return &faultySink{Sink: s, f: newFaultInjector(cfg)}
+}
+
+// Write implements Sink.
+func (s *faultySink) Write(e Entry, encoded []byte) error {
+	switch s.f.write() {
+	case faultError:
+		return s.f.cfg.Err
+	case faultPartial:
+		if err := s.Sink.Write(e, encoded[:len(encoded)/2]); err != nil {
+			return err
+		}
+		return io.ErrShortWrite
+	default:
+		return s.Sink.Write(e, encoded)
+	}
+}
+
+// Sync implements Sink.
+func (s *faultySink) Sync() error {
+	if s.f.sync() {
+		return s.f.cfg.Err
+	}
+	return s.Sink.Sync()
+}
@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DurationMS constructs a field carrying the duration as float milliseconds.
+//
+// Unlike zap.Duration, the value is numeric in every encoding, which keeps
+// downstream aggregations (Grafana panels, Loki unwrap, Elasticsearch ranges) working.
+//
+// Example:
+//
+//	log.Info("query executed", logger.DurationMS("query_ms", time.Since(start)))
+func DurationMS(key string, d time.Duration) zap.Field {
+	return zap.Float64(key, float64(d)/float64(time.Millisecond))
+}
+
+// Latency constructs the well-known "duration_ms" field from the given duration.
+//
+// Example:
+//
+//	log.Info("request completed", logger.Latency(time.Since(start)))
+func Latency(d time.Duration) zap.Field {
+	return DurationMS("duration_ms", d)
+}
@@ -0,0 +1,513 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// filterNode is a node of a parsed filter expression.
+type filterNode interface {
+	eval(e *Entry) bool
+}
+
+// filterAnd matches entries matching both sides.
+type filterAnd struct{ left, right filterNode }
+
+func (n filterAnd) eval(e *Entry) bool { return n.left.eval(e) && n.right.eval(e) }
+
+// filterOr matches entries matching either side.
+type filterOr struct{ left, right filterNode }
+
+func (n filterOr) eval(e *Entry) bool { return n.left.eval(e) || n.right.eval(e) }
+
+// filterNot matches entries not matching its operand.
+type filterNot struct{ node filterNode }
+
+func (n filterNot) eval(e *Entry) bool { return !n.node.eval(e) }
+
+// filterTruthy matches entries whose operand is present and not false, zero or empty.
+type filterTruthy struct{ operand filterOperand }
+
+func (n filterTruthy) eval(e *Entry) bool {
+	v, ok := n.operand.value(e)
+	if !ok {
+		return false
+	}
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	}
+	if f, ok := filterNumber(v); ok {
+		return f != 0
+	}
+	return true
+}
+
+// filterCompare matches entries whose operands compare as op requires.
+type filterCompare struct {
+	op          string
+	left, right filterOperand
+	re          *regexp.Regexp
+}
+
+func (n filterCompare) eval(e *Entry) bool {
+	l, lok := n.left.value(e)
+	if n.re != nil {
+		matched := lok && n.re.MatchString(filterString(l))
+		return matched == (n.op == "=~")
+	}
+	r, rok := n.right.value(e)
+	if !lok || !rok {
+		return n.op == "!="
+	}
+	var cmp int
+	switch {
+	case n.left.level || n.right.level:
+		cmp = int(filterLevel(l)) - int(filterLevel(r))
+	default:
+		lf, lnum := filterNumber(l)
+		rf, rnum := filterNumber(r)
+		if lnum && rnum {
+			cmp = compareFloats(lf, rf)
+		} else {
+			cmp = strings.Compare(filterString(l), filterString(r))
+		}
+	}
+	switch n.op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+// compareFloats returns -1, 0 or 1 as a is less than, equal to or greater than b.
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// filterOperand is a literal or a reference to a property of the entry.
+type filterOperand struct {
+	// path is the key of the referenced field, split on dots; nil for literals.
+	path []string
+	// literal is the value of a literal.
+	literal any
+	// level marks the level of the entry, or a level name compared with it.
+	level bool
+}
+
+// value returns the value of the operand for e, reporting false for missing fields.
+func (o filterOperand) value(e *Entry) (any, bool) {
+	if o.path == nil {
+		return o.literal, true
+	}
+	if len(o.path) == 1 {
+		switch o.path[0] {
+		case "level":
+			return e.Level, true
+		case "message":
+			return e.Message, true
+		case "logger":
+			return e.Logger, true
+		case "caller":
+			return e.Caller, true
+		}
+	}
+	// A key containing dots is looked up as is before reaching into nested objects.
+	if v, ok := e.Fields[strings.Join(o.path, ".")]; ok {
+		return v, true
+	}
+	var v any = e.Fields
+	for _, key := range o.path {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// filterNumber returns the value of a numeric field or literal.
+func filterNumber(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// filterString returns the string form of a value, for string comparisons and regular
+// expressions.
+func filterString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// filterLevel returns the zap level of a level value.
+func filterLevel(v any) zapcore.Level {
+	level, _ := parseFilterLevel(filterString(v))
+	return level
+}
+
+// parseFilterLevel parses a level name, case-insensitively; "warning" is accepted.
+func parseFilterLevel(name string) (zapcore.Level, bool) {
+	name = strings.ToLower(name)
+	if name == "warning" {
+		name = "warn"
+	}
+	level, err := zapcore.ParseLevel(name)
+	return level, err == nil
+}
+
+// compileFilter parses a filter expression.
+func compileFilter(expr string) (filterNode, error) {
+	tokens, err := lexFilter(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+	}
+	p := &filterParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+	}
+	return node, nil
+}
+
+// Kinds of filter tokens.
+const (
+	tokenIdent = iota
+	tokenString
+	tokenNumber
+	tokenOp
+)
+
+// filterToken is a token of a filter expression.
+type filterToken struct {
+	kind int
+	text string
+	// value is the value of string and number literals.
+	value any
+}
+
+// filterOps lists the operators, longest first.
+var filterOps = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "!~", "<", ">", "!", "(", ")"}
+
+// lexFilter splits a filter expression into tokens.
+func lexFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"' || c == '`':
+			end := i + 1
+			for end < len(expr) && expr[end] != c {
+				if c == '"' && expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, errors.New("unterminated string")
+			}
+			s, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				// Typically a regular expression such as "\d+" in a quoted string.
+				return nil, fmt.Errorf("invalid string %s: escape backslashes or use a `...` raw string", expr[i:end+1])
+			}
+			tokens = append(tokens, filterToken{kind: tokenString, text: expr[i : end+1], value: s})
+			i = end + 1
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(expr) && expr[i+1] >= '0' && expr[i+1] <= '9':
+			end := i + 1
+			for end < len(expr) && (expr[end] >= '0' && expr[end] <= '9' || expr[end] == '.') {
+				end++
+			}
+			f, err := strconv.ParseFloat(expr[i:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %s", expr[i:end])
+			}
+			tokens = append(tokens, filterToken{kind: tokenNumber, text: expr[i:end], value: f})
+			i = end
+		case isFilterIdent(c):
+			end := i + 1
+			for end < len(expr) && (isFilterIdent(expr[end]) || expr[end] >= '0' && expr[end] <= '9' || expr[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, filterToken{kind: tokenIdent, text: expr[i:end]})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range filterOps {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			tokens = append(tokens, filterToken{kind: tokenOp, text: op})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// isFilterIdent reports whether c can start an identifier.
+func isFilterIdent(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+// filterParser is a recursive descent parser of filter expressions.
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+// accept consumes the next token if it is the operator op.
+func (p *filterParser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == tokenOp && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// parseOr parses a disjunction.
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = filterOr{left, right}
+	}
+	return left, nil
+}
+
+// parseAnd parses a conjunction.
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = filterAnd{left, right}
+	}
+	return left, nil
+}
+
+// parseUnary parses a negation, a parenthesized expression or a comparison.
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.accept("!") {
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{node}, nil
+	}
+	if p.accept("(") {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, errors.New("missing )")
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+// parseComparison parses an operand, optionally compared with another one.
+func (p *filterParser) parseComparison() (filterNode, error) {
+	leftToken, left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != tokenOp {
+		return filterTruthy{left}, nil
+	}
+	op := p.tokens[p.pos].text
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "=~", "!~":
+		p.pos++
+	default:
+		return filterTruthy{left}, nil
+	}
+	rightToken, right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	node := filterCompare{op: op, left: left, right: right}
+	if op == "=~" || op == "!~" {
+		if rightToken.kind != tokenString {
+			return nil, fmt.Errorf("%s expects a quoted regular expression, got %q", op, rightToken.text)
+		}
+		if node.re, err = regexp.Compile(rightToken.value.(string)); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	// The other side of a comparison with the level is a level name.
+	if left.level {
+		if node.right, err = levelOperand(rightToken, right); err != nil {
+			return nil, err
+		}
+	} else if right.level {
+		if node.left, err = levelOperand(leftToken, left); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+// levelOperand returns the literal level named by a token compared with the level.
+func levelOperand(tok filterToken, operand filterOperand) (filterOperand, error) {
+	if operand.level {
+		return operand, nil
+	}
+	name := tok.text
+	if tok.kind == tokenString {
+		name = tok.value.(string)
+	}
+	if _, ok := parseFilterLevel(name); tok.kind == tokenNumber || !ok {
+		return filterOperand{}, fmt.Errorf("unknown level %s", tok.text)
+	}
+	return filterOperand{literal: name, level: true}, nil
+}
+
+// parseOperand parses a literal or a reference to the entry.
+func (p *filterParser) parseOperand() (filterToken, filterOperand, error) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, filterOperand{}, errors.New("unexpected end of expression")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch tok.kind {
+	case tokenString, tokenNumber:
+		return tok, filterOperand{literal: tok.value}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return tok, filterOperand{literal: true}, nil
+		case "false":
+			return tok, filterOperand{literal: false}, nil
+		case "level":
+			return tok, filterOperand{path: []string{"level"}, level: true}, nil
+		}
+		path := strings.Split(tok.text, ".")
+		for _, key := range path {
+			if key == "" {
+				return tok, filterOperand{}, fmt.Errorf("invalid field %q", tok.text)
+			}
+		}
+		return tok, filterOperand{path: path}, nil
+	default:
+		return tok, filterOperand{}, fmt.Errorf("unexpected %q", tok.text)
+	}
+}
+
+// filterCore writes to the wrapped core only the entries matching a filter expression.
+//
+// The fields attached with With are passed down and also kept in context, so that the
+// expression sees them.
+type filterCore struct {
+	zapcore.Core
+	filter  filterNode
+	context *zapcore.MapObjectEncoder
+}
+
+// newFilterCore wraps core so that only entries matching expr are written.
+func newFilterCore(core zapcore.Core, expr string) (zapcore.Core, error) {
+	filter, err := compileFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &filterCore{Core: core, filter: filter, context: zapcore.NewMapObjectEncoder()}, nil
+}
+
+// With implements zapcore.Core.
+func (c *filterCore) With(fields []zapcore.Field) zapcore.Core {
+	return &filterCore{Core: c.Core.With(fields), filter: c.filter, context: cloneContext(c.context, fields)}
+}
+
+// Check implements zapcore.Core.
+func (c *filterCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write writes the entry to the wrapped core if it matches the filter.
+func (c *filterCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	e := newEntry(ent, c.context, fields)
+	if !c.filter.eval(&e) {
+		return nil
+	}
+	return c.Core.Write(ent, fields)
+}
@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilterEval(t *testing.T) {
+	warn := Entry{
+		Level:   LevelWarn,
+		Logger:  "payments",
+		Message: "charge failed",
+		Caller:  "payments/charge.go:42",
+		Fields: map[string]any{
+			"event_code":  "PAY-1042",
+			"status":      int64(503),
+			"duration":    1.75,
+			"code":        "9",
+			"retry":       true,
+			"attempts":    int64(0),
+			"note":        "",
+			"http.method": "POST",
+			"http":        map[string]any{"method": "GET", "status": int64(500), "route": "/charge"},
+		},
+	}
+	info := Entry{Level: LevelInfo, Message: "charged", Fields: map[string]any{}}
+
+	tests := []struct {
+		name string
+		expr string
+		e    Entry
+		want bool
+	}{
+		// Precedence and grouping.
+		{"&& binds tighter than ||", `retry || missing && attempts`, warn, true},
+		{"|| within parentheses", `(retry || missing) && attempts`, warn, false},
+		{"! applies to its operand", `!retry && status == 503`, warn, false},
+		{"! applies to parentheses", `!(retry && attempts)`, warn, true},
+		{"double negation", `!!retry`, warn, true},
+
+		// Missing fields.
+		{"missing field equals nothing", `missing == ""`, warn, false},
+		{"missing field differs from anything", `missing != "x"`, warn, true},
+		{"missing field matches no regular expression", `missing =~ ".*"`, warn, false},
+		{"missing field does not match !~", `missing !~ "x"`, warn, true},
+		{"missing field is not ordered", `missing < 10 || missing >= 10`, warn, false},
+		{"missing field is falsy", `missing`, warn, false},
+
+		// Levels.
+		{"level compared with a quoted name", `level >= "warn"`, warn, true},
+		{"level compared with a bare name", `level >= warn`, warn, true},
+		{"level below the name", `level >= warn`, info, false},
+		{"level names are case-insensitive", `level == WARN`, warn, true},
+		{"warning is an alias of warn", `level >= warning`, warn, true},
+		{"level on the right side", `error > level`, warn, true},
+		{"level order is not alphabetical", `level > debug && level < error`, warn, true},
+
+		// Numbers and strings.
+		{"numbers compare numerically", `status >= 500`, warn, true},
+		{"floats", `duration > 1.5 && duration < 2`, warn, true},
+		{"negative numbers", `duration > -1`, warn, true},
+		{"number against a numeric string", `status == "503"`, warn, true},
+		{"strings compare as strings", `code < "10"`, warn, false},
+		{"string field against a number", `code < 10`, warn, false},
+		{"booleans", `retry == true && retry != false`, warn, true},
+		{"string equality", `message == "charge failed"`, warn, true},
+		{"logger", `logger == "payments"`, warn, true},
+		{"caller", `caller =~ "^payments/"`, warn, true},
+
+		// Field keys.
+		{"dotted key holding the field", `http.method == "POST"`, warn, true},
+		{"dots reach into nested objects", `http.status >= 500 && http.route == "/charge"`, warn, true},
+		{"missing nested key", `http.host`, warn, false},
+		{"dots through a non-object", `status.code`, warn, false},
+
+		// Regular expressions.
+		{"regular expression", `event_code =~ "^PAY"`, warn, true},
+		{"negated regular expression", `event_code !~ "^PAY"`, warn, false},
+		{"regular expression in a raw string", "event_code =~ `^PAY-\\d+$`", warn, true},
+		{"escaped backslash in a quoted string", `event_code =~ "^PAY-\\d+$"`, warn, true},
+		{"regular expression on a number", `status =~ "^5"`, warn, true},
+
+		// Truthiness.
+		{"true boolean", `retry`, warn, true},
+		{"zero number", `attempts`, warn, false},
+		{"empty string", `note`, warn, false},
+		{"nested object", `http`, warn, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := compileFilter(tt.expr)
+			if err != nil {
+				t.Fatalf("compileFilter(%s): %v", tt.expr, err)
+			}
+			if got := filter.eval(&tt.e); got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`message == "unterminated`, "unterminated string"},
+		{"message == `unterminated", "unterminated string"},
+		{`event_code =~ "^PAY-\d+"`, "escape backslashes or use a `...` raw string"},
+		{`message == "bad \q escape"`, "invalid string"},
+		{`(retry && status == 500`, "missing )"},
+		{`status ==`, "unexpected end of expression"},
+		{``, "unexpected end of expression"},
+		{`status == 500 retry`, `unexpected "retry"`},
+		{`status # 500`, `unexpected '#'`},
+		{`&& retry`, `unexpected "&&"`},
+		{`http. == 1`, "invalid field"},
+		{`level >= loud`, "unknown level loud"},
+		{`level == 3`, "unknown level 3"},
+		{`event_code =~ code`, "expects a quoted regular expression"},
+		{`event_code =~ "("`, "missing closing )"},
+		{`status == 1.2.3`, "invalid number 1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := compileFilter(tt.expr)
+			if err == nil {
+				t.Fatalf("compileFilter(%s) succeeded, want an error containing %q", tt.expr, tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("compileFilter(%s) = %v, want an error containing %q", tt.expr, err, tt.want)
+			}
+		})
+	}
+}
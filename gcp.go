@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"os"
+	"strconv"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// EncodingGCP emits one JSON object per line following the structured logging format of
+// Google Cloud Logging, read by Cloud Run, GKE and the Ops Agent: levels are mapped to
+// severity, the caller to logging.googleapis.com/sourceLocation, and the trace_id and
+// span_id fields to logging.googleapis.com/trace and logging.googleapis.com/spanId, so
+// that entries are correlated with Cloud Trace.
+//
+// The trace is only linked when the project is known: it is read from the
+// GOOGLE_CLOUD_PROJECT environment variable. Without it, the trace ID is emitted as is.
+const EncodingGCP = "gcp"
+
+// Special keys of the Cloud Logging structured logging format.
+const (
+	gcpTraceKey          = "logging.googleapis.com/trace"
+	gcpSpanKey           = "logging.googleapis.com/spanId"
+	gcpSourceLocationKey = "logging.googleapis.com/sourceLocation"
+)
+
+// gcpEncoderConfig returns the encoder settings of GCP outputs. The caller is encoded by
+// gcpEncoder as a source location object.
+func gcpEncoderConfig() zapcore.EncoderConfig {
+	return zapcore.EncoderConfig{
+		TimeKey:        "timestamp",
+		LevelKey:       "severity",
+		NameKey:        "logger",
+		CallerKey:      zapcore.OmitKey,
+		FunctionKey:    zapcore.OmitKey,
+		MessageKey:     "message",
+		StacktraceKey:  "stack_trace",
+		LineEnding:     zapcore.DefaultLineEnding,
+		EncodeLevel:    SeverityGCP.encodeLevel,
+		EncodeTime:     zapcore.RFC3339NanoTimeEncoder,
+		EncodeDuration: zapcore.MillisDurationEncoder,
+	}
+}
+
+// gcpEncoder wraps a JSON encoder to emit the special fields of Cloud Logging.
+type gcpEncoder struct {
+	zapcore.Encoder
+	project string
+}
+
+// newGCPEncoder returns a GCP encoder using the keys of cfg.
+func newGCPEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &gcpEncoder{Encoder: zapcore.NewJSONEncoder(cfg), project: os.Getenv("GOOGLE_CLOUD_PROJECT")}
+}
+
+// Clone implements zapcore.Encoder.
+func (e *gcpEncoder) Clone() zapcore.Encoder {
+	return &gcpEncoder{Encoder: e.Encoder.Clone(), project: e.project}
+}
+
+// AddString implements zapcore.ObjectEncoder, renaming the trace and span IDs attached
+// to derived loggers.
+func (e *gcpEncoder) AddString(key, value string) {
+	switch key {
+	case "trace_id":
+		e.Encoder.AddString(gcpTraceKey, e.trace(value))
+	case "span_id":
+		e.Encoder.AddString(gcpSpanKey, value)
+	default:
+		e.Encoder.AddString(key, value)
+	}
+}
+
+// EncodeEntry implements zapcore.Encoder.
+func (e *gcpEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	extra := 0
+	if ent.Caller.Defined {
+		extra = 1
+	}
+	out := make([]zapcore.Field, 0, len(fields)+extra)
+	for _, f := range fields {
+		if f.Type == zapcore.StringType {
+			switch f.Key {
+			case "trace_id":
+				f = zap.String(gcpTraceKey, e.trace(f.String))
+			case "span_id":
+				f.Key = gcpSpanKey
+			}
+		}
+		out = append(out, f)
+	}
+	if ent.Caller.Defined {
+		out = append(out, zap.Object(gcpSourceLocationKey, gcpSourceLocation(ent.Caller)))
+	}
+	return e.Encoder.EncodeEntry(ent, out)
+}
+
+// trace returns the trace resource name of a trace ID.
+func (e *gcpEncoder) trace(id string) string {
+	if e.project == "" {
+		return id
+	}
+	return "projects/" + e.project + "/traces/" + id
+}
+
+// gcpSourceLocation encodes a caller as a Cloud Logging source location.
+type gcpSourceLocation zapcore.EntryCaller
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (c gcpSourceLocation) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("file", c.File)
+	enc.AddString("line", strconv.Itoa(c.Line))
+	if c.Function != "" {
+		enc.AddString("function", c.Function)
+	}
+	return nil
+}
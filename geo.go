@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"net/netip"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// GeoInfo describes the coarse origin of a request.
+type GeoInfo struct {
+	// Country is the ISO 3166-1 alpha-2 country code (e.g. "DE").
+	Country string
+	// ASN is the autonomous system number of the network.
+	ASN uint
+	// Organization is the organization owning the autonomous system.
+	Organization string
+}
+
+// GeoEnricher resolves client addresses to geographic information for access logs.
+//
+// Lookup is called once per logged request and must be safe for concurrent use. It
+// returns false when the address is unknown. See the loggeo package for a MaxMind-backed
+// implementation.
+type GeoEnricher interface {
+	Lookup(ip netip.Addr) (GeoInfo, bool)
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler, omitting unknown attributes.
+func (g GeoInfo) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	if g.Country != "" {
+		enc.AddString("country", g.Country)
+	}
+	if g.ASN != 0 {
+		enc.AddUint("asn", g.ASN)
+	}
+	if g.Organization != "" {
+		enc.AddString("as_org", g.Organization)
+	}
+	return nil
+}
+
+// geoField returns the `geo` field for ip, or false when it cannot be resolved.
+//
+// Private, loopback and link-local addresses are never looked up.
+func geoField(geo GeoEnricher, ip string) (zap.Field, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || addr.IsPrivate() || addr.IsLoopback() || addr.IsLinkLocalUnicast() || addr.IsUnspecified() {
+		return zap.Field{}, false
+	}
+	info, ok := geo.Lookup(addr)
+	if !ok || info == (GeoInfo{}) {
+		return zap.Field{}, false
+	}
+	return zap.Object("geo", info), true
+}
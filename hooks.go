@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Hook observes an entry as it is written, together with all of its fields.
+type Hook func(zapcore.Entry, []zapcore.Field) error
+
+// hooks holds the registered hooks. It is replaced, never modified.
+var (
+	hooksMu sync.Mutex
+	hooks   atomic.Pointer[[]Hook]
+)
+
+// RegisterHook registers a function called for every entry written by any logger, for
+// example to feed alerting, audit or metrics systems. Unlike zap.Hooks, it receives the
+// fields: those attached with With first, then those of the logging call.
+//
+// Hooks run synchronously in the logging goroutine, after sampling, deduplication,
+// redaction and anonymization, in registration order. They must be fast, safe for
+// concurrent use, must not retain the fields slice and must not log through the same
+// logger. Returned errors are reported on the logger's error output.
+//
+// Example:
+//
+//	logger.RegisterHook(func(ent zapcore.Entry, fields []zapcore.Field) error {
+//	    if ent.Level >= zapcore.ErrorLevel {
+//	        errorsTotal.WithLabelValues(ent.LoggerName).Inc()
+//	    }
+//	    return nil
+//	})
+func RegisterHook(fn func(zapcore.Entry, []zapcore.Field) error) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	var current []Hook
+	if p := hooks.Load(); p != nil {
+		current = *p
+	}
+	updated := append(current[:len(current):len(current)], fn)
+	hooks.Store(&updated)
+}
+
+// loadHooks returns the registered hooks.
+func loadHooks() []Hook {
+	if p := hooks.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// hookCore calls the registered hooks for every entry written.
+type hookCore struct {
+	zapcore.Core
+	// context holds the fields attached with With, which hooks receive with every entry.
+	context []zapcore.Field
+}
+
+// With implements zapcore.Core.
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	context := append(c.context[:len(c.context):len(c.context)], fields...)
+	return &hookCore{Core: c.Core.With(fields), context: context}
+}
+
+// Check registers this core so that Write sees the entry, unless no hook is registered.
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if len(loadHooks()) == 0 {
+		return c.Core.Check(ent, ce)
+	}
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write calls the hooks, then writes the entry to the wrapped core.
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	all := fields
+	if len(c.context) > 0 {
+		all = make([]zapcore.Field, 0, len(c.context)+len(fields))
+		all = append(append(all, c.context...), fields...)
+	}
+	var errs []error
+	for _, hook := range loadHooks() {
+		if err := hook(ent, all); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(writeChecked(c.Core, ent, fields), errors.Join(errs...))
+}
@@ -0,0 +1,390 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// defaultMaxErrorBody is the default number of response body bytes captured for 5xx responses.
+const defaultMaxErrorBody = 1024
+
+// HTTPConfig configures the HTTP access-log middleware.
+type HTTPConfig struct {
+	// Logger writes the access log entries. Defaults to the global logger.
+	Logger *Logger
+	// MaxErrorBody bounds the number of response body bytes attached to 5xx entries.
+	// Defaults to 1024; a negative value disables body capture.
+	MaxErrorBody int
+	// ClientIP configures how the client address is resolved behind proxies.
+	ClientIP ClientIPConfig
+	// Geo, when set, adds the coarse origin (country, ASN) of the client as a `geo` field.
+	Geo GeoEnricher
+	// Rules suppress or sample the entries of high-volume requests such as health checks
+	// and static assets. The first matching rule applies.
+	Rules []AccessRule
+	// Routes overrides the level, body capture and sampling of specific routes.
+	Routes *RouteRegistry
+	// RequestIDHeader names the request and response header carrying the request ID.
+	// Defaults to X-Request-ID.
+	RequestIDHeader string
+	// TraceHeader names the request and response header carrying the W3C trace context.
+	// Defaults to traceparent.
+	TraceHeader string
+	// NewRequestID generates the IDs of requests without a valid RequestIDHeader.
+	// Defaults to random 128-bit IDs in lowercase hex.
+	NewRequestID func() string
+	// DisableResponseHeaders stops the middleware from writing correlation headers.
+	DisableResponseHeaders bool
+}
+
+// HTTPMiddleware logs one structured access-log entry per request using the global logger.
+//
+// Example:
+//
+//	mux := http.NewServeMux()
+//	mux.HandleFunc("/", handler)
+//	http.ListenAndServe(":8080", logger.HTTPMiddleware(mux))
+func HTTPMiddleware(next http.Handler) http.Handler {
+	return NewHTTPMiddleware(HTTPConfig{})(next)
+}
+
+// NewHTTPMiddleware returns an access-log middleware configured by cfg.
+//
+// Each request produces one entry with the method, path, status, duration, response size
+// and remote address. Entries are logged at INFO, WARN for 4xx and ERROR for 5xx. For 5xx
+// responses the (truncated) response body and any error recorded with SetRequestError are
+// attached, so the entry is self-contained for triage.
+//
+// Every request is assigned a request ID, logged as request_id and returned to the client
+// in a response header together with the trace context, so users can report IDs that map
+// 1:1 to log entries. Handlers get a request-scoped logger carrying the request_id from
+// FromContext(r.Context()), so their own entries share it, and can add fields to the
+// access-log entry with Attach.
+//
+// Correlation does not require OpenTelemetry: the request ID and W3C trace context sent
+// by the caller in the RequestIDHeader and TraceHeader headers are reused, and new ones
+// are generated when they are absent or invalid. Entries then carry the trace_id of the
+// caller's trace, and span_id of its calling span. When the middleware runs inside the
+// OpenTelemetry instrumentation, the trace context of the request span is used instead.
+// A generated trace context only appears in the log fields and the response header: it
+// is not an OpenTelemetry span context, so tracing inside the middleware is unaffected.
+func NewHTTPMiddleware(cfg HTTPConfig) func(http.Handler) http.Handler {
+	if cfg.MaxErrorBody == 0 {
+		cfg.MaxErrorBody = defaultMaxErrorBody
+	}
+	if cfg.RequestIDHeader == "" {
+		cfg.RequestIDHeader = HeaderRequestID
+	}
+	if cfg.TraceHeader == "" {
+		cfg.TraceHeader = HeaderTraceparent
+	}
+	if cfg.NewRequestID == nil {
+		cfg.NewRequestID = newRequestID
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			r = r.WithContext(extractTraceContext(r.Context(), r.Header.Get(cfg.TraceHeader)))
+			state := &requestState{id: cfg.requestID(r)}
+			if !cfg.DisableResponseHeaders {
+				w.Header().Set(cfg.RequestIDHeader, state.id)
+				if tp := formatTraceparent(logSpanContext(r.Context())); tp != "" {
+					w.Header().Set(cfg.TraceHeader, tp)
+				}
+			}
+			log := cfg.Logger
+			if log == nil {
+				log = Get()
+			}
+			ex := &exchange{log: log, cfg: cfg, r: r, state: state, start: start}
+			ex.route, _ = cfg.Routes.Lookup(r.URL.Path)
+			rw := &responseWriter{ResponseWriter: w, maxBody: cfg.MaxErrorBody, ex: ex}
+			if ex.route.DisableBodyCapture {
+				rw.maxBody = 0
+			}
+
+			ctx := context.WithValue(r.Context(), requestStateKey{}, state)
+			ctx = context.WithValue(ctx, loggerKey{}, requestLogger(ctx, cfg.Logger, state.id))
+			ex.ctx = WithAttachments(ctx)
+			next.ServeHTTP(rw, r.WithContext(ex.ctx))
+
+			switch {
+			case rw.hijacked:
+				// The connection outlives the handler; it logs its own closing.
+			case rw.streaming:
+				ex.logClosed(rw.statusCode(), 0, rw.written)
+			default:
+				ex.logAccess(rw)
+			}
+		})
+	}
+}
+
+// requestID returns the request ID sent by the caller, or a new one.
+func (cfg HTTPConfig) requestID(r *http.Request) string {
+	if id := r.Header.Get(cfg.RequestIDHeader); validRequestID(id) {
+		return id
+	}
+	return cfg.NewRequestID()
+}
+
+// requestLogger returns the request-scoped logger handlers get from FromContext: the
+// configured logger, or the one already carried by ctx, with the request ID attached.
+func requestLogger(ctx context.Context, log *Logger, id string) *Logger {
+	if log == nil {
+		log = contextLogger(ctx)
+	}
+	return log.WithContext(zap.String("request_id", id))
+}
+
+// exchange is a request being served through the middleware.
+type exchange struct {
+	log *Logger
+	cfg HTTPConfig
+	r   *http.Request
+	// ctx is the context of the handler, collecting the fields attached with Attach.
+	ctx   context.Context
+	state *requestState
+	start time.Time
+	// route holds the per-route overrides of the request.
+	route RouteConfig
+	// kind is the connection kind of upgraded and streaming requests.
+	kind string
+}
+
+// level returns the level of entries about a response with the given status.
+func (ex *exchange) level(status int) zapcore.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return zapcore.ErrorLevel
+	case status >= http.StatusBadRequest:
+		return zapcore.WarnLevel
+	default:
+		return parseLevel(ex.route.successLevel())
+	}
+}
+
+// check returns a checked entry for msg, or nil if it is suppressed or disabled.
+func (ex *exchange) check(status int, msg string) *zapcore.CheckedEntry {
+	// Check against the core directly: caller and stack trace would only point at
+	// the middleware itself.
+	ent := zapcore.Entry{LoggerName: ex.log.Name(), Time: time.Now(), Level: ex.level(status), Message: msg}
+	core := ex.log.Core()
+	if !shouldLog(ex.r, status, ex.route.Rules, ex.cfg.Rules) {
+		// Dropped by the rules unless the entry is exempt.
+		if core = suppressed(core, suppressionAccessRule); core == nil {
+			return nil
+		}
+	}
+	return core.Check(ent, nil)
+}
+
+// clientFields returns the client, trace and geo fields of the request.
+func (ex *exchange) clientFields() []zap.Field {
+	clientIP := ex.cfg.ClientIP.ClientIP(ex.r)
+	fields := []zap.Field{
+		zap.String("remote_ip", clientIP),
+		zap.String("user_agent", ex.r.UserAgent()),
+	}
+	fields = append(fields, TraceFields(ex.r.Context())...)
+	if ex.cfg.Geo != nil {
+		if field, ok := geoField(ex.cfg.Geo, clientIP); ok {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// logAccess writes the access-log entry of a completed request.
+func (ex *exchange) logAccess(rw *responseWriter) {
+	status := rw.statusCode()
+	ce := ex.check(status, "http request")
+	if ce == nil {
+		return
+	}
+
+	fields := []zap.Field{
+		zap.String("request_id", ex.state.id),
+		zap.String("method", ex.r.Method),
+		zap.String("path", ex.r.URL.Path),
+		zap.Int("status", status),
+		Latency(time.Since(ex.start)),
+		zap.Int64("bytes", rw.written),
+	}
+	fields = append(fields, ex.clientFields()...)
+	fields = append(fields, Attachments(ex.ctx)...)
+	if status >= http.StatusInternalServerError {
+		if err := ex.state.requestErr(); err != nil {
+			fields = append(fields, zap.Error(err))
+		}
+		if len(rw.body) > 0 {
+			fields = append(fields, zap.String("response_body", sanitizeBody(rw.body)))
+		}
+	}
+	ce.Write(fields...)
+}
+
+// remoteIP returns the host part of the request's peer address.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// sanitizeBody converts a captured body to a string, dropping invalid UTF-8 sequences
+// such as a multi-byte character cut by truncation.
+func sanitizeBody(body []byte) string {
+	return strings.ToValidUTF8(string(body), "")
+}
+
+// requestStateKey is the context key of the per-request middleware state.
+type requestStateKey struct{}
+
+// requestState collects information contributed by handlers during a request.
+type requestState struct {
+	id string
+
+	// mu guards err: handlers may record it from other goroutines, e.g. one still
+	// running after the handler returned, while the middleware reads it.
+	mu  sync.Mutex
+	err error
+}
+
+// setErr records the error of the request.
+func (s *requestState) setErr(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// requestErr returns the error recorded with SetRequestError, if any.
+func (s *requestState) requestErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// SetRequestError records err as the cause of a failed request.
+//
+// The error is attached to the access-log entry when the response is a 5xx. It is a
+// no-op when the request is not served through the HTTP middleware.
+//
+// Example:
+//
+//	if err := svc.Do(r.Context()); err != nil {
+//	    logger.SetRequestError(r.Context(), err)
+//	    http.Error(w, "internal error", http.StatusInternalServerError)
+//	    return
+//	}
+func SetRequestError(ctx context.Context, err error) {
+	if state, ok := ctx.Value(requestStateKey{}).(*requestState); ok {
+		state.setErr(err)
+	}
+}
+
+// responseWriter records the status, size and (for 5xx) the beginning of the response body.
+//
+// It also detects long-lived requests: hijacked (upgraded) connections and streamed
+// responses, which are logged as connection open and close events instead.
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+	maxBody int
+	body    []byte
+
+	ex        *exchange
+	hijacked  bool
+	streaming bool
+}
+
+// WriteHeader records the status code.
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.setStatus(status)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the response size and captures the body of 5xx responses.
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.setStatus(http.StatusOK)
+	}
+	if w.status >= http.StatusInternalServerError && len(w.body) < w.maxBody {
+		w.body = append(w.body, p[:min(len(p), w.maxBody-len(w.body))]...)
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// setStatus records the response status and detects streamed responses: server-sent
+// events and responses of streaming routes.
+func (w *responseWriter) setStatus(status int) {
+	w.status = status
+	if status < http.StatusMultipleChoices && (isEventStream(w.Header()) || w.ex.route.Streaming) {
+		w.startStream()
+	}
+}
+
+// startStream marks the response as streamed and logs the opening of the connection.
+func (w *responseWriter) startStream() {
+	if w.streaming || w.hijacked {
+		return
+	}
+	w.streaming = true
+	w.ex.logOpened(ConnStream, w.statusCode())
+}
+
+// statusCode returns the response status, defaulting to 200 when nothing was written.
+func (w *responseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Flush implements http.Flusher when the underlying writer supports it. Flushing alone
+// does not mark the response as streamed: handlers flush ordinary responses too.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.setStatus(http.StatusOK)
+		}
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer supports it. The returned
+// connection logs its closing together with the bytes transferred.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("logger: underlying ResponseWriter does not support hijacking")
+	}
+	conn, brw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.hijacked = true
+	w.ex.logOpened(upgradeKind(w.ex.r), http.StatusSwitchingProtocols)
+	conn, brw = w.ex.track(conn, brw)
+	return conn, brw, nil
+}
+
+// Unwrap returns the underlying writer, for use with http.ResponseController.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
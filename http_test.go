@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSetRequestError(t *testing.T) {
+	buf := NewMemoryBuffer(10)
+	log, err := New(Config{Level: LevelInfo, Outputs: []OutputConfig{{Writer: buf, Encoding: EncodingJSON}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errGateway := errors.New("payment gateway down")
+
+	tests := []struct {
+		name   string
+		status int
+		want   bool
+	}{
+		{"server error", http.StatusInternalServerError, true},
+		{"client error", http.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make(chan struct{})
+			h := NewHTTPMiddleware(HTTPConfig{Logger: log})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				SetRequestError(r.Context(), errGateway)
+				// A goroutine outliving the handler may record the error while the
+				// middleware reads it.
+				go func() {
+					defer close(done)
+					SetRequestError(r.Context(), errGateway)
+				}()
+				w.WriteHeader(tt.status)
+			}))
+			before := len(buf.Lines())
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/charge", nil))
+			<-done
+
+			lines := buf.Lines()[before:]
+			if len(lines) != 1 {
+				t.Fatalf("logged %q, want one access-log entry", lines)
+			}
+			if got := bytes.Contains(lines[0], []byte(errGateway.Error())); got != tt.want {
+				t.Fatalf("entry %s: error attached = %v, want %v", lines[0], got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Connection kinds of long-lived requests.
+const (
+	// ConnWebSocket is a connection upgraded to the WebSocket protocol.
+	ConnWebSocket = "websocket"
+	// ConnUpgrade is a connection hijacked or upgraded to another protocol.
+	ConnUpgrade = "upgrade"
+	// ConnStream is a streamed response, such as server-sent events or a long poll of a
+	// streaming route.
+	ConnStream = "stream"
+)
+
+// upgradeKind returns the connection kind of a hijacked request.
+func upgradeKind(r *http.Request) string {
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return ConnWebSocket
+	}
+	return ConnUpgrade
+}
+
+// isEventStream reports whether the response headers announce server-sent events.
+func isEventStream(h http.Header) bool {
+	return strings.HasPrefix(h.Get("Content-Type"), "text/event-stream")
+}
+
+// logOpened writes the entry marking the start of a long-lived connection.
+func (ex *exchange) logOpened(kind string, status int) {
+	ex.kind = kind
+	ce := ex.check(status, "connection opened")
+	if ce == nil {
+		return
+	}
+	fields := []zap.Field{
+		zap.String("request_id", ex.state.id),
+		zap.String("method", ex.r.Method),
+		zap.String("path", ex.r.URL.Path),
+		zap.String("connection", kind),
+		zap.Int("status", status),
+	}
+	ce.Write(append(fields, ex.clientFields()...)...)
+}
+
+// logClosed writes the entry marking the end of a long-lived connection, with its
+// duration and the bytes transferred in each direction.
+func (ex *exchange) logClosed(status int, bytesIn, bytesOut int64) {
+	ce := ex.check(status, "connection closed")
+	if ce == nil {
+		return
+	}
+	fields := []zap.Field{
+		zap.String("request_id", ex.state.id),
+		zap.String("method", ex.r.Method),
+		zap.String("path", ex.r.URL.Path),
+		zap.String("connection", ex.kind),
+		zap.Int("status", status),
+		Latency(time.Since(ex.start)),
+		zap.Int64("bytes_in", bytesIn),
+		zap.Int64("bytes_out", bytesOut),
+	}
+	fields = append(fields, ex.clientFields()...)
+	ce.Write(append(fields, Attachments(ex.ctx)...)...)
+}
+
+// trackedConn counts the bytes transferred over a hijacked connection and logs its
+// closing exactly once.
+type trackedConn struct {
+	net.Conn
+	ex       *exchange
+	read     atomic.Int64
+	written  atomic.Int64
+	closeLog sync.Once
+}
+
+// Read implements net.Conn, counting received bytes.
+func (c *trackedConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+// Write implements net.Conn, counting sent bytes.
+func (c *trackedConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+// Close implements net.Conn and logs the end of the connection.
+func (c *trackedConn) Close() error {
+	err := c.Conn.Close()
+	c.closeLog.Do(func() {
+		c.ex.logClosed(http.StatusSwitchingProtocols, c.read.Load(), c.written.Load())
+	})
+	return err
+}
+
+// countingReader counts the bytes read through the hijacked connection's buffered reader.
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+// Read implements io.Reader.
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+// track wraps a hijacked connection and its buffered reader/writer so that traffic
+// through either is counted.
+func (ex *exchange) track(conn net.Conn, brw *bufio.ReadWriter) (net.Conn, *bufio.ReadWriter) {
+	tc := &trackedConn{Conn: conn, ex: ex}
+	// The original reader may hold buffered bytes, so keep reading through it.
+	reader := bufio.NewReaderSize(countingReader{r: brw.Reader, n: &tc.read}, brw.Reader.Size())
+	writer := bufio.NewWriterSize(tc, brw.Writer.Size())
+	return tc, bufio.NewReadWriter(reader, writer)
+}
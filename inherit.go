@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// EnvInheritedFields is the environment variable carrying the fields a child process
+// inherits from its parent (see InheritEnv).
+const EnvInheritedFields = "LOG_INHERITED_FIELDS"
+
+// InheritEnv returns the environment entries passing the logging context of ctx to a
+// child process, to be appended to its environment before it is started. A child
+// configured with FromEnv attaches the inherited fields to all of its entries, so log
+// lineage survives process boundaries.
+//
+// The child inherits the fields its parent itself inherited, the parent's service name
+// and process ID (parent_service, parent_pid), the request_id and W3C traceparent carried
+// by ctx, and fields, e.g. the tenant.
+//
+// Example:
+//
+//	cmd := exec.CommandContext(ctx, "convert", input)
+//	cmd.Env = append(os.Environ(), logger.InheritEnv(ctx, zap.String("tenant", tenant))...)
+func InheritEnv(ctx context.Context, fields ...zap.Field) []string {
+	inherited := make(map[string]any)
+	if g := globalState.Load(); g != nil {
+		p := g.core.current.Load()
+		maps.Copy(inherited, p.inherited)
+		inherited["parent_service"] = p.service
+	}
+	inherited["parent_pid"] = os.Getpid()
+	if id := RequestID(ctx); id != "" {
+		inherited["request_id"] = id
+	}
+	if tp := traceparent(ctx); tp != "" {
+		inherited["traceparent"] = tp
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	maps.Copy(inherited, enc.Fields)
+	encoded, err := json.Marshal(inherited)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "logger: failed to encode inherited fields: %v\n", err)
+		return nil
+	}
+	return []string{EnvInheritedFields + "=" + string(encoded)}
+}
+
+// inheritedFromEnv returns the fields inherited from the parent process, or nil when
+// there are none.
+func inheritedFromEnv() map[string]any {
+	value := os.Getenv(EnvInheritedFields)
+	if value == "" {
+		return nil
+	}
+	dec := json.NewDecoder(strings.NewReader(value))
+	// Keep integers as integers rather than converting them to float64.
+	dec.UseNumber()
+	var inherited map[string]any
+	if err := dec.Decode(&inherited); err != nil {
+		fmt.Fprintf(os.Stderr, "logger: ignoring invalid %s: %v\n", EnvInheritedFields, err)
+		return nil
+	}
+	for k, v := range inherited {
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				inherited[k] = i
+			} else if f, err := n.Float64(); err == nil {
+				inherited[k] = f
+			}
+		}
+	}
+	return inherited
+}
+
+// inheritedFields returns the inherited fields of cfg, in key order.
+func inheritedFields(cfg Config) []zapcore.Field {
+	keys := slices.Sorted(maps.Keys(cfg.Inherited))
+	fields := make([]zapcore.Field, len(keys))
+	for i, k := range keys {
+		fields[i] = zap.Any(k, cfg.Inherited[k])
+	}
+	return fields
+}
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"sync"
+	"unique"
+
+	"go.uber.org/zap"
+)
+
+// Intern returns a canonical copy of s, so that frequently repeated values built at
+// runtime (route patterns, event codes, hostnames) share one allocation, which matters
+// when many entries are held in the async queue or in remote sink batches.
+func Intern(s string) string {
+	return unique.Make(s).Value()
+}
+
+// InternedString constructs a string field with an interned value.
+func InternedString(key, value string) zap.Field {
+	return zap.String(key, Intern(value))
+}
+
+// FieldSetCache caches derived loggers for recurring field sets, such as the fields
+// describing a route or an event type.
+//
+// The fields of a derived logger are encoded once, when it is created, instead of on
+// every entry; caching the derived logger per key therefore saves both the allocations
+// and the encoding CPU of those fields. The cache is bounded: once full, loggers for new
+// keys are derived without being cached.
+//
+// Example:
+//
+//	routes := logger.NewFieldSetCache(log, 1024)
+//
+//	routes.With(pattern, func() []zap.Field {
+//	    return []zap.Field{zap.String("route", pattern), zap.String("handler", name)}
+//	}).Info("request handled", zap.Int("status", status))
+type FieldSetCache struct {
+	base    *Logger
+	maxSets int
+
+	mu      sync.RWMutex
+	loggers map[string]*Logger
+}
+
+// NewFieldSetCache returns a cache deriving loggers from base, holding at most maxSets
+// field sets.
+func NewFieldSetCache(base *Logger, maxSets int) *FieldSetCache {
+	return &FieldSetCache{base: base, maxSets: maxSets, loggers: make(map[string]*Logger)}
+}
+
+// With returns the logger derived with the field set identified by key, calling fields
+// to build the set on first use.
+func (c *FieldSetCache) With(key string, fields func() []zap.Field) *Logger {
+	c.mu.RLock()
+	l, ok := c.loggers[key]
+	c.mu.RUnlock()
+	if ok {
+		return l
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if l, ok := c.loggers[key]; ok {
+		return l
+	}
+	l = c.base.WithContext(fields()...)
+	if len(c.loggers) < c.maxSets {
+		c.loggers[key] = l
+	}
+	return l
+}
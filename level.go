@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// SetLevel changes the minimum level of the global logger at runtime, e.g. to
+// temporarily bump a production service to DEBUG without restarting it.
+//
+// The change applies to every logger derived from the global logger and to all outputs
+// without their own OutputConfig.Level. It lasts until the next SetLevel or Reconfigure.
+func SetLevel(level LogLevel) {
+	globalLevel().SetLevel(parseLevel(level))
+}
+
+// CurrentLevel returns the minimum level of the global logger.
+func CurrentLevel() LogLevel {
+	return LogLevel(globalLevel().Level().CapitalString())
+}
+
+// LevelHandler returns an http.Handler reporting and changing the level of the global
+// logger, with the semantics of zap.AtomicLevel.ServeHTTP:
+//
+//   - GET returns the current level as {"level":"info"}.
+//   - PUT sets the level from a JSON body ({"level":"debug"}) or a form value (level=debug).
+//
+// The handler has no authentication of its own; mount it on an internal or protected
+// listener.
+//
+// Example:
+//
+//	mux.Handle("/debug/log/level", logger.LevelHandler())
+//
+//	// curl -X PUT -d '{"level":"debug"}' localhost:6060/debug/log/level
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Resolve the level per request: Reconfigure replaces it.
+		globalLevel().ServeHTTP(w, r)
+	})
+}
+
+// globalLevel returns the adjustable level of the current global pipeline.
+func globalLevel() zap.AtomicLevel {
+	Get()
+	return globalState.Load().core.current.Load().level
+}
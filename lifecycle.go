@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Lifecycle phases reported by Lifecycle.
+const (
+	PhaseStarting = "starting"
+	PhaseReady    = "ready"
+	PhaseDraining = "draining"
+	PhaseStopped  = "stopped"
+)
+
+// Lifecycle logs standardized process lifecycle events with uptime information.
+//
+// Every entry carries a `lifecycle` field with the phase name, the process ID and the uptime
+// in milliseconds, giving orchestrators and dashboards a uniform view of restarts and
+// shutdowns across services.
+//
+// Example:
+//
+//	lc := logger.NewLifecycle(logger.Get())
+//	ctx, stop := lc.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//
+//	srv := startServer()
+//	lc.Ready(zap.String("addr", srv.Addr))
+//
+//	<-ctx.Done()
+//	err := srv.Shutdown(context.Background())
+//	lc.Stopped(err)
+type Lifecycle struct {
+	log   *Logger
+	start time.Time
+}
+
+// NewLifecycle creates a lifecycle logger and immediately reports the "starting" phase.
+func NewLifecycle(log *Logger, fields ...zap.Field) *Lifecycle {
+	lc := &Lifecycle{log: log, start: time.Now()}
+	lc.event(PhaseStarting, "process starting", fields)
+	return lc
+}
+
+// Ready reports that the process is ready to serve traffic.
+func (lc *Lifecycle) Ready(fields ...zap.Field) {
+	lc.event(PhaseReady, "process ready", fields)
+}
+
+// Draining reports that the process stopped accepting new work and is shutting down.
+//
+// sig may be nil when draining was not triggered by a signal.
+func (lc *Lifecycle) Draining(sig os.Signal, fields ...zap.Field) {
+	if sig != nil {
+		fields = append(fields, zap.String("signal", sig.String()))
+	}
+	lc.event(PhaseDraining, "process draining", fields)
+}
+
+// Stopped reports that the process has finished shutting down.
+//
+// A non-nil err is attached to the entry, which is then logged at ERROR level.
+func (lc *Lifecycle) Stopped(err error, fields ...zap.Field) {
+	if err != nil {
+		lc.log.Error("process stopped", lc.fields(PhaseStopped, append(fields, zap.Error(err)))...)
+		return
+	}
+	lc.event(PhaseStopped, "process stopped", fields)
+}
+
+// NotifyContext returns a copy of parent that is cancelled when one of the given signals
+// arrives, reporting the "draining" phase with the received signal before cancelling.
+//
+// It mirrors signal.NotifyContext; calling stop releases the signal handler.
+func (lc *Lifecycle) NotifyContext(parent context.Context, signals ...os.Signal) (ctx context.Context, stop context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+
+	go func() {
+		select {
+		case sig := <-ch:
+			lc.Draining(sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(ch)
+		cancel()
+	}
+}
+
+// Uptime returns the time elapsed since the lifecycle was created.
+func (lc *Lifecycle) Uptime() time.Duration {
+	return time.Since(lc.start)
+}
+
+// event logs a lifecycle phase at INFO level.
+func (lc *Lifecycle) event(phase, msg string, fields []zap.Field) {
+	lc.log.Info(msg, lc.fields(phase, fields)...)
+}
+
+// fields prepends the standard lifecycle fields to the given ones.
+func (lc *Lifecycle) fields(phase string, extra []zap.Field) []zap.Field {
+	return append([]zap.Field{
+		zap.String("lifecycle", phase),
+		zap.Int("pid", os.Getpid()),
+		DurationMS("uptime_ms", lc.Uptime()),
+	}, extra...)
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package logeventlog
+
+import "errors"
+
+// open fails: the Event Log only exists on Windows.
+func open(Config) (eventLog, error) {
+	return nil, errors.New("logeventlog: the Windows Event Log is only available on Windows")
+}
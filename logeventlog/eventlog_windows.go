@@ -0,0 +1,41 @@
+package logeventlog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// windowsLog writes events with the Event Log API.
+type windowsLog struct {
+	*eventlog.Log
+}
+
+// open registers the event source of cfg, installing it first if requested.
+func open(cfg Config) (eventLog, error) {
+	if cfg.Install {
+		err := eventlog.InstallAsEventCreate(cfg.Source, eventlog.Error|eventlog.Warning|eventlog.Info)
+		if err != nil && !strings.Contains(err.Error(), "already exists") {
+			fmt.Fprintf(os.Stderr, "logger: failed to install event source %q: %v\n", cfg.Source, err)
+		}
+	}
+	log, err := eventlog.Open(cfg.Source)
+	if err != nil {
+		return nil, fmt.Errorf("logeventlog: failed to open event source %q: %w", cfg.Source, err)
+	}
+	return windowsLog{log}, nil
+}
+
+// report implements eventLog.
+func (l windowsLog) report(typ eventType, id uint32, msg string) error {
+	switch typ {
+	case eventError:
+		return l.Error(id, msg)
+	case eventWarning:
+		return l.Warning(id, msg)
+	default:
+		return l.Info(id, msg)
+	}
+}
@@ -0,0 +1,194 @@
+// Package logeventlog provides a logger.Sink writing entries to the Windows Event Log,
+// for services on Windows servers whose operators only look at Event Viewer.
+//
+// Importing the package registers the "eventlog" URL scheme, whose host is the event
+// source. The sink writes WARN entries and above by default, so that lower levels only
+// go to the other outputs:
+//
+//	import _ "github.com/matteocavestri/logger-gath-test/logeventlog"
+//
+//	cfg.Outputs = []logger.OutputConfig{
+//	    {Path: "C:\\ProgramData\\Orders\\orders.log"},
+//	    {Path: "eventlog://Orders", Encoding: "console"},
+//	}
+//
+// The sink can also be built from a Config and set as OutputConfig.Sink:
+//
+//	sink, err := logeventlog.New(logeventlog.Config{Source: "Orders", Install: true})
+//	if err != nil {
+//	    panic(err)
+//	}
+//	cfg.Outputs = []logger.OutputConfig{{Sink: sink}}
+//
+// The text of an event is the entry encoded with the output's Encoding. WARN entries are
+// written as warnings and ERROR entries and above as errors. On other platforms, New
+// fails, so that a configuration written for Windows is not silently ignored.
+package logeventlog
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+)
+
+// Defaults of the sink.
+const (
+	defaultEventID = 1
+	// maxMessageSize bounds the text of an event, which the Event Log limits to 31839
+	// characters.
+	maxMessageSize = 31_000
+)
+
+// levels lists the levels in increasing order of severity.
+var levels = []logger.LogLevel{logger.LevelDebug, logger.LevelInfo, logger.LevelWarn, logger.LevelError, "DPANIC", "PANIC", "FATAL"}
+
+// Config describes the event source to write entries to.
+type Config struct {
+	// Source is the event source, shown in the Source column of Event Viewer, e.g. the
+	// name of the service.
+	Source string
+	// Level is the minimum level of the entries written. Defaults to WARN.
+	Level logger.LogLevel
+	// EventID is the ID of the events, between 1 and 1000. Defaults to 1.
+	EventID uint32
+	// Install registers Source in the Application log when it is not registered yet, so
+	// that Event Viewer shows the text of events without a notice. It requires
+	// administrator rights: when it fails, the error is reported on stderr and events are
+	// still written.
+	Install bool
+}
+
+func init() {
+	_ = logger.RegisterSink("eventlog", func(u *url.URL) (logger.Sink, error) {
+		cfg, err := configFromURL(u)
+		if err != nil {
+			return nil, err
+		}
+		return New(cfg)
+	})
+}
+
+// configFromURL returns the configuration described by an eventlog:// URL.
+func configFromURL(u *url.URL) (Config, error) {
+	q := u.Query()
+	cfg := Config{
+		Source:  u.Host,
+		Level:   logger.LogLevel(strings.ToUpper(q.Get("level"))),
+		Install: q.Get("install") == "true",
+	}
+	if id := q.Get("event_id"); id != "" {
+		n, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return Config{}, fmt.Errorf("logeventlog: invalid event_id %q", id)
+		}
+		cfg.EventID = uint32(n)
+	}
+	return cfg, nil
+}
+
+// validate checks the configuration and applies its defaults.
+func (c Config) validate() (Config, error) {
+	if c.Source == "" {
+		return c, errors.New("logeventlog: source is required")
+	}
+	if c.Level == "" {
+		c.Level = logger.LevelWarn
+	}
+	if !slices.Contains(levels, c.Level) {
+		return c, fmt.Errorf("logeventlog: unknown level %q", c.Level)
+	}
+	if c.EventID == 0 {
+		c.EventID = defaultEventID
+	}
+	if c.EventID > 1000 {
+		return c, fmt.Errorf("logeventlog: event_id %d is above 1000", c.EventID)
+	}
+	return c, nil
+}
+
+// eventType is the type of an event.
+type eventType int
+
+const (
+	eventInfo eventType = iota
+	eventWarning
+	eventError
+)
+
+// eventLog writes events to an event source. It is implemented on Windows only.
+type eventLog interface {
+	report(typ eventType, id uint32, msg string) error
+	Close() error
+}
+
+// Sink writes entries to the Windows Event Log. It is safe for concurrent use.
+type Sink struct {
+	cfg   Config
+	level int
+	log   eventLog
+}
+
+var _ logger.Sink = (*Sink)(nil)
+
+// New validates cfg, applies its defaults and opens its event source.
+func New(cfg Config) (*Sink, error) {
+	cfg, err := cfg.validate()
+	if err != nil {
+		return nil, err
+	}
+	log, err := open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{cfg: cfg, level: slices.Index(levels, cfg.Level), log: log}, nil
+}
+
+// Write implements logger.Sink, writing an event for entries at or above the level.
+func (s *Sink) Write(e logger.Entry, encoded []byte) error {
+	level := slices.Index(levels, e.Level)
+	if level < s.level {
+		return nil
+	}
+	typ := eventInfo
+	switch {
+	case e.Level == logger.LevelWarn:
+		typ = eventWarning
+	case level >= slices.Index(levels, logger.LevelError):
+		typ = eventError
+	}
+	return s.log.report(typ, s.cfg.EventID, message(encoded))
+}
+
+// message returns the text of the event of an encoded entry.
+func message(encoded []byte) string {
+	msg := strings.TrimRight(string(encoded), "\r\n")
+	if len(msg) <= maxMessageSize {
+		return msg
+	}
+	msg = msg[:maxMessageSize]
+	for !utf8.ValidString(msg) {
+		msg = msg[:len(msg)-1]
+	}
+	return msg + "…"
+}
+
+// Sync implements logger.Sink. Events are written synchronously.
+func (s *Sink) Sync() error {
+	return nil
+}
+
+// Close implements logger.Sink, closing the event source.
+func (s *Sink) Close() error {
+	return s.log.Close()
+}
+
+// String implements logger.Sink.
+func (s *Sink) String() string {
+	return "eventlog://" + s.cfg.Source
+}
@@ -0,0 +1,446 @@
+// Package logfluent provides a logger.Sink speaking the Fluentd Forward protocol, so that
+// containers can ship entries directly to a Fluentd or Fluent Bit aggregator.
+//
+// Importing the package registers the "fluent" URL scheme, so that an output can be
+// configured by its Path alone:
+//
+//	import _ "github.com/matteocavestri/logger-gath-test/logfluent"
+//
+//	cfg.Outputs = []logger.OutputConfig{{Path: "fluent://fluent-bit:24224?tag=app.orders"}}
+//
+// The sink can also be built from a Config and set as OutputConfig.Sink:
+//
+//	sink, err := logfluent.New(logfluent.Config{Host: "fluent-bit", Tag: "app.orders"})
+//	if err != nil {
+//	    panic(err)
+//	}
+//	cfg.Outputs = []logger.OutputConfig{{Sink: sink}}
+//
+// Entries are sent as records in Forward mode, with their time as an EventTime carrying
+// nanoseconds. A record holds the level, message, logger name, caller and stack trace,
+// and the fields of the entry, with nested objects as maps. The output's Encoding is not
+// used.
+//
+// Records are buffered and sent in chunks from a background goroutine. Each chunk waits
+// for the acknowledgement of the aggregator unless acks are disabled, so that a chunk is
+// only discarded once the aggregator has it. When the connection fails, the sink
+// reconnects with exponential backoff and resends the chunk; entries logged meanwhile
+// are buffered up to BufferSize, and the newest ones are dropped beyond.
+package logfluent
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+)
+
+// Defaults of the sink.
+const (
+	DefaultPort          = 24224
+	defaultTag           = "logger"
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+	defaultBufferSize    = 10_000
+	defaultTimeout       = 10 * time.Second
+	defaultMinBackoff    = 500 * time.Millisecond
+	defaultMaxBackoff    = 30 * time.Second
+)
+
+// Config describes the Fluentd or Fluent Bit forward input to send entries to.
+type Config struct {
+	// Host is the address of the forward input.
+	Host string
+	// Port is the port of the forward input. Defaults to 24224.
+	Port int
+	// Tag is the Fluentd tag of the records, which routes them in the aggregator. Defaults
+	// to the service of the entry, or "logger" without one.
+	Tag string
+	// DisableAck sends chunks without waiting for their acknowledgement: faster, but
+	// chunks in flight are lost when the connection fails.
+	DisableAck bool
+	// TLSConfig enables TLS, for inputs with tls on.
+	TLSConfig *tls.Config
+	// BatchSize is the number of buffered entries that triggers sending a chunk. Defaults
+	// to 100.
+	BatchSize int
+	// FlushInterval is the maximum time entries wait before being sent. Defaults to 1s.
+	FlushInterval time.Duration
+	// BufferSize bounds the number of buffered entries, e.g. while the aggregator is
+	// unreachable. Defaults to 10000.
+	BufferSize int
+	// Timeout bounds connecting, sending a chunk and waiting for its acknowledgement.
+	// Defaults to 10s.
+	Timeout time.Duration
+	// MinBackoff and MaxBackoff bound the exponential backoff between reconnections.
+	// Default to 500ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func init() {
+	_ = logger.RegisterSink("fluent", func(u *url.URL) (logger.Sink, error) {
+		cfg, err := configFromURL(u)
+		if err != nil {
+			return nil, err
+		}
+		return New(cfg)
+	})
+}
+
+// configFromURL returns the configuration described by a fluent:// URL.
+func configFromURL(u *url.URL) (Config, error) {
+	q := u.Query()
+	cfg := Config{
+		Host:       u.Hostname(),
+		Tag:        q.Get("tag"),
+		DisableAck: q.Get("ack") == "false",
+	}
+	if port := u.Port(); port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			return Config{}, fmt.Errorf("logfluent: invalid port %q", port)
+		}
+		cfg.Port = n
+	}
+	if q.Get("tls") == "true" {
+		cfg.TLSConfig = &tls.Config{ServerName: cfg.Host}
+	}
+	return cfg, nil
+}
+
+// withDefaults returns a copy of the configuration with defaults applied.
+func (c Config) withDefaults() Config {
+	if c.Port == 0 {
+		c.Port = DefaultPort
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultFlushInterval
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = defaultBufferSize
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = defaultMinBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultMaxBackoff
+	}
+	return c
+}
+
+// Sink sends entries to a Fluentd forward input. It is safe for concurrent use.
+type Sink struct {
+	cfg  Config
+	addr string
+
+	mu      sync.Mutex
+	queue   []record
+	dropped int
+
+	// sendMu serializes chunks so that records keep their order. It guards conn.
+	sendMu sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+
+	kick      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ logger.Sink = (*Sink)(nil)
+
+// record is an entry waiting to be sent: its tag and its encoded [time, record] pair.
+type record struct {
+	tag   string
+	entry []byte
+}
+
+// New validates cfg, applies its defaults and starts sending. The aggregator is
+// contacted when the first chunk is sent.
+func New(cfg Config) (*Sink, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("logfluent: host is required")
+	}
+	cfg = cfg.withDefaults()
+	s := &Sink{
+		cfg:  cfg,
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		kick: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write implements logger.Sink. The entry is queued; it is sent asynchronously.
+func (s *Sink) Write(e logger.Entry, _ []byte) error {
+	r := record{tag: s.tag(e), entry: encodeEntry(e)}
+	s.mu.Lock()
+	if len(s.queue) >= s.cfg.BufferSize {
+		s.dropped++
+		s.mu.Unlock()
+		return nil
+	}
+	s.queue = append(s.queue, r)
+	full := len(s.queue) >= s.cfg.BatchSize
+	s.mu.Unlock()
+	if full {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// tag returns the tag of the record of an entry.
+func (s *Sink) tag(e logger.Entry) string {
+	if s.cfg.Tag != "" {
+		return s.cfg.Tag
+	}
+	if service, ok := e.Fields["service"].(string); ok && service != "" {
+		return service
+	}
+	return defaultTag
+}
+
+// encodeEntry encodes the [time, record] pair of an entry.
+func encodeEntry(e logger.Entry) []byte {
+	b := appendArrayHeader(make([]byte, 0, 256), 2)
+	b = appendEventTime(b, e.Time)
+	meta := map[string]string{"level": string(e.Level), "message": e.Message}
+	for key, value := range map[string]string{"logger": e.Logger, "caller": e.Caller, "stacktrace": e.Stack} {
+		if value != "" {
+			meta[key] = value
+		}
+	}
+	n := len(meta)
+	for key := range e.Fields {
+		if _, ok := meta[key]; !ok {
+			n++
+		}
+	}
+	b = appendMapHeader(b, n)
+	for _, key := range slices.Sorted(maps.Keys(meta)) {
+		b = appendString(b, key)
+		b = appendString(b, meta[key])
+	}
+	for _, key := range slices.Sorted(maps.Keys(e.Fields)) {
+		if _, ok := meta[key]; ok {
+			continue
+		}
+		b = appendString(b, key)
+		b = appendValue(b, e.Fields[key])
+	}
+	return b
+}
+
+// run sends chunks on every tick or when a chunk fills up. When the aggregator cannot be
+// reached, it retries with exponential backoff until the chunk is sent or the sink is
+// closed.
+func (s *Sink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+	backoff := s.cfg.MinBackoff
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.kick:
+		case <-s.stop:
+			return
+		}
+		for {
+			err := s.flush()
+			if err == nil {
+				backoff = s.cfg.MinBackoff
+				break
+			}
+			fmt.Fprintf(os.Stderr, "logger: fluent send to %s failed, retrying in %s: %v\n", s.addr, backoff, err)
+			select {
+			case <-time.After(backoff):
+			case <-s.stop:
+				return
+			}
+			backoff = min(backoff*2, s.cfg.MaxBackoff)
+		}
+	}
+}
+
+// flush sends all queued records, one chunk per tag and batch. When a chunk cannot be
+// sent, its records stay queued and the error is returned.
+func (s *Sink) flush() error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	for {
+		s.mu.Lock()
+		n := min(len(s.queue), s.cfg.BatchSize)
+		batch := s.queue[:n:n]
+		dropped := s.dropped
+		s.dropped = 0
+		s.mu.Unlock()
+
+		if dropped > 0 {
+			fmt.Fprintf(os.Stderr, "logger: fluent buffer full, dropped %d entries\n", dropped)
+		}
+		if n == 0 {
+			return nil
+		}
+		if err := s.send(batch); err != nil {
+			s.disconnect()
+			return err
+		}
+		s.dequeue(n)
+	}
+}
+
+// dequeue removes the first n records of the queue, once sent.
+func (s *Sink) dequeue(n int) {
+	s.mu.Lock()
+	s.queue = s.queue[n:]
+	s.mu.Unlock()
+}
+
+// send sends a batch as Forward mode messages, one per tag, and waits for their acks.
+func (s *Sink) send(batch []record) error {
+	if err := s.connect(); err != nil {
+		return err
+	}
+	var tags []string
+	byTag := make(map[string][]record)
+	for _, r := range batch {
+		if _, ok := byTag[r.tag]; !ok {
+			tags = append(tags, r.tag)
+		}
+		byTag[r.tag] = append(byTag[r.tag], r)
+	}
+	for _, tag := range tags {
+		if err := s.sendChunk(tag, byTag[tag]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendChunk sends the records of a tag as one Forward mode message.
+func (s *Sink) sendChunk(tag string, records []record) error {
+	var chunk string
+	options := 0
+	if !s.cfg.DisableAck {
+		id := make([]byte, 16)
+		_, _ = rand.Read(id)
+		chunk = base64.StdEncoding.EncodeToString(id)
+		options = 1
+	}
+	msg := appendArrayHeader(nil, 3)
+	msg = appendString(msg, tag)
+	msg = appendArrayHeader(msg, len(records))
+	for _, r := range records {
+		msg = append(msg, r.entry...)
+	}
+	msg = appendMapHeader(msg, options)
+	if chunk != "" {
+		msg = appendString(msg, "chunk")
+		msg = appendString(msg, chunk)
+	}
+
+	_ = s.conn.SetDeadline(time.Now().Add(s.cfg.Timeout))
+	if _, err := s.conn.Write(msg); err != nil {
+		return err
+	}
+	if chunk == "" {
+		return nil
+	}
+	resp, err := readStringMap(s.reader)
+	if err != nil {
+		return fmt.Errorf("reading ack: %w", err)
+	}
+	if resp["ack"] != chunk {
+		return fmt.Errorf("unexpected ack %q", resp["ack"])
+	}
+	return nil
+}
+
+// connect opens the connection to the aggregator if needed. It must be called with
+// sendMu held.
+func (s *Sink) connect() error {
+	if s.conn != nil {
+		return nil
+	}
+	dialer := &net.Dialer{Timeout: s.cfg.Timeout}
+	var conn net.Conn
+	var err error
+	if s.cfg.TLSConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.addr, s.cfg.TLSConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", s.addr)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn, s.reader = conn, bufio.NewReader(conn)
+	return nil
+}
+
+// disconnect closes the connection after a failure, so that the next chunk reconnects.
+// It must be called with sendMu held.
+func (s *Sink) disconnect() {
+	if s.conn != nil {
+		_ = s.conn.Close()
+		s.conn, s.reader = nil, nil
+	}
+}
+
+// Sync implements logger.Sink, sending all queued records. It does not wait for an
+// unreachable aggregator: the records stay queued and the error is returned.
+func (s *Sink) Sync() error {
+	return s.flush()
+}
+
+// Close implements logger.Sink. It sends the remaining records and closes the
+// connection.
+func (s *Sink) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		if err = s.flush(); err != nil {
+			s.mu.Lock()
+			n := len(s.queue)
+			s.queue = nil
+			s.mu.Unlock()
+			fmt.Fprintf(os.Stderr, "logger: fluent send to %s failed, dropped %d entries: %v\n", s.addr, n, err)
+		}
+		s.sendMu.Lock()
+		s.disconnect()
+		s.sendMu.Unlock()
+	})
+	return err
+}
+
+// String implements logger.Sink.
+func (s *Sink) String() string {
+	return "fluent://" + s.addr
+}
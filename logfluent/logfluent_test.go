@@ -0,0 +1,382 @@
+package logfluent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+)
+
+// testEntry is the entry of the fixtures.
+var testEntry = logger.Entry{
+	Time:    time.Unix(1_700_000_000, 123_456_789),
+	Level:   logger.LevelInfo,
+	Message: "hi",
+	Fields:  map[string]any{"n": 1, "service": "orders"},
+}
+
+// testRecord is the [time, record] pair of testEntry.
+var testRecord = []byte{
+	// [time, record], with the time as an EventTime.
+	0x92, 0xd7, 0x00, 0x65, 0x53, 0xf1, 0x00, 0x07, 0x5b, 0xcd, 0x15,
+	// 4 pairs: the metadata, then the fields, each in key order.
+	0x84,
+	0xa5, 'l', 'e', 'v', 'e', 'l', 0xa4, 'I', 'N', 'F', 'O',
+	0xa7, 'm', 'e', 's', 's', 'a', 'g', 'e', 0xa2, 'h', 'i',
+	0xa1, 'n', 0x01,
+	0xa7, 's', 'e', 'r', 'v', 'i', 'c', 'e', 0xa6, 'o', 'r', 'd', 'e', 'r', 's',
+}
+
+func TestEncodeEntry(t *testing.T) {
+	if got := encodeEntry(testEntry); !bytes.Equal(got, testRecord) {
+		t.Fatalf("encodeEntry() =\n% x\nwant\n% x", got, testRecord)
+	}
+}
+
+func TestSendChunkWithoutAck(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	s := &Sink{cfg: Config{DisableAck: true, Timeout: 5 * time.Second}, conn: client, reader: bufio.NewReader(client)}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.sendChunk("app", []record{{tag: "app", entry: testRecord}})
+		_ = client.Close()
+	}()
+	got, _ := io.ReadAll(server)
+	if err := <-errc; err != nil {
+		t.Fatalf("sendChunk() = %v", err)
+	}
+	// [tag, [entries], {}]: Forward mode without the chunk option.
+	want := append(append([]byte{0x93, 0xa3, 'a', 'p', 'p', 0x91}, testRecord...), 0x80)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("sent\n% x\nwant\n% x", got, want)
+	}
+}
+
+// forwardMessage is a Forward mode message received by the fake server.
+type forwardMessage struct {
+	tag     string
+	entries []any
+	options map[string]any
+}
+
+// Replies of the fake server to a chunk.
+const (
+	replyAck      = iota // acknowledge the chunk
+	replyWrongAck        // acknowledge another chunk
+	replyClose           // close the connection without acknowledging
+)
+
+// fakeServer is a Fluentd forward input. It replies to each message with the next reply
+// queued in replies, acknowledging the chunk once they are exhausted.
+type fakeServer struct {
+	t  *testing.T
+	ln net.Listener
+
+	mu       sync.Mutex
+	replies  []int
+	messages []forwardMessage
+	received chan struct{}
+}
+
+func newFakeServer(t *testing.T, replies ...int) *fakeServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeServer{t: t, ln: ln, replies: replies, received: make(chan struct{}, 100)}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.handle(nc)
+		}
+	}()
+	return f
+}
+
+// config returns the configuration of a sink sending to the server.
+func (f *fakeServer) config() Config {
+	host, port, _ := net.SplitHostPort(f.ln.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return Config{Host: host, Port: p, FlushInterval: time.Hour, Timeout: 5 * time.Second}
+}
+
+func (f *fakeServer) handle(nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	for {
+		v, err := decode(r)
+		if err != nil {
+			return
+		}
+		msg, ok := v.([]any)
+		if !ok || len(msg) != 3 {
+			f.t.Errorf("received %v, want a Forward mode message", v)
+			return
+		}
+		m := forwardMessage{}
+		m.tag, _ = msg[0].(string)
+		m.entries, _ = msg[1].([]any)
+		m.options, _ = msg[2].(map[string]any)
+
+		f.mu.Lock()
+		f.messages = append(f.messages, m)
+		reply := replyAck
+		if len(f.replies) > 0 {
+			reply = f.replies[0]
+			f.replies = f.replies[1:]
+		}
+		f.mu.Unlock()
+		f.received <- struct{}{}
+
+		chunk, _ := m.options["chunk"].(string)
+		switch {
+		case reply == replyClose:
+			return
+		case chunk == "":
+			continue
+		case reply == replyWrongAck:
+			chunk = "other"
+		}
+		ack := appendString(appendString(appendMapHeader(nil, 1), "ack"), chunk)
+		if _, err := nc.Write(ack); err != nil {
+			return
+		}
+	}
+}
+
+// eventTime is a decoded Fluentd EventTime.
+type eventTime struct{ sec, nsec uint32 }
+
+// decode decodes the MessagePack values the sink sends.
+func decode(r *bufio.Reader) (any, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	read := func(n int) ([]byte, error) {
+		b := make([]byte, n)
+		_, err := io.ReadFull(r, b)
+		return b, err
+	}
+	length := func(size int) (int, error) {
+		b, err := read(size)
+		if err != nil {
+			return 0, err
+		}
+		if size == 1 {
+			return int(b[0]), nil
+		}
+		if size == 2 {
+			return int(binary.BigEndian.Uint16(b)), nil
+		}
+		return int(binary.BigEndian.Uint32(b)), nil
+	}
+	var n int
+	switch {
+	case tag <= 0x7f:
+		return int64(tag), nil
+	case tag >= 0xe0:
+		return int64(int8(tag)), nil
+	case tag == 0xc0:
+		return nil, nil
+	case tag == 0xc2 || tag == 0xc3:
+		return tag == 0xc3, nil
+	case tag&0xe0 == 0xa0:
+		b, err := read(int(tag & 0x1f))
+		return string(b), err
+	case tag == 0xd9 || tag == 0xda || tag == 0xdb:
+		if n, err = length(1 << (tag - 0xd9)); err != nil {
+			return nil, err
+		}
+		b, err := read(n)
+		return string(b), err
+	case tag == 0xd7:
+		b, err := read(9)
+		if err != nil || b[0] != eventTimeExt {
+			return nil, fmt.Errorf("unexpected extension % x: %v", b, err)
+		}
+		return eventTime{binary.BigEndian.Uint32(b[1:]), binary.BigEndian.Uint32(b[5:])}, nil
+	case tag&0xf0 == 0x90, tag == 0xdc, tag == 0xdd:
+		if n = int(tag & 0x0f); tag == 0xdc || tag == 0xdd {
+			if n, err = length(2 << (tag - 0xdc)); err != nil {
+				return nil, err
+			}
+		}
+		a := make([]any, n)
+		for i := range a {
+			if a[i], err = decode(r); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case tag&0xf0 == 0x80, tag == 0xde, tag == 0xdf:
+		if n = int(tag & 0x0f); tag == 0xde || tag == 0xdf {
+			if n, err = length(2 << (tag - 0xde)); err != nil {
+				return nil, err
+			}
+		}
+		m := make(map[string]any, n)
+		for range n {
+			k, err := decode(r)
+			if err != nil {
+				return nil, err
+			}
+			if m[fmt.Sprint(k)], err = decode(r); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unexpected msgpack tag 0x%02x", tag)
+	}
+}
+
+// newTestSink returns a sink built from cfg, closed at the end of the test.
+func newTestSink(t *testing.T, cfg Config) *Sink {
+	t.Helper()
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+// entries returns the entries received by the server, as tag:message, by message.
+func (f *fakeServer) entries() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var got [][]string
+	for _, m := range f.messages {
+		var msgs []string
+		for _, e := range m.entries {
+			pair, _ := e.([]any)
+			if len(pair) != 2 {
+				f.t.Errorf("entry %v, want a [time, record] pair", e)
+				continue
+			}
+			if _, ok := pair[0].(eventTime); !ok {
+				f.t.Errorf("entry time %v, want an EventTime", pair[0])
+			}
+			rec, _ := pair[1].(map[string]any)
+			msgs = append(msgs, m.tag+":"+fmt.Sprint(rec["message"]))
+		}
+		got = append(got, msgs)
+	}
+	return got
+}
+
+// write writes entries with the given messages to the sink.
+func write(t *testing.T, s *Sink, messages ...string) {
+	t.Helper()
+	for _, msg := range messages {
+		e := logger.Entry{Time: time.Now(), Level: logger.LevelInfo, Message: msg, Fields: map[string]any{"service": "orders"}}
+		if err := s.Write(e, nil); err != nil {
+			t.Fatalf("Write() = %v", err)
+		}
+	}
+}
+
+func TestSinkAcks(t *testing.T) {
+	f := newFakeServer(t)
+	s := newTestSink(t, f.config())
+	write(t, s, "a", "b")
+	if err := s.Sync(); err != nil {
+		t.Fatalf("Sync() = %v", err)
+	}
+	if got, want := fmt.Sprint(f.entries()), "[[orders:a orders:b]]"; got != want {
+		t.Fatalf("received %s, want %s", got, want)
+	}
+	f.mu.Lock()
+	chunk, _ := f.messages[0].options["chunk"].(string)
+	f.mu.Unlock()
+	if chunk == "" {
+		t.Fatal("chunk option missing, want one to acknowledge")
+	}
+}
+
+func TestSinkResendsUnacknowledgedChunks(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		reply int
+		want  string
+	}{
+		{"wrong ack", replyWrongAck, `unexpected ack "other"`},
+		{"connection closed", replyClose, "reading ack: EOF"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeServer(t, tt.reply)
+			s := newTestSink(t, f.config())
+			write(t, s, "a", "b")
+			if err := s.Sync(); err == nil || err.Error() != tt.want {
+				t.Fatalf("Sync() = %v, want %q", err, tt.want)
+			}
+			// The chunk stays queued, and is sent again over a new connection.
+			write(t, s, "c")
+			if err := s.Sync(); err != nil {
+				t.Fatalf("Sync() = %v", err)
+			}
+			if got, want := fmt.Sprint(f.entries()), "[[orders:a orders:b] [orders:a orders:b orders:c]]"; got != want {
+				t.Fatalf("received %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestSinkRetriesInBackground(t *testing.T) {
+	f := newFakeServer(t, replyClose, replyClose)
+	cfg := f.config()
+	cfg.BatchSize = 1
+	cfg.MinBackoff = time.Millisecond
+	cfg.MaxBackoff = 4 * time.Millisecond
+	s := newTestSink(t, cfg)
+	write(t, s, "a")
+	for range 3 {
+		select {
+		case <-f.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %v, want the chunk sent 3 times", f.entries())
+		}
+	}
+	if got, want := fmt.Sprint(f.entries()), "[[orders:a] [orders:a] [orders:a]]"; got != want {
+		t.Fatalf("received %s, want %s", got, want)
+	}
+}
+
+func TestSinkWithoutAcks(t *testing.T) {
+	f := newFakeServer(t)
+	cfg := f.config()
+	cfg.DisableAck = true
+	cfg.Tag = "app.orders"
+	s := newTestSink(t, cfg)
+	write(t, s, "a")
+	if err := s.Sync(); err != nil {
+		t.Fatalf("Sync() = %v", err)
+	}
+	select {
+	case <-f.received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	if got, want := fmt.Sprint(f.entries()), "[[app.orders:a]]"; got != want {
+		t.Fatalf("received %s, want %s", got, want)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.messages[0].options) != 0 {
+		t.Fatalf("options %v, want none without acks", f.messages[0].options)
+	}
+}
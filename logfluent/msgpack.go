@@ -0,0 +1,249 @@
+package logfluent
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// The Forward protocol is MessagePack-encoded. The sink only needs to encode records and
+// to decode the map of an ack, so it carries a minimal codec instead of a dependency.
+
+// eventTimeExt is the MessagePack extension type of Fluentd EventTime.
+const eventTimeExt = 0
+
+// appendNil appends a MessagePack nil.
+func appendNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+// appendBool appends a MessagePack boolean.
+func appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+	return append(b, 0xc2)
+}
+
+// appendInt appends a MessagePack integer in its shortest form.
+func appendInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v <= math.MaxInt8:
+		return append(b, byte(v))
+	case v < 0 && v >= -32:
+		return append(b, byte(v))
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		return append(b, 0xd0, byte(v))
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(v))
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+	}
+}
+
+// appendUint appends a MessagePack unsigned integer.
+func appendUint(b []byte, v uint64) []byte {
+	if v <= math.MaxInt64 {
+		return appendInt(b, int64(v))
+	}
+	return binary.BigEndian.AppendUint64(append(b, 0xcf), v)
+}
+
+// appendFloat appends a MessagePack float64.
+func appendFloat(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+// appendString appends a MessagePack string.
+func appendString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// appendBinary appends MessagePack binary data.
+func appendBinary(b []byte, v []byte) []byte {
+	switch n := len(v); {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n))
+	}
+	return append(b, v...)
+}
+
+// appendArrayHeader appends the header of a MessagePack array of n elements.
+func appendArrayHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+// appendMapHeader appends the header of a MessagePack map of n pairs.
+func appendMapHeader(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+// appendEventTime appends a Fluentd EventTime, which keeps nanoseconds.
+func appendEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, eventTimeExt)
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Unix()))
+	return binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+}
+
+// appendValue appends a field value of a logger.Entry.
+func appendValue(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case nil:
+		return appendNil(b)
+	case string:
+		return appendString(b, v)
+	case bool:
+		return appendBool(b, v)
+	case int:
+		return appendInt(b, int64(v))
+	case int8:
+		return appendInt(b, int64(v))
+	case int16:
+		return appendInt(b, int64(v))
+	case int32:
+		return appendInt(b, int64(v))
+	case int64:
+		return appendInt(b, v)
+	case uint:
+		return appendUint(b, uint64(v))
+	case uint8:
+		return appendUint(b, uint64(v))
+	case uint16:
+		return appendUint(b, uint64(v))
+	case uint32:
+		return appendUint(b, uint64(v))
+	case uint64:
+		return appendUint(b, v)
+	case float32:
+		return appendFloat(b, float64(v))
+	case float64:
+		return appendFloat(b, v)
+	case []byte:
+		return appendBinary(b, v)
+	case time.Time:
+		return appendString(b, v.Format(time.RFC3339Nano))
+	case time.Duration:
+		return appendString(b, v.String())
+	case []any:
+		b = appendArrayHeader(b, len(v))
+		for _, item := range v {
+			b = appendValue(b, item)
+		}
+		return b
+	case map[string]any:
+		b = appendMapHeader(b, len(v))
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			b = appendString(b, key)
+			b = appendValue(b, v[key])
+		}
+		return b
+	default:
+		return appendString(b, fmt.Sprint(v))
+	}
+}
+
+// errUnsupported reports a MessagePack value the decoder does not handle.
+var errUnsupported = errors.New("unsupported msgpack value")
+
+// readStringMap reads a MessagePack map of strings, such as the ack of a chunk. Values
+// other than strings are rejected.
+func readStringMap(r *bufio.Reader) (map[string]string, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	var n int
+	switch {
+	case tag&0xf0 == 0x80:
+		n = int(tag & 0x0f)
+	case tag == 0xde:
+		var size [2]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return nil, err
+		}
+		n = int(binary.BigEndian.Uint16(size[:]))
+	default:
+		return nil, fmt.Errorf("%w: 0x%02x", errUnsupported, tag)
+	}
+	m := make(map[string]string, n)
+	for range n {
+		key, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readString(r)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = value
+	}
+	return m, nil
+}
+
+// readString reads a MessagePack string.
+func readString(r *bufio.Reader) (string, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	var n int
+	switch {
+	case tag&0xe0 == 0xa0:
+		n = int(tag & 0x1f)
+	case tag == 0xd9:
+		size, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		n = int(size)
+	case tag == 0xda:
+		var size [2]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return "", err
+		}
+		n = int(binary.BigEndian.Uint16(size[:]))
+	default:
+		return "", fmt.Errorf("%w: 0x%02x", errUnsupported, tag)
+	}
+	s := make([]byte, n)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
@@ -0,0 +1,119 @@
+package logfluent
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The fixtures below are written from the MessagePack specification, not from the
+// encoder.
+
+func TestAppendValue(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want []byte
+	}{
+		{"nil", nil, []byte{0xc0}},
+		{"false", false, []byte{0xc2}},
+		{"true", true, []byte{0xc3}},
+		{"positive fixint", 127, []byte{0x7f}},
+		{"negative fixint", -32, []byte{0xe0}},
+		{"int 8", int8(-33), []byte{0xd0, 0xdf}},
+		{"int 16", 128, []byte{0xd1, 0x00, 0x80}},
+		{"negative int 16", int16(-129), []byte{0xd1, 0xff, 0x7f}},
+		{"int 32", int32(1 << 16), []byte{0xd2, 0x00, 0x01, 0x00, 0x00}},
+		{"int 64", int64(1) << 32, []byte{0xd3, 0, 0, 0, 0x01, 0, 0, 0, 0}},
+		{"small uint", uint8(7), []byte{0x07}},
+		{"uint 64", uint64(1) << 63, []byte{0xcf, 0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{"float 64", 1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"float 32", float32(-2), []byte{0xcb, 0xc0, 0, 0, 0, 0, 0, 0, 0}},
+		{"fixstr", "abc", []byte{0xa3, 'a', 'b', 'c'}},
+		{"empty string", "", []byte{0xa0}},
+		{"binary", []byte{1, 2}, []byte{0xc4, 0x02, 1, 2}},
+		{"duration", 1500 * time.Millisecond, []byte{0xa4, '1', '.', '5', 's'}},
+		{"time", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), append([]byte{0xb4}, "2024-01-02T03:04:05Z"...)},
+		{"array", []any{1, "a", nil}, []byte{0x93, 0x01, 0xa1, 'a', 0xc0}},
+		{"map in key order", map[string]any{"b": 2, "a": map[string]any{}}, []byte{0x82, 0xa1, 'a', 0x80, 0xa1, 'b', 0x02}},
+		{"other types as strings", errors.New("boom"), []byte{0xa4, 'b', 'o', 'o', 'm'}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := appendValue(nil, tt.v); !bytes.Equal(got, tt.want) {
+				t.Fatalf("appendValue(%v) = % x, want % x", tt.v, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendLengths(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []byte
+		head []byte
+	}{
+		{"str 8", appendString(nil, strings.Repeat("x", 32)), []byte{0xd9, 32}},
+		{"str 16", appendString(nil, strings.Repeat("x", 256)), []byte{0xda, 0x01, 0x00}},
+		{"str 32", appendString(nil, strings.Repeat("x", 1<<16)), []byte{0xdb, 0, 0x01, 0, 0}},
+		{"bin 16", appendBinary(nil, make([]byte, 256)), []byte{0xc5, 0x01, 0x00}},
+		{"fixarray", appendArrayHeader(nil, 15), []byte{0x9f}},
+		{"array 16", appendArrayHeader(nil, 16), []byte{0xdc, 0, 16}},
+		{"array 32", appendArrayHeader(nil, 1<<16), []byte{0xdd, 0, 0x01, 0, 0}},
+		{"fixmap", appendMapHeader(nil, 15), []byte{0x8f}},
+		{"map 16", appendMapHeader(nil, 16), []byte{0xde, 0, 16}},
+		{"map 32", appendMapHeader(nil, 1<<16), []byte{0xdf, 0, 0x01, 0, 0}},
+	}
+	for _, tt := range tests {
+		if !bytes.HasPrefix(tt.got, tt.head) {
+			t.Errorf("%s: header % x, want % x", tt.name, tt.got[:min(len(tt.got), 5)], tt.head)
+		}
+	}
+}
+
+func TestAppendEventTime(t *testing.T) {
+	// fixext 8 of type 0: seconds and nanoseconds as big-endian uint32s.
+	want := []byte{0xd7, 0x00, 0x65, 0x53, 0xf1, 0x00, 0x07, 0x5b, 0xcd, 0x15}
+	if got := appendEventTime(nil, time.Unix(1_700_000_000, 123_456_789)); !bytes.Equal(got, want) {
+		t.Fatalf("appendEventTime() = % x, want % x", got, want)
+	}
+}
+
+func TestReadStringMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		b       []byte
+		want    map[string]string
+		wantErr string
+	}{
+		{"fixmap", []byte{0x81, 0xa3, 'a', 'c', 'k', 0xa2, 'i', 'd'}, map[string]string{"ack": "id"}, ""},
+		{"map 16", []byte{0xde, 0, 1, 0xa1, 'k', 0xd9, 1, 'v'}, map[string]string{"k": "v"}, ""},
+		{"str 16 value", []byte{0x81, 0xa1, 'k', 0xda, 0, 1, 'v'}, map[string]string{"k": "v"}, ""},
+		{"empty", []byte{0x80}, map[string]string{}, ""},
+		{"not a map", []byte{0x91, 0xa0}, nil, "unsupported msgpack value: 0x91"},
+		{"value not a string", []byte{0x81, 0xa1, 'k', 0x01}, nil, "unsupported msgpack value: 0x01"},
+		{"truncated", []byte{0x81, 0xa3, 'a'}, nil, "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readStringMap(bufio.NewReader(bytes.NewReader(tt.b)))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("readStringMap() = %v, %v, want error %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || len(got) != len(tt.want) {
+				t.Fatalf("readStringMap() = %v, %v, want %v", got, err, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Fatalf("readStringMap() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
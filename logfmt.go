@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// EncodingLogfmt emits entries as logfmt key=value lines.
+const EncodingLogfmt = "logfmt"
+
+// logfmtBufferPool provides buffers for the logfmt encoder.
+var logfmtBufferPool = buffer.NewPool()
+
+// logfmtEncoder renders entries as logfmt lines.
+//
+// Fields are accumulated in a MapObjectEncoder and written after the standard keys in
+// lexical order; nested objects and arrays are rendered as quoted JSON.
+type logfmtEncoder struct {
+	*zapcore.MapObjectEncoder
+	cfg zapcore.EncoderConfig
+}
+
+// newLogfmtEncoder returns a logfmt encoder using the keys of cfg.
+func newLogfmtEncoder(cfg zapcore.EncoderConfig) zapcore.Encoder {
+	return &logfmtEncoder{MapObjectEncoder: zapcore.NewMapObjectEncoder(), cfg: cfg}
+}
+
+// Clone implements zapcore.Encoder.
+func (e *logfmtEncoder) Clone() zapcore.Encoder {
+	clone := zapcore.NewMapObjectEncoder()
+	for k, v := range e.Fields {
+		clone.Fields[k] = v
+	}
+	return &logfmtEncoder{MapObjectEncoder: clone, cfg: e.cfg}
+}
+
+// EncodeEntry implements zapcore.Encoder.
+func (e *logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	enc := e.Clone().(*logfmtEncoder)
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	buf := logfmtBufferPool.Get()
+	if e.cfg.TimeKey != "" && e.cfg.TimeKey != zapcore.OmitKey {
+		appendLogfmtPair(buf, e.cfg.TimeKey, ent.Time.Format("2006-01-02T15:04:05.000Z0700"))
+	}
+	if e.cfg.LevelKey != "" && e.cfg.LevelKey != zapcore.OmitKey {
+		appendLogfmtPair(buf, e.cfg.LevelKey, levelString(e.cfg.EncodeLevel, ent.Level))
+	}
+	if ent.LoggerName != "" && e.cfg.NameKey != "" && e.cfg.NameKey != zapcore.OmitKey {
+		appendLogfmtPair(buf, e.cfg.NameKey, ent.LoggerName)
+	}
+	if ent.Caller.Defined && e.cfg.CallerKey != "" && e.cfg.CallerKey != zapcore.OmitKey {
+		appendLogfmtPair(buf, e.cfg.CallerKey, ent.Caller.TrimmedPath())
+	}
+	if e.cfg.MessageKey != "" && e.cfg.MessageKey != zapcore.OmitKey {
+		appendLogfmtPair(buf, e.cfg.MessageKey, ent.Message)
+	}
+
+	keys := make([]string, 0, len(enc.Fields))
+	for k := range enc.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		appendLogfmtPair(buf, k, logfmtValue(enc.Fields[k]))
+	}
+
+	if ent.Stack != "" && e.cfg.StacktraceKey != "" && e.cfg.StacktraceKey != zapcore.OmitKey {
+		appendLogfmtPair(buf, e.cfg.StacktraceKey, ent.Stack)
+	}
+	buf.AppendString(zapcore.DefaultLineEnding)
+	return buf, nil
+}
+
+// logfmtValue converts an accumulated field value to its logfmt text form.
+func logfmtValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	case map[string]any, []any:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(raw)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// appendLogfmtPair appends " key=value", quoting the value when necessary.
+func appendLogfmtPair(buf *buffer.Buffer, key, value string) {
+	if buf.Len() > 0 {
+		buf.AppendByte(' ')
+	}
+	buf.AppendString(key)
+	buf.AppendByte('=')
+	if needsLogfmtQuoting(value) {
+		buf.AppendString(strconv.Quote(value))
+		return
+	}
+	buf.AppendString(value)
+}
+
+// needsLogfmtQuoting reports whether a value must be quoted to stay a single token.
+func needsLogfmtQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return true
+		}
+	}
+	return strings.ContainsRune(s, '\\')
+}
+
+// levelString returns the level as encoded by encode, or its lowercase name when encode
+// is nil or does not produce a string.
+func levelString(encode zapcore.LevelEncoder, l zapcore.Level) string {
+	if encode == nil {
+		return l.String()
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	_ = enc.AddArray("level", zapcore.ArrayMarshalerFunc(func(arr zapcore.ArrayEncoder) error {
+		encode(l, arr)
+		return nil
+	}))
+	if values, ok := enc.Fields["level"].([]any); ok && len(values) == 1 {
+		if s, ok := values[0].(string); ok {
+			return s
+		}
+	}
+	return l.String()
+}
@@ -0,0 +1,435 @@
+// Package loggelf provides a logger.Sink sending entries to Graylog in the GELF 1.1
+// format, over UDP (chunked, optionally compressed) or TCP.
+//
+// Importing the package registers the "gelf" URL scheme, so that an output can be
+// configured by its Path alone. The query selects the protocol and compression, and
+// parameters starting with an underscore are static additional fields:
+//
+//	import _ "github.com/matteocavestri/logger-gath-test/loggelf"
+//
+//	cfg.Outputs = []logger.OutputConfig{{
+//	    Path: "gelf://graylog.internal:12201?protocol=udp&compression=gzip&_cluster=eu-1",
+//	}}
+//
+// The sink can also be built from a Config and set as OutputConfig.Sink:
+//
+//	sink, err := loggelf.New(loggelf.Config{Host: "graylog.internal", Protocol: loggelf.TCP})
+//	if err != nil {
+//	    panic(err)
+//	}
+//	cfg.Outputs = []logger.OutputConfig{{Sink: sink}}
+//
+// The message is the GELF short_message and the stack trace, if any, its full_message.
+// The fields of the entry, the logger name and the caller are sent as additional fields.
+// The output's Encoding is not used.
+package loggelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+)
+
+// Protocols of the GELF transport.
+const (
+	UDP = "udp"
+	TCP = "tcp"
+)
+
+// Compressions of GELF UDP messages.
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZlib = "zlib"
+)
+
+// Defaults and limits of the GELF transport.
+const (
+	DefaultPort = 12201
+	// DefaultChunkSize is the maximum size of UDP datagrams, suitable for WAN links.
+	DefaultChunkSize = 1420
+	// maxChunks is the maximum number of chunks of a message allowed by GELF.
+	maxChunks = 128
+	// chunkHeaderSize is the size of the magic bytes, message ID, sequence number and
+	// sequence count of a chunk.
+	chunkHeaderSize = 12
+	dialTimeout     = 5 * time.Second
+)
+
+// chunkMagic starts every chunk of a chunked GELF message.
+var chunkMagic = []byte{0x1e, 0x0f}
+
+// Config describes the Graylog input to send entries to.
+type Config struct {
+	// Host is the address of the Graylog input.
+	Host string
+	// Port is the port of the Graylog input. Defaults to 12201.
+	Port int
+	// Protocol is UDP or TCP. Defaults to UDP.
+	Protocol string
+	// Compression is CompressionNone, CompressionGzip or CompressionZlib. Only UDP
+	// messages can be compressed. Defaults to CompressionNone.
+	Compression string
+	// ChunkSize is the maximum size of UDP datagrams: larger messages are split into up to
+	// 128 chunks. Defaults to 1420.
+	ChunkSize int
+	// Hostname is the GELF host field. Defaults to the hostname of the machine.
+	Hostname string
+	// Fields are static additional fields attached to every message, e.g.
+	// {"cluster": "eu-1"}. Keys are sent with the GELF underscore prefix.
+	Fields map[string]any
+}
+
+func init() {
+	_ = logger.RegisterSink("gelf", func(u *url.URL) (logger.Sink, error) {
+		cfg, err := configFromURL(u)
+		if err != nil {
+			return nil, err
+		}
+		return New(cfg)
+	})
+}
+
+// configFromURL returns the configuration described by a gelf:// URL.
+func configFromURL(u *url.URL) (Config, error) {
+	q := u.Query()
+	cfg := Config{
+		Host:        u.Hostname(),
+		Protocol:    q.Get("protocol"),
+		Compression: q.Get("compression"),
+		Hostname:    q.Get("hostname"),
+		Fields:      make(map[string]any),
+	}
+	if port := u.Port(); port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			return Config{}, fmt.Errorf("loggelf: invalid port %q", port)
+		}
+		cfg.Port = n
+	}
+	if size := q.Get("chunk_size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil {
+			return Config{}, fmt.Errorf("loggelf: invalid chunk_size %q", size)
+		}
+		cfg.ChunkSize = n
+	}
+	for key, values := range q {
+		if name, ok := strings.CutPrefix(key, "_"); ok && len(values) > 0 {
+			cfg.Fields[name] = values[0]
+		}
+	}
+	return cfg, nil
+}
+
+// Sink sends entries to a Graylog GELF input. It is safe for concurrent use.
+type Sink struct {
+	cfg      Config
+	address  string
+	hostname string
+	// static holds the encoded static additional fields, each followed by a comma.
+	static []byte
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+var _ logger.Sink = (*Sink)(nil)
+
+// New validates cfg, applies its defaults and connects to the Graylog input.
+func New(cfg Config) (*Sink, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("loggelf: host is required")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = DefaultPort
+	}
+	switch cfg.Protocol {
+	case "":
+		cfg.Protocol = UDP
+	case UDP, TCP:
+	default:
+		return nil, fmt.Errorf("loggelf: unknown protocol %q", cfg.Protocol)
+	}
+	switch cfg.Compression {
+	case "":
+		cfg.Compression = CompressionNone
+	case CompressionNone:
+	case CompressionGzip, CompressionZlib:
+		if cfg.Protocol == TCP {
+			return nil, errors.New("loggelf: GELF TCP messages cannot be compressed")
+		}
+	default:
+		return nil, fmt.Errorf("loggelf: unknown compression %q", cfg.Compression)
+	}
+	if cfg.ChunkSize == 0 {
+		cfg.ChunkSize = DefaultChunkSize
+	}
+	if cfg.ChunkSize <= chunkHeaderSize {
+		return nil, fmt.Errorf("loggelf: chunk size %d is too small", cfg.ChunkSize)
+	}
+
+	s := &Sink{cfg: cfg, address: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)), hostname: cfg.Hostname}
+	if s.hostname == "" {
+		s.hostname, _ = os.Hostname()
+	}
+	for key, value := range cfg.Fields {
+		s.static = appendField(s.static, key, value)
+	}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// connect opens the connection to the input.
+func (s *Sink) connect() error {
+	conn, err := net.DialTimeout(s.cfg.Protocol, s.address, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("loggelf: %w", err)
+	}
+	s.conn = conn
+	return nil
+}
+
+// Write implements logger.Sink.
+func (s *Sink) Write(e logger.Entry, _ []byte) error {
+	msg, err := s.encode(e)
+	if err != nil {
+		return err
+	}
+	if s.cfg.Protocol == TCP {
+		// GELF TCP frames are delimited by a null byte.
+		return s.send([][]byte{append(msg, 0)})
+	}
+	if msg, err = s.compress(msg); err != nil {
+		return err
+	}
+	packets, err := s.chunk(msg)
+	if err != nil {
+		return err
+	}
+	return s.send(packets)
+}
+
+// send writes the packets of a message, reconnecting once after a write failure.
+func (s *Sink) send(packets [][]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		if err := s.writePackets(packets); err == nil {
+			return nil
+		}
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	// Reconnect once, e.g. after Graylog restarted.
+	if err := s.connect(); err != nil {
+		return err
+	}
+	return s.writePackets(packets)
+}
+
+// writePackets writes packets to the connection.
+func (s *Sink) writePackets(packets [][]byte) error {
+	for _, p := range packets {
+		if _, err := s.conn.Write(p); err != nil {
+			return fmt.Errorf("loggelf: %w", err)
+		}
+	}
+	return nil
+}
+
+// encode returns the GELF message of an entry.
+func (s *Sink) encode(e logger.Entry) ([]byte, error) {
+	short, err := json.Marshal(e.Message)
+	if err != nil {
+		return nil, fmt.Errorf("loggelf: %w", err)
+	}
+	host, _ := json.Marshal(s.hostname)
+	b := make([]byte, 0, 256+len(s.static))
+	b = append(b, `{"version":"1.1","host":`...)
+	b = append(b, host...)
+	b = append(b, `,"short_message":`...)
+	b = append(b, short...)
+	if e.Stack != "" {
+		full, _ := json.Marshal(e.Message + "\n" + e.Stack)
+		b = append(b, `,"full_message":`...)
+		b = append(b, full...)
+	}
+	b = append(b, `,"timestamp":`...)
+	b = strconv.AppendFloat(b, float64(e.Time.UnixMilli())/1000, 'f', 3, 64)
+	b = append(b, `,"level":`...)
+	b = strconv.AppendInt(b, int64(severity(e.Level)), 10)
+	b = append(b, ',')
+	b = append(b, s.static...)
+	if e.Logger != "" {
+		b = appendField(b, "logger", e.Logger)
+	}
+	if e.Caller != "" {
+		b = appendField(b, "caller", e.Caller)
+	}
+	for key, value := range e.Fields {
+		b = appendField(b, key, value)
+	}
+	b[len(b)-1] = '}'
+	return b, nil
+}
+
+// appendField appends an additional field and a comma to b. GELF values are strings or
+// numbers: other values are sent as their JSON encoding.
+func appendField(b []byte, key string, value any) []byte {
+	name := fieldName(key)
+	if name == "" {
+		return b
+	}
+	b = strconv.AppendQuote(b, "_"+name)
+	b = append(b, ':')
+	switch v := value.(type) {
+	case string:
+		b = appendString(b, v)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		if encoded, err := json.Marshal(v); err == nil {
+			b = append(b, encoded...)
+		} else {
+			// NaN and infinities.
+			b = appendString(b, fmt.Sprint(v))
+		}
+	case time.Time:
+		b = appendString(b, v.Format(time.RFC3339Nano))
+	case time.Duration:
+		b = appendString(b, v.String())
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			encoded = []byte(fmt.Sprint(v))
+		}
+		b = appendString(b, string(encoded))
+	}
+	return append(b, ',')
+}
+
+// appendString appends s as a JSON string.
+func appendString(b []byte, s string) []byte {
+	encoded, _ := json.Marshal(s)
+	return append(b, encoded...)
+}
+
+// fieldName returns key with the characters not allowed in GELF field names replaced by
+// underscores. The id field is reserved, and renamed to id_.
+func fieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if name == "id" {
+		return "id_"
+	}
+	return name
+}
+
+// severity maps a level to a syslog severity, as used by the GELF level field.
+func severity(level logger.LogLevel) int {
+	switch level {
+	case logger.LevelDebug:
+		return 7 // debug
+	case logger.LevelInfo:
+		return 6 // informational
+	case logger.LevelWarn:
+		return 4 // warning
+	case logger.LevelError:
+		return 3 // error
+	case "DPANIC", "PANIC":
+		return 2 // critical
+	case "FATAL":
+		return 1 // alert
+	default:
+		return 6
+	}
+}
+
+// compress returns msg compressed with the configured compression.
+func (s *Sink) compress(msg []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch s.cfg.Compression {
+	case CompressionGzip:
+		w = gzip.NewWriter(&buf)
+	case CompressionZlib:
+		w = zlib.NewWriter(&buf)
+	default:
+		return msg, nil
+	}
+	if _, err := w.Write(msg); err != nil {
+		return nil, fmt.Errorf("loggelf: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("loggelf: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// chunk splits msg into datagrams of at most the configured chunk size.
+func (s *Sink) chunk(msg []byte) ([][]byte, error) {
+	if len(msg) <= s.cfg.ChunkSize {
+		return [][]byte{msg}, nil
+	}
+	size := s.cfg.ChunkSize - chunkHeaderSize
+	count := (len(msg) + size - 1) / size
+	if count > maxChunks {
+		return nil, fmt.Errorf("loggelf: message of %d bytes exceeds %d chunks", len(msg), maxChunks)
+	}
+	id := rand.Uint64()
+	packets := make([][]byte, 0, count)
+	for i := range count {
+		data := msg[i*size : min((i+1)*size, len(msg))]
+		p := make([]byte, 0, chunkHeaderSize+len(data))
+		p = append(p, chunkMagic...)
+		p = binary.BigEndian.AppendUint64(p, id)
+		p = append(p, byte(i), byte(count))
+		p = append(p, data...)
+		packets = append(packets, p)
+	}
+	return packets, nil
+}
+
+// Sync implements logger.Sink. Messages are sent as they are written.
+func (s *Sink) Sync() error {
+	return nil
+}
+
+// Close implements logger.Sink.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// String implements logger.Sink.
+func (s *Sink) String() string {
+	return "gelf+" + s.cfg.Protocol + "://" + s.address
+}
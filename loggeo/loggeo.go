@@ -0,0 +1,110 @@
+// Package loggeo provides a MaxMind-backed logger.GeoEnricher for HTTP access logs.
+//
+// It reads GeoLite2/GeoIP2 databases in the MaxMind DB format: a Country (or City)
+// database for the country code and an ASN database for the autonomous system. Either
+// may be omitted.
+//
+// Example usage:
+//
+//	geo, err := loggeo.Open(loggeo.Options{
+//	    CountryDB: "/usr/share/GeoIP/GeoLite2-Country.mmdb",
+//	    ASNDB:     "/usr/share/GeoIP/GeoLite2-ASN.mmdb",
+//	})
+//	if err != nil {
+//	    panic(err)
+//	}
+//	defer geo.Close()
+//
+//	handler := logger.NewHTTPMiddleware(logger.HTTPConfig{Geo: geo})(mux)
+package loggeo
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Options names the databases to load.
+type Options struct {
+	// CountryDB is the path of a GeoLite2-Country, GeoIP2-Country or City database.
+	CountryDB string
+	// ASNDB is the path of a GeoLite2-ASN or GeoIP2-ISP database.
+	ASNDB string
+}
+
+// MaxMind resolves addresses using MaxMind databases. It is safe for concurrent use.
+type MaxMind struct {
+	country *maxminddb.Reader
+	asn     *maxminddb.Reader
+}
+
+var _ logger.GeoEnricher = (*MaxMind)(nil)
+
+// Open loads the databases named by opts. At least one database is required.
+func Open(opts Options) (*MaxMind, error) {
+	if opts.CountryDB == "" && opts.ASNDB == "" {
+		return nil, errors.New("loggeo: no database configured")
+	}
+	m := &MaxMind{}
+	var err error
+	if opts.CountryDB != "" {
+		if m.country, err = maxminddb.Open(opts.CountryDB); err != nil {
+			return nil, fmt.Errorf("loggeo: failed to open country database: %w", err)
+		}
+	}
+	if opts.ASNDB != "" {
+		if m.asn, err = maxminddb.Open(opts.ASNDB); err != nil {
+			m.Close()
+			return nil, fmt.Errorf("loggeo: failed to open ASN database: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// countryRecord is the subset of a Country/City record read by Lookup.
+type countryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+// asnRecord is the subset of an ASN record read by Lookup.
+type asnRecord struct {
+	Number       uint   `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// Lookup implements logger.GeoEnricher.
+func (m *MaxMind) Lookup(ip netip.Addr) (logger.GeoInfo, bool) {
+	var info logger.GeoInfo
+	addr := net.IP(ip.Unmap().AsSlice())
+	if m.country != nil {
+		var rec countryRecord
+		if err := m.country.Lookup(addr, &rec); err == nil {
+			info.Country = rec.Country.ISOCode
+		}
+	}
+	if m.asn != nil {
+		var rec asnRecord
+		if err := m.asn.Lookup(addr, &rec); err == nil {
+			info.ASN, info.Organization = rec.Number, rec.Organization
+		}
+	}
+	return info, info != logger.GeoInfo{}
+}
+
+// Close releases the databases.
+func (m *MaxMind) Close() error {
+	var errs []error
+	if m.country != nil {
+		errs = append(errs, m.country.Close())
+	}
+	if m.asn != nil {
+		errs = append(errs, m.asn.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -35,9 +35,11 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
+	"sync/atomic"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -49,6 +51,9 @@ import (
 // global logger pattern used throughout the application.
 type Logger struct {
 	*zap.Logger
+	// shutdown flushes and closes the outputs of the logger, nil when it does not own
+	// them.
+	shutdown func(context.Context) error
 }
 
 // LogLevel represents the verbosity level for the logger.
@@ -66,8 +71,8 @@ const (
 )
 
 var (
-	// globalLogger is the shared singleton logger instance for the application.
-	globalLogger *Logger
+	// globalState holds the shared singleton logger instance for the application.
+	globalState atomic.Pointer[global]
 )
 
 // Config defines the configuration parameters for the logger.
@@ -78,12 +83,108 @@ type Config struct {
 	Level       LogLevel
 	Environment string // "development" or "production"
 	ServiceName string // Service identifier for log enrichment
+
+	// Outputs lists the destinations entries are written to. Each output has its own
+	// encoding and optional field mapping, so the same entry can be emitted in several
+	// schemas at once. When empty, a single stdout output is used.
+	Outputs []OutputConfig
+
+	// FieldValidation enables type checks for well-known fields such as trace_id,
+	// status, and duration_ms. Disabled by default.
+	FieldValidation ValidationMode
+
+	// Caller controls how caller locations are trimmed. Defaults to zap's short form.
+	Caller CallerConfig
+
+	// Diagnostics tunes the caller, stack trace, function name and DPANIC settings
+	// independently of Environment. Defaults derived from Environment when nil.
+	Diagnostics *DiagnosticsConfig
+
+	// Banner emits a single "logger initialized" entry describing the effective
+	// configuration and build information as soon as the logger is built.
+	Banner bool
+
+	// CgroupEnrichment attaches the container's cgroup memory and CPU limits to every
+	// entry, so OOM-adjacent log lines carry the context needed to interpret them.
+	CgroupEnrichment bool
+
+	// Retention is the default retention class of entries that are not explicitly tagged
+	// with Retention or WithRetention. Defaults to RetentionHot.
+	Retention RetentionClass
+
+	// Anonymization hashes or truncates IP addresses and hostnames of the selected fields
+	// before entries are emitted. Disabled when nil.
+	Anonymization *AnonymizationConfig
+
+	// Redaction replaces secrets and personal data with [REDACTED] before entries are
+	// emitted. Disabled when nil.
+	Redaction *RedactionConfig
+
+	// SamplingExemptions declares entries that Once, EveryN, access log rules and
+	// sampling never drop. Audit entries are always exempt.
+	SamplingExemptions *SamplingExemptions
+
+	// Async writes the outputs from a background goroutine with prioritized queues.
+	// Entries are written synchronously when nil.
+	Async *AsyncConfig
+
+	// Levels overrides the minimum level of named loggers (see Named), e.g.
+	// {"db": LevelDebug, "http": LevelWarn}.
+	Levels map[string]LogLevel
+
+	// SinkInstrumentation traces and times the batch flushes of remote sinks.
+	SinkInstrumentation *SinkInstrumentation
+
+	// Sampling caps the volume of repeated entries. Disabled when nil.
+	Sampling *SamplingConfig
+
+	// Dedup suppresses identical entries repeated within a window and summarizes them.
+	// Disabled when nil.
+	Dedup *DedupConfig
+
+	// SuppressionReports periodically reports the entries dropped by sampling, Once,
+	// EveryN, access log rules and the async queue. Disabled when nil.
+	SuppressionReports *SuppressionReportConfig
+
+	// Processors transform or drop entries in order, after sampling and deduplication
+	// but before validation and redaction, so that the fields they add are redacted too.
+	Processors []Processor
+
+	// Inherited holds fields passed on by a parent process (see InheritEnv), attached to
+	// every entry.
+	Inherited map[string]any
+
+	// Profiling periodically reports the CPU and allocation cost of the logger itself.
+	// Disabled when nil.
+	Profiling *ProfilingConfig
+
+	// FatalLoop records FATAL entries in a state file to detect crash loops across
+	// restarts. Disabled when nil.
+	FatalLoop *FatalLoopConfig
+
+	// SecretCheck selects how secrets found in static fields are handled: the service
+	// name, inherited fields, field mapping constants and Loki labels. Defaults to
+	// refusing the configuration. Credentials in output URLs are always masked in the
+	// banner and in errors.
+	SecretCheck SecretCheckMode
+
+	// Backend selects the library encoding and writing the entries of file, standard
+	// stream and Writer outputs: BackendZap (default), BackendSlog, or a backend
+	// registered with RegisterBackend such as "zerolog" (see the logzerolog package).
+	// The Logger API and the processing of entries are unchanged, so that backends can be
+	// compared on the same workload (see loggerbench.Compare). Other backends write
+	// their own key names and formats, and ignore the output's encoder settings.
+	Backend string
+
+	// encoderOverride is set from WithEncoderConfig.
+	encoderOverride func(*zapcore.EncoderConfig)
 }
 
 // New creates a new logger instance according to the given configuration.
 //
 // In production mode, logs are formatted as structured JSON suitable for ingestion by Loki,
 // FluentBit, or Elasticsearch. In development mode, logs use a colorized console encoder.
+// Options customize caller reporting, stack traces, the clock and the encoders.
 //
 // Example:
 //
@@ -91,67 +192,93 @@ type Config struct {
 //	    Level:       logger.LevelDebug,
 //	    Environment: "production",
 //	    ServiceName: "api-service",
-//	})
+//	}, logger.WithStacktraceLevel(logger.LevelWarn))
 //	if err != nil {
 //	    panic(err)
 //	}
-func New(cfg Config) (*Logger, error) {
-	var zapLevel zapcore.Level
+func New(cfg Config, opts ...Option) (*Logger, error) {
+	o := newOptions(opts)
+	cfg.encoderOverride = o.encoderConfig
+	p, err := buildPipeline(cfg)
+	if err != nil {
+		return nil, err
+	}
+	logger, err := newLogger(cfg, p.core, o)
+	if err != nil {
+		return nil, err
+	}
+	logger.shutdown = p.shutdown
+	return logger, nil
+}
 
-	// Map custom log levels to zap internal levels
-	switch strings.ToUpper(string(cfg.Level)) {
-	case string(LevelDebug):
-		zapLevel = zapcore.DebugLevel
-	case string(LevelInfo):
-		zapLevel = zapcore.InfoLevel
-	case string(LevelWarn):
-		zapLevel = zapcore.WarnLevel
-	case string(LevelError):
-		zapLevel = zapcore.ErrorLevel
-	default:
-		zapLevel = zapcore.InfoLevel
+// newLogger wraps core in a Logger with the options implied by the configuration.
+func newLogger(cfg Config, core zapcore.Core, o *options) (*Logger, error) {
+	errSink, _, err := zap.Open("stderr")
+	if err != nil {
+		return nil, fmt.Errorf("failed to build logger: %w", err)
 	}
 
-	var zapConfig zap.Config
-	if cfg.Environment == "production" {
-		zapConfig = zap.Config{
-			Level:            zap.NewAtomicLevelAt(zapLevel),
-			Development:      false,
-			Encoding:         "json",
-			EncoderConfig:    productionEncoderConfig(),
-			OutputPaths:      []string{"stdout"},
-			ErrorOutputPaths: []string{"stderr"},
-		}
-	} else {
-		zapConfig = zap.Config{
-			Level:            zap.NewAtomicLevelAt(zapLevel),
-			Development:      true,
-			Encoding:         "console",
-			EncoderConfig:    developmentEncoderConfig(),
-			OutputPaths:      []string{"stdout"},
-			ErrorOutputPaths: []string{"stderr"},
-		}
+	opts := []zap.Option{zap.ErrorOutput(errSink)}
+	if development(cfg) {
+		opts = append(opts, zap.Development())
 	}
+	opts = append(opts, o.zap(cfg.Diagnostics)...)
+
+	logger := &Logger{Logger: zap.New(core, opts...)}
+	if cfg.Banner {
+		logger.logBanner(cfg)
+	}
+	return logger, nil
+}
 
-	zapLogger, err := zapConfig.Build(
-		zap.AddCallerSkip(1),
-		zap.AddStacktrace(zapcore.ErrorLevel),
-	)
+// NewCore builds the output pipeline described by the configuration as a bare zapcore.Core.
+//
+// Unlike New, the core carries no enrichment fields (service, environment, ...) and no
+// caller or stacktrace options. It is meant for tools that write pre-built entries, such
+// as log replay and conversion.
+func NewCore(cfg Config) (zapcore.Core, error) {
+	cfg, err := checkSecrets(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build logger: %w", err)
+		return nil, err
 	}
+	core, _, err := buildCore(cfg, parseLevel(cfg.Level), nil)
+	return core, err
+}
 
-	zapLogger = zapLogger.With(
-		zap.String("service", cfg.ServiceName),
-		zap.String("environment", cfg.Environment),
-	)
+// parseLevel maps a LogLevel to the corresponding zap level.
+//
+// Unknown or empty values fall back to INFO.
+func parseLevel(level LogLevel) zapcore.Level {
+	switch strings.ToUpper(string(level)) {
+	case string(LevelDebug):
+		return zapcore.DebugLevel
+	case string(LevelInfo):
+		return zapcore.InfoLevel
+	case string(LevelWarn):
+		return zapcore.WarnLevel
+	case string(LevelError):
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
 
-	return &Logger{Logger: zapLogger}, nil
+// ZapLevel returns the zap level of l, parsed as Config.Level is: case-insensitively,
+// with INFO for unknown or empty values. Integration packages use it to honor LogLevel
+// options the same way the core package does.
+func (l LogLevel) ZapLevel() zapcore.Level {
+	return parseLevel(l)
+}
+
+// isProduction reports whether the configuration targets the production environment.
+func isProduction(cfg Config) bool {
+	return cfg.Environment == "production"
 }
 
 // productionEncoderConfig defines the encoder settings for production JSON logs.
 //
 // The output schema is compatible with Loki and other structured logging systems.
+// Durations are emitted as float milliseconds so they can be aggregated numerically.
 func productionEncoderConfig() zapcore.EncoderConfig {
 	return zapcore.EncoderConfig{
 		TimeKey:        "timestamp",
@@ -164,7 +291,7 @@ func productionEncoderConfig() zapcore.EncoderConfig {
 		LineEnding:     zapcore.DefaultLineEnding,
 		EncodeLevel:    zapcore.LowercaseLevelEncoder,
 		EncodeTime:     zapcore.ISO8601TimeEncoder,
-		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeDuration: zapcore.MillisDurationEncoder,
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
 }
@@ -192,13 +319,15 @@ func developmentEncoderConfig() zapcore.EncoderConfig {
 // InitGlobal initializes the global singleton logger.
 //
 // This should be called during application startup to make the logger globally accessible.
-// It replaces any existing global logger instance.
-func InitGlobal(cfg Config) error {
-	logger, err := New(cfg)
+// It replaces any existing global logger instance; loggers previously derived from the old
+// instance keep writing to the old outputs. Use Reconfigure to change the configuration of
+// a running application.
+func InitGlobal(cfg Config, opts ...Option) error {
+	g, err := newGlobal(cfg, newOptions(opts))
 	if err != nil {
 		return err
 	}
-	globalLogger = logger
+	globalState.Store(g)
 	return nil
 }
 
@@ -207,16 +336,94 @@ func InitGlobal(cfg Config) error {
 // If no global logger is initialized, it automatically creates a development-mode logger
 // with default parameters. This ensures logging always works even in early initialization stages.
 func Get() *Logger {
-	if globalLogger == nil {
-		cfg := Config{
-			Level:       LevelInfo,
-			Environment: "development",
-			ServiceName: "gath-stack",
-		}
-		logger, _ := New(cfg)
-		globalLogger = logger
+	if g := globalState.Load(); g != nil {
+		return g.logger
+	}
+	g, err := newGlobal(Config{
+		Level:       LevelInfo,
+		Environment: "development",
+		ServiceName: "gath-stack",
+	}, newOptions(nil))
+	if err != nil {
+		return nopLogger
+	}
+	if !globalState.CompareAndSwap(nil, g) {
+		g.core.current.Load().retire(0)
+	}
+	return globalState.Load().logger
+}
+
+// Reconfigure atomically rebuilds the pipeline of the global logger from cfg.
+//
+// Outputs, levels and enrichment fields are replaced for the global logger and every
+// logger derived from it. Writes already in flight complete against the old pipeline,
+// which is then flushed and closed before Reconfigure returns. Logger options fixed at
+// initialization (development mode and the Options passed to InitGlobal) are not changed.
+//
+// If no global logger exists yet, Reconfigure behaves like InitGlobal.
+func Reconfigure(cfg Config) error {
+	g := globalState.Load()
+	if g == nil {
+		return InitGlobal(cfg)
+	}
+	return g.reconfigure(cfg)
+}
+
+// global holds the global logger together with its reloadable core. Managers hold one
+// per pipeline as well.
+type global struct {
+	logger *Logger
+	// pkg is the logger used by the package-level logging functions: it skips their
+	// frame when reporting the caller.
+	pkg  *Logger
+	core *reloadableCore
+	opts *options
+}
+
+// newGlobal builds a logger whose pipeline can later be swapped by Reconfigure.
+func newGlobal(cfg Config, o *options) (*global, error) {
+	cfg.encoderOverride = o.encoderConfig
+	p, err := buildPipeline(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return newGlobalWithPipeline(cfg, p, o)
+}
+
+// newGlobalWithPipeline returns the global state writing through p.
+func newGlobalWithPipeline(cfg Config, p *pipeline, o *options) (*global, error) {
+	core := newReloadableCore(p)
+	logger, err := newLogger(cfg, core, o)
+	if err != nil {
+		return nil, err
 	}
-	return globalLogger
+	logger.shutdown = core.shutdown
+	pkg := &Logger{Logger: logger.WithOptions(zap.AddCallerSkip(1)), shutdown: core.shutdown}
+	return &global{logger: logger, pkg: pkg, core: core, opts: o}, nil
+}
+
+// reconfigure rebuilds the pipeline from cfg, as described by Reconfigure.
+func (g *global) reconfigure(cfg Config) error {
+	cfg.encoderOverride = g.opts.encoderConfig
+	p, err := buildPipeline(cfg)
+	if err != nil {
+		return err
+	}
+	old := g.core.swap(p)
+	if cfg.Banner {
+		g.logger.logBanner(cfg)
+	}
+	old.retire(drainTimeout)
+	return nil
+}
+
+// packageLogger returns the global logger used by the package-level logging functions.
+func packageLogger() *Logger {
+	l := Get()
+	if g := globalState.Load(); g != nil {
+		return g.pkg
+	}
+	return l
 }
 
 // WithContext returns a derived logger enriched with additional structured fields.
@@ -226,7 +433,20 @@ func Get() *Logger {
 //	log := logger.Get().WithContext(zap.String("user_id", "abc123"))
 //	log.Info("User login succeeded")
 func (l *Logger) WithContext(fields ...zap.Field) *Logger {
-	return &Logger{Logger: l.With(fields...)}
+	return &Logger{Logger: l.With(fields...), shutdown: l.shutdown}
+}
+
+// WithMinLevel returns a derived logger that only emits entries at or above the given level.
+//
+// The level can only be raised: requesting a level below the parent's minimum has no effect
+// and is reported on the error output. This lets library code be handed a quieter logger
+// without building a separate pipeline.
+//
+// Example:
+//
+//	client := thirdparty.New(thirdparty.WithLogger(log.WithMinLevel(logger.LevelWarn)))
+func (l *Logger) WithMinLevel(level LogLevel) *Logger {
+	return &Logger{Logger: l.WithOptions(zap.IncreaseLevel(parseLevel(level))), shutdown: l.shutdown}
 }
 
 // FromEnv builds a logger configuration using environment variables.
@@ -235,11 +455,39 @@ func (l *Logger) WithContext(fields ...zap.Field) *Logger {
 //   - LOG_LEVEL: sets log level (DEBUG, INFO, WARN, ERROR)
 //   - APP_ENV: defines environment ("development" or "production")
 //   - APP_NAME: sets the service name field
+//   - LOG_FIELD_VALIDATION: enables well-known field validation ("warn" or "strict")
+//   - LOG_BANNER: emits the startup configuration banner when set to "true"
+//   - LOG_CGROUP: attaches container cgroup limits to every entry when set to "true"
+//   - LOG_LEVELS: per-name levels of named loggers, e.g. "db=DEBUG,http=WARN"
+//   - LOG_SAMPLING_INITIAL, LOG_SAMPLING_THEREAFTER: enable sampling when either is set
+//   - LOG_REDACT: redacts DefaultRedactedKeys when set to "true"
+//   - LOG_REDACT_KEYS: additional redacted field keys, e.g. "iban,ssn" (enables redaction)
+//   - LOG_DEDUP_WINDOW: enables deduplication of repeated entries over the given window, e.g. "10s"
+//   - LOG_SUPPRESSION_REPORTS: reports suppressed entries over the given interval, e.g. "1m"
+//   - LOG_INHERITED_FIELDS: fields inherited from the parent process, set by InheritEnv
+//   - LOG_FATAL_STATE: enables crash loop detection with the given state file
+//   - LOG_CALLER, LOG_STACKTRACE_LEVEL, LOG_FUNCTION_NAMES, LOG_DPANIC: diagnostics, e.g.
+//     "false", "WARN" (or "NONE"), "true" and "panic"
+//   - LOG_SECRET_CHECK: handling of secrets in static fields ("mask" or "off")
+//   - LOG_BACKEND: the backend writing the outputs ("zap", "slog" or a registered one)
 func FromEnv() Config {
 	return Config{
-		Level:       LogLevel(getEnv("LOG_LEVEL", "INFO")),
-		Environment: getEnv("APP_ENV", "development"),
-		ServiceName: getEnv("APP_NAME", "gath-stack"),
+		Level:              LogLevel(getEnv("LOG_LEVEL", "INFO")),
+		Environment:        getEnv("APP_ENV", "development"),
+		ServiceName:        getEnv("APP_NAME", "gath-stack"),
+		FieldValidation:    ValidationMode(getEnv("LOG_FIELD_VALIDATION", "")),
+		Banner:             getEnv("LOG_BANNER", "false") == "true",
+		CgroupEnrichment:   getEnv("LOG_CGROUP", "false") == "true",
+		Levels:             parseNamedLevels(os.Getenv("LOG_LEVELS")),
+		Sampling:           samplingFromEnv(),
+		Dedup:              dedupFromEnv(),
+		SuppressionReports: suppressionReportsFromEnv(),
+		Redaction:          redactionFromEnv(),
+		Inherited:          inheritedFromEnv(),
+		FatalLoop:          fatalLoopFromEnv(),
+		Diagnostics:        diagnosticsFromEnv(),
+		SecretCheck:        SecretCheckMode(os.Getenv("LOG_SECRET_CHECK")),
+		Backend:            os.Getenv("LOG_BACKEND"),
 	}
 }
 
@@ -260,29 +508,29 @@ func (l *Logger) Sync() error {
 
 // Debug logs a message at the DEBUG level using the global logger.
 func Debug(msg string, fields ...zap.Field) {
-	Get().Debug(msg, fields...)
+	packageLogger().Debug(msg, fields...)
 }
 
 // Info logs a message at the INFO level using the global logger.
 func Info(msg string, fields ...zap.Field) {
-	Get().Info(msg, fields...)
+	packageLogger().Info(msg, fields...)
 }
 
 // Warn logs a message at the WARN level using the global logger.
 func Warn(msg string, fields ...zap.Field) {
-	Get().Warn(msg, fields...)
+	packageLogger().Warn(msg, fields...)
 }
 
 // Error logs a message at the ERROR level using the global logger.
 func Error(msg string, fields ...zap.Field) {
-	Get().Error(msg, fields...)
+	packageLogger().Error(msg, fields...)
 }
 
 // Fatal logs a message at the FATAL level and terminates the application.
 //
 // Use this sparingly—prefer returning errors whenever possible.
 func Fatal(msg string, fields ...zap.Field) {
-	Get().Fatal(msg, fields...)
+	packageLogger().Fatal(msg, fields...)
 }
 
 // WithFields creates a derived logger with pre-attached structured fields using the global logger.
@@ -0,0 +1,137 @@
+// Package loggerall is the batteries-included entry point of the logger: it links every
+// integration package, for programs that want all of them without picking each one.
+//
+// The core logger package only depends on zap and a few light modules. Integrations
+// with heavy dependencies (gRPC, Kafka, cloud SDKs) live in their own subpackages, and
+// remote sinks register their URL schemes with logger.RegisterSink when imported, so
+// that binaries only logging to the console or to files do not link them. Importing
+// this package for its side effects enables every sink URL scheme:
+//
+//	import _ "github.com/matteocavestri/logger-gath-test/loggerall"
+//
+//	cfg.Outputs = []logger.OutputConfig{{Path: "kafka://broker:9092/logs"}}
+//
+// It also registers the optional backends, such as "zerolog", and re-exports the
+// constructors of the integrations that are used directly, such as OpenGeo and the gRPC
+// interceptors.
+//
+// Conversely, the sinks built into the core package can be left out of a binary with the
+// logger_noloki and logger_nosyslog build tags.
+package loggerall
+
+import (
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/matteocavestri/logger-gath-test/logeventlog"
+	"github.com/matteocavestri/logger-gath-test/logfluent"
+	"github.com/matteocavestri/logger-gath-test/loggelf"
+	"github.com/matteocavestri/logger-gath-test/loggeo"
+	"github.com/matteocavestri/logger-gath-test/loggrpc"
+	"github.com/matteocavestri/logger-gath-test/logkafka"
+	"github.com/matteocavestri/logger-gath-test/logotlp"
+	"github.com/matteocavestri/logger-gath-test/logsentry"
+	"github.com/matteocavestri/logger-gath-test/logsql"
+	// Registers the "zerolog" backend (see logger.Config.Backend).
+	_ "github.com/matteocavestri/logger-gath-test/logzerolog"
+	"google.golang.org/grpc"
+)
+
+// EventLogConfig configures NewEventLog; see logeventlog.Config.
+type EventLogConfig = logeventlog.Config
+
+// NewEventLog returns a sink writing entries to the Windows Event Log; see
+// logeventlog.New.
+func NewEventLog(cfg EventLogConfig) (*logeventlog.Sink, error) {
+	return logeventlog.New(cfg)
+}
+
+// FluentConfig configures NewFluent; see logfluent.Config.
+type FluentConfig = logfluent.Config
+
+// NewFluent returns a sink sending entries to a Fluentd or Fluent Bit forward input; see
+// logfluent.New.
+func NewFluent(cfg FluentConfig) (*logfluent.Sink, error) {
+	return logfluent.New(cfg)
+}
+
+// GELFConfig configures NewGELF; see loggelf.Config.
+type GELFConfig = loggelf.Config
+
+// NewGELF returns a sink sending entries to a Graylog GELF input; see loggelf.New.
+func NewGELF(cfg GELFConfig) (*loggelf.Sink, error) {
+	return loggelf.New(cfg)
+}
+
+// GeoOptions configures OpenGeo; see loggeo.Options.
+type GeoOptions = loggeo.Options
+
+// OpenGeo opens MaxMind databases for the geo enrichment of access logs; see loggeo.Open.
+func OpenGeo(opts GeoOptions) (*loggeo.MaxMind, error) {
+	return loggeo.Open(opts)
+}
+
+// GRPCOptions configures the gRPC interceptors; see loggrpc.Options.
+type GRPCOptions = loggrpc.Options
+
+// GRPCServerOptions returns the server options installing the unary and stream logging
+// interceptors of loggrpc.
+//
+// Example:
+//
+//	srv := grpc.NewServer(loggerall.GRPCServerOptions(loggerall.GRPCOptions{})...)
+func GRPCServerOptions(opts GRPCOptions) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(loggrpc.UnaryServerInterceptor(opts)),
+		grpc.ChainStreamInterceptor(loggrpc.StreamServerInterceptor(opts)),
+	}
+}
+
+// GRPCDialOptions returns the dial options installing the unary and stream logging
+// interceptors of loggrpc on a client connection.
+func GRPCDialOptions(opts GRPCOptions) []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(loggrpc.UnaryClientInterceptor(opts)),
+		grpc.WithChainStreamInterceptor(loggrpc.StreamClientInterceptor(opts)),
+	}
+}
+
+// KafkaConfig configures NewKafka; see logkafka.Config.
+type KafkaConfig = logkafka.Config
+
+// NewKafka returns a sink publishing entries to a Kafka topic; see logkafka.New.
+func NewKafka(cfg KafkaConfig) (*logkafka.Sink, error) {
+	return logkafka.New(cfg)
+}
+
+// OTLPConfig configures NewOTLP; see logotlp.Config.
+type OTLPConfig = logotlp.Config
+
+// NewOTLP returns a sink exporting entries to an OpenTelemetry Collector over OTLP; see
+// logotlp.New.
+func NewOTLP(cfg OTLPConfig) (*logotlp.Sink, error) {
+	return logotlp.New(cfg)
+}
+
+// SentryConfig configures NewSentry; see logsentry.Config.
+type SentryConfig = logsentry.Config
+
+// NewSentry returns a sink reporting error entries to Sentry as events; see
+// logsentry.New.
+func NewSentry(cfg SentryConfig) (*logsentry.Sink, error) {
+	return logsentry.New(cfg)
+}
+
+// SQLOptions configures OpenSQL and WrapSQL; see logsql.Options.
+type SQLOptions = logsql.Options
+
+// OpenSQL opens a database like sql.Open, with statement logging enabled; see
+// logsql.Open.
+func OpenSQL(driverName, dsn string, opts SQLOptions) (*sql.DB, error) {
+	return logsql.Open(driverName, dsn, opts)
+}
+
+// WrapSQL returns a connector whose connections log their statements; see logsql.Wrap.
+func WrapSQL(c driver.Connector, opts SQLOptions) driver.Connector {
+	return logsql.Wrap(c, opts)
+}
@@ -0,0 +1,328 @@
+// Package loggerbench drives logger pipelines at a target entry rate and reports
+// throughput, allocation, latency, and drop statistics.
+//
+// It is intended for validating sink configurations before a production rollout:
+// point it at the same Config a service uses, run it for a few seconds (or hours,
+// as a soak test), and inspect the resulting Report.
+//
+// Example usage:
+//
+//	log, err := logger.New(cfg)
+//	if err != nil {
+//	    panic(err)
+//	}
+//	report, err := loggerbench.Run(ctx, log, loggerbench.Options{
+//	    Rate:     20000,
+//	    Duration: 30 * time.Second,
+//	    Workers:  8,
+//	})
+//	if err != nil {
+//	    panic(err)
+//	}
+//	fmt.Println(report)
+package loggerbench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// latencySampleEvery controls how often a call's latency is recorded.
+const latencySampleEvery = 16
+
+// maxLatencySamples bounds the number of latency samples kept per worker.
+const maxLatencySamples = 1 << 14
+
+// Options configures a benchmark or soak run.
+type Options struct {
+	// Rate is the target number of entries per second across all workers.
+	// Zero runs unthrottled.
+	Rate int
+	// Duration bounds the run. Zero runs until the context is cancelled.
+	Duration time.Duration
+	// Workers is the number of concurrent producer goroutines. Defaults to 1.
+	Workers int
+	// Level is the level of the generated entries. Defaults to INFO.
+	Level zapcore.Level
+	// Message is the message of the generated entries.
+	Message string
+	// Fields are attached to every generated entry.
+	Fields []zap.Field
+	// ReportInterval enables periodic reports for soak tests. Zero disables them.
+	ReportInterval time.Duration
+	// OnReport receives a cumulative report every ReportInterval.
+	OnReport func(Report)
+}
+
+// Report summarizes a run.
+type Report struct {
+	// Issued is the number of logging calls made.
+	Issued uint64
+	// Written is the number of entries accepted by the pipeline.
+	Written uint64
+	// Dropped is the number of entries rejected by the pipeline (level, sampling, ...).
+	Dropped uint64
+	// Elapsed is the wall-clock duration of the run.
+	Elapsed time.Duration
+	// Throughput is the number of written entries per second.
+	Throughput float64
+	// AllocsPerEntry is the average number of heap allocations per logging call.
+	AllocsPerEntry float64
+	// BytesPerEntry is the average number of heap bytes allocated per logging call.
+	BytesPerEntry float64
+	// LatencyP50, LatencyP99 and LatencyMax describe the cost of a single logging call.
+	LatencyP50 time.Duration
+	LatencyP99 time.Duration
+	LatencyMax time.Duration
+	// SyncDuration is the time spent flushing the pipeline at the end of the run.
+	SyncDuration time.Duration
+}
+
+// String returns a one-line human-readable summary of the report.
+func (r Report) String() string {
+	return fmt.Sprintf(
+		"issued=%d written=%d dropped=%d elapsed=%s throughput=%.0f/s allocs/op=%.1f B/op=%.0f p50=%s p99=%s max=%s sync=%s",
+		r.Issued, r.Written, r.Dropped, r.Elapsed.Round(time.Millisecond), r.Throughput,
+		r.AllocsPerEntry, r.BytesPerEntry, r.LatencyP50, r.LatencyP99, r.LatencyMax, r.SyncDuration,
+	)
+}
+
+// Run drives log with generated entries according to opts and returns the final report.
+//
+// Run returns when opts.Duration has elapsed or ctx is cancelled, whichever comes first.
+// A cancelled context is not treated as an error.
+func Run(ctx context.Context, log *logger.Logger, opts Options) (Report, error) {
+	if log == nil {
+		return Report{}, errors.New("loggerbench: nil logger")
+	}
+	if opts.Duration <= 0 && ctx.Done() == nil {
+		return Report{}, errors.New("loggerbench: either Duration or a cancellable context is required")
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.Message == "" {
+		opts.Message = "loggerbench entry"
+	}
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	r := &run{opts: opts}
+	zl := log.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &countingCore{Core: core, written: &r.written}
+	}))
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	r.start = time.Now()
+
+	var wg sync.WaitGroup
+	samples := make([][]time.Duration, opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			samples[i] = r.produce(ctx, zl, i)
+		}(i)
+	}
+
+	if opts.ReportInterval > 0 && opts.OnReport != nil {
+		go r.reportPeriodically(ctx, &before)
+	}
+
+	wg.Wait()
+	elapsed := time.Since(r.start)
+
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+
+	syncStart := time.Now()
+	_ = zl.Sync()
+
+	report := r.snapshot(elapsed, &before, &after)
+	report.SyncDuration = time.Since(syncStart)
+	report.LatencyP50, report.LatencyP99, report.LatencyMax = percentiles(samples)
+	return report, nil
+}
+
+// BackendReport is the report of one backend in a comparison.
+type BackendReport struct {
+	// Backend is the name of the backend, as set in Config.Backend.
+	Backend string
+	Report
+}
+
+// String returns a one-line human-readable summary of the report.
+func (r BackendReport) String() string {
+	return fmt.Sprintf("backend=%s %s", r.Backend, r.Report)
+}
+
+// Compare runs the same workload against the pipeline described by cfg with each backend
+// in turn, e.g. "zap", "slog" and "zerolog" (see logger.Config.Backend), and returns
+// their reports in order. Only the backend changes between runs, so that the reports
+// compare the libraries encoding and writing the entries. opts.Duration is required.
+//
+// Example:
+//
+//	reports, err := loggerbench.Compare(ctx, cfg, []string{"zap", "slog", "zerolog"}, loggerbench.Options{
+//	    Duration: 10 * time.Second,
+//	    Workers:  4,
+//	    Fields:   []zap.Field{zap.String("route", "/orders"), zap.Int("status", 200)},
+//	})
+func Compare(ctx context.Context, cfg logger.Config, backends []string, opts Options) ([]BackendReport, error) {
+	if opts.Duration <= 0 {
+		return nil, errors.New("loggerbench: Duration is required to compare backends")
+	}
+	reports := make([]BackendReport, 0, len(backends))
+	for _, backend := range backends {
+		cfg.Backend = backend
+		log, err := logger.New(cfg)
+		if err != nil {
+			return reports, fmt.Errorf("loggerbench: backend %q: %w", backend, err)
+		}
+		report, err := Run(ctx, log, opts)
+		if err != nil {
+			return reports, err
+		}
+		reports = append(reports, BackendReport{Backend: backend, Report: report})
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return reports, nil
+}
+
+// run holds the shared state of a single benchmark run.
+type run struct {
+	opts    Options
+	start   time.Time
+	issued  atomic.Uint64
+	written atomic.Uint64
+}
+
+// produce emits entries for a single worker until ctx is done and returns latency samples.
+func (r *run) produce(ctx context.Context, zl *zap.Logger, worker int) []time.Duration {
+	samples := make([]time.Duration, 0, 1024)
+	perWorker := float64(r.opts.Rate) / float64(r.opts.Workers)
+
+	var n uint64
+	for ctx.Err() == nil {
+		if perWorker > 0 {
+			due := uint64(time.Since(r.start).Seconds() * perWorker)
+			if n >= due {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+		}
+
+		n++
+		if n%latencySampleEvery != 0 {
+			r.emit(zl)
+			continue
+		}
+		callStart := time.Now()
+		r.emit(zl)
+		latency := time.Since(callStart)
+		if len(samples) < maxLatencySamples {
+			samples = append(samples, latency)
+		} else {
+			samples[int(n/latencySampleEvery)%maxLatencySamples] = latency
+		}
+	}
+	return samples
+}
+
+// emit writes a single generated entry.
+func (r *run) emit(zl *zap.Logger) {
+	r.issued.Add(1)
+	if ce := zl.Check(r.opts.Level, r.opts.Message); ce != nil {
+		ce.Write(r.opts.Fields...)
+	}
+}
+
+// reportPeriodically publishes cumulative reports until ctx is done.
+func (r *run) reportPeriodically(ctx context.Context, before *runtime.MemStats) {
+	ticker := time.NewTicker(r.opts.ReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var now runtime.MemStats
+			runtime.ReadMemStats(&now)
+			r.opts.OnReport(r.snapshot(time.Since(r.start), before, &now))
+		}
+	}
+}
+
+// snapshot builds a report from the current counters and memory statistics.
+func (r *run) snapshot(elapsed time.Duration, before, after *runtime.MemStats) Report {
+	issued := r.issued.Load()
+	written := r.written.Load()
+	report := Report{
+		Issued:  issued,
+		Written: written,
+		Elapsed: elapsed,
+	}
+	if issued > written {
+		report.Dropped = issued - written
+	}
+	if elapsed > 0 {
+		report.Throughput = float64(written) / elapsed.Seconds()
+	}
+	if issued > 0 {
+		report.AllocsPerEntry = float64(after.Mallocs-before.Mallocs) / float64(issued)
+		report.BytesPerEntry = float64(after.TotalAlloc-before.TotalAlloc) / float64(issued)
+	}
+	return report
+}
+
+// percentiles returns the p50, p99 and maximum of all collected latency samples.
+func percentiles(perWorker [][]time.Duration) (p50, p99, maxLatency time.Duration) {
+	var all []time.Duration
+	for _, s := range perWorker {
+		all = append(all, s...)
+	}
+	if len(all) == 0 {
+		return 0, 0, 0
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+	return all[len(all)*50/100], all[len(all)*99/100], all[len(all)-1]
+}
+
+// countingCore counts the entries accepted by the wrapped core.
+//
+// zap always starts a check with a nil CheckedEntry, so an entry is accepted
+// exactly when the wrapped core returns a non-nil one.
+type countingCore struct {
+	zapcore.Core
+	written *atomic.Uint64
+}
+
+// With preserves the counter on derived cores.
+func (c *countingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &countingCore{Core: c.Core.With(fields), written: c.written}
+}
+
+// Check delegates to the wrapped core and counts accepted entries.
+func (c *countingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	out := c.Core.Check(ent, ce)
+	if out != nil && out != ce {
+		c.written.Add(1)
+	}
+	return out
+}
@@ -0,0 +1,246 @@
+// Package loggrpc logs gRPC calls through the logger package.
+//
+// Its server interceptors write one entry per RPC with the method, status code, duration
+// and peer address, and give the handler a per-RPC logger: logger.FromContext(ctx) in a
+// handler returns a logger carrying the request_id, grpc_service and grpc_method of the
+// call. The request ID is taken from the x-request-id metadata of the call, so that IDs
+// propagate across services, or generated. Its client interceptors log outgoing calls the
+// same way and forward the request ID of the context in the outgoing metadata.
+//
+// Entries are logged at INFO, WARN for codes caused by the caller (InvalidArgument,
+// NotFound, PermissionDenied, ...) and ERROR for server failures (Internal, Unavailable,
+// DeadlineExceeded, ...).
+//
+// Example usage:
+//
+//	srv := grpc.NewServer(
+//	    grpc.ChainUnaryInterceptor(loggrpc.UnaryServerInterceptor(loggrpc.Options{})),
+//	    grpc.ChainStreamInterceptor(loggrpc.StreamServerInterceptor(loggrpc.Options{})),
+//	)
+//
+//	conn, err := grpc.NewClient(target,
+//	    grpc.WithUnaryInterceptor(loggrpc.UnaryClientInterceptor(loggrpc.Options{})),
+//	    grpc.WithStreamInterceptor(loggrpc.StreamClientInterceptor(loggrpc.Options{})),
+//	)
+package loggrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataRequestID is the metadata key carrying the request ID.
+const MetadataRequestID = logger.CarrierRequestID
+
+// MetadataCarrier adapts gRPC metadata to logger.Carrier, so that logger.Inject and
+// logger.Extract carry the correlation of a call across hops not covered by the
+// interceptors, e.g. in a proxy:
+//
+//	md, _ := metadata.FromIncomingContext(ctx)
+//	ctx = logger.Extract(ctx, loggrpc.MetadataCarrier(md))
+type MetadataCarrier metadata.MD
+
+// Get implements logger.Carrier.
+func (c MetadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Set implements logger.Carrier.
+func (c MetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Options configures the interceptors.
+type Options struct {
+	// Logger writes the call entries and is the base of the per-RPC loggers. Defaults to
+	// logger.FromContext of the call's context.
+	Logger *logger.Logger
+	// CodeLevel returns the level of entries about calls ending with the given code.
+	// Defaults to DefaultCodeLevel.
+	CodeLevel func(codes.Code) logger.LogLevel
+}
+
+// DefaultCodeLevel logs successful and canceled calls at INFO, calls failed because of
+// the caller at WARN and other failures at ERROR.
+func DefaultCodeLevel(code codes.Code) logger.LogLevel {
+	switch code {
+	case codes.OK, codes.Canceled:
+		return logger.LevelInfo
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.Unauthenticated, codes.FailedPrecondition, codes.OutOfRange,
+		codes.ResourceExhausted, codes.Aborted:
+		return logger.LevelWarn
+	default:
+		return logger.LevelError
+	}
+}
+
+// requestIDKey is the context key of the request ID of an RPC.
+type requestIDKey struct{}
+
+// RequestID returns the request ID of the RPC served with ctx, or the ID assigned by the
+// logger's HTTP middleware, or "" when there is none.
+func RequestID(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	return logger.RequestID(ctx)
+}
+
+// UnaryServerInterceptor returns an interceptor logging unary RPCs.
+func UnaryServerInterceptor(opts Options) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx, log := opts.serverContext(ctx, info.FullMethod)
+		resp, err := handler(ctx, req)
+		opts.log(log, ctx, "grpc request", start, err, logger.Attachments(ctx)...)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor logging streaming RPCs once they end.
+func StreamServerInterceptor(opts Options) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, log := opts.serverContext(ss.Context(), info.FullMethod)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		opts.log(log, ctx, "grpc stream", start, err, logger.Attachments(ctx)...)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor logging outgoing unary calls.
+func UnaryClientInterceptor(opts Options) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx = outgoingContext(ctx)
+		err := invoker(ctx, method, req, reply, cc, callOpts...)
+		opts.log(opts.logger(ctx), ctx, "grpc client call", start, err, clientFields(method, cc)...)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor logging the opening of outgoing
+// streams. Streams are logged when they are established, as their end is only known to
+// the code receiving from them.
+func StreamClientInterceptor(opts Options) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx = outgoingContext(ctx)
+		cs, err := streamer(ctx, desc, cc, method, callOpts...)
+		opts.log(opts.logger(ctx), ctx, "grpc client stream", start, err, clientFields(method, cc)...)
+		return cs, err
+	}
+}
+
+// serverStream overrides the context of a server stream with the per-RPC one.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context implements grpc.ServerStream.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// logger returns the logger of the call entries.
+func (o Options) logger(ctx context.Context) *logger.Logger {
+	if o.Logger != nil {
+		return o.Logger
+	}
+	return logger.FromContext(ctx)
+}
+
+// serverContext returns the context of a served RPC, carrying its request ID and its
+// per-RPC logger and collecting the fields attached with logger.Attach, and that logger.
+func (o Options) serverContext(ctx context.Context, method string) (context.Context, *logger.Logger) {
+	id := incomingRequestID(ctx)
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	log := o.logger(ctx).WithContext(append([]zap.Field{zap.String("request_id", id)}, methodFields(method)...)...)
+	return logger.WithAttachments(logger.ContextWithLogger(ctx, log)), log
+}
+
+// clientFields returns the fields describing an outgoing call.
+func clientFields(method string, cc *grpc.ClientConn) []zap.Field {
+	return append(methodFields(method), zap.String("grpc_target", cc.Target()))
+}
+
+// incomingRequestID returns the request ID of the incoming metadata, or a new one.
+func incomingRequestID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(MetadataRequestID); len(ids) > 0 && ids[0] != "" {
+			return ids[0]
+		}
+	}
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// outgoingContext forwards the request ID of ctx in the outgoing metadata.
+func outgoingContext(ctx context.Context) context.Context {
+	id := RequestID(ctx)
+	if id == "" {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(MetadataRequestID)) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, MetadataRequestID, id)
+}
+
+// log writes the entry of a completed call.
+func (o Options) log(log *logger.Logger, ctx context.Context, msg string, start time.Time, err error, extra ...zap.Field) {
+	code := status.Code(err)
+	levelOf := o.CodeLevel
+	if levelOf == nil {
+		levelOf = DefaultCodeLevel
+	}
+	level, lerr := zapcore.ParseLevel(strings.ToLower(string(levelOf(code))))
+	if lerr != nil {
+		level = zapcore.InfoLevel
+	}
+	// Check against the core directly: the caller would only point at the interceptor.
+	ent := zapcore.Entry{LoggerName: log.Name(), Time: time.Now(), Level: level, Message: msg}
+	ce := log.Core().Check(ent, nil)
+	if ce == nil {
+		return
+	}
+	fields := append(extra,
+		zap.String("grpc_code", code.String()),
+		logger.Latency(time.Since(start)),
+	)
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields = append(fields, zap.String("peer", p.Addr.String()))
+	}
+	if err != nil {
+		fields = append(fields, zap.String("error", status.Convert(err).Message()))
+	}
+	ce.Write(fields...)
+}
+
+// methodFields returns the grpc_service and grpc_method fields of a full method name such
+// as "/orders.v1.Orders/Create".
+func methodFields(full string) []zap.Field {
+	service, method := "", strings.TrimPrefix(full, "/")
+	if i := strings.LastIndex(method, "/"); i >= 0 {
+		service, method = method[:i], method[i+1:]
+	}
+	return []zap.Field{zap.String("grpc_service", service), zap.String("grpc_method", method)}
+}
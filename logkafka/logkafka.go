@@ -0,0 +1,645 @@
+// Package logkafka provides a logger.Sink publishing entries to a Kafka topic, for
+// pipelines consuming logs from Kafka without a sidecar shipping them.
+//
+// Importing the package registers the "kafka" URL scheme, so that an output can be
+// configured by its Path alone. The host lists the bootstrap brokers and the path is the
+// topic:
+//
+//	import _ "github.com/matteocavestri/logger-gath-test/logkafka"
+//
+//	cfg.Outputs = []logger.OutputConfig{{
+//	    Path: "kafka://broker-1:9092,broker-2:9092/logs?key=service&acks=all&drop=oldest",
+//	}}
+//
+// The sink can also be built from a Config and set as OutputConfig.Sink:
+//
+//	sink, err := logkafka.New(logkafka.Config{Brokers: []string{"broker-1:9092"}, Topic: "logs"})
+//	if err != nil {
+//	    panic(err)
+//	}
+//	cfg.Outputs = []logger.OutputConfig{{Sink: sink}}
+//
+// Each entry is a message whose value is the entry encoded with the output's Encoding,
+// JSON by default, without the trailing newline. The message key is the value of the key
+// field, the service by default: entries with the same key go to the same partition,
+// which keeps their order, using the partitioner of the Java client. Entries without the
+// key field are spread over the partitions.
+//
+// Messages are queued in a bounded buffer and produced in batches from a background
+// goroutine, so logging never waits for the brokers. When the buffer is full, the newest
+// or the oldest entries are dropped, and the drops are reported on stderr. With a spool
+// (Config.Spool, or the spool parameter of the URL naming its directory), the batches
+// that cannot be produced are written to disk and produced once the brokers are
+// reachable again, instead of being dropped. The spool_key_env parameter names the
+// environment variable holding the key that encrypts the spool.
+//
+// The sink implements the Kafka protocol itself (Kafka 0.11 and later), without
+// compression, SASL or idempotence, so that it does not pull in a client library.
+package logkafka
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+)
+
+// Acknowledgements required from the brokers before a batch is considered written.
+const (
+	// AcksNone does not wait for the brokers: batches can be lost silently.
+	AcksNone = "none"
+	// AcksLeader waits for the partition leader to write the batch (default).
+	AcksLeader = "leader"
+	// AcksAll waits for all in-sync replicas to write the batch.
+	AcksAll = "all"
+)
+
+// Policies applied when the buffer is full.
+const (
+	// DropNewest drops the entries logged while the buffer is full (default).
+	DropNewest = "newest"
+	// DropOldest drops the oldest buffered entries to make room for new ones.
+	DropOldest = "oldest"
+)
+
+// Defaults of the sink.
+const (
+	defaultKeyField      = "service"
+	defaultBatchSize     = 500
+	defaultBatchBytes    = 1 << 20
+	defaultFlushInterval = time.Second
+	defaultBufferSize    = 10_000
+	defaultMaxRetries    = 3
+	defaultTimeout       = 10 * time.Second
+	defaultClientID      = "logger"
+	minBackoff           = 100 * time.Millisecond
+	maxBackoff           = 5 * time.Second
+)
+
+// Config describes the Kafka topic to publish entries to.
+type Config struct {
+	// Brokers are the host:port addresses of the bootstrap brokers.
+	Brokers []string
+	// Topic is the topic the entries are published to. It must exist.
+	Topic string
+	// KeyField is the field whose value is the message key, which selects the partition.
+	// Defaults to "service".
+	KeyField string
+	// Acks is AcksNone, AcksLeader or AcksAll. Defaults to AcksLeader.
+	Acks string
+	// BatchSize is the number of buffered entries that triggers a produce request.
+	// Defaults to 500.
+	BatchSize int
+	// BatchBytes bounds the size of the messages of a produce request. Defaults to 1 MiB.
+	BatchBytes int
+	// FlushInterval is the maximum time entries wait before being produced. Defaults to
+	// 1s.
+	FlushInterval time.Duration
+	// BufferSize bounds the number of buffered entries, e.g. while the brokers are
+	// unreachable. Defaults to 10000.
+	BufferSize int
+	// DropPolicy is DropNewest or DropOldest. Defaults to DropNewest.
+	DropPolicy string
+	// MaxRetries bounds the retries of a failed batch; the batch is dropped afterwards.
+	// Defaults to 3; a negative value disables retries.
+	MaxRetries int
+	// Timeout bounds connections and requests to the brokers. Defaults to 10s.
+	Timeout time.Duration
+	// ClientID identifies the producer in the broker logs and quotas. Defaults to
+	// "logger".
+	ClientID string
+	// TLSConfig enables TLS to the brokers.
+	TLSConfig *tls.Config
+	// OnDrop, when set, is called with the number of entries dropped because the buffer
+	// was full or a batch failed, e.g. to increment a metric. It must be fast and must not
+	// log through the same logger.
+	OnDrop func(n int)
+	// Spool, when set, writes the batches that could not be produced after MaxRetries to
+	// disk instead of dropping them, and produces them once the brokers are reachable
+	// again.
+	Spool *logger.SpoolConfig
+}
+
+func init() {
+	_ = logger.RegisterSink("kafka", func(u *url.URL) (logger.Sink, error) {
+		cfg, err := configFromURL(u)
+		if err != nil {
+			return nil, err
+		}
+		return New(cfg)
+	})
+}
+
+// configFromURL returns the configuration described by a kafka:// URL.
+func configFromURL(u *url.URL) (Config, error) {
+	q := u.Query()
+	cfg := Config{
+		Brokers:    strings.Split(u.Host, ","),
+		Topic:      strings.Trim(u.Path, "/"),
+		KeyField:   q.Get("key"),
+		Acks:       q.Get("acks"),
+		DropPolicy: q.Get("drop"),
+		ClientID:   q.Get("client_id"),
+	}
+	for name, dst := range map[string]*int{"batch_size": &cfg.BatchSize, "buffer_size": &cfg.BufferSize} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return Config{}, fmt.Errorf("logkafka: invalid %s %q", name, v)
+			}
+			*dst = n
+		}
+	}
+	if v := q.Get("flush_interval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("logkafka: invalid flush_interval %q", v)
+		}
+		cfg.FlushInterval = d
+	}
+	if q.Get("tls") == "true" {
+		cfg.TLSConfig = &tls.Config{}
+	}
+	if dir := q.Get("spool"); dir != "" {
+		cfg.Spool = &logger.SpoolConfig{Dir: dir}
+		if env := q.Get("spool_key_env"); env != "" {
+			cfg.Spool.Encryption = &logger.EncryptionConfig{KeyEnv: env}
+		}
+	}
+	return cfg, nil
+}
+
+// withDefaults returns a copy of the configuration with defaults applied.
+func (c Config) withDefaults() Config {
+	if c.KeyField == "" {
+		c.KeyField = defaultKeyField
+	}
+	if c.Acks == "" {
+		c.Acks = AcksLeader
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.BatchBytes <= 0 {
+		c.BatchBytes = defaultBatchBytes
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultFlushInterval
+	}
+	if c.BufferSize <= 0 {
+		c.BufferSize = defaultBufferSize
+	}
+	if c.DropPolicy == "" {
+		c.DropPolicy = DropNewest
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = defaultMaxRetries
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.ClientID == "" {
+		c.ClientID = defaultClientID
+	}
+	return c
+}
+
+// acks returns the acks of the produce requests.
+func (c Config) acks() int16 {
+	switch c.Acks {
+	case AcksNone:
+		return 0
+	case AcksAll:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// Sink publishes entries to a Kafka topic. It is safe for concurrent use.
+type Sink struct {
+	cfg Config
+
+	mu      sync.Mutex
+	queue   []message
+	dropped int
+
+	// produceMu serializes batches so that messages of a partition keep their order.
+	// It guards the fields below.
+	produceMu  sync.Mutex
+	meta       *metadata
+	conns      map[int32]*conn
+	roundRobin int
+	// spool holds the batches that could not be produced, nil without Config.Spool.
+	spool *logger.Spool
+
+	kick      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ logger.Sink = (*Sink)(nil)
+
+// New validates cfg, applies its defaults and starts publishing. The brokers are
+// contacted when the first batch is produced.
+func New(cfg Config) (*Sink, error) {
+	switch {
+	case len(cfg.Brokers) == 0 || cfg.Brokers[0] == "":
+		return nil, errors.New("logkafka: at least one broker is required")
+	case cfg.Topic == "":
+		return nil, errors.New("logkafka: topic is required")
+	}
+	cfg = cfg.withDefaults()
+	switch {
+	case cfg.Acks != AcksNone && cfg.Acks != AcksLeader && cfg.Acks != AcksAll:
+		return nil, fmt.Errorf("logkafka: unknown acks %q", cfg.Acks)
+	case cfg.DropPolicy != DropNewest && cfg.DropPolicy != DropOldest:
+		return nil, fmt.Errorf("logkafka: unknown drop policy %q", cfg.DropPolicy)
+	}
+	s := &Sink{
+		cfg:   cfg,
+		conns: make(map[int32]*conn),
+		kick:  make(chan struct{}, 1),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if cfg.Spool != nil {
+		spool, err := logger.OpenSpool(*cfg.Spool)
+		if err != nil {
+			return nil, fmt.Errorf("logkafka: %w", err)
+		}
+		s.spool = spool
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write implements logger.Sink. The entry is queued; it is published asynchronously.
+func (s *Sink) Write(e logger.Entry, encoded []byte) error {
+	m := message{
+		value: []byte(strings.TrimSuffix(string(encoded), "\n")),
+		time:  e.Time,
+	}
+	if v, ok := e.Fields[s.cfg.KeyField]; ok {
+		m.key = fmt.Append(nil, v)
+	}
+	s.mu.Lock()
+	if len(s.queue) >= s.cfg.BufferSize {
+		s.dropped++
+		if s.cfg.DropPolicy == DropNewest {
+			s.mu.Unlock()
+			return nil
+		}
+		s.queue = s.queue[1:]
+	}
+	s.queue = append(s.queue, m)
+	full := len(s.queue) >= s.cfg.BatchSize
+	s.mu.Unlock()
+	if full {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// run produces batches on every tick or when a batch fills up.
+func (s *Sink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.kick:
+		case <-s.stop:
+			return
+		}
+		_ = s.flush()
+	}
+}
+
+// flush produces all queued messages, one batch at a time. With a spool, the spooled
+// messages are produced first, and batches are spooled rather than dropped when they
+// cannot be produced.
+func (s *Sink) flush() error {
+	s.produceMu.Lock()
+	defer s.produceMu.Unlock()
+
+	var errs []error
+	// spoolErr is set while older messages are still spooled: new batches go behind them.
+	spoolErr := s.replay()
+	for {
+		batch := s.next()
+		if len(batch) == 0 {
+			return errors.Join(errs...)
+		}
+		if spoolErr != nil {
+			s.spoolBatch(batch, spoolErr)
+			continue
+		}
+		if err := s.produceWithRetry(batch); err != nil {
+			if s.spool != nil {
+				s.spoolBatch(batch, err)
+				spoolErr = err
+			} else {
+				s.drop(len(batch))
+				fmt.Fprintf(os.Stderr, "logger: kafka produce to %s failed, dropped %d entries: %v\n", s.cfg.Topic, len(batch), err)
+			}
+			errs = append(errs, err)
+		}
+	}
+}
+
+// replay produces the spooled messages, returning the error of the first batch that
+// could not be produced. Each batch is produced once: the next flush tries again. It must
+// be called with produceMu held.
+func (s *Sink) replay() error {
+	if s.spool == nil || s.spool.Empty() {
+		return nil
+	}
+	return s.spool.Replay(s.cfg.BatchSize, func(records []logger.SpoolRecord) error {
+		batch := make([]message, len(records))
+		for i, r := range records {
+			batch[i] = message{value: r.Entry, time: r.Time}
+			if key, ok := r.Attributes["key"]; ok {
+				batch[i].key = []byte(key)
+			}
+		}
+		if _, err := s.produce(s.partition(batch)); err != nil {
+			s.meta = nil
+			return err
+		}
+		return nil
+	})
+}
+
+// spoolBatch writes a batch that could not be produced because of cause to the spool,
+// dropping it if that fails too.
+func (s *Sink) spoolBatch(batch []message, cause error) {
+	records := make([]logger.SpoolRecord, len(batch))
+	for i, m := range batch {
+		records[i] = logger.SpoolRecord{Time: m.time, Entry: m.value}
+		if m.key != nil {
+			records[i].Attributes = map[string]string{"key": string(m.key)}
+		}
+	}
+	if err := s.spool.Append(records); err != nil {
+		s.drop(len(batch))
+		fmt.Fprintf(os.Stderr, "logger: kafka produce to %s failed, dropped %d entries: %v (%v)\n", s.cfg.Topic, len(batch), cause, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "logger: kafka produce to %s failed, spooled %d entries: %v\n", s.cfg.Topic, len(batch), cause)
+}
+
+// next dequeues the next batch, bounded by BatchSize and BatchBytes, and reports the
+// entries dropped since the previous batch.
+func (s *Sink) next() []message {
+	s.mu.Lock()
+	n, size := 0, 0
+	for n < len(s.queue) && n < s.cfg.BatchSize {
+		size += len(s.queue[n].key) + len(s.queue[n].value)
+		if n > 0 && size > s.cfg.BatchBytes {
+			break
+		}
+		n++
+	}
+	batch := s.queue[:n:n]
+	s.queue = s.queue[n:]
+	dropped := s.dropped
+	s.dropped = 0
+	s.mu.Unlock()
+
+	if dropped > 0 {
+		s.drop(dropped)
+		fmt.Fprintf(os.Stderr, "logger: kafka buffer full, dropped %d entries\n", dropped)
+	}
+	return batch
+}
+
+// drop reports dropped entries to OnDrop.
+func (s *Sink) drop(n int) {
+	if s.cfg.OnDrop != nil {
+		s.cfg.OnDrop(n)
+	}
+}
+
+// produceWithRetry produces a batch, retrying the partitions that failed with retriable
+// errors after refreshing the metadata. It must be called with produceMu held.
+func (s *Sink) produceWithRetry(batch []message) error {
+	pending := s.partition(batch)
+	backoff := minBackoff
+	for attempt := 0; ; attempt++ {
+		failed, err := s.produce(pending)
+		if err == nil {
+			return nil
+		}
+		var kerr kafkaError
+		if errors.As(err, &kerr) && !kerr.retriable() || attempt >= s.cfg.MaxRetries {
+			return err
+		}
+		s.meta = nil
+		select {
+		case <-time.After(backoff):
+		case <-s.stop:
+			// Shutting down: make one last attempt without waiting.
+			_, err = s.produce(failed)
+			return err
+		}
+		backoff = min(backoff*2, maxBackoff)
+		pending = failed
+	}
+}
+
+// partitioned holds messages by partition index.
+type partitioned map[int32][]message
+
+// partition assigns the messages of a batch to partitions. Messages with a key go to the
+// partition the Java client would choose; the others are spread round-robin. Without
+// metadata, messages are kept under partition -1 until it can be fetched.
+func (s *Sink) partition(batch []message) partitioned {
+	if err := s.refresh(); err != nil {
+		return partitioned{-1: batch}
+	}
+	n := int32(len(s.meta.leaders))
+	parts := make(partitioned)
+	for _, m := range batch {
+		var p int32
+		if m.key != nil {
+			p = (murmur2(m.key) & 0x7fffffff) % n
+		} else {
+			s.roundRobin++
+			p = int32(s.roundRobin % int(n))
+		}
+		parts[p] = append(parts[p], m)
+	}
+	return parts
+}
+
+// produce sends the messages to the leaders of their partitions, and returns those of
+// the partitions that failed with the first error.
+func (s *Sink) produce(parts partitioned) (partitioned, error) {
+	if err := s.refresh(); err != nil {
+		return parts, err
+	}
+	if msgs, ok := parts[-1]; ok {
+		delete(parts, -1)
+		for p, m := range s.partition(msgs) {
+			parts[p] = append(parts[p], m...)
+		}
+	}
+	byLeader := make(map[int32]map[int32][]message)
+	for p, msgs := range parts {
+		leader := s.meta.leaders[p]
+		if byLeader[leader] == nil {
+			byLeader[leader] = make(map[int32][]message)
+		}
+		byLeader[leader][p] = msgs
+	}
+	failed := make(partitioned)
+	var firstErr error
+	fail := func(batches map[int32][]message, err error) {
+		for p, msgs := range batches {
+			failed[p] = msgs
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	for leader, batches := range byLeader {
+		c, err := s.leader(leader)
+		if err != nil {
+			fail(batches, err)
+			continue
+		}
+		errs, err := c.produce(s.cfg.Topic, s.cfg.acks(), s.cfg.Timeout, batches)
+		if err != nil {
+			_ = c.Close()
+			delete(s.conns, leader)
+			fail(batches, kafkaError(errNetworkException))
+			continue
+		}
+		for p, err := range errs {
+			fail(map[int32][]message{p: batches[p]}, err)
+		}
+	}
+	return failed, firstErr
+}
+
+// leader returns the connection to a partition leader.
+func (s *Sink) leader(id int32) (*conn, error) {
+	if c, ok := s.conns[id]; ok {
+		return c, nil
+	}
+	addr, ok := s.meta.brokers[id]
+	if !ok {
+		return nil, kafkaError(errLeaderNotAvailable)
+	}
+	c, err := dial(addr, s.cfg.TLSConfig, s.cfg.ClientID, s.cfg.Timeout)
+	if err != nil {
+		return nil, kafkaError(errNetworkException)
+	}
+	s.conns[id] = c
+	return c, nil
+}
+
+// refresh fetches the metadata of the topic from the bootstrap brokers, if it is not
+// cached.
+func (s *Sink) refresh() error {
+	if s.meta != nil {
+		return nil
+	}
+	var errs []error
+	for _, i := range rand.Perm(len(s.cfg.Brokers)) {
+		c, err := dial(s.cfg.Brokers[i], s.cfg.TLSConfig, s.cfg.ClientID, s.cfg.Timeout)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		md, err := c.fetchMetadata(s.cfg.Topic)
+		_ = c.Close()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.meta = md
+		return nil
+	}
+	return fmt.Errorf("logkafka: fetching metadata: %w", errors.Join(errs...))
+}
+
+// murmur2 is the hash of the default partitioner of the Java client.
+func murmur2(data []byte) int32 {
+	const (
+		seed = 0x9747b28c
+		m    = 0x5bd1e995
+		r    = 24
+	)
+	h := uint32(seed) ^ uint32(len(data))
+	n := len(data) &^ 3
+	for i := 0; i < n; i += 4 {
+		k := binary.LittleEndian.Uint32(data[i:])
+		k *= m
+		k ^= k >> r
+		k *= m
+		h *= m
+		h ^= k
+	}
+	switch tail := data[n:]; len(tail) {
+	case 3:
+		h ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint32(tail[0])
+		h *= m
+	}
+	h ^= h >> 13
+	h *= m
+	h ^= h >> 15
+	return int32(h)
+}
+
+// Sync implements logger.Sink, producing all queued entries.
+func (s *Sink) Sync() error {
+	return s.flush()
+}
+
+// Close implements logger.Sink. It produces the remaining entries and closes the
+// connections.
+func (s *Sink) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		err = s.flush()
+		s.produceMu.Lock()
+		defer s.produceMu.Unlock()
+		for id, c := range s.conns {
+			_ = c.Close()
+			delete(s.conns, id)
+		}
+		if s.spool != nil {
+			_ = s.spool.Close()
+		}
+	})
+	return err
+}
+
+// String implements logger.Sink.
+func (s *Sink) String() string {
+	return "kafka://" + strings.Join(s.cfg.Brokers, ",") + "/" + s.cfg.Topic
+}
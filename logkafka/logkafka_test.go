@@ -0,0 +1,390 @@
+package logkafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+)
+
+// produceRequest is a produce request received by the fake broker.
+type produceRequest struct {
+	clientID string
+	acks     int16
+	topic    string
+	// records holds the records of each partition.
+	records map[int32][]message
+}
+
+// fakeBroker is a single Kafka broker leading every partition of its topic. It answers
+// Metadata v1 and Produce v3 requests, failing produce requests with the error codes
+// queued in produceErrors.
+type fakeBroker struct {
+	t          *testing.T
+	ln         net.Listener
+	topic      string
+	partitions int32
+
+	mu            sync.Mutex
+	produceErrors []int16
+	metadata      int
+	produced      []produceRequest
+}
+
+func newFakeBroker(t *testing.T, topic string, partitions int32) *fakeBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{t: t, ln: ln, topic: topic, partitions: partitions}
+	t.Cleanup(func() { _ = ln.Close() })
+	go b.serve()
+	return b
+}
+
+func (b *fakeBroker) addr() string { return b.ln.Addr().String() }
+
+// waitProduced waits for n produce requests, which acks=0 requests reach after the
+// sink returns, and locks the broker.
+func (b *fakeBroker) waitProduced(n int) {
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		b.mu.Lock()
+		if len(b.produced) >= n || time.Now().After(deadline) {
+			return
+		}
+		b.mu.Unlock()
+	}
+}
+
+func (b *fakeBroker) serve() {
+	for {
+		nc, err := b.ln.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(nc)
+	}
+}
+
+// handle answers the requests of a connection.
+func (b *fakeBroker) handle(nc net.Conn) {
+	defer nc.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(nc, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(nc, req); err != nil {
+			return
+		}
+		d := decoder{b: req}
+		apiKey, version, correlation, clientID := d.int16(), d.int16(), d.int32(), d.string()
+		var resp []byte
+		switch {
+		case apiKey == apiMetadata && version == metadataVersion:
+			resp = b.metadataResponse(&d)
+		case apiKey == apiProduce && version == produceVersion:
+			var respond bool
+			resp, respond = b.produceResponse(&d, clientID)
+			if !respond {
+				continue
+			}
+		default:
+			b.t.Errorf("unexpected request: api key %d, version %d", apiKey, version)
+			return
+		}
+		if d.err != nil {
+			b.t.Errorf("decoding request: %v", d.err)
+			return
+		}
+		out := binary.BigEndian.AppendUint32(nil, uint32(4+len(resp)))
+		out = binary.BigEndian.AppendUint32(out, uint32(correlation))
+		if _, err := nc.Write(append(out, resp...)); err != nil {
+			return
+		}
+	}
+}
+
+// metadataResponse returns a Metadata v1 response naming the broker as the leader of
+// every partition.
+func (b *fakeBroker) metadataResponse(d *decoder) []byte {
+	if n := d.int32(); n != 1 || d.string() != b.topic {
+		b.t.Errorf("metadata request for %d topics, want %q alone", n, b.topic)
+	}
+	b.mu.Lock()
+	b.metadata++
+	b.mu.Unlock()
+	host, port, _ := net.SplitHostPort(b.addr())
+	p, _ := strconv.Atoi(port)
+	r := binary.BigEndian.AppendUint32(nil, 1) // brokers
+	r = binary.BigEndian.AppendUint32(r, 0)    // node ID
+	r = appendString(r, host)
+	r = binary.BigEndian.AppendUint32(r, uint32(p))
+	r = binary.BigEndian.AppendUint16(r, 0xffff) // null rack
+	r = binary.BigEndian.AppendUint32(r, 0)      // controller
+	r = binary.BigEndian.AppendUint32(r, 1)      // topics
+	r = binary.BigEndian.AppendUint16(r, errNone)
+	r = appendString(r, b.topic)
+	r = append(r, 0) // internal
+	r = binary.BigEndian.AppendUint32(r, uint32(b.partitions))
+	for i := range b.partitions {
+		r = binary.BigEndian.AppendUint16(r, errNone)
+		r = binary.BigEndian.AppendUint32(r, uint32(i))
+		r = binary.BigEndian.AppendUint32(r, 0) // leader
+		r = binary.BigEndian.AppendUint32(r, 1) // replicas
+		r = binary.BigEndian.AppendUint32(r, 0) // replica 0
+		r = binary.BigEndian.AppendUint32(r, 1) // in-sync replicas
+		r = binary.BigEndian.AppendUint32(r, 0) // replica 0
+	}
+	return r
+}
+
+// produceResponse records a produce request and returns its Produce v3 response, with
+// the next queued error code for every partition. It reports false for acks=0, which
+// gets no response.
+func (b *fakeBroker) produceResponse(d *decoder, clientID string) ([]byte, bool) {
+	req := produceRequest{clientID: clientID, records: make(map[int32][]message)}
+	if id := d.int16(); id != -1 {
+		b.t.Errorf("transactional ID length = %d, want null", id)
+	}
+	req.acks = d.int16()
+	d.int32() // timeout
+	if n := d.int32(); n != 1 {
+		b.t.Errorf("produce request for %d topics, want 1", n)
+	}
+	req.topic = d.string()
+	var partitions []int32
+	for n := d.int32(); n > 0 && d.err == nil; n-- {
+		p := d.int32()
+		partitions = append(partitions, p)
+		req.records[p] = decodeRecordBatch(b.t, d.next(int(d.int32())))
+	}
+
+	b.mu.Lock()
+	b.produced = append(b.produced, req)
+	code := int16(errNone)
+	if len(b.produceErrors) > 0 {
+		code = b.produceErrors[0]
+		b.produceErrors = b.produceErrors[1:]
+	}
+	b.mu.Unlock()
+
+	r := binary.BigEndian.AppendUint32(nil, 1)
+	r = appendString(r, req.topic)
+	r = binary.BigEndian.AppendUint32(r, uint32(len(partitions)))
+	for _, p := range partitions {
+		r = binary.BigEndian.AppendUint32(r, uint32(p))
+		r = binary.BigEndian.AppendUint16(r, uint16(code))
+		r = binary.BigEndian.AppendUint64(r, 0)          // base offset
+		r = binary.BigEndian.AppendUint64(r, 0xffffffff) // log append time
+	}
+	r = binary.BigEndian.AppendUint32(r, 0) // throttle time
+	return r, req.acks != 0
+}
+
+// decodeRecordBatch decodes a v2 record batch, checking its framing and checksum.
+func decodeRecordBatch(t *testing.T, batch []byte) []message {
+	t.Helper()
+	if len(batch) < 61 {
+		t.Errorf("record batch of %d bytes", len(batch))
+		return nil
+	}
+	if n := binary.BigEndian.Uint32(batch[8:]); int(n) != len(batch)-12 {
+		t.Errorf("batch length = %d, want %d", n, len(batch)-12)
+	}
+	if batch[16] != 2 {
+		t.Errorf("magic = %d, want 2", batch[16])
+	}
+	if crc := crc32.Checksum(batch[21:], crc32.MakeTable(crc32.Castagnoli)); crc != binary.BigEndian.Uint32(batch[17:]) {
+		t.Errorf("batch CRC = %#x, want %#x", binary.BigEndian.Uint32(batch[17:]), crc)
+	}
+	first := int64(binary.BigEndian.Uint64(batch[27:]))
+	count := int(binary.BigEndian.Uint32(batch[57:]))
+	if last := int(binary.BigEndian.Uint32(batch[23:])); last != count-1 {
+		t.Errorf("last offset delta = %d for %d records", last, count)
+	}
+	r := bytes.NewReader(batch[61:])
+	varint := func() int64 {
+		v, err := binary.ReadVarint(r)
+		if err != nil {
+			t.Errorf("decoding record: %v", err)
+		}
+		return v
+	}
+	bytesOf := func(n int64) []byte {
+		if n < 0 {
+			return nil
+		}
+		b := make([]byte, n)
+		_, _ = io.ReadFull(r, b)
+		return b
+	}
+	var msgs []message
+	for i := range count {
+		varint() // length
+		_, _ = r.ReadByte()
+		ts := first + varint()
+		if delta := varint(); delta != int64(i) {
+			t.Errorf("offset delta = %d, want %d", delta, i)
+		}
+		m := message{time: time.UnixMilli(ts)}
+		m.key = bytesOf(varint())
+		m.value = bytesOf(varint())
+		if h := varint(); h != 0 {
+			t.Errorf("record with %d headers", h)
+		}
+		msgs = append(msgs, m)
+	}
+	if r.Len() != 0 {
+		t.Errorf("%d bytes after the records", r.Len())
+	}
+	return msgs
+}
+
+// newTestSink returns a sink producing to the broker, without a background flush.
+func newTestSink(t *testing.T, b *fakeBroker, cfg Config) *Sink {
+	t.Helper()
+	cfg.Brokers = []string{b.addr()}
+	cfg.Topic = b.topic
+	cfg.FlushInterval = time.Hour
+	cfg.Timeout = 5 * time.Second
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New() = %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+// write writes entries with the given service field to the sink.
+func write(t *testing.T, s *Sink, service string, values ...string) {
+	t.Helper()
+	for _, v := range values {
+		e := logger.Entry{Time: time.UnixMilli(1_700_000_000_000), Fields: map[string]any{"service": service}}
+		if err := s.Write(e, []byte(v+"\n")); err != nil {
+			t.Fatalf("Write() = %v", err)
+		}
+	}
+}
+
+func TestSinkProduces(t *testing.T) {
+	for _, tt := range []struct {
+		acks string
+		want int16
+	}{{AcksNone, 0}, {AcksLeader, 1}, {AcksAll, -1}} {
+		t.Run(tt.acks, func(t *testing.T) {
+			b := newFakeBroker(t, "logs", 1)
+			s := newTestSink(t, b, Config{Acks: tt.acks, ClientID: "orders"})
+			write(t, s, "orders", `{"msg":"a"}`, `{"msg":"b"}`)
+			if err := s.Sync(); err != nil {
+				t.Fatalf("Sync() = %v", err)
+			}
+			b.waitProduced(1)
+			defer b.mu.Unlock()
+			if len(b.produced) != 1 {
+				t.Fatalf("%d produce requests, want 1", len(b.produced))
+			}
+			req := b.produced[0]
+			if req.clientID != "orders" || req.acks != tt.want || req.topic != "logs" {
+				t.Errorf("request from %q with acks %d to %q, want orders, %d, logs", req.clientID, req.acks, req.topic, tt.want)
+			}
+			msgs := req.records[0]
+			if len(msgs) != 2 {
+				t.Fatalf("produced %d records, want 2", len(msgs))
+			}
+			for i, want := range []string{`{"msg":"a"}`, `{"msg":"b"}`} {
+				m := msgs[i]
+				if string(m.key) != "orders" || string(m.value) != want || m.time.UnixMilli() != 1_700_000_000_000 {
+					t.Errorf("record %d = key %q, value %q, time %v", i, m.key, m.value, m.time)
+				}
+			}
+		})
+	}
+}
+
+func TestSinkRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		errors   []int16
+		retries  int
+		wantErr  error
+		attempts int
+		dropped  int
+	}{
+		{"retriable error", []int16{errNotLeaderForPartition, errRequestTimedOut}, 3, nil, 3, 0},
+		{"retries exhausted", []int16{errNotLeaderForPartition, errNotLeaderForPartition}, 1, kafkaError(errNotLeaderForPartition), 2, 2},
+		{"not retriable", []int16{10}, 3, kafkaError(10), 1, 2},
+		{"retries disabled", []int16{errRequestTimedOut}, -1, kafkaError(errRequestTimedOut), 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newFakeBroker(t, "logs", 1)
+			b.produceErrors = tt.errors
+			var dropped int
+			s := newTestSink(t, b, Config{MaxRetries: tt.retries, OnDrop: func(n int) { dropped += n }})
+			write(t, s, "orders", "a", "b")
+			if err := s.Sync(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Sync() = %v, want %v", err, tt.wantErr)
+			}
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if len(b.produced) != tt.attempts {
+				t.Errorf("%d produce requests, want %d", len(b.produced), tt.attempts)
+			}
+			// Retries refresh the metadata, as the leader may have moved.
+			if b.metadata != tt.attempts {
+				t.Errorf("%d metadata requests, want %d", b.metadata, tt.attempts)
+			}
+			for _, req := range b.produced {
+				if got := len(req.records[0]); got != 2 {
+					t.Errorf("request with %d records, want the 2 of the batch", got)
+				}
+			}
+			if dropped != tt.dropped {
+				t.Errorf("dropped %d entries, want %d", dropped, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestSinkPartitionsByKey(t *testing.T) {
+	b := newFakeBroker(t, "logs", 4)
+	s := newTestSink(t, b, Config{})
+	write(t, s, "foobar", "a", "b")
+	write(t, s, "abc", "c")
+	if err := s.Sync(); err != nil {
+		t.Fatalf("Sync() = %v", err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// The partitions the Java client picks: murmur2 & 0x7fffffff % 4.
+	want := map[int32]string{
+		(-790332482 & 0x7fffffff) % 4: "ab",
+		479470107 % 4:                 "c",
+	}
+	got := make(map[int32]string)
+	for _, req := range b.produced {
+		for p, msgs := range req.records {
+			for _, m := range msgs {
+				got[p] += string(m.value)
+			}
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("produced %q by partition, want %q", got, want)
+	}
+	for p, v := range want {
+		if got[p] != v {
+			t.Fatalf("produced %q by partition, want %q", got, want)
+		}
+	}
+}
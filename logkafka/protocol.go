@@ -0,0 +1,364 @@
+package logkafka
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// The sink speaks the subset of the Kafka protocol needed to produce: Metadata v1 and
+// Produce v3 with v2 record batches, supported by Kafka 0.11 and later. Records are not
+// compressed and the producer is not idempotent.
+
+// API keys and versions of the requests.
+const (
+	apiProduce        = 0
+	apiMetadata       = 3
+	produceVersion    = 3
+	metadataVersion   = 1
+	recordBatchMagic  = 2
+	maxResponseLength = 64 << 20
+)
+
+// Error codes the sink reacts to; see the Kafka protocol guide for the others.
+const (
+	errNone                    = 0
+	errUnknownTopicOrPartition = 3
+	errLeaderNotAvailable      = 5
+	errNotLeaderForPartition   = 6
+	errRequestTimedOut         = 7
+	errNetworkException        = 13
+	errNotEnoughReplicas       = 19
+	errNotEnoughReplicasAfter  = 20
+)
+
+// kafkaError is an error code returned by a broker.
+type kafkaError int16
+
+func (e kafkaError) Error() string {
+	return "kafka error code " + strconv.Itoa(int(e))
+}
+
+// retriable reports whether the request may succeed once retried, possibly against
+// another leader.
+func (e kafkaError) retriable() bool {
+	switch e {
+	case errUnknownTopicOrPartition, errLeaderNotAvailable, errNotLeaderForPartition,
+		errRequestTimedOut, errNetworkException, errNotEnoughReplicas, errNotEnoughReplicasAfter:
+		return true
+	default:
+		return false
+	}
+}
+
+// castagnoli is the CRC-32C table of record batch checksums.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// conn is a connection to a broker. It is not safe for concurrent use.
+type conn struct {
+	nc          net.Conn
+	r           *bufio.Reader
+	clientID    string
+	correlation int32
+	timeout     time.Duration
+}
+
+// dial connects to a broker.
+func dial(addr string, tlsConfig *tls.Config, clientID string, timeout time.Duration) (*conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	var nc net.Conn
+	var err error
+	if tlsConfig != nil {
+		nc, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		nc, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &conn{nc: nc, r: bufio.NewReader(nc), clientID: clientID, timeout: timeout}, nil
+}
+
+// roundTrip sends a request and returns the body of its response, or nil when noResponse
+// is set (produce requests with acks=0).
+func (c *conn) roundTrip(apiKey, version int16, body []byte, noResponse bool) ([]byte, error) {
+	c.correlation++
+	req := make([]byte, 4, 4+14+len(c.clientID)+len(body))
+	req = binary.BigEndian.AppendUint16(req, uint16(apiKey))
+	req = binary.BigEndian.AppendUint16(req, uint16(version))
+	req = binary.BigEndian.AppendUint32(req, uint32(c.correlation))
+	req = appendString(req, c.clientID)
+	req = append(req, body...)
+	binary.BigEndian.PutUint32(req, uint32(len(req)-4))
+
+	_ = c.nc.SetDeadline(time.Now().Add(c.timeout))
+	if _, err := c.nc.Write(req); err != nil {
+		return nil, err
+	}
+	if noResponse {
+		return nil, nil
+	}
+	var header [8]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return nil, err
+	}
+	size := int32(binary.BigEndian.Uint32(header[:4]))
+	if size < 4 || size > maxResponseLength {
+		return nil, fmt.Errorf("invalid response size %d", size)
+	}
+	if id := int32(binary.BigEndian.Uint32(header[4:])); id != c.correlation {
+		return nil, fmt.Errorf("unexpected correlation id %d, want %d", id, c.correlation)
+	}
+	resp := make([]byte, size-4)
+	if _, err := io.ReadFull(c.r, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Close closes the connection.
+func (c *conn) Close() error {
+	return c.nc.Close()
+}
+
+// metadata holds the brokers and the partition leaders of a topic.
+type metadata struct {
+	brokers map[int32]string
+	// leaders holds the node ID of the leader of each partition, by partition index; -1
+	// when the partition has no leader.
+	leaders []int32
+}
+
+// fetchMetadata requests the metadata of a topic.
+func (c *conn) fetchMetadata(topic string) (*metadata, error) {
+	body := binary.BigEndian.AppendUint32(nil, 1)
+	body = appendString(body, topic)
+	resp, err := c.roundTrip(apiMetadata, metadataVersion, body, false)
+	if err != nil {
+		return nil, err
+	}
+	d := decoder{b: resp}
+	md := &metadata{brokers: make(map[int32]string)}
+	for n := d.int32(); n > 0 && d.err == nil; n-- {
+		id := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		md.brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.int32() // controller
+	for n := d.int32(); n > 0 && d.err == nil; n-- {
+		code := kafkaError(d.int16())
+		name := d.string()
+		d.int8() // internal
+		partitions := d.int32()
+		if name != topic {
+			d.skipPartitions(partitions)
+			continue
+		}
+		if code != errNone {
+			return nil, fmt.Errorf("topic %q: %w", topic, code)
+		}
+		md.leaders = make([]int32, max(partitions, 0))
+		for ; partitions > 0 && d.err == nil; partitions-- {
+			d.int16() // error code
+			index := d.int32()
+			leader := d.int32()
+			d.skipArray(4) // replicas
+			d.skipArray(4) // in-sync replicas
+			if index >= 0 && int(index) < len(md.leaders) {
+				md.leaders[index] = leader
+			}
+		}
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("decoding metadata: %w", d.err)
+	}
+	if len(md.leaders) == 0 {
+		return nil, fmt.Errorf("topic %q: %w", topic, kafkaError(errUnknownTopicOrPartition))
+	}
+	return md, nil
+}
+
+// message is a record to produce.
+type message struct {
+	key   []byte
+	value []byte
+	time  time.Time
+}
+
+// produce writes record batches to partitions of a topic led by the broker, and returns
+// the error of each partition.
+func (c *conn) produce(topic string, acks int16, timeout time.Duration, batches map[int32][]message) (map[int32]error, error) {
+	body := binary.BigEndian.AppendUint16(nil, 0xffff) // null transactional ID
+	body = binary.BigEndian.AppendUint16(body, uint16(acks))
+	body = binary.BigEndian.AppendUint32(body, uint32(timeout.Milliseconds()))
+	body = binary.BigEndian.AppendUint32(body, 1)
+	body = appendString(body, topic)
+	body = binary.BigEndian.AppendUint32(body, uint32(len(batches)))
+	for partition, msgs := range batches {
+		body = binary.BigEndian.AppendUint32(body, uint32(partition))
+		batch := appendRecordBatch(nil, msgs)
+		body = binary.BigEndian.AppendUint32(body, uint32(len(batch)))
+		body = append(body, batch...)
+	}
+	resp, err := c.roundTrip(apiProduce, produceVersion, body, acks == 0)
+	if err != nil || acks == 0 {
+		return nil, err
+	}
+	d := decoder{b: resp}
+	errs := make(map[int32]error)
+	for n := d.int32(); n > 0 && d.err == nil; n-- {
+		d.string() // topic
+		for p := d.int32(); p > 0 && d.err == nil; p-- {
+			partition := d.int32()
+			if code := kafkaError(d.int16()); code != errNone {
+				errs[partition] = code
+			}
+			d.int64() // base offset
+			d.int64() // log append time
+		}
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("decoding produce response: %w", d.err)
+	}
+	return errs, nil
+}
+
+// appendRecordBatch appends an uncompressed v2 record batch of the messages.
+func appendRecordBatch(b []byte, msgs []message) []byte {
+	first, last := msgs[0].time, msgs[0].time
+	for _, m := range msgs[1:] {
+		if m.time.Before(first) {
+			first = m.time
+		}
+		if m.time.After(last) {
+			last = m.time
+		}
+	}
+	start := len(b)
+	b = binary.BigEndian.AppendUint64(b, 0)          // base offset
+	b = binary.BigEndian.AppendUint32(b, 0)          // batch length, set below
+	b = binary.BigEndian.AppendUint32(b, 0xffffffff) // partition leader epoch
+	b = append(b, recordBatchMagic)                  // magic
+	b = binary.BigEndian.AppendUint32(b, 0)          // CRC, set below
+	crcStart := len(b)
+	b = binary.BigEndian.AppendUint16(b, 0)                         // attributes
+	b = binary.BigEndian.AppendUint32(b, uint32(len(msgs)-1))       // last offset delta
+	b = binary.BigEndian.AppendUint64(b, uint64(first.UnixMilli())) // first timestamp
+	b = binary.BigEndian.AppendUint64(b, uint64(last.UnixMilli()))  // max timestamp
+	b = binary.BigEndian.AppendUint64(b, 0xffffffffffffffff)        // producer ID
+	b = binary.BigEndian.AppendUint16(b, 0xffff)                    // producer epoch
+	b = binary.BigEndian.AppendUint32(b, 0xffffffff)                // base sequence
+	b = binary.BigEndian.AppendUint32(b, uint32(len(msgs)))
+	var record []byte
+	for i, m := range msgs {
+		record = append(record[:0], 0) // attributes
+		record = binary.AppendVarint(record, m.time.UnixMilli()-first.UnixMilli())
+		record = binary.AppendVarint(record, int64(i))
+		if m.key == nil {
+			record = binary.AppendVarint(record, -1)
+		} else {
+			record = binary.AppendVarint(record, int64(len(m.key)))
+			record = append(record, m.key...)
+		}
+		record = binary.AppendVarint(record, int64(len(m.value)))
+		record = append(record, m.value...)
+		record = binary.AppendVarint(record, 0) // headers
+		b = binary.AppendVarint(b, int64(len(record)))
+		b = append(b, record...)
+	}
+	binary.BigEndian.PutUint32(b[start+8:], uint32(len(b)-start-12))
+	binary.BigEndian.PutUint32(b[crcStart-4:], crc32.Checksum(b[crcStart:], castagnoli))
+	return b
+}
+
+// appendString appends a protocol string.
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// errShortResponse reports a truncated response.
+var errShortResponse = errors.New("short response")
+
+// decoder reads the fields of a response, recording the first error.
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.b) < n {
+		d.err = errShortResponse
+		return nil
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) int8() int8 {
+	if v := d.next(1); v != nil {
+		return int8(v[0])
+	}
+	return 0
+}
+
+func (d *decoder) int16() int16 {
+	if v := d.next(2); v != nil {
+		return int16(binary.BigEndian.Uint16(v))
+	}
+	return 0
+}
+
+func (d *decoder) int32() int32 {
+	if v := d.next(4); v != nil {
+		return int32(binary.BigEndian.Uint32(v))
+	}
+	return 0
+}
+
+func (d *decoder) int64() int64 {
+	if v := d.next(8); v != nil {
+		return int64(binary.BigEndian.Uint64(v))
+	}
+	return 0
+}
+
+// string reads a nullable string; null is read as "".
+func (d *decoder) string() string {
+	n := d.int16()
+	if n <= 0 {
+		return ""
+	}
+	return string(d.next(int(n)))
+}
+
+// skipArray skips an array of fixed-size elements.
+func (d *decoder) skipArray(size int) {
+	if n := d.int32(); n > 0 {
+		d.next(int(n) * size)
+	}
+}
+
+// skipPartitions skips the partitions of a topic in a metadata response.
+func (d *decoder) skipPartitions(n int32) {
+	for ; n > 0 && d.err == nil; n-- {
+		d.int16()
+		d.int32()
+		d.int32()
+		d.skipArray(4)
+		d.skipArray(4)
+	}
+}
@@ -0,0 +1,77 @@
+package logkafka
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// The fixtures below are written from the Kafka protocol guide, not from the encoder.
+
+func TestAppendRecordBatch(t *testing.T) {
+	first := time.UnixMilli(1_700_000_000_000)
+	msgs := []message{
+		{key: []byte("k"), value: []byte("v"), time: first},
+		{value: []byte("w"), time: first.Add(5 * time.Millisecond)},
+	}
+	want := []byte{
+		0, 0, 0, 0, 0, 0, 0, 0, // base offset
+		0, 0, 0, 66, // batch length
+		0xff, 0xff, 0xff, 0xff, // partition leader epoch
+		2,                      // magic
+		0x01, 0x94, 0x9b, 0x28, // CRC-32C of the rest of the batch
+		0, 0, // attributes
+		0, 0, 0, 1, // last offset delta
+		0, 0, 0x01, 0x8b, 0xcf, 0xe5, 0x68, 0x00, // first timestamp
+		0, 0, 0x01, 0x8b, 0xcf, 0xe5, 0x68, 0x05, // max timestamp
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // producer ID
+		0xff, 0xff, // producer epoch
+		0xff, 0xff, 0xff, 0xff, // base sequence
+		0, 0, 0, 2, // record count
+		// length 8, attributes, timestamp delta 0, offset delta 0, key "k", value "v",
+		// no headers; lengths and deltas are zigzag varints.
+		0x10, 0, 0, 0, 0x02, 'k', 0x02, 'v', 0,
+		// length 7, attributes, timestamp delta 5, offset delta 1, null key, value "w",
+		// no headers.
+		0x0e, 0, 0x0a, 0x02, 0x01, 0x02, 'w', 0,
+	}
+	if got := appendRecordBatch(nil, msgs); !bytes.Equal(got, want) {
+		t.Fatalf("appendRecordBatch() =\n% x\nwant\n% x", got, want)
+	}
+}
+
+func TestAppendString(t *testing.T) {
+	if got, want := appendString([]byte{1}, "logs"), []byte{1, 0, 4, 'l', 'o', 'g', 's'}; !bytes.Equal(got, want) {
+		t.Fatalf("appendString() = % x, want % x", got, want)
+	}
+}
+
+func TestMurmur2(t *testing.T) {
+	// The vectors of the Java client's tests, whose default partitioner the sink follows.
+	tests := []struct {
+		key  string
+		want int32
+	}{
+		{"21", -973932308},
+		{"foobar", -790332482},
+		{"a-little-bit-long-string", -985981536},
+		{"a-little-bit-longer-string", -1486304829},
+		{"lkjh234lh9fiuh90y23oiuhsafujhadof229phr9h19h89h8", -58897971},
+		{"abc", 479470107},
+	}
+	for _, tt := range tests {
+		if got := murmur2([]byte(tt.key)); got != tt.want {
+			t.Errorf("murmur2(%q) = %d, want %d", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeShortResponse(t *testing.T) {
+	d := decoder{b: []byte{0, 5, 'a', 'b'}}
+	if s := d.string(); s != "" || d.err != errShortResponse {
+		t.Fatalf("string() = %q, err %v, want a short response", s, d.err)
+	}
+	if v := d.int32(); v != 0 || d.err != errShortResponse {
+		t.Fatalf("int32() after an error = %d, err %v", v, d.err)
+	}
+}
@@ -0,0 +1,612 @@
+// Package logotlp provides a logger.Sink exporting entries as OpenTelemetry log records
+// to an OpenTelemetry Collector (or any OTLP endpoint), over OTLP/gRPC or OTLP/HTTP.
+//
+// Importing the package registers the "otlp" URL scheme, so that an output can be
+// configured by its Path alone:
+//
+//	import _ "github.com/matteocavestri/logger-gath-test/logotlp"
+//
+//	cfg.Outputs = []logger.OutputConfig{
+//	    {Path: "otlp://otel-collector:4317?insecure=true"},                    // OTLP/gRPC
+//	    {Path: "otlp://otel-collector:4318?protocol=http/protobuf&insecure=true"}, // OTLP/HTTP
+//	}
+//
+// The sink can also be built from a Config and set as OutputConfig.Sink.
+//
+// Entries are mapped to the OpenTelemetry log data model:
+//
+//   - the level to the severity number and text (DEBUG 5, INFO 9, WARN 13, ERROR 17,
+//     DPANIC 19, PANIC and FATAL 21)
+//   - the message to the body
+//   - the trace_id, span_id and trace_flags fields to the trace context of the record
+//   - the service and environment fields to the service.name and
+//     deployment.environment.name resource attributes
+//   - the logger name to the instrumentation scope
+//   - the caller and stack trace to the code.filepath, code.lineno and
+//     exception.stacktrace attributes
+//   - the other fields to attributes, with nested objects as maps
+//
+// Records are batched and exported from a background goroutine; failed exports are
+// retried with exponential backoff. The output's Encoding is not used.
+package logotlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Protocols of the OTLP transport.
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http/protobuf"
+)
+
+// Defaults of the exporter.
+const (
+	defaultBatchSize     = 512
+	defaultFlushInterval = time.Second
+	defaultTimeout       = 10 * time.Second
+	defaultMaxRetries    = 5
+	minBackoff           = 500 * time.Millisecond
+	maxBackoff           = 30 * time.Second
+	// maxBuffered bounds the records held while the collector is unreachable.
+	maxBuffered = 100_000
+	// httpLogsPath is the path of the OTLP/HTTP logs endpoint.
+	httpLogsPath = "/v1/logs"
+	// defaultScope is the instrumentation scope of the entries of the root logger.
+	defaultScope = "github.com/matteocavestri/logger-gath-test"
+)
+
+// Config describes the OTLP endpoint to export entries to.
+type Config struct {
+	// Endpoint is the host:port of the OTLP/gRPC receiver, or the URL of the OTLP/HTTP
+	// receiver (e.g. "http://otel-collector:4318"; /v1/logs is appended when the URL has
+	// no path).
+	Endpoint string
+	// Protocol is ProtocolGRPC or ProtocolHTTP. Defaults to ProtocolGRPC.
+	Protocol string
+	// Insecure disables TLS for OTLP/gRPC.
+	Insecure bool
+	// TLSConfig configures TLS for OTLP/gRPC. Defaults to the system roots.
+	TLSConfig *tls.Config
+	// Headers are sent with every export, e.g. an API key of a hosted backend.
+	Headers map[string]string
+	// Resource holds attributes added to the resource of every record, e.g.
+	// {"k8s.pod.name": os.Getenv("POD_NAME")}.
+	Resource map[string]any
+	// BatchSize is the number of records that triggers an export. Defaults to 512.
+	BatchSize int
+	// FlushInterval is the maximum time records wait before being exported. Defaults
+	// to 1s.
+	FlushInterval time.Duration
+	// Timeout bounds a single export. Defaults to 10s.
+	Timeout time.Duration
+	// MaxRetries bounds the retries of a failed export; the batch is dropped afterwards.
+	// Defaults to 5; a negative value disables retries.
+	MaxRetries int
+	// Client is the HTTP client of OTLP/HTTP exports. Defaults to a client with Timeout.
+	Client *http.Client
+}
+
+func init() {
+	_ = logger.RegisterSink("otlp", func(u *url.URL) (logger.Sink, error) {
+		return New(configFromURL(u))
+	})
+}
+
+// configFromURL returns the configuration described by an otlp:// URL.
+func configFromURL(u *url.URL) Config {
+	q := u.Query()
+	cfg := Config{Endpoint: u.Host, Protocol: q.Get("protocol"), Insecure: q.Get("insecure") == "true"}
+	if cfg.Protocol == ProtocolHTTP {
+		scheme := "https"
+		if cfg.Insecure {
+			scheme = "http"
+		}
+		cfg.Endpoint = scheme + "://" + u.Host + u.Path
+	}
+	return cfg
+}
+
+// withDefaults returns a copy of the configuration with defaults applied.
+func (c Config) withDefaults() Config {
+	if c.Protocol == "" {
+		c.Protocol = ProtocolGRPC
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultFlushInterval
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = defaultMaxRetries
+	}
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: c.Timeout}
+	}
+	return c
+}
+
+// Sink exports entries to an OTLP endpoint. It is safe for concurrent use.
+type Sink struct {
+	cfg      Config
+	resource []*commonpb.KeyValue
+	export   func(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (retry bool, err error)
+	conn     *grpc.ClientConn
+
+	mu      sync.Mutex
+	pending []*record
+	dropped int
+
+	// exportMu serializes exports so that records arrive in order.
+	exportMu  sync.Mutex
+	kick      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+var _ logger.Sink = (*Sink)(nil)
+
+// record is a log record waiting to be exported, with its resource and scope.
+type record struct {
+	service     string
+	environment string
+	scope       string
+	log         *logspb.LogRecord
+}
+
+// New validates cfg, applies its defaults and starts exporting.
+func New(cfg Config) (*Sink, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("logotlp: endpoint is required")
+	}
+	s := &Sink{
+		cfg:  cfg.withDefaults(),
+		kick: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	for key, value := range cfg.Resource {
+		s.resource = append(s.resource, keyValue(key, value))
+	}
+	switch s.cfg.Protocol {
+	case ProtocolGRPC:
+		creds := insecure.NewCredentials()
+		if !s.cfg.Insecure {
+			creds = credentials.NewTLS(s.cfg.TLSConfig)
+		}
+		conn, err := grpc.NewClient(s.cfg.Endpoint, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return nil, fmt.Errorf("logotlp: %w", err)
+		}
+		s.conn = conn
+		client := collogspb.NewLogsServiceClient(conn)
+		s.export = func(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (bool, error) {
+			return s.exportGRPC(ctx, client, req)
+		}
+	case ProtocolHTTP:
+		endpoint, err := httpEndpoint(s.cfg.Endpoint)
+		if err != nil {
+			return nil, err
+		}
+		s.export = func(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (bool, error) {
+			return s.exportHTTP(ctx, endpoint, req)
+		}
+	default:
+		return nil, fmt.Errorf("logotlp: unknown protocol %q", s.cfg.Protocol)
+	}
+	go s.run()
+	return s, nil
+}
+
+// httpEndpoint returns the URL of the OTLP/HTTP logs endpoint.
+func httpEndpoint(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("logotlp: invalid OTLP/HTTP endpoint %q", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = httpLogsPath
+	}
+	return u.String(), nil
+}
+
+// Write implements logger.Sink. The entry is queued; it is exported asynchronously.
+func (s *Sink) Write(e logger.Entry, _ []byte) error {
+	r := newRecord(e)
+	s.mu.Lock()
+	if len(s.pending) >= maxBuffered {
+		s.dropped++
+		s.mu.Unlock()
+		return nil
+	}
+	s.pending = append(s.pending, r)
+	full := len(s.pending) >= s.cfg.BatchSize
+	s.mu.Unlock()
+	if full {
+		select {
+		case s.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// run exports batches on every tick or when a batch fills up.
+func (s *Sink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.kick:
+		case <-s.stop:
+			return
+		}
+		_ = s.flush()
+	}
+}
+
+// flush exports all queued records, one batch at a time.
+func (s *Sink) flush() error {
+	s.exportMu.Lock()
+	defer s.exportMu.Unlock()
+
+	var errs []error
+	for {
+		s.mu.Lock()
+		n := min(len(s.pending), s.cfg.BatchSize)
+		batch := s.pending[:n:n]
+		s.pending = s.pending[n:]
+		dropped := s.dropped
+		s.dropped = 0
+		s.mu.Unlock()
+
+		if dropped > 0 {
+			fmt.Fprintf(os.Stderr, "logger: otlp buffer full, dropped %d entries\n", dropped)
+		}
+		if len(batch) == 0 {
+			return errors.Join(errs...)
+		}
+		if err := s.exportWithRetry(s.request(batch)); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: otlp export failed, dropped %d entries: %v\n", len(batch), err)
+			errs = append(errs, err)
+		}
+	}
+}
+
+// exportWithRetry exports a request, retrying with exponential backoff on failure.
+func (s *Sink) exportWithRetry(req *collogspb.ExportLogsServiceRequest) error {
+	backoff := minBackoff
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+		retry, err := s.export(ctx, req)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.cfg.MaxRetries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-s.stop:
+			// Shutting down: make one last attempt without waiting.
+			ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+			defer cancel()
+			_, err = s.export(ctx, req)
+			return err
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// exportGRPC sends a request over OTLP/gRPC, reporting whether a failure is worth
+// retrying.
+func (s *Sink) exportGRPC(ctx context.Context, client collogspb.LogsServiceClient, req *collogspb.ExportLogsServiceRequest) (bool, error) {
+	if len(s.cfg.Headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(s.cfg.Headers))
+	}
+	_, err := client.Export(ctx, req)
+	if err == nil {
+		return false, nil
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted:
+		return true, fmt.Errorf("logotlp: %w", err)
+	default:
+		return false, fmt.Errorf("logotlp: %w", err)
+	}
+}
+
+// exportHTTP sends a request over OTLP/HTTP, reporting whether a failure is worth
+// retrying.
+func (s *Sink) exportHTTP(ctx context.Context, endpoint string, req *collogspb.ExportLogsServiceRequest) (bool, error) {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return false, fmt.Errorf("logotlp: %w", err)
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("logotlp: %w", err)
+	}
+	hreq.Header.Set("Content-Type", "application/x-protobuf")
+	for key, value := range s.cfg.Headers {
+		hreq.Header.Set(key, value)
+	}
+	resp, err := s.cfg.Client.Do(hreq)
+	if err != nil {
+		return true, fmt.Errorf("logotlp: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("logotlp: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	// Throttling and unavailability are transient; other errors are not.
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, err
+	default:
+		return false, err
+	}
+}
+
+// request groups a batch of records by resource and scope.
+func (s *Sink) request(batch []*record) *collogspb.ExportLogsServiceRequest {
+	type resourceKey struct{ service, environment string }
+	resources := make(map[resourceKey]*logspb.ResourceLogs)
+	scopes := make(map[resourceKey]map[string]*logspb.ScopeLogs)
+	req := &collogspb.ExportLogsServiceRequest{}
+	for _, r := range batch {
+		key := resourceKey{r.service, r.environment}
+		rl, ok := resources[key]
+		if !ok {
+			rl = &logspb.ResourceLogs{Resource: &resourcepb.Resource{Attributes: s.resourceAttributes(r)}}
+			resources[key] = rl
+			scopes[key] = make(map[string]*logspb.ScopeLogs)
+			req.ResourceLogs = append(req.ResourceLogs, rl)
+		}
+		sl, ok := scopes[key][r.scope]
+		if !ok {
+			sl = &logspb.ScopeLogs{Scope: &commonpb.InstrumentationScope{Name: r.scope}}
+			scopes[key][r.scope] = sl
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+		}
+		sl.LogRecords = append(sl.LogRecords, r.log)
+	}
+	return req
+}
+
+// resourceAttributes returns the attributes of the resource of a record.
+func (s *Sink) resourceAttributes(r *record) []*commonpb.KeyValue {
+	attrs := make([]*commonpb.KeyValue, 0, len(s.resource)+2)
+	if r.service != "" {
+		attrs = append(attrs, keyValue("service.name", r.service))
+	}
+	if r.environment != "" {
+		attrs = append(attrs, keyValue("deployment.environment.name", r.environment))
+	}
+	return append(attrs, s.resource...)
+}
+
+// newRecord maps an entry to a log record.
+func newRecord(e logger.Entry) *record {
+	now := time.Now()
+	number, text := severity(e.Level)
+	log := &logspb.LogRecord{
+		TimeUnixNano:         uint64(e.Time.UnixNano()),
+		ObservedTimeUnixNano: uint64(now.UnixNano()),
+		SeverityNumber:       number,
+		SeverityText:         text,
+		Body:                 &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: e.Message}},
+	}
+	r := &record{scope: e.Logger, log: log}
+	if r.scope == "" {
+		r.scope = defaultScope
+	}
+	for _, key := range slices.Sorted(maps.Keys(e.Fields)) {
+		value := e.Fields[key]
+		switch key {
+		case "service":
+			if s, ok := value.(string); ok {
+				r.service = s
+				continue
+			}
+		case "environment":
+			if s, ok := value.(string); ok {
+				r.environment = s
+				continue
+			}
+		case "trace_id":
+			if id, ok := decodeID(value, 16); ok {
+				log.TraceId = id
+				continue
+			}
+		case "span_id":
+			if id, ok := decodeID(value, 8); ok {
+				log.SpanId = id
+				continue
+			}
+		case "trace_flags":
+			if s, ok := value.(string); ok {
+				if flags, err := strconv.ParseUint(s, 16, 8); err == nil {
+					log.Flags = uint32(flags)
+					continue
+				}
+			}
+		}
+		log.Attributes = append(log.Attributes, keyValue(key, value))
+	}
+	if e.Caller != "" {
+		file, line := e.Caller, ""
+		if i := strings.LastIndexByte(e.Caller, ':'); i >= 0 {
+			file, line = e.Caller[:i], e.Caller[i+1:]
+		}
+		log.Attributes = append(log.Attributes, keyValue("code.filepath", file))
+		if n, err := strconv.ParseInt(line, 10, 64); err == nil {
+			log.Attributes = append(log.Attributes, keyValue("code.lineno", n))
+		}
+	}
+	if e.Stack != "" {
+		log.Attributes = append(log.Attributes, keyValue("exception.stacktrace", e.Stack))
+	}
+	return r
+}
+
+// decodeID decodes a hex trace or span ID of n bytes.
+func decodeID(value any, n int) ([]byte, bool) {
+	s, ok := value.(string)
+	if !ok || len(s) != 2*n {
+		return nil, false
+	}
+	id, err := hex.DecodeString(s)
+	return id, err == nil
+}
+
+// severity maps a level to an OpenTelemetry severity number and text.
+func severity(level logger.LogLevel) (logspb.SeverityNumber, string) {
+	switch level {
+	case logger.LevelDebug:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, "DEBUG"
+	case logger.LevelInfo:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "INFO"
+	case logger.LevelWarn:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN, "WARN"
+	case logger.LevelError:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "ERROR"
+	case "DPANIC":
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR3, "DPANIC"
+	case "PANIC", "FATAL":
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL, string(level)
+	default:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, string(level)
+	}
+}
+
+// keyValue returns an attribute.
+func keyValue(key string, value any) *commonpb.KeyValue {
+	return &commonpb.KeyValue{Key: key, Value: anyValue(value)}
+}
+
+// anyValue maps a field value to an attribute value.
+func anyValue(value any) *commonpb.AnyValue {
+	switch v := value.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case int:
+		return intValue(int64(v))
+	case int8:
+		return intValue(int64(v))
+	case int16:
+		return intValue(int64(v))
+	case int32:
+		return intValue(int64(v))
+	case int64:
+		return intValue(v)
+	case uint:
+		return intValue(int64(v))
+	case uint8:
+		return intValue(int64(v))
+	case uint16:
+		return intValue(int64(v))
+	case uint32:
+		return intValue(int64(v))
+	case uint64:
+		return intValue(int64(v))
+	case float32:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: float64(v)}}
+	case float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: v}}
+	case []byte:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v}}
+	case time.Time:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Format(time.RFC3339Nano)}}
+	case time.Duration:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.String()}}
+	case []any:
+		values := make([]*commonpb.AnyValue, len(v))
+		for i, item := range v {
+			values[i] = anyValue(item)
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case map[string]any:
+		values := make([]*commonpb.KeyValue, 0, len(v))
+		for key, item := range v {
+			values = append(values, keyValue(key, item))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: values}}}
+	default:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprint(v)}}
+	}
+}
+
+// intValue returns an integer attribute value.
+func intValue(v int64) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: v}}
+}
+
+// Sync implements logger.Sink, exporting all queued records.
+func (s *Sink) Sync() error {
+	return s.flush()
+}
+
+// Close implements logger.Sink. It exports the remaining records and closes the
+// connection.
+func (s *Sink) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.stop)
+		<-s.done
+		err = s.flush()
+		if s.conn != nil {
+			err = errors.Join(err, s.conn.Close())
+		}
+	})
+	return err
+}
+
+// String implements logger.Sink.
+func (s *Sink) String() string {
+	if s.cfg.Protocol == ProtocolHTTP {
+		return "otlp " + s.cfg.Endpoint
+	}
+	return "otlp grpc://" + s.cfg.Endpoint
+}
@@ -0,0 +1,312 @@
+// Package logparse reads the JSON logs emitted by the logger package, so that tools,
+// tests and viewers can consume them without re-implementing the schema.
+//
+// Parse decodes a single line, and a Decoder streams the entries of a reader:
+//
+//	dec := logparse.NewDecoder(f)
+//	for {
+//	    e, err := dec.Next()
+//	    if err == io.EOF {
+//	        break
+//	    }
+//	    var lineErr *logparse.LineError
+//	    if errors.As(err, &lineErr) {
+//	        continue // not an entry, e.g. a panic message
+//	    }
+//	    if err != nil {
+//	        return err
+//	    }
+//	    if status, ok := e.Field("status"); ok {
+//	        ...
+//	    }
+//	}
+//
+// An entry carries the standard keys (time, level, logger name, caller, message and
+// stack trace) and the other keys as fields, in their original order. Integral numbers
+// are decoded as int64, other numbers as float64, and nested objects as map[string]any.
+package logparse
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+)
+
+// maxLineSize bounds the length of the lines read by a Decoder.
+const maxLineSize = 16 * 1024 * 1024
+
+// Keys names the standard keys of the logs.
+type Keys struct {
+	Time       string
+	Level      string
+	Name       string
+	Caller     string
+	Message    string
+	Stacktrace string
+}
+
+// DefaultKeys are the keys emitted by the logger package's JSON encoding.
+var DefaultKeys = Keys{
+	Time:       "timestamp",
+	Level:      "level",
+	Name:       "logger",
+	Caller:     "caller",
+	Message:    "message",
+	Stacktrace: "stacktrace",
+}
+
+// Entry is a parsed log entry.
+type Entry struct {
+	// Time is the time of the entry, zero when the line has none.
+	Time time.Time
+	// Level is the level of the entry in upper case, e.g. INFO, empty when the line has
+	// none. Levels unknown to the logger are kept as is.
+	Level logger.LogLevel
+	// Logger is the name of the logger, empty for the root logger.
+	Logger string
+	// Message is the log message.
+	Message string
+	// Caller is the file:line location of the logging call, empty when not recorded.
+	Caller string
+	// Stack is the stack trace of the logging call, empty when not recorded. See Frames.
+	Stack string
+	// Fields holds the other keys of the entry, in their original order.
+	Fields []Field
+	// Raw is the line the entry was parsed from.
+	Raw []byte
+}
+
+// Field is a key of an entry other than the standard ones.
+type Field struct {
+	Key string
+	// Value is a string, bool, int64, float64, nil, []any or map[string]any.
+	Value any
+}
+
+// Field returns the value of the first field with the given key.
+func (e Entry) Field(key string) (any, bool) {
+	for _, f := range e.Fields {
+		if f.Key == key {
+			return f.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Map returns the fields of the entry by key, e.g. to build a logger.Entry.
+func (e Entry) Map() map[string]any {
+	m := make(map[string]any, len(e.Fields))
+	for _, f := range e.Fields {
+		m[f.Key] = f.Value
+	}
+	return m
+}
+
+// Frames returns the stack trace of the entry, innermost call first.
+func (e Entry) Frames() []Frame {
+	return ParseStack(e.Stack)
+}
+
+// CallerFrame returns the location of the logging call, reporting false when the entry
+// has none.
+func (e Entry) CallerFrame() (Frame, bool) {
+	if e.Caller == "" {
+		return Frame{}, false
+	}
+	return parseLocation(e.Caller), true
+}
+
+// Frame is a call in a stack trace.
+type Frame struct {
+	// Function is the package-qualified function name, empty for the caller of an entry.
+	Function string
+	// File is the path of the source file, as recorded.
+	File string
+	// Line is the line number, 0 when unknown.
+	Line int
+}
+
+// ParseStack parses a stack trace recorded by the logger, made of function lines each
+// followed by an indented file:line line. Frames are returned innermost call first.
+func ParseStack(stack string) []Frame {
+	lines := strings.Split(strings.TrimSpace(stack), "\n")
+	var frames []Frame
+	for i := 0; i+1 < len(lines); i += 2 {
+		frame := parseLocation(strings.TrimSpace(lines[i+1]))
+		frame.Function = strings.TrimSpace(lines[i])
+		frames = append(frames, frame)
+	}
+	return frames
+}
+
+// parseLocation parses a file:line location.
+func parseLocation(s string) Frame {
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return Frame{File: s}
+	}
+	line, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return Frame{File: s}
+	}
+	return Frame{File: s[:i], Line: line}
+}
+
+// timeLayouts lists the timestamp formats accepted by Parse.
+var timeLayouts = []string{
+	"2006-01-02T15:04:05.000Z0700",
+	time.RFC3339Nano,
+}
+
+// Parse decodes a log line with the default keys.
+func Parse(line []byte) (Entry, error) {
+	return ParseKeys(line, DefaultKeys)
+}
+
+// ParseKeys decodes a log line whose standard keys are named by keys.
+func ParseKeys(line []byte, keys Keys) (Entry, error) {
+	e := Entry{Raw: line}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return e, errors.New("not a JSON object")
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return e, err
+		}
+		key, _ := tok.(string)
+		var value any
+		if err := dec.Decode(&value); err != nil {
+			return e, fmt.Errorf("field %q: %w", key, err)
+		}
+
+		text, isText := value.(string)
+		switch {
+		case key == keys.Time && isText:
+			if e.Time, err = parseTime(text); err != nil {
+				return e, err
+			}
+		case key == keys.Level && isText:
+			e.Level = logger.LogLevel(strings.ToUpper(text))
+		case key == keys.Name && isText:
+			e.Logger = text
+		case key == keys.Caller && isText:
+			e.Caller = text
+		case key == keys.Message && isText:
+			e.Message = text
+		case key == keys.Stacktrace && isText:
+			e.Stack = text
+		default:
+			e.Fields = append(e.Fields, Field{Key: key, Value: convert(value)})
+		}
+	}
+	return e, nil
+}
+
+// parseTime parses a timestamp in one of the supported layouts.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+// convert replaces the JSON numbers of a decoded value with int64 or float64.
+func convert(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []any:
+		for i := range v {
+			v[i] = convert(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = convert(v[k])
+		}
+	}
+	return value
+}
+
+// LineError reports a line of a Decoder that is not a log entry. The decoder can go on
+// with the next line.
+type LineError struct {
+	// Line is the line number, starting at 1.
+	Line int
+	// Raw is the content of the line.
+	Raw []byte
+	// Err is the parse error.
+	Err error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Decoder reads the entries of a stream of log lines.
+type Decoder struct {
+	scanner *bufio.Scanner
+	keys    Keys
+	line    int
+}
+
+// NewDecoder returns a decoder reading r with the default keys.
+func NewDecoder(r io.Reader) *Decoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	return &Decoder{scanner: scanner, keys: DefaultKeys}
+}
+
+// SetKeys sets the standard keys of the logs.
+func (d *Decoder) SetKeys(keys Keys) {
+	d.keys = keys
+}
+
+// Next returns the next entry, skipping blank lines. It returns a *LineError for a line
+// that is not an entry, and io.EOF at the end of the stream.
+func (d *Decoder) Next() (Entry, error) {
+	for d.scanner.Scan() {
+		d.line++
+		raw := bytes.TrimSpace(d.scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		raw = bytes.Clone(raw)
+		e, err := ParseKeys(raw, d.keys)
+		if err != nil {
+			return e, &LineError{Line: d.line, Raw: raw, Err: err}
+		}
+		return e, nil
+	}
+	if err := d.scanner.Err(); err != nil {
+		return Entry{}, err
+	}
+	return Entry{}, io.EOF
+}
+
+// Line returns the number of the last line read.
+func (d *Decoder) Line() int {
+	return d.line
+}
@@ -0,0 +1,181 @@
+// Package logreplay reads previously emitted JSON logs and re-encodes them into another
+// configured format or sink.
+//
+// Typical uses are schema migrations (JSON → logfmt, legacy keys → new keys) and incident
+// backfills (file → Loki). Original timestamps, levels, logger names, callers and stack
+// traces are preserved; all other keys are replayed as fields, in their original order.
+//
+// Example usage:
+//
+//	f, err := os.Open("/var/log/app/app.json")
+//	if err != nil {
+//	    panic(err)
+//	}
+//	defer f.Close()
+//
+//	stats, err := logreplay.ReplayConfig(f, logger.Config{
+//	    Level:   logger.LevelDebug,
+//	    Outputs: []logger.OutputConfig{{Path: "app.logfmt", Encoding: "logfmt"}},
+//	}, logreplay.Options{})
+package logreplay
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+	"github.com/matteocavestri/logger-gath-test/logparse"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Keys names the standard keys of the source logs.
+type Keys = logparse.Keys
+
+// DefaultKeys are the keys emitted by the logger package's JSON encoding.
+var DefaultKeys = logparse.DefaultKeys
+
+// Options configures a replay.
+type Options struct {
+	// Keys names the standard keys of the source logs. Defaults to DefaultKeys.
+	Keys *Keys
+	// SkipInvalid skips lines that cannot be parsed instead of aborting the replay.
+	SkipInvalid bool
+	// Filter, when set, only replays entries for which it returns true.
+	Filter func(zapcore.Entry, []zapcore.Field) bool
+}
+
+// Stats summarizes a replay.
+type Stats struct {
+	// Read is the number of non-empty lines read.
+	Read int
+	// Replayed is the number of entries written to the target.
+	Replayed int
+	// Skipped is the number of entries filtered out, below the target level, or invalid.
+	Skipped int
+}
+
+// ReplayConfig replays the logs read from r into a pipeline built from cfg.
+//
+// The pipeline is flushed before returning.
+func ReplayConfig(r io.Reader, cfg logger.Config, opts Options) (Stats, error) {
+	core, err := logger.NewCore(cfg)
+	if err != nil {
+		return Stats{}, err
+	}
+	stats, err := Replay(r, core, opts)
+	if syncErr := core.Sync(); err == nil {
+		err = syncErr
+	}
+	return stats, err
+}
+
+// Replay replays the logs read from r into core.
+func Replay(r io.Reader, core zapcore.Core, opts Options) (Stats, error) {
+	keys := DefaultKeys
+	if opts.Keys != nil {
+		keys = *opts.Keys
+	}
+
+	var stats Stats
+	dec := logparse.NewDecoder(r)
+	dec.SetKeys(keys)
+	for {
+		parsed, err := dec.Next()
+		if err == io.EOF {
+			return stats, nil
+		}
+		var lineErr *logparse.LineError
+		if errors.As(err, &lineErr) {
+			err = lineErr.Err
+		} else if err != nil {
+			return stats, err
+		}
+		stats.Read++
+
+		var ent zapcore.Entry
+		var fields []zapcore.Field
+		if err == nil {
+			ent, fields, err = convert(parsed, keys)
+		}
+		if err != nil {
+			if opts.SkipInvalid {
+				stats.Skipped++
+				continue
+			}
+			return stats, fmt.Errorf("line %d: %w", dec.Line(), err)
+		}
+		if opts.Filter != nil && !opts.Filter(ent, fields) {
+			stats.Skipped++
+			continue
+		}
+
+		ce := core.Check(ent, nil)
+		if ce == nil {
+			stats.Skipped++
+			continue
+		}
+		ce.Write(fields...)
+		stats.Replayed++
+	}
+}
+
+// Parse decodes a single JSON log line into an entry and its fields.
+//
+// Field order is preserved. Integral numbers become int64 fields, other numbers float64,
+// and nested objects and arrays are replayed as-is.
+func Parse(line []byte, keys Keys) (zapcore.Entry, []zapcore.Field, error) {
+	e, err := logparse.ParseKeys(line, keys)
+	if err != nil {
+		return zapcore.Entry{}, nil, err
+	}
+	return convert(e, keys)
+}
+
+// convert returns the zap entry and fields of a parsed entry. It fails when the entry
+// has no timestamp or an unknown level.
+func convert(e logparse.Entry, keys Keys) (zapcore.Entry, []zapcore.Field, error) {
+	ent := zapcore.Entry{
+		Time:       e.Time,
+		LoggerName: e.Logger,
+		Message:    e.Message,
+		Stack:      e.Stack,
+	}
+	if ent.Time.IsZero() {
+		return ent, nil, fmt.Errorf("missing %q", keys.Time)
+	}
+	if e.Level != "" {
+		var err error
+		if ent.Level, err = zapcore.ParseLevel(strings.ToLower(string(e.Level))); err != nil {
+			return ent, nil, err
+		}
+	}
+	if frame, ok := e.CallerFrame(); ok {
+		ent.Caller = zapcore.EntryCaller{Defined: true, File: frame.File, Line: frame.Line}
+	}
+	fields := make([]zapcore.Field, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = field(f.Key, f.Value)
+	}
+	return ent, fields, nil
+}
+
+// field converts a parsed value to a typed zap field.
+func field(key string, value any) zapcore.Field {
+	switch v := value.(type) {
+	case string:
+		return zap.String(key, v)
+	case bool:
+		return zap.Bool(key, v)
+	case nil:
+		return zap.Reflect(key, nil)
+	case int64:
+		return zap.Int64(key, v)
+	case float64:
+		return zap.Float64(key, v)
+	default:
+		return zap.Any(key, v)
+	}
+}
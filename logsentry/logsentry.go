@@ -0,0 +1,268 @@
+// Package logsentry provides a logger.Sink reporting error entries to Sentry as events,
+// so that error paths are instrumented once, through the logger.
+//
+// Importing the package registers the "sentry" URL scheme: the URL is the DSN of the
+// project with the sentry scheme instead of https. Set the output Level to the minimum
+// level reported, ERROR by default:
+//
+//	import _ "github.com/matteocavestri/logger-gath-test/logsentry"
+//
+//	cfg.Outputs = []logger.OutputConfig{
+//	    {Path: "stdout"},
+//	    {Path: "sentry://public-key@o1.ingest.sentry.io/42?sample_rate=0.5", Level: logger.LevelError},
+//	}
+//
+// The sink can also be built from a Config and set as OutputConfig.Sink. Without a DSN,
+// it reports through the hub of an application already calling sentry.Init:
+//
+//	sink, err := logsentry.New(logsentry.Config{})
+//	if err != nil {
+//	    panic(err)
+//	}
+//	cfg.Outputs = []logger.OutputConfig{{Sink: sink, Level: logger.LevelError}}
+//
+// Each entry becomes an event whose exception carries the message, the error field and
+// the stack trace of the entry. The fields listed in TagFields become tags, searchable in
+// Sentry, and the other fields become extra data. Events are sent asynchronously, and
+// flushed on Sync and Close, and right away for DPANIC, PANIC and FATAL entries, which
+// precede a crash. The output's Encoding is not used.
+package logsentry
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	logger "github.com/matteocavestri/logger-gath-test"
+	"github.com/matteocavestri/logger-gath-test/logparse"
+)
+
+// Defaults of the sink.
+const (
+	defaultFlushTimeout = 2 * time.Second
+)
+
+// DefaultTagFields lists the fields sent as tags unless Config.TagFields is set.
+var DefaultTagFields = []string{"service", "environment", "logger", "trace_id", "request_id"}
+
+// levels lists the levels in increasing order of severity.
+var levels = []logger.LogLevel{logger.LevelDebug, logger.LevelInfo, logger.LevelWarn, logger.LevelError, "DPANIC", "PANIC", "FATAL"}
+
+// Config describes the Sentry project to report entries to.
+type Config struct {
+	// DSN is the Data Source Name of the Sentry project. When empty, events are captured
+	// with Hub, or the current hub of the application (see sentry.Init).
+	DSN string
+	// Hub is the hub capturing events when DSN is empty. Defaults to sentry.CurrentHub().
+	Hub *sentry.Hub
+	// Level is the minimum level of the entries reported. Defaults to ERROR.
+	Level logger.LogLevel
+	// Environment and Release are set on the client created for DSN. The environment
+	// defaults to the environment field of the entries.
+	Environment string
+	Release     string
+	// SampleRate is the fraction of events sent, between 0 and 1, on the client created
+	// for DSN. Defaults to 1.
+	SampleRate float64
+	// TagFields lists the fields sent as tags. Defaults to DefaultTagFields.
+	TagFields []string
+	// FlushTimeout bounds the time Sync, Close and crashing entries wait for pending
+	// events. Defaults to 2s.
+	FlushTimeout time.Duration
+}
+
+func init() {
+	_ = logger.RegisterSink("sentry", func(u *url.URL) (logger.Sink, error) {
+		cfg, err := configFromURL(u)
+		if err != nil {
+			return nil, err
+		}
+		return New(cfg)
+	})
+}
+
+// configFromURL returns the configuration described by a sentry:// URL.
+func configFromURL(u *url.URL) (Config, error) {
+	q := u.Query()
+	dsn := *u
+	dsn.Scheme, dsn.RawQuery = "https", ""
+	if q.Get("insecure") == "true" {
+		dsn.Scheme = "http"
+	}
+	cfg := Config{
+		DSN:         dsn.String(),
+		Level:       logger.LogLevel(strings.ToUpper(q.Get("level"))),
+		Environment: q.Get("environment"),
+		Release:     q.Get("release"),
+	}
+	if rate := q.Get("sample_rate"); rate != "" {
+		f, err := strconv.ParseFloat(rate, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("logsentry: invalid sample_rate %q", rate)
+		}
+		cfg.SampleRate = f
+	}
+	if tags := q.Get("tags"); tags != "" {
+		cfg.TagFields = strings.Split(tags, ",")
+	}
+	return cfg, nil
+}
+
+// Sink reports entries to Sentry. It is safe for concurrent use.
+type Sink struct {
+	hub          *sentry.Hub
+	level        int
+	tags         []string
+	environment  string
+	flushTimeout time.Duration
+	description  string
+}
+
+var _ logger.Sink = (*Sink)(nil)
+
+// New validates cfg, applies its defaults and creates the Sentry client for its DSN.
+func New(cfg Config) (*Sink, error) {
+	s := &Sink{
+		level:        slices.Index(levels, logger.LevelError),
+		tags:         cfg.TagFields,
+		environment:  cfg.Environment,
+		flushTimeout: cfg.FlushTimeout,
+		description:  "sentry",
+	}
+	if cfg.Level != "" {
+		if s.level = slices.Index(levels, cfg.Level); s.level < 0 {
+			return nil, fmt.Errorf("logsentry: unknown level %q", cfg.Level)
+		}
+	}
+	if s.tags == nil {
+		s.tags = DefaultTagFields
+	}
+	if s.flushTimeout <= 0 {
+		s.flushTimeout = defaultFlushTimeout
+	}
+	switch {
+	case cfg.DSN != "":
+		client, err := sentry.NewClient(sentry.ClientOptions{
+			Dsn:         cfg.DSN,
+			Environment: cfg.Environment,
+			Release:     cfg.Release,
+			SampleRate:  cfg.SampleRate,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("logsentry: %w", err)
+		}
+		s.hub = sentry.NewHub(client, sentry.NewScope())
+		if u, err := url.Parse(cfg.DSN); err == nil {
+			s.description = "sentry://" + u.Host + u.Path
+		}
+	case cfg.Hub != nil:
+		s.hub = cfg.Hub
+	default:
+		s.hub = sentry.CurrentHub()
+	}
+	if s.hub.Client() == nil {
+		return nil, errors.New("logsentry: no DSN and no Sentry client initialized")
+	}
+	return s, nil
+}
+
+// Write implements logger.Sink, capturing an event for entries at or above the level.
+func (s *Sink) Write(e logger.Entry, _ []byte) error {
+	level := slices.Index(levels, e.Level)
+	if level < s.level {
+		return nil
+	}
+	s.hub.CaptureEvent(s.event(e))
+	if level > slices.Index(levels, logger.LevelError) {
+		// The process is about to panic or exit: do not lose the event.
+		s.hub.Flush(s.flushTimeout)
+	}
+	return nil
+}
+
+// event maps an entry to a Sentry event.
+func (s *Sink) event(e logger.Entry) *sentry.Event {
+	event := sentry.NewEvent()
+	event.Timestamp = e.Time
+	event.Level = severity(e.Level)
+	event.Message = e.Message
+	event.Logger = e.Logger
+	event.Environment = s.environment
+
+	exception := sentry.Exception{Type: e.Message, Value: e.Message}
+	for key, value := range e.Fields {
+		switch {
+		case key == "error":
+			exception.Value = fmt.Sprint(value)
+		case key == "environment" && event.Environment == "":
+			event.Environment = fmt.Sprint(value)
+		}
+		if slices.Contains(s.tags, key) {
+			event.Tags[key] = fmt.Sprint(value)
+			continue
+		}
+		event.Extra[key] = value
+	}
+	if e.Logger != "" && slices.Contains(s.tags, "logger") {
+		event.Tags["logger"] = e.Logger
+	}
+	if e.Caller != "" {
+		event.Extra["caller"] = e.Caller
+	}
+	if e.Stack != "" {
+		exception.Stacktrace = stacktrace(e.Stack)
+	}
+	event.Exception = []sentry.Exception{exception}
+	return event
+}
+
+// severity maps a level to a Sentry level.
+func severity(level logger.LogLevel) sentry.Level {
+	switch level {
+	case logger.LevelDebug:
+		return sentry.LevelDebug
+	case logger.LevelInfo:
+		return sentry.LevelInfo
+	case logger.LevelWarn:
+		return sentry.LevelWarning
+	case "PANIC", "FATAL":
+		return sentry.LevelFatal
+	default:
+		return sentry.LevelError
+	}
+}
+
+// stacktrace converts the stack trace of an entry into a Sentry stack trace, oldest
+// frame first.
+func stacktrace(stack string) *sentry.Stacktrace {
+	var frames []sentry.Frame
+	for _, f := range logparse.ParseStack(stack) {
+		frames = append(frames, sentry.NewFrame(runtime.Frame{Function: f.Function, File: f.File, Line: f.Line}))
+	}
+	slices.Reverse(frames)
+	return &sentry.Stacktrace{Frames: frames}
+}
+
+// Sync implements logger.Sink, waiting for the pending events up to FlushTimeout.
+func (s *Sink) Sync() error {
+	if !s.hub.Flush(s.flushTimeout) {
+		return errors.New("logsentry: timed out flushing events")
+	}
+	return nil
+}
+
+// Close implements logger.Sink, flushing the pending events.
+func (s *Sink) Close() error {
+	return s.Sync()
+}
+
+// String implements logger.Sink.
+func (s *Sink) String() string {
+	return s.description
+}
@@ -0,0 +1,479 @@
+// Package logsql logs database/sql statements through the logger package.
+//
+// It wraps a database/sql driver so that every statement produces one entry carrying the
+// normalized query (literals replaced by "?"), a stable fingerprint of the statement
+// shape, and the duration. Slow-query dashboards can then group by query_fingerprint
+// instead of by each literal-bearing string, and literal values (which may be personal
+// data) never reach the logs.
+//
+// Entries are written through the logger carried by the statement's context
+// (logger.FromContext), so they inherit request-scoped fields such as request_id when the
+// *Context methods of database/sql are used.
+//
+// Transactions are logged too: "sql begin", "sql commit" and "sql rollback" entries, and
+// every statement executed within a transaction, carry the same tx_id so that the full
+// timeline of a transaction can be reconstructed. pgx users get the same logging through
+// its database/sql driver (github.com/jackc/pgx/v5/stdlib).
+//
+// Example usage:
+//
+//	db, err := logsql.Open("postgres", dsn, logsql.Options{SlowThreshold: 100 * time.Millisecond})
+//	if err != nil {
+//	    panic(err)
+//	}
+//	defer db.Close()
+//
+//	rows, err := db.QueryContext(ctx, "SELECT id FROM orders WHERE customer_id = $1", id)
+package logsql
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// defaultSlowThreshold is the duration from which statements are reported as slow.
+const defaultSlowThreshold = 200 * time.Millisecond
+
+// Options configures statement logging.
+type Options struct {
+	// Logger writes the statement entries. Defaults to logger.FromContext of the
+	// statement's context.
+	Logger *logger.Logger
+	// Level is the level of regular statements. Defaults to DEBUG; unknown levels are
+	// read as INFO, as in logger.Config.
+	Level logger.LogLevel
+	// SlowThreshold is the duration from which statements are logged at WARN with
+	// `slow: true`. Defaults to 200ms; a negative value disables slow-query reporting.
+	SlowThreshold time.Duration
+}
+
+// config is the resolved form of Options shared by all wrappers of a database.
+type config struct {
+	logger *logger.Logger
+	level  zapcore.Level
+	slow   time.Duration
+}
+
+// newConfig resolves opts.
+func newConfig(opts Options) *config {
+	c := &config{logger: opts.Logger, level: zapcore.DebugLevel, slow: opts.SlowThreshold}
+	if opts.Level != "" {
+		c.level = opts.Level.ZapLevel()
+	}
+	if c.slow == 0 {
+		c.slow = defaultSlowThreshold
+	}
+	return c
+}
+
+// Open opens a database like sql.Open, with statement logging enabled.
+func Open(driverName, dsn string, opts Options) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	_ = db.Close()
+
+	if dc, ok := d.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return sql.OpenDB(Wrap(connector, opts)), nil
+	}
+	return sql.OpenDB(dsnConnector{dsn: dsn, driver: WrapDriver(d, opts)}), nil
+}
+
+// Wrap returns a connector whose connections log their statements.
+//
+// Example:
+//
+//	db := sql.OpenDB(logsql.Wrap(pgConnector, logsql.Options{}))
+func Wrap(c driver.Connector, opts Options) driver.Connector {
+	return &connector{Connector: c, cfg: newConfig(opts)}
+}
+
+// WrapDriver returns a driver whose connections log their statements.
+func WrapDriver(d driver.Driver, opts Options) driver.Driver {
+	return &wrappedDriver{Driver: d, cfg: newConfig(opts)}
+}
+
+// wrappedDriver wraps the connections of a driver.
+type wrappedDriver struct {
+	driver.Driver
+	cfg *config
+}
+
+// Open implements driver.Driver.
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: c, cfg: d.cfg}, nil
+}
+
+// dsnConnector adapts a driver without connector support to driver.Connector.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+// Connect implements driver.Connector.
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+
+// Driver implements driver.Connector.
+func (c dsnConnector) Driver() driver.Driver { return c.driver }
+
+// connector wraps the connections of a connector.
+type connector struct {
+	driver.Connector
+	cfg *config
+}
+
+// Connect implements driver.Connector.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dc, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc, cfg: c.cfg}, nil
+}
+
+// Driver implements driver.Connector.
+func (c *connector) Driver() driver.Driver {
+	return &wrappedDriver{Driver: c.Connector.Driver(), cfg: c.cfg}
+}
+
+// statement describes an executed statement or transaction event for logging.
+type statement struct {
+	op       string
+	query    string
+	start    time.Time
+	err      error
+	affected int64
+	hasRows  bool
+	// tx is the transaction the statement belongs to, if any.
+	tx *txState
+}
+
+// txState tracks an open transaction of a connection.
+type txState struct {
+	id         string
+	start      time.Time
+	statements int
+}
+
+// newTxID returns a random 64-bit transaction ID in lowercase hex.
+func newTxID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// log writes the entry of a completed statement.
+func (c *config) log(ctx context.Context, s statement) {
+	elapsed := time.Since(s.start)
+	slow := c.slow > 0 && elapsed >= c.slow
+
+	level := c.level
+	switch {
+	case s.err != nil && s.err != driver.ErrSkip:
+		level = zapcore.ErrorLevel
+	case slow && level < zapcore.WarnLevel:
+		level = zapcore.WarnLevel
+	}
+
+	log := c.logger
+	if log == nil {
+		log = logger.FromContext(ctx)
+	}
+	// Check against the core directly: the caller would only point into database/sql.
+	ent := zapcore.Entry{LoggerName: log.Name(), Time: time.Now(), Level: level, Message: "sql " + s.op}
+	ce := log.Core().Check(ent, nil)
+	if ce == nil {
+		return
+	}
+
+	fields := []zap.Field{zap.String("db_operation", s.op)}
+	if s.query != "" {
+		q := normalizeCached(s.query)
+		fields = append(fields,
+			zap.String("query", q.normalized),
+			zap.String("query_fingerprint", q.fingerprint),
+		)
+	}
+	fields = append(fields, logger.Latency(elapsed))
+	if s.tx != nil {
+		fields = append(fields, zap.String("tx_id", s.tx.id))
+		if s.op == "commit" || s.op == "rollback" {
+			fields = append(fields, zap.Int("tx_statements", s.tx.statements))
+		}
+	}
+	if s.hasRows {
+		fields = append(fields, zap.Int64("rows_affected", s.affected))
+	}
+	if slow {
+		fields = append(fields, zap.Bool("slow", true))
+	}
+	if s.err != nil {
+		fields = append(fields, zap.Error(s.err))
+	}
+	ce.Write(fields...)
+}
+
+// conn logs the statements executed on a driver connection.
+//
+// database/sql never uses a connection concurrently, and a transaction holds its
+// connection exclusively, so the open transaction is tracked on the connection.
+type conn struct {
+	driver.Conn
+	cfg *config
+	tx  *txState
+}
+
+// log logs a statement, attributing it to the open transaction.
+func (c *conn) log(ctx context.Context, s statement) {
+	if c.tx != nil {
+		c.tx.statements++
+		s.tx = c.tx
+	}
+	c.cfg.log(ctx, s)
+}
+
+// logExec logs an exec statement with its result.
+func (c *conn) logExec(ctx context.Context, query string, start time.Time, res driver.Result, err error) {
+	s := statement{op: "exec", query: query, start: start, err: err}
+	if err == nil && res != nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			s.affected, s.hasRows = n, true
+		}
+	}
+	c.log(ctx, s)
+}
+
+// Prepare implements driver.Conn.
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements driver.ConnPrepareContext.
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var st driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		st, err = p.PrepareContext(ctx, query)
+	} else {
+		st, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: st, query: query, conn: c}, nil
+}
+
+// ExecContext implements driver.ExecerContext.
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.logExec(ctx, query, start, res, err)
+	}
+	return res, err
+}
+
+// QueryContext implements driver.QueryerContext.
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.log(ctx, statement{op: "query", query: query, start: start, err: err})
+	}
+	return rows, err
+}
+
+// Begin implements driver.Conn.
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements driver.ConnBeginTx, assigning a transaction ID to the statements
+// executed until the transaction ends.
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var dtx driver.Tx
+	var err error
+	// Without ConnBeginTx, options are rejected as database/sql does for such drivers,
+	// rather than silently dropped.
+	switch b, ok := c.Conn.(driver.ConnBeginTx); {
+	case ok:
+		dtx, err = b.BeginTx(ctx, opts)
+	case ctx.Err() != nil:
+		err = ctx.Err()
+	case opts.Isolation != driver.IsolationLevel(sql.LevelDefault):
+		err = errors.New("sql: driver does not support non-default isolation level")
+	case opts.ReadOnly:
+		err = errors.New("sql: driver does not support read-only transactions")
+	default:
+		dtx, err = c.Conn.Begin()
+	}
+	state := &txState{id: newTxID(), start: start}
+	c.cfg.log(ctx, statement{op: "begin", start: start, err: err, tx: state})
+	if err != nil {
+		return nil, err
+	}
+	c.tx = state
+	return &tx{Tx: dtx, conn: c, ctx: ctx}, nil
+}
+
+// Ping implements driver.Pinger.
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession implements driver.SessionResetter.
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator.
+func (c *conn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue implements driver.NamedValueChecker.
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// tx logs the end of a transaction.
+type tx struct {
+	driver.Tx
+	conn *conn
+	ctx  context.Context
+}
+
+// Commit implements driver.Tx.
+func (t *tx) Commit() error {
+	err := t.Tx.Commit()
+	t.end("commit", err)
+	return err
+}
+
+// Rollback implements driver.Tx.
+func (t *tx) Rollback() error {
+	err := t.Tx.Rollback()
+	t.end("rollback", err)
+	return err
+}
+
+// end logs the end of the transaction with its total duration and statement count.
+func (t *tx) end(op string, err error) {
+	state := t.conn.tx
+	t.conn.tx = nil
+	if state == nil {
+		return
+	}
+	t.conn.cfg.log(t.ctx, statement{op: op, start: state.start, err: err, tx: state})
+}
+
+// stmt logs the executions of a prepared statement.
+type stmt struct {
+	driver.Stmt
+	query string
+	conn  *conn
+}
+
+// Exec implements driver.Stmt.
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+
+// Query implements driver.Stmt.
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+
+// ExecContext implements driver.StmtExecContext.
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		res, err = s.Stmt.Exec(values(args))
+	}
+	s.conn.logExec(ctx, s.query, start, res, err)
+	return res, err
+}
+
+// QueryContext implements driver.StmtQueryContext.
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(values(args))
+	}
+	s.conn.log(ctx, statement{op: "query", query: s.query, start: start, err: err})
+	return rows, err
+}
+
+// CheckNamedValue implements driver.NamedValueChecker.
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// named converts positional values to named values.
+func named(args []driver.Value) []driver.NamedValue {
+	out := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		out[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return out
+}
+
+// values converts named values to positional values.
+func values(args []driver.NamedValue) []driver.Value {
+	out := make([]driver.Value, len(args))
+	for i, nv := range args {
+		out[i] = nv.Value
+	}
+	return out
+}
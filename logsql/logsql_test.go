@@ -0,0 +1,101 @@
+package logsql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+	"go.uber.org/zap/zapcore"
+)
+
+// fakeConn is a driver connection without ConnBeginTx, like those of older drivers.
+type fakeConn struct{}
+
+func (fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (fakeConn) Close() error                        { return nil }
+func (fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+func TestNewConfigLevel(t *testing.T) {
+	tests := []struct {
+		level logger.LogLevel
+		want  zapcore.Level
+	}{
+		{"", zapcore.DebugLevel},
+		{logger.LevelWarn, zapcore.WarnLevel},
+		{"warn", zapcore.WarnLevel},
+		{"Error", zapcore.ErrorLevel},
+		// As in logger.Config: unknown levels are read as INFO.
+		{"WARNING", zapcore.InfoLevel},
+		{"verbose", zapcore.InfoLevel},
+	}
+	for _, tt := range tests {
+		if got := newConfig(Options{Level: tt.level}).level; got != tt.want {
+			t.Errorf("newConfig(Level: %q).level = %s, want %s", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestBeginTxWithoutConnBeginTx(t *testing.T) {
+	buf := logger.NewMemoryBuffer(10)
+	log, err := logger.New(logger.Config{
+		Level:   logger.LevelDebug,
+		Outputs: []logger.OutputConfig{{Writer: buf, Encoding: logger.EncodingJSON}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(Wrap(fakeConnector{}, Options{Logger: log}))
+	defer db.Close()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		opts *sql.TxOptions
+		want string
+	}{
+		{"default options", nil, ""},
+		{"isolation level", &sql.TxOptions{Isolation: sql.LevelSerializable}, "non-default isolation level"},
+		{"read-only", &sql.TxOptions{ReadOnly: true}, "read-only transactions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(buf.Lines())
+			tx, err := db.BeginTx(ctx, tt.opts)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("BeginTx() = %v", err)
+				}
+				_ = tx.Rollback()
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("BeginTx() = %v, want an error containing %q", err, tt.want)
+			}
+			lines := buf.Lines()[before:]
+			if len(lines) != 1 || !bytes.Contains(lines[0], []byte("sql begin")) || !bytes.Contains(lines[0], []byte(tt.want)) {
+				t.Errorf("logged %q, want the failed sql begin entry", lines)
+			}
+		})
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := db.BeginTx(canceled, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("BeginTx() with a canceled context = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,245 @@
+package logsql
+
+import (
+	"encoding/hex"
+	"hash/fnv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// maxCachedQueries bounds the number of normalized queries kept in memory.
+const maxCachedQueries = 4096
+
+// normalizedQuery is the cached normalized form and fingerprint of a query.
+type normalizedQuery struct {
+	normalized  string
+	fingerprint string
+}
+
+var (
+	// queryCache maps raw queries to their normalized form. Applications run a bounded
+	// set of statements, so caching avoids normalizing and hashing them on every call.
+	queryCache sync.Map
+	// queryCacheSize counts the cached queries.
+	queryCacheSize atomic.Int64
+)
+
+// Normalize replaces the literals of query with "?" placeholders, strips comments and
+// collapses whitespace, so statements differing only by their values share one shape.
+//
+// String literals (including PostgreSQL dollar-quoted strings) and numeric literals are
+// replaced; bind parameters such as "$1" and "?" are kept. Lists of placeholders, as
+// produced by "IN (1, 2, 3)", collapse to a single "(?)".
+//
+//	Normalize("SELECT * FROM users WHERE id = 42 AND name = 'bob'")
+//	// SELECT * FROM users WHERE id = ? AND name = ?
+func Normalize(query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	space := false
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'':
+			i = skipQuoted(query, i, '\'')
+			writeToken(&b, "?", &space)
+		case c == '$' && dollarTag(query, i) != "":
+			tag := dollarTag(query, i)
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += len(tag) + end + len(tag)
+			}
+			writeToken(&b, "?", &space)
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			j := i + 1
+			for j < len(query) && isDigit(query[j]) {
+				j++
+			}
+			writeToken(&b, query[i:j], &space)
+			i = j
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+			space = b.Len() > 0
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				i += 2 + end + 2
+			} else {
+				i = len(query)
+			}
+			space = b.Len() > 0
+		case c == '"' || c == '`':
+			j := skipQuoted(query, i, c)
+			writeToken(&b, query[i:j], &space)
+			i = j
+		case isDigit(c) || (c == '.' && i+1 < len(query) && isDigit(query[i+1])):
+			if i > 0 && isIdent(query[i-1]) {
+				// Part of an identifier such as "table2".
+				j := i
+				for j < len(query) && isIdent(query[j]) {
+					j++
+				}
+				writeToken(&b, query[i:j], &space)
+				i = j
+				continue
+			}
+			i = skipNumber(query, i)
+			writeToken(&b, "?", &space)
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = b.Len() > 0
+			i++
+		default:
+			writeToken(&b, query[i:i+1], &space)
+			i++
+		}
+	}
+	return collapseLists(b.String())
+}
+
+// Fingerprint returns a short stable identifier of the statement shape of query.
+//
+// Queries with the same normalized form, regardless of keyword case, share a fingerprint.
+func Fingerprint(query string) string {
+	return fingerprintNormalized(Normalize(query))
+}
+
+// normalizeCached returns the normalized form and fingerprint of query, caching them
+// for up to maxCachedQueries distinct queries.
+func normalizeCached(query string) normalizedQuery {
+	if q, ok := queryCache.Load(query); ok {
+		return q.(normalizedQuery)
+	}
+	normalized := Normalize(query)
+	q := normalizedQuery{normalized: normalized, fingerprint: fingerprintNormalized(normalized)}
+	if queryCacheSize.Load() < maxCachedQueries {
+		if _, loaded := queryCache.LoadOrStore(query, q); !loaded {
+			queryCacheSize.Add(1)
+		}
+	}
+	return q
+}
+
+// fingerprintNormalized hashes an already normalized query.
+func fingerprintNormalized(normalized string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(strings.ToLower(normalized)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeToken appends a token, preceded by a single space when whitespace was skipped.
+func writeToken(b *strings.Builder, token string, space *bool) {
+	if *space {
+		b.WriteByte(' ')
+		*space = false
+	}
+	b.WriteString(token)
+}
+
+// skipQuoted returns the index following the quoted section starting at i. Doubled
+// quotes and backslash escapes are treated as part of the section.
+func skipQuoted(s string, i int, quote byte) int {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case quote:
+			if j+1 < len(s) && s[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(s)
+}
+
+// skipNumber returns the index following the numeric literal starting at i.
+func skipNumber(s string, i int) int {
+	if strings.HasPrefix(s[i:], "0x") || strings.HasPrefix(s[i:], "0X") {
+		i += 2
+		for i < len(s) && strings.IndexByte("0123456789abcdefABCDEF", s[i]) >= 0 {
+			i++
+		}
+		return i
+	}
+	for i < len(s) {
+		switch c := s[i]; {
+		case isDigit(c) || c == '.':
+			i++
+		case (c == 'e' || c == 'E') && i+1 < len(s) && (isDigit(s[i+1]) || s[i+1] == '+' || s[i+1] == '-'):
+			i += 2
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// dollarTag returns the PostgreSQL dollar-quote tag ("$$" or "$name$") starting at i,
+// or "" when there is none.
+func dollarTag(s string, i int) string {
+	for j := i + 1; j < len(s); j++ {
+		switch c := s[j]; {
+		case c == '$':
+			return s[i : j+1]
+		case isIdent(c) && !(j == i+1 && isDigit(c)):
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// collapseLists replaces parenthesized lists of placeholders with a single "(?)".
+func collapseLists(s string) string {
+	if !strings.Contains(s, "?,") {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '(' {
+			if end, ok := placeholderList(s, i+1); ok {
+				b.WriteString("(?)")
+				i = end
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// placeholderList reports whether s[i:] starts with "?, ?, ...)" and returns the index
+// of the closing parenthesis.
+func placeholderList(s string, i int) (int, bool) {
+	expectValue := true
+	for ; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == ' ':
+		case c == '?' && expectValue:
+			expectValue = false
+		case c == ',' && !expectValue:
+			expectValue = true
+		case c == ')' && !expectValue:
+			return i, true
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// isDigit reports whether c is an ASCII digit.
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// isIdent reports whether c can be part of an unquoted identifier.
+func isIdent(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
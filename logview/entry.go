@@ -0,0 +1,149 @@
+package logview
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/matteocavestri/logger-gath-test/logparse"
+)
+
+// levelRanks orders the level names emitted by the logger.
+var levelRanks = map[string]int{
+	"debug":  0,
+	"info":   1,
+	"warn":   2,
+	"error":  3,
+	"dpanic": 4,
+	"panic":  5,
+	"fatal":  6,
+}
+
+// levelColors maps level names to ANSI color codes.
+var levelColors = map[string]string{
+	"debug": "\x1b[35m",
+	"info":  "\x1b[34m",
+	"warn":  "\x1b[33m",
+}
+
+// entry is a single parsed log line.
+type entry struct {
+	raw string
+	// parsed is nil for lines that are not JSON log entries.
+	parsed *logparse.Entry
+	// level is the lower-case level name, as emitted.
+	level string
+}
+
+// parseEntry parses a JSON log line. Lines that are not log entries are kept as raw text.
+func parseEntry(line []byte) entry {
+	e := entry{raw: strings.TrimRight(string(line), "\r\n")}
+	parsed, err := logparse.Parse(line)
+	if err != nil {
+		return e
+	}
+	e.parsed = &parsed
+	e.level = strings.ToLower(string(parsed.Level))
+	return e
+}
+
+// value returns the value of a key of the entry, standard keys included.
+func (e entry) value(key string) (any, bool) {
+	keys := logparse.DefaultKeys
+	values := map[string]string{
+		keys.Level:      e.level,
+		keys.Name:       e.parsed.Logger,
+		keys.Caller:     e.parsed.Caller,
+		keys.Message:    e.parsed.Message,
+		keys.Stacktrace: e.parsed.Stack,
+	}
+	if v, ok := values[key]; ok && v != "" {
+		return v, true
+	}
+	if key == keys.Time && !e.parsed.Time.IsZero() {
+		return e.parsed.Time, true
+	}
+	return e.parsed.Field(key)
+}
+
+// render formats the entry on a single line of at most width runes (0 means unlimited).
+func (e entry) render(width int, color bool) string {
+	if e.parsed == nil {
+		return truncate(e.raw, width)
+	}
+
+	fields := e.parsed.Map()
+	if e.parsed.Logger != "" {
+		fields[logparse.DefaultKeys.Name] = e.parsed.Logger
+	}
+	keys := slices.Sorted(maps.Keys(fields))
+
+	var b strings.Builder
+	if !e.parsed.Time.IsZero() {
+		b.WriteString(e.parsed.Time.Format("15:04:05.000"))
+	}
+	b.WriteByte(' ')
+	b.WriteString(fmt.Sprintf("%-5s", strings.ToUpper(e.level)))
+	b.WriteByte(' ')
+	b.WriteString(e.parsed.Message)
+	for _, k := range keys {
+		b.WriteString(fmt.Sprintf(" %s=%v", k, fields[k]))
+	}
+
+	line := truncate(b.String(), width)
+	if !color {
+		return line
+	}
+	code, ok := levelColors[e.level]
+	if !ok && levelRanks[e.level] >= levelRanks["error"] {
+		code, ok = "\x1b[31m", true
+	}
+	if !ok {
+		return line
+	}
+	return code + line + "\x1b[0m"
+}
+
+// filter selects the entries shown by the viewer.
+type filter struct {
+	minLevelName string
+	minLevel     int
+	search       string
+	key, value   string
+}
+
+// newFilter builds a filter from a minimum level name and a search expression.
+//
+// A search of the form key=value matches entries whose field contains value;
+// any other search matches entries whose raw line contains it (case-insensitively).
+func newFilter(minLevel, search string) filter {
+	f := filter{minLevelName: strings.ToLower(minLevel), search: search}
+	if f.minLevelName == "" {
+		f.minLevelName = "debug"
+	}
+	f.minLevel = levelRanks[f.minLevelName]
+	if k, v, ok := strings.Cut(search, "="); ok && k != "" && !strings.ContainsAny(k, " \t") {
+		f.key, f.value = k, v
+	}
+	return f
+}
+
+// match reports whether the entry passes the filter.
+func (f filter) match(e entry) bool {
+	if rank, ok := levelRanks[e.level]; ok && rank < f.minLevel {
+		return false
+	}
+	switch {
+	case f.search == "":
+		return true
+	case f.key != "":
+		if e.parsed == nil {
+			return false
+		}
+		value, ok := e.value(f.key)
+		return ok && strings.Contains(fmt.Sprint(value), f.value)
+	default:
+		return strings.Contains(strings.ToLower(e.raw), strings.ToLower(f.search))
+	}
+}
@@ -0,0 +1,80 @@
+package logview
+
+import (
+	"bufio"
+	"io"
+	"unicode/utf8"
+)
+
+// Special key codes.
+const (
+	keyNone = iota
+	keyEnter
+	keyEscape
+	keyBackspace
+	keyCtrlC
+	keyUp
+	keyDown
+)
+
+// key is a single key press: either a printable rune or a special code.
+type key struct {
+	r    rune
+	code int
+}
+
+// readKeys decodes key presses from a raw-mode terminal until in is closed.
+func readKeys(in io.Reader, keys chan<- key) {
+	defer close(keys)
+	r := bufio.NewReader(in)
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return
+		}
+		switch b {
+		case '\r', '\n':
+			keys <- key{code: keyEnter}
+		case 0x03:
+			keys <- key{code: keyCtrlC}
+		case 0x7f, 0x08:
+			keys <- key{code: keyBackspace}
+		case 0x1b:
+			keys <- decodeEscape(r)
+		default:
+			if b < utf8.RuneSelf {
+				if b >= 0x20 {
+					keys <- key{r: rune(b)}
+				}
+				continue
+			}
+			_ = r.UnreadByte()
+			ru, _, err := r.ReadRune()
+			if err != nil {
+				return
+			}
+			keys <- key{r: ru}
+		}
+	}
+}
+
+// decodeEscape decodes the arrow key sequences following an ESC byte.
+//
+// A lone ESC (no pending input) is reported as keyEscape.
+func decodeEscape(r *bufio.Reader) key {
+	if r.Buffered() < 2 {
+		return key{code: keyEscape}
+	}
+	seq := make([]byte, 2)
+	if _, err := io.ReadFull(r, seq); err != nil || seq[0] != '[' {
+		return key{code: keyEscape}
+	}
+	switch seq[1] {
+	case 'A':
+		return key{code: keyUp}
+	case 'B':
+		return key{code: keyDown}
+	default:
+		return key{code: keyNone}
+	}
+}
@@ -0,0 +1,282 @@
+// Package logview provides a minimal interactive terminal viewer for logs emitted by the
+// logger package.
+//
+// It consumes either a JSON log file or an in-memory logger.MemoryBuffer and offers
+// level filtering, field search and follow mode, for developers running services locally
+// without Grafana or another log UI.
+//
+// Key bindings:
+//
+//	d i w e   show DEBUG / INFO / WARN / ERROR and above
+//	/         search (free text, or key=value to match a field)
+//	f         toggle follow mode
+//	j k       scroll down / up (arrow keys work too)
+//	g G       jump to top / bottom
+//	q         quit
+//
+// When standard input is not a terminal, matching entries are simply printed.
+//
+// Example usage:
+//
+//	src, err := logview.FileSource("/var/log/app.json")
+//	if err != nil {
+//	    panic(err)
+//	}
+//	defer src.Close()
+//	if err := logview.Run(ctx, src, logview.Options{Follow: true}); err != nil {
+//	    panic(err)
+//	}
+package logview
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// pollInterval is how often sources are polled for new lines in follow mode.
+const pollInterval = 250 * time.Millisecond
+
+// defaultMaxLines bounds the number of entries retained by the viewer.
+const defaultMaxLines = 10000
+
+// Options configures the viewer.
+type Options struct {
+	// MinLevel hides entries below this level ("debug", "info", "warn", "error").
+	MinLevel string
+	// Search initially filters entries by free text or by a key=value field match.
+	Search string
+	// Follow keeps polling the source for new entries.
+	Follow bool
+	// MaxLines bounds the number of entries retained. Defaults to 10000.
+	MaxLines int
+}
+
+// Run displays the entries of src until the user quits, ctx is cancelled, or, when not
+// following, the source is exhausted in non-interactive mode.
+func Run(ctx context.Context, src Source, opts Options) error {
+	if opts.MaxLines <= 0 {
+		opts.MaxLines = defaultMaxLines
+	}
+	v := &viewer{
+		src:    src,
+		filter: newFilter(opts.MinLevel, opts.Search),
+		follow: opts.Follow,
+		max:    opts.MaxLines,
+	}
+	if _, err := v.pull(); err != nil {
+		return err
+	}
+
+	stdin := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdin) {
+		return v.stream(ctx, os.Stdout)
+	}
+
+	state, err := term.MakeRaw(stdin)
+	if err != nil {
+		return fmt.Errorf("logview: failed to enter raw mode: %w", err)
+	}
+	defer func() {
+		_ = term.Restore(stdin, state)
+		fmt.Fprint(os.Stdout, "\x1b[2J\x1b[H")
+	}()
+	return v.interactive(ctx, os.Stdin, os.Stdout)
+}
+
+// viewer holds the state of a viewing session.
+type viewer struct {
+	src     Source
+	entries []entry
+	filter  filter
+	follow  bool
+	max     int
+	// offset is the number of matching entries hidden below the bottom of the screen.
+	offset int
+	// searching is true while the search prompt is open; input holds its content.
+	searching bool
+	input     string
+}
+
+// pull appends the lines newly available from the source and returns the number of old
+// entries discarded to honor MaxLines.
+func (v *viewer) pull() (trimmed int, err error) {
+	lines, err := v.src.Next()
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range lines {
+		v.entries = append(v.entries, parseEntry(line))
+	}
+	if over := len(v.entries) - v.max; over > 0 {
+		v.entries = append(v.entries[:0], v.entries[over:]...)
+		trimmed = over
+	}
+	return trimmed, nil
+}
+
+// stream prints matching entries without interaction.
+func (v *viewer) stream(ctx context.Context, w io.Writer) error {
+	printed := 0
+	for {
+		for _, e := range v.entries[printed:] {
+			if v.filter.match(e) {
+				fmt.Fprintln(w, e.render(0, false))
+			}
+		}
+		printed = len(v.entries)
+		if !v.follow {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+		trimmed, err := v.pull()
+		if err != nil {
+			return err
+		}
+		// Entries trimmed by MaxLines shift the slice; never re-print them.
+		printed = max(printed-trimmed, 0)
+	}
+}
+
+// interactive runs the full-screen viewer loop.
+func (v *viewer) interactive(ctx context.Context, in io.Reader, out io.Writer) error {
+	keys := make(chan key)
+	go readKeys(in, keys)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	v.draw(out)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			if quit := v.handle(k); quit {
+				return nil
+			}
+		case <-ticker.C:
+			if !v.follow {
+				continue
+			}
+			if _, err := v.pull(); err != nil {
+				return err
+			}
+		}
+		v.draw(out)
+	}
+}
+
+// handle applies a key press and reports whether the viewer should quit.
+func (v *viewer) handle(k key) bool {
+	if v.searching {
+		switch {
+		case k.code == keyEnter:
+			v.filter = newFilter(v.filter.minLevelName, v.input)
+			v.searching, v.offset = false, 0
+		case k.code == keyEscape:
+			v.searching = false
+		case k.code == keyBackspace:
+			if len(v.input) > 0 {
+				v.input = v.input[:len(v.input)-1]
+			}
+		case k.r != 0:
+			v.input += string(k.r)
+		}
+		return false
+	}
+
+	switch {
+	case k.r == 'q' || k.code == keyCtrlC:
+		return true
+	case k.r == 'f':
+		v.follow = !v.follow
+		if v.follow {
+			v.offset = 0
+		}
+	case k.r == 'd', k.r == 'i', k.r == 'w', k.r == 'e':
+		v.filter = newFilter(map[rune]string{'d': "debug", 'i': "info", 'w': "warn", 'e': "error"}[k.r], v.filter.search)
+		v.offset = 0
+	case k.r == '/':
+		v.searching, v.input = true, v.filter.search
+	case k.r == 'k' || k.code == keyUp:
+		v.offset++
+		v.follow = false
+	case k.r == 'j' || k.code == keyDown:
+		if v.offset > 0 {
+			v.offset--
+		}
+	case k.r == 'g':
+		v.offset = len(v.entries)
+		v.follow = false
+	case k.r == 'G':
+		v.offset = 0
+	}
+	return false
+}
+
+// draw renders the screen.
+func (v *viewer) draw(out io.Writer) {
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height < 3 {
+		width, height = 120, 40
+	}
+
+	var matching []entry
+	for _, e := range v.entries {
+		if v.filter.match(e) {
+			matching = append(matching, e)
+		}
+	}
+
+	rows := height - 2
+	if maxOffset := len(matching) - rows; v.offset > maxOffset {
+		v.offset = max(maxOffset, 0)
+	}
+	end := len(matching) - v.offset
+	start := max(end-rows, 0)
+
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	for _, e := range matching[start:end] {
+		b.WriteString(e.render(width, true))
+		b.WriteString("\r\n")
+	}
+	for i := end - start; i < rows; i++ {
+		b.WriteString("\r\n")
+	}
+
+	status := fmt.Sprintf("level>=%s  search=%q  follow=%t  %d/%d entries",
+		v.filter.minLevelName, v.filter.search, v.follow, len(matching), len(v.entries))
+	if v.searching {
+		status = "search: " + v.input
+	}
+	b.WriteString("\x1b[7m")
+	b.WriteString(truncate(status, width))
+	b.WriteString("\x1b[0m")
+	fmt.Fprint(out, b.String())
+}
+
+// truncate shortens s to at most width runes.
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return s
+	}
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+	return string(runes[:width])
+}
@@ -0,0 +1,91 @@
+package logview
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+)
+
+// Source provides log lines to the viewer.
+type Source interface {
+	// Next returns the lines that became available since the previous call.
+	Next() ([][]byte, error)
+}
+
+// File is a Source reading a JSON log file, including data appended after it was opened.
+//
+// Truncation (e.g. by copytruncate rotation) is detected and reading restarts at the
+// beginning of the file.
+type File struct {
+	f       *os.File
+	r       *bufio.Reader
+	offset  int64
+	partial []byte
+}
+
+// FileSource opens the log file at path.
+func FileSource(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("logview: %w", err)
+	}
+	return &File{f: f, r: bufio.NewReaderSize(f, 64*1024)}, nil
+}
+
+// Next implements Source.
+func (s *File) Next() ([][]byte, error) {
+	if info, err := s.f.Stat(); err == nil && info.Size() < s.offset {
+		if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		s.r.Reset(s.f)
+		s.offset, s.partial = 0, nil
+	}
+
+	var lines [][]byte
+	for {
+		chunk, err := s.r.ReadBytes('\n')
+		s.offset += int64(len(chunk))
+		if len(chunk) > 0 {
+			s.partial = append(s.partial, chunk...)
+		}
+		if err == io.EOF {
+			// Keep an incomplete trailing line until the writer finishes it.
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+		if line := bytes.TrimSpace(s.partial); len(line) > 0 {
+			lines = append(lines, append([]byte(nil), line...))
+		}
+		s.partial = s.partial[:0]
+	}
+}
+
+// Close closes the underlying file.
+func (s *File) Close() error {
+	return s.f.Close()
+}
+
+// Buffer is a Source reading from an in-memory logger.MemoryBuffer.
+type Buffer struct {
+	buf  *logger.MemoryBuffer
+	next uint64
+}
+
+// BufferSource returns a Source consuming buf, starting with the entries it currently retains.
+func BufferSource(buf *logger.MemoryBuffer) *Buffer {
+	return &Buffer{buf: buf}
+}
+
+// Next implements Source.
+func (s *Buffer) Next() ([][]byte, error) {
+	var lines [][]byte
+	lines, s.next = s.buf.Since(s.next)
+	return lines, nil
+}
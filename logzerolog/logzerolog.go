@@ -0,0 +1,143 @@
+// Package logzerolog provides a zerolog backend for the logger, so that the zap pipeline
+// can be compared with zerolog behind the same Logger API.
+//
+// Importing the package registers the "zerolog" backend (see logger.Config.Backend):
+//
+//	import _ "github.com/matteocavestri/logger-gath-test/logzerolog"
+//
+//	cfg.Backend = "zerolog"
+//
+// The entries of file, standard stream and Writer outputs are then encoded and written
+// by zerolog: "json" and "ecs" outputs as zerolog JSON, with zerolog's key names, and the
+// other ones with zerolog.ConsoleWriter. The entry time, logger name, caller and stack
+// trace are written under the "time", "logger", "caller" and "stack" keys. Namespaces
+// opened with With only hold the fields of the same With call.
+package logzerolog
+
+import (
+	"io"
+	"math"
+	"time"
+
+	logger "github.com/matteocavestri/logger-gath-test"
+	"github.com/rs/zerolog"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	_ = logger.RegisterBackend("zerolog", New)
+}
+
+// New returns the core writing the entries of an output with zerolog. It is the
+// logger.BackendFactory of the "zerolog" backend.
+func New(out logger.BackendOutput) (zapcore.Core, error) {
+	var w io.Writer = out.Writer
+	switch out.Encoding {
+	case logger.EncodingJSON, logger.EncodingECS:
+	default:
+		w = zerolog.ConsoleWriter{Out: out.Writer, NoColor: out.Encoding != logger.EncodingConsole}
+	}
+	return &core{log: zerolog.New(w)}, nil
+}
+
+// core implements zapcore.Core on top of a zerolog.Logger. The logger applies the level
+// of the output.
+type core struct {
+	log zerolog.Logger
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *core) Enabled(zapcore.Level) bool { return true }
+
+// With implements zapcore.Core, encoding the fields in the context of the zerolog logger.
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &core{log: c.log.With().Fields(enc.Fields).Logger()}
+}
+
+// Check implements zapcore.Core.
+func (c *core) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce.AddCore(ent, c)
+}
+
+// Write implements zapcore.Core.
+func (c *core) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ev := c.log.WithLevel(level(ent.Level)).Time(zerolog.TimestampFieldName, ent.Time)
+	if ent.LoggerName != "" {
+		ev = ev.Str("logger", ent.LoggerName)
+	}
+	if ent.Caller.Defined {
+		ev = ev.Str(zerolog.CallerFieldName, ent.Caller.TrimmedPath())
+	}
+	addFields(ev, fields)
+	if ent.Stack != "" {
+		ev = ev.Str(zerolog.ErrorStackFieldName, ent.Stack)
+	}
+	ev.Msg(ent.Message)
+	return nil
+}
+
+// Sync implements zapcore.Core. The logger syncs the writer of the output.
+func (c *core) Sync() error { return nil }
+
+// level maps a zap level to a zerolog level. DPANIC, above ERROR, maps to panic.
+func level(l zapcore.Level) zerolog.Level {
+	switch l {
+	case zapcore.DebugLevel:
+		return zerolog.DebugLevel
+	case zapcore.InfoLevel:
+		return zerolog.InfoLevel
+	case zapcore.WarnLevel:
+		return zerolog.WarnLevel
+	case zapcore.ErrorLevel:
+		return zerolog.ErrorLevel
+	case zapcore.FatalLevel:
+		return zerolog.FatalLevel
+	default:
+		return zerolog.PanicLevel
+	}
+}
+
+// addFields adds fields to an event with the typed zerolog methods, nesting the fields
+// following a namespace in a dictionary.
+func addFields(ev *zerolog.Event, fields []zapcore.Field) {
+	for i, f := range fields {
+		switch f.Type {
+		case zapcore.StringType:
+			ev.Str(f.Key, f.String)
+		case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+			ev.Int64(f.Key, f.Integer)
+		case zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type, zapcore.UintptrType:
+			ev.Uint64(f.Key, uint64(f.Integer))
+		case zapcore.Float64Type:
+			ev.Float64(f.Key, math.Float64frombits(uint64(f.Integer)))
+		case zapcore.Float32Type:
+			ev.Float32(f.Key, math.Float32frombits(uint32(f.Integer)))
+		case zapcore.BoolType:
+			ev.Bool(f.Key, f.Integer == 1)
+		case zapcore.DurationType:
+			ev.Dur(f.Key, time.Duration(f.Integer))
+		case zapcore.TimeType:
+			t := time.Unix(0, f.Integer)
+			if loc, ok := f.Interface.(*time.Location); ok {
+				t = t.In(loc)
+			}
+			ev.Time(f.Key, t)
+		case zapcore.ErrorType:
+			ev.AnErr(f.Key, f.Interface.(error))
+		case zapcore.SkipType:
+		case zapcore.NamespaceType:
+			dict := zerolog.Dict()
+			addFields(dict, fields[i+1:])
+			ev.Dict(f.Key, dict)
+			return
+		default:
+			enc := zapcore.NewMapObjectEncoder()
+			f.AddTo(enc)
+			ev.Interface(f.Key, enc.Fields[f.Key])
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// lokiPushPath is the path of the Loki push API.
+const lokiPushPath = "/loki/api/v1/push"
+
+// Defaults of the Loki sink.
+const (
+	defaultLokiBatchSize     = 1000
+	defaultLokiFlushInterval = time.Second
+	defaultLokiMaxRetries    = 5
+	defaultLokiMinBackoff    = 500 * time.Millisecond
+	defaultLokiMaxBackoff    = 30 * time.Second
+	defaultLokiTimeout       = 10 * time.Second
+)
+
+// LokiConfig makes an output push entries to the Loki HTTP push API, so small services
+// can ship logs without running Promtail.
+//
+// Entries are encoded with the output's encoding and grouped into streams labeled with
+// the service, the environment, the entry level, and the configured Labels. Keep labels
+// low-cardinality: anything request-specific belongs in the log line, or in the
+// structured metadata of the entry with Loki 3.x (see StructuredMetadata).
+type LokiConfig struct {
+	// URL is the Loki base URL (e.g. "http://loki:3100") or the full push endpoint.
+	URL string
+	// Labels are added to every stream.
+	Labels map[string]string
+	// StructuredMetadata lists the fields sent as structured metadata of each entry
+	// instead of in the log line, e.g. DefaultLokiStructuredMetadata. Unlike labels,
+	// high-cardinality fields such as trace_id can be filtered on without creating
+	// streams. Requires Loki 3.x with structured metadata allowed. Values other than
+	// strings are sent as their JSON encoding.
+	StructuredMetadata []string
+	// TenantID, when set, is sent as the X-Scope-OrgID header of multi-tenant Loki.
+	TenantID string
+	// Username and Password enable HTTP basic authentication (e.g. Grafana Cloud).
+	Username string
+	Password string
+	// BatchSize is the number of entries that triggers a push. Defaults to 1000.
+	BatchSize int
+	// FlushInterval is the maximum time entries wait before being pushed. Defaults to 1s.
+	FlushInterval time.Duration
+	// MaxRetries bounds the retries of a failed push; the batch is dropped afterwards, or
+	// spooled with Spool. Defaults to 5; a negative value disables retries.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the exponential backoff between retries.
+	// Default to 500ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Timeout bounds a single push request. Defaults to 10s.
+	Timeout time.Duration
+	// Client is the HTTP client used for pushes. Defaults to a client with Timeout.
+	Client *http.Client
+	// Spool, when set, writes the batches that could not be pushed after MaxRetries to
+	// disk instead of dropping them, and pushes them once Loki is reachable again.
+	Spool *SpoolConfig
+}
+
+// DefaultLokiStructuredMetadata lists the high-cardinality correlation fields attached by
+// the logger, for LokiConfig.StructuredMetadata.
+var DefaultLokiStructuredMetadata = []string{"trace_id", "span_id", "request_id"}
+
+// pushURL returns the push endpoint of the configured URL.
+func (c *LokiConfig) pushURL() string {
+	url := strings.TrimSuffix(c.URL, "/")
+	if strings.HasSuffix(url, lokiPushPath) {
+		return url
+	}
+	return url + lokiPushPath
+}
+
+// withDefaults returns a copy of the configuration with defaults applied.
+func (c LokiConfig) withDefaults() LokiConfig {
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultLokiBatchSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = defaultLokiFlushInterval
+	}
+	if c.MaxRetries == 0 {
+		c.MaxRetries = defaultLokiMaxRetries
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = defaultLokiMinBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaultLokiMaxBackoff
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaultLokiTimeout
+	}
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: c.Timeout}
+	}
+	return c
+}
@@ -0,0 +1,14 @@
+//go:build logger_noloki
+
+package logger
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// newLokiCore reports that the Loki sink was left out of the build.
+func newLokiCore(Config, *LokiConfig, zapcore.Encoder, zapcore.LevelEnabler) (zapcore.Core, func(), error) {
+	return nil, nil, errors.New("loki sink not available: built with the logger_noloki tag")
+}
@@ -0,0 +1,410 @@
+//go:build !logger_noloki
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// lokiMaxBuffered bounds the entries held while Loki is unreachable.
+const lokiMaxBuffered = 100_000
+
+// lokiEntry is a single encoded entry waiting to be pushed.
+type lokiEntry struct {
+	time     time.Time
+	level    zapcore.Level
+	line     string
+	metadata map[string]string
+}
+
+// lokiClient batches entries and pushes them to Loki from a background goroutine.
+type lokiClient struct {
+	cfg    LokiConfig
+	url    string
+	labels map[string]string
+	inst   *SinkInstrumentation
+	// spool holds the batches that could not be pushed, nil without LokiConfig.Spool.
+	spool *Spool
+
+	mu      sync.Mutex
+	pending []lokiEntry
+	dropped int
+
+	// pushMu serializes pushes so that entries arrive in order.
+	pushMu sync.Mutex
+	kick   chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+// newLokiClient starts a client pushing to the configured Loki instance.
+func newLokiClient(cfg Config, lc *LokiConfig) (*lokiClient, error) {
+	if lc.URL == "" {
+		return nil, errors.New("loki: URL is required")
+	}
+	labels := map[string]string{
+		"service":     cfg.ServiceName,
+		"environment": cfg.Environment,
+	}
+	maps.Copy(labels, lc.Labels)
+
+	c := &lokiClient{
+		cfg:    lc.withDefaults(),
+		url:    lc.pushURL(),
+		labels: labels,
+		inst:   cfg.SinkInstrumentation,
+		kick:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if lc.Spool != nil {
+		spool, err := OpenSpool(*lc.Spool)
+		if err != nil {
+			return nil, fmt.Errorf("loki: %w", err)
+		}
+		c.spool = spool
+	}
+	go c.run()
+	return c, nil
+}
+
+// add queues an entry, triggering a push when the batch is full.
+func (c *lokiClient) add(e lokiEntry) {
+	c.mu.Lock()
+	if len(c.pending) >= lokiMaxBuffered {
+		c.dropped++
+		c.mu.Unlock()
+		return
+	}
+	c.pending = append(c.pending, e)
+	full := len(c.pending) >= c.cfg.BatchSize
+	c.mu.Unlock()
+
+	if full {
+		select {
+		case c.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run pushes batches on every tick or when a batch fills up.
+func (c *lokiClient) run() {
+	defer close(c.done)
+	labelGoroutine("loki")
+	ticker := time.NewTicker(c.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.kick:
+		case <-c.stop:
+			return
+		}
+		_ = c.flush()
+	}
+}
+
+// flush pushes all queued entries, one batch at a time. With a spool, the spooled
+// entries are pushed first, and batches are spooled rather than dropped when they cannot
+// be pushed.
+func (c *lokiClient) flush() error {
+	c.pushMu.Lock()
+	defer c.pushMu.Unlock()
+
+	var errs []error
+	// spoolErr is set while older entries are still spooled: new batches go behind them.
+	spoolErr := c.replay()
+	for {
+		c.mu.Lock()
+		n := min(len(c.pending), c.cfg.BatchSize)
+		batch := c.pending[:n:n]
+		c.pending = c.pending[n:]
+		dropped := c.dropped
+		c.dropped = 0
+		c.mu.Unlock()
+
+		if dropped > 0 {
+			fmt.Fprintf(os.Stderr, "logger: loki buffer full, dropped %d entries\n", dropped)
+		}
+		if len(batch) == 0 {
+			return errors.Join(errs...)
+		}
+		if spoolErr != nil {
+			c.spoolBatch(batch, spoolErr)
+			continue
+		}
+		if err := c.pushWithRetry(batch); err != nil {
+			if c.spool != nil {
+				c.spoolBatch(batch, err)
+				spoolErr = err
+			} else {
+				fmt.Fprintf(os.Stderr, "logger: loki push failed, dropped %d entries: %v\n", len(batch), err)
+			}
+			errs = append(errs, err)
+		}
+	}
+}
+
+// replay pushes the spooled entries, returning the error of the first batch that could
+// not be pushed. Each batch is pushed once: the next flush tries again.
+func (c *lokiClient) replay() error {
+	if c.spool == nil || c.spool.Empty() {
+		return nil
+	}
+	return c.spool.Replay(c.cfg.BatchSize, func(records []SpoolRecord) (err error) {
+		ctx, rec := c.inst.startFlush("loki", len(records))
+		defer func() { rec.end(err) }()
+		batch := make([]lokiEntry, len(records))
+		for i, r := range records {
+			batch[i] = lokiEntry{time: r.Time, level: r.zapLevel(), line: string(r.Entry), metadata: r.Attributes}
+		}
+		body, err := c.encode(batch)
+		if err != nil {
+			return err
+		}
+		rec.attempt(len(body))
+		_, err = c.push(ctx, body)
+		return err
+	})
+}
+
+// spoolBatch writes a batch that could not be pushed because of cause to the spool,
+// dropping it if that fails too.
+func (c *lokiClient) spoolBatch(batch []lokiEntry, cause error) {
+	records := make([]SpoolRecord, len(batch))
+	for i, e := range batch {
+		records[i] = SpoolRecord{Time: e.time, Level: LogLevel(e.level.CapitalString()), Attributes: e.metadata, Entry: []byte(e.line)}
+	}
+	if err := c.spool.Append(records); err != nil {
+		fmt.Fprintf(os.Stderr, "logger: loki push failed, dropped %d entries: %v (%v)\n", len(batch), cause, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "logger: loki push failed, spooled %d entries: %v\n", len(batch), cause)
+}
+
+// pushWithRetry pushes a batch, retrying with exponential backoff on failure.
+func (c *lokiClient) pushWithRetry(batch []lokiEntry) (err error) {
+	ctx, rec := c.inst.startFlush("loki", len(batch))
+	defer func() { rec.end(err) }()
+
+	body, err := c.encode(batch)
+	if err != nil {
+		return err
+	}
+	backoff := c.cfg.MinBackoff
+	for attempt := 0; ; attempt++ {
+		rec.attempt(len(body))
+		retry, err := c.push(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= c.cfg.MaxRetries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-c.stop:
+			// Shutting down: make one last attempt without waiting.
+			rec.attempt(len(body))
+			_, err = c.push(ctx, body)
+			return err
+		}
+		backoff = min(backoff*2, c.cfg.MaxBackoff)
+	}
+}
+
+// lokiStream is a stream of the push API request.
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][]any           `json:"values"`
+}
+
+// encode builds the push API request body, with one stream per level.
+func (c *lokiClient) encode(batch []lokiEntry) ([]byte, error) {
+	streams := make(map[zapcore.Level]*lokiStream)
+	var order []*lokiStream
+	for _, e := range batch {
+		s, ok := streams[e.level]
+		if !ok {
+			labels := maps.Clone(c.labels)
+			labels["level"] = e.level.String()
+			s = &lokiStream{Stream: labels}
+			streams[e.level] = s
+			order = append(order, s)
+		}
+		value := []any{strconv.FormatInt(e.time.UnixNano(), 10), e.line}
+		if len(e.metadata) > 0 {
+			value = append(value, e.metadata)
+		}
+		s.Values = append(s.Values, value)
+	}
+	return json.Marshal(map[string]any{"streams": order})
+}
+
+// push sends a request body once, reporting whether a failure is worth retrying.
+func (c *lokiClient) push(ctx context.Context, body []byte) (retry bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", c.cfg.TenantID)
+	}
+	if c.cfg.Username != "" {
+		req.SetBasicAuth(c.cfg.Username, c.cfg.Password)
+	}
+
+	resp, err := c.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	err = fmt.Errorf("loki: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	// Server errors and rate limiting are transient; other client errors are not.
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+}
+
+// close stops the background goroutine and pushes the remaining entries.
+func (c *lokiClient) close() {
+	close(c.stop)
+	<-c.done
+	_ = c.flush()
+	if c.spool != nil {
+		_ = c.spool.Close()
+	}
+}
+
+// lokiCore encodes entries with the output's encoder and hands them to a Loki client.
+type lokiCore struct {
+	zapcore.LevelEnabler
+	enc    zapcore.Encoder
+	client *lokiClient
+	// metadataKeys holds the keys of the fields sent as structured metadata, and metadata
+	// those of the fields attached with With.
+	metadataKeys map[string]struct{}
+	metadata     map[string]string
+}
+
+// newLokiCore returns a core pushing to the Loki instance described by lc, and a
+// function flushing and stopping it.
+func newLokiCore(cfg Config, lc *LokiConfig, enc zapcore.Encoder, level zapcore.LevelEnabler) (zapcore.Core, func(), error) {
+	client, err := newLokiClient(cfg, lc)
+	if err != nil {
+		return nil, nil, err
+	}
+	core := &lokiCore{LevelEnabler: level, enc: enc, client: client}
+	if len(lc.StructuredMetadata) > 0 {
+		core.metadataKeys = make(map[string]struct{}, len(lc.StructuredMetadata))
+		for _, key := range lc.StructuredMetadata {
+			core.metadataKeys[key] = struct{}{}
+		}
+	}
+	return core, client.close, nil
+}
+
+// With implements zapcore.Core.
+func (c *lokiCore) With(fields []zapcore.Field) zapcore.Core {
+	fields, metadata := c.splitMetadata(fields, c.metadata)
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &lokiCore{LevelEnabler: c.LevelEnabler, enc: enc, client: c.client, metadataKeys: c.metadataKeys, metadata: metadata}
+}
+
+// splitMetadata separates the fields sent as structured metadata from the others. The
+// metadata is returned merged over base, which is not modified.
+func (c *lokiCore) splitMetadata(fields []zapcore.Field, base map[string]string) ([]zapcore.Field, map[string]string) {
+	if len(c.metadataKeys) == 0 {
+		return fields, base
+	}
+	var enc *zapcore.MapObjectEncoder
+	line := fields
+	for i, f := range fields {
+		if _, ok := c.metadataKeys[f.Key]; !ok {
+			if enc != nil {
+				line = append(line, f)
+			}
+			continue
+		}
+		if enc == nil {
+			enc = zapcore.NewMapObjectEncoder()
+			line = make([]zapcore.Field, i, len(fields))
+			copy(line, fields[:i])
+		}
+		f.AddTo(enc)
+	}
+	if enc == nil {
+		return fields, base
+	}
+	metadata := maps.Clone(base)
+	if metadata == nil {
+		metadata = make(map[string]string, len(enc.Fields))
+	}
+	for key, value := range enc.Fields {
+		metadata[key] = lokiMetadataValue(value)
+	}
+	return line, metadata
+}
+
+// lokiMetadataValue returns the structured metadata value of a field value.
+func lokiMetadataValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// Check implements zapcore.Core.
+func (c *lokiCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core. The entry is queued; it is pushed asynchronously.
+func (c *lokiCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	fields, metadata := c.splitMetadata(fields, c.metadata)
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	line := strings.TrimSuffix(buf.String(), "\n")
+	buf.Free()
+	c.client.add(lokiEntry{time: ent.Time, level: ent.Level, line: line, metadata: metadata})
+	return nil
+}
+
+// Sync implements zapcore.Core, pushing all queued entries.
+func (c *lokiCore) Sync() error {
+	return c.client.flush()
+}
@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// FieldMapping describes a per-output transformation of fields.
+//
+// Mappings let one logging call satisfy several downstream schema requirements: each
+// output can rename, drop, or add fields independently of the others. Rename and Drop
+// apply both to structured fields and to the encoder's standard keys (timestamp, level,
+// message, caller, ...), so an output can be switched to a different schema without
+// touching any logging call site.
+//
+// Example (rename `service` to `app` only for the Splunk output):
+//
+//	&logger.FieldMapping{
+//	    Rename: map[string]string{"service": "app"},
+//	    Drop:   []string{"caller"},
+//	    Add:    map[string]any{"index": "main"},
+//	}
+type FieldMapping struct {
+	// Rename maps original field names to the names emitted by this output.
+	Rename map[string]string
+	// Drop lists field names that are removed from this output.
+	Drop []string
+	// Add lists constant fields attached to every entry written to this output.
+	Add map[string]any
+}
+
+// fieldMapper is the compiled, lookup-friendly form of a FieldMapping.
+type fieldMapper struct {
+	rename map[string]string
+	drop   map[string]struct{}
+}
+
+// compile converts the mapping into its lookup-friendly form.
+func (m *FieldMapping) compile() *fieldMapper {
+	fm := &fieldMapper{rename: m.Rename, drop: make(map[string]struct{}, len(m.Drop))}
+	for _, key := range m.Drop {
+		fm.drop[key] = struct{}{}
+	}
+	return fm
+}
+
+// constants returns the fields declared in Add.
+func (m *FieldMapping) constants() []zapcore.Field {
+	fields := make([]zapcore.Field, 0, len(m.Add))
+	for key, value := range m.Add {
+		fields = append(fields, zap.Any(key, value))
+	}
+	return fields
+}
+
+// renameKey returns the output name for the given standard key, or OmitKey if it is dropped.
+func (m *FieldMapping) renameKey(key string) string {
+	if m == nil || key == "" || key == zapcore.OmitKey {
+		return key
+	}
+	for _, dropped := range m.Drop {
+		if dropped == key {
+			return zapcore.OmitKey
+		}
+	}
+	if renamed, ok := m.Rename[key]; ok {
+		return renamed
+	}
+	return key
+}
+
+// encoderConfig applies the mapping to the standard keys of an encoder configuration.
+func (m *FieldMapping) encoderConfig(cfg zapcore.EncoderConfig) zapcore.EncoderConfig {
+	if m == nil {
+		return cfg
+	}
+	cfg.TimeKey = m.renameKey(cfg.TimeKey)
+	cfg.LevelKey = m.renameKey(cfg.LevelKey)
+	cfg.NameKey = m.renameKey(cfg.NameKey)
+	cfg.CallerKey = m.renameKey(cfg.CallerKey)
+	cfg.FunctionKey = m.renameKey(cfg.FunctionKey)
+	cfg.MessageKey = m.renameKey(cfg.MessageKey)
+	cfg.StacktraceKey = m.renameKey(cfg.StacktraceKey)
+	return cfg
+}
+
+// apply returns the fields with renames and drops applied.
+//
+// The input slice is never modified; a copy is made only when a field is changed.
+func (fm *fieldMapper) apply(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		_, dropped := fm.drop[f.Key]
+		renamed, isRenamed := fm.rename[f.Key]
+		if !dropped && !isRenamed {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		if dropped {
+			continue
+		}
+		f.Key = renamed
+		out = append(out, f)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// mappingCore wraps a core and applies a FieldMapping to every field passing through it.
+type mappingCore struct {
+	zapcore.Core
+	mapper *fieldMapper
+}
+
+// newMappingCore wraps core so that all fields are transformed by mapping.
+//
+// Constant fields are attached to the wrapped core directly and are therefore
+// not subject to the mapping's renames and drops.
+func newMappingCore(core zapcore.Core, mapping *FieldMapping) zapcore.Core {
+	if len(mapping.Add) > 0 {
+		core = core.With(mapping.constants())
+	}
+	return &mappingCore{Core: core, mapper: mapping.compile()}
+}
+
+// With applies the mapping to contextual fields before passing them down.
+func (c *mappingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &mappingCore{Core: c.Core.With(c.mapper.apply(fields)), mapper: c.mapper}
+}
+
+// Check registers this core, rather than the wrapped one, so Write sees every entry.
+func (c *mappingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write applies the mapping to the entry's fields and writes it to the wrapped core.
+func (c *mappingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.mapper.apply(fields))
+}
@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"sync"
+)
+
+// MemoryBuffer is an in-memory output retaining the most recent encoded entries.
+//
+// It is useful for local development tooling (such as the logview package), debug
+// endpoints exposing recent logs, and tests. Use it as an output's Writer:
+//
+//	buf := logger.NewMemoryBuffer(10000)
+//	cfg.Outputs = append(cfg.Outputs, logger.OutputConfig{Writer: buf, Encoding: "json"})
+type MemoryBuffer struct {
+	mu    sync.Mutex
+	lines [][]byte
+	// written is the total number of lines ever written; it doubles as the sequence
+	// number of the next line.
+	written uint64
+}
+
+// NewMemoryBuffer creates a buffer retaining up to capacity entries.
+func NewMemoryBuffer(capacity int) *MemoryBuffer {
+	if capacity <= 0 {
+		capacity = 1
+	}
+	return &MemoryBuffer{lines: make([][]byte, capacity)}
+}
+
+// Write stores a copy of a single encoded entry, evicting the oldest one when full.
+func (b *MemoryBuffer) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+
+	b.mu.Lock()
+	b.lines[b.written%uint64(len(b.lines))] = line
+	b.written++
+	b.mu.Unlock()
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer; it is a no-op.
+func (b *MemoryBuffer) Sync() error {
+	return nil
+}
+
+// Since returns the retained entries with a sequence number of at least seq, oldest first,
+// together with the sequence number to pass to the next call.
+//
+// Passing 0 returns every retained entry. Entries evicted before being read are skipped.
+func (b *MemoryBuffer) Since(seq uint64) (lines [][]byte, next uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	capacity := uint64(len(b.lines))
+	if b.written > capacity && seq < b.written-capacity {
+		seq = b.written - capacity
+	}
+	for i := seq; i < b.written; i++ {
+		lines = append(lines, b.lines[i%capacity])
+	}
+	return lines, b.written
+}
+
+// Lines returns every retained entry, oldest first.
+func (b *MemoryBuffer) Lines() [][]byte {
+	lines, _ := b.Since(0)
+	return lines
+}
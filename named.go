@@ -0,0 +1,156 @@
+package logger
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Named returns a derived logger for a subsystem. Names nest with dots: Named("db") then
+// Named("pool") yields "db.pool".
+//
+// Each name can have its own minimum level (Config.Levels, LOG_LEVELS or SetNamedLevel),
+// independent of the logger-wide level. A name without its own level inherits the level of
+// its closest parent name, then the logger-wide level.
+//
+// Example:
+//
+//	var dbLog = logger.Named("db")
+//
+//	dbLog.Debug("query planned", zap.String("plan", plan))
+func (l *Logger) Named(name string) *Logger {
+	return &Logger{Logger: l.Logger.Named(name), shutdown: l.shutdown}
+}
+
+// Named returns a derived logger for a subsystem using the global logger.
+func Named(name string) *Logger {
+	return Get().Named(name)
+}
+
+// SetNamedLevel changes the minimum level of the loggers with the given name (and their
+// children) in the global logger at runtime. An empty level removes the override.
+//
+// Example:
+//
+//	logger.SetNamedLevel("db", logger.LevelDebug)
+func SetNamedLevel(name string, level LogLevel) {
+	Get()
+	globalState.Load().core.current.Load().levels.set(name, level)
+}
+
+// parseNamedLevels parses a list of name=LEVEL pairs separated by commas, as used by the
+// LOG_LEVELS environment variable. Malformed pairs are ignored.
+func parseNamedLevels(s string) map[string]LogLevel {
+	if s == "" {
+		return nil
+	}
+	levels := make(map[string]LogLevel)
+	for _, pair := range strings.Split(s, ",") {
+		name, level, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" || level == "" {
+			continue
+		}
+		levels[strings.TrimSpace(name)] = LogLevel(strings.TrimSpace(level))
+	}
+	return levels
+}
+
+// levelRegistry holds the logger-wide level and the per-name overrides of a pipeline.
+//
+// As a zapcore.LevelEnabler it accepts every level that at least one name could emit, so
+// outputs let entries of verbose names through; nameLevelCore then applies the exact
+// level of each entry's logger name.
+type levelRegistry struct {
+	global zap.AtomicLevel
+
+	mu     sync.RWMutex
+	levels map[string]zapcore.Level
+	// min is the lowest overridden level, or math.MaxInt32 without overrides.
+	min atomic.Int32
+}
+
+// newLevelRegistry returns a registry with the configured overrides.
+func newLevelRegistry(global zap.AtomicLevel, levels map[string]LogLevel) *levelRegistry {
+	r := &levelRegistry{global: global, levels: make(map[string]zapcore.Level, len(levels))}
+	for name, level := range levels {
+		r.levels[name] = parseLevel(level)
+	}
+	r.updateMin()
+	return r
+}
+
+// set changes or, with an empty level, removes the override of name.
+func (r *levelRegistry) set(name string, level LogLevel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if level == "" {
+		delete(r.levels, name)
+	} else {
+		r.levels[name] = parseLevel(level)
+	}
+	r.updateMin()
+}
+
+// updateMin recomputes the lowest overridden level. The caller holds mu or owns r.
+func (r *levelRegistry) updateMin() {
+	lowest := int32(math.MaxInt32)
+	for _, level := range r.levels {
+		lowest = min(lowest, int32(level))
+	}
+	r.min.Store(lowest)
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (r *levelRegistry) Enabled(level zapcore.Level) bool {
+	return r.global.Enabled(level) || int32(level) >= r.min.Load()
+}
+
+// enabledFor reports whether an entry of the given level and logger name is emitted.
+func (r *levelRegistry) enabledFor(name string, level zapcore.Level) bool {
+	if r.min.Load() == math.MaxInt32 {
+		return r.global.Enabled(level)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for {
+		if minLevel, ok := r.levels[name]; ok {
+			return level >= minLevel
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return r.global.Enabled(level)
+		}
+		name = name[:i]
+	}
+}
+
+// nameLevelCore applies the level of each entry's logger name.
+type nameLevelCore struct {
+	zapcore.Core
+	levels *levelRegistry
+}
+
+// With implements zapcore.Core.
+func (c *nameLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &nameLevelCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+// Check implements zapcore.Core.
+func (c *nameLevelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.levels.enabledFor(ent.LoggerName, ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// writeChecked implements checkedWriter.
+func (c *nameLevelCore) writeChecked(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.levels.enabledFor(ent.LoggerName, ent.Level) {
+		return nil
+	}
+	return writeChecked(c.Core, ent, fields)
+}
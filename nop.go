@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewNop returns a logger that discards every entry without allocating, e.g. for
+// libraries whose caller does not want their output.
+func NewNop() *Logger {
+	return &Logger{Logger: zap.NewNop()}
+}
+
+// Disable silences the global logger, including the loggers already derived from it
+// (Get, Named, WithFields, context loggers), e.g. in benchmarks. Logging calls then
+// return before encoding anything and do not allocate. The current outputs are flushed
+// and closed. A later Reconfigure or InitGlobal enables logging again.
+func Disable() {
+	Get()
+	old := globalState.Load().core.swap(newNopPipeline())
+	old.retire(drainTimeout)
+}
+
+// newNopPipeline returns a pipeline discarding every entry.
+func newNopPipeline() *pipeline {
+	levels := newLevelRegistry(zap.NewAtomicLevelAt(zapcore.InfoLevel), nil)
+	return newPipeline(zapcore.NewNopCore(), nil, levels, defaultExemptions, nil)
+}
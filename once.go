@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// nopLogger is returned by the throttling helpers when an entry must be skipped.
+	nopLogger = &Logger{Logger: zap.NewNop()}
+
+	// onceKeys records the keys already used with Once.
+	onceKeys sync.Map
+
+	// everyNCounters holds one call counter per key used with EveryN.
+	everyNCounters sync.Map
+)
+
+// Once returns the logger the first time it is called with a given key in this process.
+// Subsequent calls return a logger that only writes entries exempt from volume reduction
+// (see SamplingExemptions).
+//
+// It replaces hand-rolled sync.Once guards around deprecation and misconfiguration warnings.
+//
+// Example:
+//
+//	log.Once("config.legacy-timeout").Warn("LEGACY_TIMEOUT is deprecated, use HTTP_TIMEOUT")
+func (l *Logger) Once(key string) *Logger {
+	if _, loaded := onceKeys.LoadOrStore(key, struct{}{}); loaded {
+		return l.suppressedLogger()
+	}
+	return l
+}
+
+// EveryN returns the logger on the first call and every nth call thereafter for a given key.
+// Other calls return a logger that only writes exempt entries. Values of n below 2 always
+// return the logger.
+//
+// Example:
+//
+//	log.EveryN("cache.miss", 1000).Info("cache miss", zap.String("key", key))
+func (l *Logger) EveryN(key string, n int) *Logger {
+	if n < 2 {
+		return l
+	}
+	counter, _ := everyNCounters.LoadOrStore(key, new(atomic.Uint64))
+	if (counter.(*atomic.Uint64).Add(1)-1)%uint64(n) != 0 {
+		return l.suppressedLogger()
+	}
+	return l
+}
+
+// Once returns the global logger the first time it is called with a given key.
+// Subsequent calls return a logger that only writes entries exempt from volume reduction
+// (see SamplingExemptions).
+//
+// Example:
+//
+//	logger.Once("startup.no-tls").Warn("TLS disabled; do not use in production")
+func Once(key string) *Logger {
+	return Get().Once(key)
+}
+
+// EveryN returns the global logger on the first call and every nth call thereafter
+// for a given key. Other calls return a logger that only writes exempt entries.
+func EveryN(key string, n int) *Logger {
+	return Get().EveryN(key, n)
+}
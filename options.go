@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Option customizes the logger built by New or InitGlobal beyond what Config describes.
+type Option func(*options)
+
+// options holds the settings applied by Options.
+type options struct {
+	callerSkip      int
+	stacktraceLevel zapcore.Level
+	clock           zapcore.Clock
+	encoderConfig   func(*zapcore.EncoderConfig)
+	zapOptions      []zap.Option
+}
+
+// newOptions applies opts over the defaults.
+func newOptions(opts []Option) *options {
+	o := &options{stacktraceLevel: zapcore.ErrorLevel}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithCallerSkip adds n frames to skip when reporting the caller, for loggers used
+// through wrapper functions.
+//
+// Example:
+//
+//	// Reports the caller of logInfo rather than logInfo itself.
+//	log, _ := logger.New(cfg, logger.WithCallerSkip(1))
+//
+//	func logInfo(msg string) { log.Info(msg) }
+func WithCallerSkip(n int) Option {
+	return func(o *options) { o.callerSkip += n }
+}
+
+// WithStacktraceLevel sets the minimum level of the entries carrying a stack trace.
+// Defaults to ERROR.
+func WithStacktraceLevel(level LogLevel) Option {
+	return func(o *options) { o.stacktraceLevel = parseLevel(level) }
+}
+
+// WithClock sets the clock used to timestamp entries, e.g. a fixed clock in tests.
+func WithClock(clock zapcore.Clock) Option {
+	return func(o *options) { o.clock = clock }
+}
+
+// WithEncoderConfig customizes the encoder configuration of every structured output
+// (JSON, console and logfmt), after the package defaults and field mappings are applied.
+//
+// Example:
+//
+//	logger.WithEncoderConfig(func(ec *zapcore.EncoderConfig) {
+//	    ec.TimeKey = "@timestamp"
+//	    ec.EncodeTime = zapcore.EpochMillisTimeEncoder
+//	})
+func WithEncoderConfig(fn func(*zapcore.EncoderConfig)) Option {
+	return func(o *options) { o.encoderConfig = fn }
+}
+
+// WithZapOptions appends raw zap options, applied after all other options.
+func WithZapOptions(opts ...zap.Option) Option {
+	return func(o *options) { o.zapOptions = append(o.zapOptions, opts...) }
+}
+
+// zap returns the zap options implementing o, with the diagnostics of the configuration
+// applied over them.
+func (o *options) zap(diagnostics *DiagnosticsConfig) []zap.Option {
+	opts := []zap.Option{
+		zap.AddCaller(),
+		zap.AddCallerSkip(o.callerSkip),
+		zap.AddStacktrace(o.stacktraceLevel),
+	}
+	opts = append(opts, diagnostics.zap()...)
+	if o.clock != nil {
+		opts = append(opts, zap.WithClock(o.clock))
+	}
+	return append(opts, o.zapOptions...)
+}
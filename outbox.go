@@ -0,0 +1,227 @@
+package logger
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Outbox SQL dialects.
+const (
+	// DialectPostgres uses $n placeholders and row locking with SKIP LOCKED.
+	DialectPostgres = "postgres"
+	// DialectMySQL uses ? placeholders and row locking with SKIP LOCKED (MySQL 8+).
+	DialectMySQL = "mysql"
+	// DialectSQLite uses ? placeholders without row locking.
+	DialectSQLite = "sqlite"
+)
+
+// Defaults of the audit outbox.
+const (
+	defaultOutboxTable        = "audit_outbox"
+	defaultOutboxBatchSize    = 100
+	defaultOutboxPollInterval = time.Second
+)
+
+// OutboxOptions configures an AuditOutbox.
+type OutboxOptions struct {
+	// Table is the outbox table. Defaults to "audit_outbox".
+	Table string
+	// Dialect selects the placeholder syntax and locking. Defaults to DialectPostgres.
+	Dialect string
+	// BatchSize is the maximum number of events shipped per transaction. Defaults to 100.
+	BatchSize int
+	// PollInterval is the delay between polls when the outbox is empty. Defaults to 1s.
+	PollInterval time.Duration
+	// Logger ships the events. Defaults to the global logger.
+	Logger *Logger
+}
+
+// AuditOutbox implements the transactional outbox pattern for audit events.
+//
+// Record stores an event in the application's database within the business transaction,
+// so the audit record exists if and only if the business change committed. Run (or Ship)
+// then logs stored events asynchronously and removes them once logged. Delivery is
+// at-least-once: an event may be logged twice if the process stops between logging and
+// deleting it, so consumers should deduplicate on the event ID.
+//
+// The outbox table can be created with CreateTable or with an equivalent migration:
+//
+//	CREATE TABLE audit_outbox (
+//	    id         VARCHAR(64) PRIMARY KEY,
+//	    created_at BIGINT NOT NULL,
+//	    payload    TEXT NOT NULL
+//	);
+type AuditOutbox struct {
+	db   *sql.DB
+	opts OutboxOptions
+}
+
+// NewAuditOutbox returns an outbox stored in db.
+//
+// Example:
+//
+//	outbox := logger.NewAuditOutbox(db, logger.OutboxOptions{})
+//	go outbox.Run(ctx)
+//
+//	tx, _ := db.BeginTx(ctx, nil)
+//	_, err := tx.ExecContext(ctx, "UPDATE users SET role = $1 WHERE id = $2", role, id)
+//	if err == nil {
+//	    err = outbox.Record(ctx, tx, logger.AuditEvent{Action: "user.role_changed", Target: id})
+//	}
+//	if err == nil {
+//	    err = tx.Commit()
+//	}
+func NewAuditOutbox(db *sql.DB, opts OutboxOptions) *AuditOutbox {
+	if opts.Table == "" {
+		opts.Table = defaultOutboxTable
+	}
+	if opts.Dialect == "" {
+		opts.Dialect = DialectPostgres
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultOutboxBatchSize
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultOutboxPollInterval
+	}
+	return &AuditOutbox{db: db, opts: opts}
+}
+
+// CreateTable creates the outbox table if it does not exist.
+func (o *AuditOutbox) CreateTable(ctx context.Context) error {
+	_, err := o.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+o.opts.Table+
+		" (id VARCHAR(64) PRIMARY KEY, created_at BIGINT NOT NULL, payload TEXT NOT NULL)")
+	return err
+}
+
+// Record stores ev in the outbox as part of tx.
+func (o *AuditOutbox) Record(ctx context.Context, tx *sql.Tx, ev AuditEvent) error {
+	ev = ev.withDefaults()
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("audit outbox: %w", err)
+	}
+	query := "INSERT INTO " + o.opts.Table + " (id, created_at, payload) VALUES (" +
+		o.placeholder(1) + ", " + o.placeholder(2) + ", " + o.placeholder(3) + ")"
+	if _, err := tx.ExecContext(ctx, query, ev.ID, ev.Time.UnixNano(), string(payload)); err != nil {
+		return fmt.Errorf("audit outbox: %w", err)
+	}
+	return nil
+}
+
+// Run ships stored events until ctx is cancelled, polling every PollInterval when the
+// outbox is empty. Shipping errors are logged and retried.
+func (o *AuditOutbox) Run(ctx context.Context) error {
+	for {
+		n, err := o.Ship(ctx)
+		if err != nil && ctx.Err() == nil {
+			o.logger().Error("audit outbox shipping failed", zap.Error(err))
+		}
+		if n == o.opts.BatchSize && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(o.opts.PollInterval):
+		}
+	}
+}
+
+// Ship logs one batch of stored events and removes them from the outbox. It returns the
+// number of events shipped.
+func (o *AuditOutbox) Ship(ctx context.Context) (int, error) {
+	tx, err := o.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := "SELECT id, payload FROM " + o.opts.Table + " ORDER BY created_at, id LIMIT " +
+		strconv.Itoa(o.opts.BatchSize)
+	if o.opts.Dialect != DialectSQLite {
+		// Let concurrent shippers work on disjoint batches.
+		query += " FOR UPDATE SKIP LOCKED"
+	}
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	var ids []any
+	var events []AuditEvent
+	for rows.Next() {
+		var id, payload string
+		if err := rows.Scan(&id, &payload); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		var ev AuditEvent
+		if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+			// Keep the record in the logs rather than losing it.
+			ev = AuditEvent{ID: id, Time: time.Now(), Action: "audit.undecodable",
+				Attributes: map[string]any{"payload": payload, "error": err.Error()}}
+		}
+		ids = append(ids, id)
+		events = append(events, ev)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	log := o.logger()
+	for _, ev := range events {
+		logAudit(log, ev)
+	}
+	// Make sure the events reached the outputs before deleting them.
+	if err := log.Sync(); err != nil && !isIgnorableSyncError(err) {
+		return 0, fmt.Errorf("audit outbox: %w", err)
+	}
+
+	placeholders := make([]string, len(ids))
+	for i := range ids {
+		placeholders[i] = o.placeholder(i + 1)
+	}
+	del := "DELETE FROM " + o.opts.Table + " WHERE id IN (" + strings.Join(placeholders, ", ") + ")"
+	if _, err := tx.ExecContext(ctx, del, ids...); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// placeholder returns the i-th (1-based) bind placeholder of the dialect.
+func (o *AuditOutbox) placeholder(i int) string {
+	if o.opts.Dialect == DialectPostgres {
+		return "$" + strconv.Itoa(i)
+	}
+	return "?"
+}
+
+// logger returns the logger shipping the events.
+func (o *AuditOutbox) logger() *Logger {
+	if o.opts.Logger != nil {
+		return o.opts.Logger
+	}
+	return Get()
+}
+
+// isIgnorableSyncError reports whether a Sync error only reflects that the destination
+// (a terminal or pipe) cannot be synced, rather than lost entries.
+func isIgnorableSyncError(err error) bool {
+	return errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY)
+}
@@ -0,0 +1,402 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Supported output encodings.
+const (
+	// EncodingJSON emits one structured JSON object per line.
+	EncodingJSON = "json"
+	// EncodingConsole emits human-readable, colorized console lines.
+	EncodingConsole = "console"
+)
+
+// OutputConfig describes a single log destination (sink).
+//
+// Every entry is written to all configured outputs. Because each output carries its own
+// encoding and field mapping, a single logging call can satisfy several downstream schemas
+// at once — for example during a schema migration window, where the legacy file sink keeps
+// the old field names while the new pipeline receives the new ones:
+//
+//	cfg.Outputs = []logger.OutputConfig{
+//	    {Path: "/var/log/app/legacy.log", Encoding: "json", Mapping: &logger.FieldMapping{
+//	        Rename: map[string]string{"message": "msg", "timestamp": "ts"},
+//	    }},
+//	    {Path: "stdout", Encoding: "json"},
+//	}
+//
+// Each output can also have its own minimum level, e.g. a colorized console at DEBUG for
+// developers next to a JSON file at INFO for operations.
+//
+// Outputs with the same encoding, other than "ecs", and without Encoder, Mapping,
+// IndexPrefix, Loki, Syslog, Sink, Severity, Filter or Categories share a single encoding
+// of each entry: the encoded bytes are written to each of them.
+type OutputConfig struct {
+	// Path is the destination: "stdout", "stderr", a file path, or a URL whose scheme was
+	// registered with RegisterSink. Defaults to "stdout".
+	Path string
+	// Writer, when set, is used as the destination instead of Path. It allows custom
+	// destinations such as a MemoryBuffer.
+	Writer zapcore.WriteSyncer
+	// Encoding is "json", "ecs", "gcp", "console", "logfmt", or "plain". Defaults to
+	// "json" in production and "console" otherwise.
+	Encoding string
+	// Encoder, when set, encodes the entries of this output instead of Encoding.
+	Encoder EntryEncoder
+	// Level is the minimum level of entries written to this output. Defaults to the
+	// logger-wide Config.Level.
+	Level LogLevel
+	// Mapping optionally renames, drops, or adds fields for this output only.
+	Mapping *FieldMapping
+	// IndexPrefix, when set, attaches an `index_name` field derived from the entry's
+	// retention class (e.g. "app-audit-2025.10.16"), for ILM-aligned index routing.
+	IndexPrefix string
+	// Encryption, when set, encrypts every entry at rest. Intended for local files
+	// on hosts where buffered logs may contain sensitive data.
+	Encryption *EncryptionConfig
+	// Rotation, when set, rotates the file at Path by size and prunes old files.
+	Rotation *RotationConfig
+	// Loki, when set, pushes entries to a Loki instance instead of writing to Path.
+	Loki *LokiConfig
+	// Syslog, when set, sends entries to syslog instead of writing to Path.
+	Syslog *SyslogConfig
+	// Sink, when set, writes entries to a destination provided by an integration package
+	// instead of Path. A Path whose URL scheme was registered with RegisterSink selects
+	// such a destination as well.
+	Sink Sink
+	// Fsync selects when the file at Path is flushed to stable storage. Defaults to
+	// FsyncNever.
+	Fsync FsyncPolicy
+	// FsyncInterval is the minimum time between two flushes with FsyncInterval. Defaults
+	// to 1s.
+	FsyncInterval time.Duration
+	// Buffer, when set, buffers the writes to Path in per-processor shards to reduce lock
+	// contention between goroutines. Ignored in async mode.
+	Buffer *BufferConfig
+	// Faults, when set, injects latency and failures into the writes to the destination,
+	// for tests. Not supported with Loki and Syslog.
+	Faults *FaultConfig
+	// Budget, when set, alerts when the entries or bytes written to the output per hour or
+	// per day exceed a budget.
+	Budget *BudgetConfig
+	// Severity, when set, writes the level of entries as the severity the destination
+	// expects, e.g. SeveritySyslog or SeverityPagerDuty. It applies to the built-in
+	// encodings.
+	Severity SeverityProfile
+	// Filter, when set, is an expression selecting the entries written to the output,
+	// e.g. `event_code =~ "^PAY" && level >= error`. Operands are "level", "message",
+	// "logger", "caller", field keys (dots reach into nested objects), quoted strings,
+	// numbers, true and false; the level is compared with level names. The operators are
+	// == != < <= > >=, =~ and !~ for regular expressions, and && || ! with parentheses.
+	// Quoted strings take Go escapes, so backslashes in regular expressions are escaped,
+	// as in "^PAY-\\d+", or the expression is written as a `...` raw string.
+	// A missing field equals nothing and matches no regular expression, and a field alone
+	// tests that it is present and not false, zero or empty.
+	Filter string
+	// Categories, when set, restricts the output to the entries labelled with one of these
+	// categories (see WithCategory), e.g. to route billing entries to their own file.
+	Categories []string
+}
+
+// path returns the destination of the output, defaulting to stdout.
+func (o OutputConfig) path() string {
+	switch {
+	case o.Sink != nil:
+		return o.Sink.String()
+	case o.Path != "":
+		return o.Path
+	case o.Loki != nil:
+		return o.Loki.pushURL()
+	case o.Syslog != nil:
+		return o.Syslog.description()
+	case o.Writer != nil:
+		return "writer"
+	default:
+		return "stdout"
+	}
+}
+
+// displayPath returns the destination of the output as shown in the banner, alerts and
+// errors, with credentials masked.
+func (o OutputConfig) displayPath() string {
+	return maskURL(o.path())
+}
+
+// open returns the destination writer of the output and a function releasing it.
+func (o OutputConfig) open() (zapcore.WriteSyncer, func(), error) {
+	if o.Writer != nil {
+		return zapcore.Lock(o.Writer), func() {}, nil
+	}
+	if o.Rotation != nil {
+		sink, closeSink, err := openRotating(o.path(), o.Rotation)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open %q: %w", o.path(), err)
+		}
+		return zapcore.Lock(sink), closeSink, nil
+	}
+	sink, closeSink, err := zap.Open(o.path())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %q: %w", o.displayPath(), maskError(err, o.path()))
+	}
+	return sink, closeSink, nil
+}
+
+// encoding returns the encoding of the output, defaulting to the environment's encoding.
+func (o OutputConfig) encoding(cfg Config) string {
+	if o.Encoding != "" {
+		return o.Encoding
+	}
+	if isProduction(cfg) {
+		return EncodingJSON
+	}
+	return EncodingConsole
+}
+
+// levelEnabler returns the minimum level of the output, falling back to the
+// logger-wide level.
+func (o OutputConfig) levelEnabler(level zapcore.LevelEnabler) zapcore.LevelEnabler {
+	if o.Level != "" {
+		return parseLevel(o.Level)
+	}
+	return level
+}
+
+// outputs returns the configured outputs, or the default stdout output when none are set.
+func (c Config) outputs() []OutputConfig {
+	if len(c.Outputs) == 0 {
+		return []OutputConfig{{}}
+	}
+	return c.Outputs
+}
+
+// buildOutputs constructs one core per configured output and tees them together.
+//
+// Plain outputs with the same encoding share a single core, so that each entry is encoded
+// once and the same bytes are written to all of them. Writes to Path destinations are
+// batched by batches, unless it is nil. The returned closers release the resources opened
+// by the outputs.
+func buildOutputs(cfg Config, level zapcore.LevelEnabler, batches *batchGroup, budgets *budgetReporter) (zapcore.Core, []func(), error) {
+	backend, err := cfg.backendFactory()
+	if err != nil {
+		return nil, nil, err
+	}
+	outputs := cfg.outputs()
+	cores := make([]zapcore.Core, 0, len(outputs))
+	closers := make([]func(), 0, len(outputs))
+	shared := make(map[string]*fanoutCore)
+	for i, out := range outputs {
+		if backend != nil || !out.sharesEncoding() {
+			core, closer, err := buildOutput(cfg, out, level, batches, budgets)
+			if err != nil {
+				closeAll(closers)
+				return nil, nil, fmt.Errorf("output %d: %w", i, err)
+			}
+			cores = append(cores, core)
+			closers = append(closers, closer)
+			continue
+		}
+		sink, closer, err := out.writer(batches, budgets)
+		if err != nil {
+			closeAll(closers)
+			return nil, nil, fmt.Errorf("output %d: output %q: %w", i, out.displayPath(), err)
+		}
+		closers = append(closers, closer)
+		destination := fanoutDestination{out: sink, level: out.levelEnabler(level)}
+		encoding := out.encoding(cfg)
+		if encoding == EncodingConsole {
+			encoding = detectConsole(out).key()
+		}
+		if core, ok := shared[encoding]; ok {
+			core.destinations = append(core.destinations, destination)
+			continue
+		}
+		encoder, err := newEncoder(cfg, out)
+		if err != nil {
+			closeAll(closers)
+			return nil, nil, fmt.Errorf("output %d: %w", i, err)
+		}
+		core := newFanoutCore(encoder, []fanoutDestination{destination})
+		shared[encoding] = core
+		cores = append(cores, core)
+	}
+
+	return newEmergencyCore(cores), closers, nil
+}
+
+// closeAll calls every closer in order.
+func closeAll(closers []func()) {
+	for _, closer := range closers {
+		closer()
+	}
+}
+
+// buildOutput constructs the core for a single output.
+func buildOutput(cfg Config, out OutputConfig, level zapcore.LevelEnabler, batches *batchGroup, budgets *budgetReporter) (zapcore.Core, func(), error) {
+	path := out.displayPath()
+
+	encoder, err := newEncoder(cfg, out)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	core, closer, err := out.newCore(cfg, encoder, out.levelEnabler(level), batches, budgets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("output %q: %w", path, err)
+	}
+	if mapping := out.mapping(); mapping != nil {
+		core = newMappingCore(core, mapping)
+	}
+	if out.IndexPrefix != "" {
+		core = newIndexCore(core, out.IndexPrefix, cfg.Retention)
+	}
+	if out.Filter != "" {
+		// The filter sees the fields as logged, before the mapping of the output.
+		if core, err = newFilterCore(core, out.Filter); err != nil {
+			closer()
+			return nil, nil, err
+		}
+	}
+	if len(out.Categories) > 0 {
+		core = newCategoryCore(core, out.Categories)
+	}
+	return core, closer, nil
+}
+
+// newCore returns the core writing encoded entries to the output's destination.
+func (o OutputConfig) newCore(cfg Config, encoder zapcore.Encoder, level zapcore.LevelEnabler, batches *batchGroup, budgets *budgetReporter) (zapcore.Core, func(), error) {
+	switch {
+	case o.Loki != nil:
+		return newLokiCore(cfg, o.Loki, encoder, level)
+	case o.Syslog != nil:
+		return newSyslogCore(cfg, o.Syslog, encoder, level)
+	case o.Sink != nil:
+		core, closer := newSinkCore(budgets.wrapSink(o.faultySink(o.Sink), o), encoder, level)
+		return core, closer, nil
+	}
+	if factory, u := sinkFactory(o.Path); factory != nil {
+		sink, err := factory(u)
+		if err != nil {
+			return nil, nil, err
+		}
+		core, closer := newSinkCore(budgets.wrapSink(o.faultySink(sink), o), encoder, level)
+		return core, closer, nil
+	}
+
+	sink, closer, err := o.writer(batches, budgets)
+	if err != nil {
+		return nil, nil, err
+	}
+	if backend, _ := cfg.backendFactory(); backend != nil {
+		core, err := newBackendCore(backend, BackendOutput{Writer: sink, Level: level, Encoding: o.encoding(cfg)})
+		if err != nil {
+			closer()
+			return nil, nil, err
+		}
+		return core, closer, nil
+	}
+	return zapcore.NewCore(encoder, sink, level), closer, nil
+}
+
+// sharesEncoding reports whether the output writes the entries exactly as encoded, so it
+// can share the encoded bytes with the other outputs of the same encoding.
+func (o OutputConfig) sharesEncoding() bool {
+	if o.Encoder != nil || o.Loki != nil || o.Syslog != nil || o.Sink != nil || o.mapping() != nil || o.IndexPrefix != "" || o.Severity != nil || o.Filter != "" || len(o.Categories) > 0 {
+		return false
+	}
+	factory, _ := sinkFactory(o.Path)
+	return factory == nil
+}
+
+// writer opens the destination of the output, applying its fsync policy, buffering,
+// encryption and budgets. Writes to Path destinations are batched by batches, unless it is
+// nil; custom Writers keep receiving one entry per write. Budgets are counted by budgets.
+func (o OutputConfig) writer(batches *batchGroup, budgets *budgetReporter) (zapcore.WriteSyncer, func(), error) {
+	if err := o.Fsync.validate(); err != nil {
+		return nil, nil, err
+	}
+	sink, closer, err := o.open()
+	if err != nil {
+		return nil, nil, err
+	}
+	if o.Faults != nil {
+		sink = NewFaultyWriter(sink, *o.Faults)
+	}
+	if o.Writer == nil {
+		sink = batches.wrap(sink, o)
+		if o.Buffer != nil && batches == nil {
+			sharded, release := newShardedWriter(o.path(), sink, o.Buffer), closer
+			sink, closer = sharded, func() {
+				sharded.close()
+				release()
+			}
+		}
+	}
+	if o.Encryption != nil {
+		if sink, err = newEncryptingWriter(sink, o.Encryption); err != nil {
+			closer()
+			return nil, nil, err
+		}
+	}
+	return budgets.wrap(sink, o), closer, nil
+}
+
+// mapping returns the field mapping of the output: its Mapping, combined with the ECS
+// field names for the "ecs" encoding.
+func (o OutputConfig) mapping() *FieldMapping {
+	if o.Encoding == EncodingECS && o.Encoder == nil {
+		return ecsMapping(o.Mapping)
+	}
+	return o.Mapping
+}
+
+// faultySink returns sink with the faults of the output injected, if any.
+func (o OutputConfig) faultySink(sink Sink) Sink {
+	if o.Faults == nil {
+		return sink
+	}
+	return NewFaultySink(sink, *o.Faults)
+}
+
+// newEncoder returns the encoder selected by the output, falling back to the
+// environment's default encoding.
+func newEncoder(cfg Config, out OutputConfig) (zapcore.Encoder, error) {
+	if out.Encoder != nil {
+		return newEntryEncoder(out.Encoder), nil
+	}
+	switch encoding := out.encoding(cfg); encoding {
+	case EncodingJSON:
+		return zapcore.NewJSONEncoder(encoderConfig(cfg, out, productionEncoderConfig())), nil
+	case EncodingECS:
+		return zapcore.NewJSONEncoder(encoderConfig(cfg, out, ecsEncoderConfig())), nil
+	case EncodingGCP:
+		return newGCPEncoder(encoderConfig(cfg, out, gcpEncoderConfig())), nil
+	case EncodingConsole:
+		base := detectConsole(out).encoderConfig(developmentEncoderConfig())
+		return zapcore.NewConsoleEncoder(encoderConfig(cfg, out, base)), nil
+	case EncodingPlain:
+		return newPlainEncoder(), nil
+	case EncodingLogfmt:
+		return newLogfmtEncoder(encoderConfig(cfg, out, productionEncoderConfig())), nil
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", encoding)
+	}
+}
+
+// encoderConfig applies the logger-wide and per-output settings to a base encoder configuration.
+func encoderConfig(cfg Config, out OutputConfig, base zapcore.EncoderConfig) zapcore.EncoderConfig {
+	if cfg.Caller.enabled() {
+		base.EncodeCaller = cfg.Caller.encoder()
+	}
+	base = cfg.Diagnostics.encoderConfig(base)
+	base = out.Severity.encoderConfig(base)
+	base = out.mapping().encoderConfig(base)
+	if cfg.encoderOverride != nil {
+		cfg.encoderOverride(&base)
+	}
+	return base
+}
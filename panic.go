@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RecoverAndLog recovers a panic and logs it at ERROR with the panic value, the stack
+// trace of the panicking goroutine, the fields of the logger carried by ctx and those
+// attached to ctx with Attach. It must be deferred directly. The goroutine then returns normally from the deferring function.
+//
+// Example:
+//
+//	go func() {
+//	    defer logger.RecoverAndLog(ctx)
+//	    worker.Run(ctx)
+//	}()
+func RecoverAndLog(ctx context.Context) {
+	if v := recover(); v != nil {
+		logPanic(FromContext(ctx).WithContext(Attachments(ctx)...), v, "panic recovered")
+	}
+}
+
+// LogPanic logs a panic like RecoverAndLog, using the global logger, flushes the outputs
+// and panics again with the same value, so that the process still crashes but the cause
+// is in the logs. It must be deferred directly.
+//
+// Example:
+//
+//	func main() {
+//	    defer logger.LogPanic()
+//	    ...
+//	}
+func LogPanic() {
+	if v := recover(); v != nil {
+		log := Get()
+		logPanic(log, v, "panic")
+		_ = log.Sync()
+		panic(v)
+	}
+}
+
+// RecoverMiddleware recovers the panics of HTTP handlers, logs them like RecoverAndLog
+// with the method and path of the request, and responds with 500 Internal Server Error.
+// Inside HTTPMiddleware, the access-log entry of the request records the 500 status and
+// the panic as its error. The http.ErrAbortHandler panic, used to abort a response, is
+// not logged.
+//
+// Example:
+//
+//	http.ListenAndServe(":8080", logger.HTTPMiddleware(logger.RecoverMiddleware(mux)))
+func RecoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+			fields := append([]zap.Field{
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+			}, Attachments(r.Context())...)
+			log := FromContext(r.Context()).WithContext(fields...)
+			logPanic(log, v, "panic recovered")
+			SetRequestError(r.Context(), fmt.Errorf("panic: %v", v))
+			w.WriteHeader(http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// logPanic writes the entry of a recovered panic with value v.
+func logPanic(log *Logger, v any, msg string) {
+	// Check against the core directly: the caller would only point at the deferred call.
+	ent := zapcore.Entry{LoggerName: log.Name(), Time: time.Now(), Level: zapcore.ErrorLevel, Message: msg}
+	ce := log.Core().Check(ent, nil)
+	if ce == nil {
+		return
+	}
+	ce.Stack = panicStack()
+	field := zap.Any("panic", v)
+	if err, ok := v.(error); ok {
+		field = zap.NamedError("panic", err)
+	}
+	ce.Write(field)
+}
+
+// panicStack returns the stack trace of the panicking goroutine from the panic call,
+// without the frames of the recovery.
+func panicStack() string {
+	stack := debug.Stack()
+	header, _, _ := bytes.Cut(stack, []byte("\n"))
+	if i := bytes.Index(stack, []byte("\npanic(")); i >= 0 {
+		return string(header) + string(stack[i:])
+	}
+	return string(stack)
+}
@@ -0,0 +1,346 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// drainTimeout bounds how long a retired pipeline waits for in-flight writes.
+const drainTimeout = 5 * time.Second
+
+// pipeline is a fully built output pipeline together with the resources it owns.
+type pipeline struct {
+	// core writes to all outputs and carries the enrichment fields.
+	core zapcore.Core
+	// level is the logger-wide minimum level, adjustable at runtime.
+	level zap.AtomicLevel
+	// levels holds the per-name level overrides.
+	levels *levelRegistry
+	// closers release the resources (files, sockets) opened by the outputs.
+	closers   []func()
+	closeOnce sync.Once
+	// inflight counts entries checked against this pipeline but not yet written.
+	inflight atomic.Int64
+	// release is added to checked entries to decrement inflight once written.
+	release zapcore.Core
+	// service and inherited are passed on to child processes by InheritEnv.
+	service   string
+	inherited map[string]any
+}
+
+// buildPipeline constructs the pipeline described by cfg, including enrichment fields.
+func buildPipeline(cfg Config) (*pipeline, error) {
+	cfg, err := checkSecrets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	level := zap.NewAtomicLevelAt(parseLevel(cfg.Level))
+	levels := newLevelRegistry(level, cfg.Levels)
+	reports := newSuppressionReporter(cfg.SuppressionReports)
+	core, closers, err := buildCore(cfg, levels, reports)
+	if err != nil {
+		return nil, err
+	}
+	p := newPipeline(core.With(enrichmentFields(cfg)), closers, levels, cfg.exemptions(), reports)
+	p.service, p.inherited = cfg.ServiceName, cfg.Inherited
+	return p, nil
+}
+
+// newPipeline wraps the output core with the per-name levels, exemptions and suppression
+// reporter of a pipeline.
+func newPipeline(core zapcore.Core, closers []func(), levels *levelRegistry, set *exemptionSet, reports *suppressionReporter) *pipeline {
+	core = &nameLevelCore{Core: core, levels: levels}
+	core = &exemptionCore{Core: core, set: set, reports: reports}
+	p := &pipeline{core: core, level: levels.global, levels: levels, closers: closers}
+	p.release = releaseCore{p: p}
+	return p
+}
+
+// buildCore constructs the output cores and processing layers described by cfg, using
+// level as the minimum level of outputs without their own. Entries dropped by sampling
+// and the async queue are counted by reports, unless it is nil.
+func buildCore(cfg Config, level zapcore.LevelEnabler, reports *suppressionReporter) (zapcore.Core, []func(), error) {
+	var batches *batchGroup
+	if cfg.Async != nil {
+		if err := cfg.Async.Overflow.validate(); err != nil {
+			return nil, nil, fmt.Errorf("failed to build logger: %w", err)
+		}
+		batches = &batchGroup{}
+	}
+	budgets := newBudgetReporter(cfg.outputs())
+	core, closers, err := buildOutputs(cfg, level, batches, budgets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build logger: %w", err)
+	}
+	if cfg.Async != nil {
+		var closeQueue func()
+		async := *cfg.Async
+		async.OnDropped = reports.hook(suppressionShed, async.OnDropped)
+		core, closeQueue = newAsyncCore(core, &async, batches.flush)
+		// Drain the queue before the outputs are closed.
+		closers = append([]func(){closeQueue}, closers...)
+	}
+	// Hooks can be registered at any time: the core is always present. It sees the
+	// entries as transformed by the layers wrapping it.
+	core = &hookCore{Core: core}
+	if cfg.Anonymization != nil {
+		if core, err = newAnonymizationCore(core, cfg.Anonymization); err != nil {
+			closeAll(closers)
+			return nil, nil, fmt.Errorf("failed to build logger: %w", err)
+		}
+	}
+	// Scrubbers can be registered at any time: the core is always present.
+	core = &scrubCore{Core: core}
+	if cfg.Redaction != nil {
+		if core, err = newRedactionCore(core, cfg.Redaction); err != nil {
+			closeAll(closers)
+			return nil, nil, fmt.Errorf("failed to build logger: %w", err)
+		}
+	}
+	if cfg.FieldValidation != ValidationOff {
+		core = newValidationCore(core, cfg.FieldValidation)
+	}
+	if len(cfg.Processors) > 0 {
+		core = newProcessorCore(core, cfg.Processors)
+	}
+	if reports != nil {
+		// Reports are logged below sampling and deduplication, so that they are never
+		// suppressed themselves.
+		reports.start(core.With(enrichmentFields(cfg)))
+		closers = append([]func(){reports.close}, closers...)
+	}
+	if cfg.Sampling != nil {
+		sampling := *cfg.Sampling
+		sampling.OnDropped = reports.hook(suppressionSampled, sampling.OnDropped)
+		core = newSamplingCore(core, &sampling, cfg.exemptions())
+	}
+	if cfg.Dedup != nil {
+		var closeDedup func()
+		core, closeDedup = newDedupCore(core, cfg.Dedup, cfg.exemptions())
+		// Write the pending summaries before the queue and outputs are closed.
+		closers = append([]func(){closeDedup}, closers...)
+	}
+	if cfg.Profiling != nil {
+		var closeProfiler func()
+		core, closeProfiler = newProfilingCore(core, cfg.Profiling, enrichmentFields(cfg))
+		closers = append([]func(){closeProfiler}, closers...)
+	}
+	if cfg.FatalLoop != nil {
+		if core, err = newFatalLoopCore(core, cfg.FatalLoop); err != nil {
+			closeAll(closers)
+			return nil, nil, fmt.Errorf("failed to build logger: %w", err)
+		}
+	}
+	if budgets != nil {
+		// Budget alerts are logged through the whole pipeline, like any other entry.
+		budgets.start(core.With(enrichmentFields(cfg)))
+		closers = append([]func(){budgets.close}, closers...)
+	}
+	return core, closers, nil
+}
+
+// checkedWriter is implemented by the cores whose Check does more than registering
+// themselves when enabled, such as sampling, so that writeChecked applies the same
+// decisions.
+type checkedWriter interface {
+	// writeChecked checks the entry as Check does and writes it to the cores accepting
+	// it, returning their write errors.
+	writeChecked(ent zapcore.Entry, fields []zapcore.Field) error
+}
+
+// writeChecked writes an entry through core after checking it again, so that the
+// per-output levels below a pipeline-wide transform still apply. It returns the errors
+// of the cores' Write, which checked entries only print to their ErrorOutput. Cores not
+// implementing checkedWriter must register themselves when enabled, as zapcore.NewCore
+// does.
+func writeChecked(core zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) error {
+	if w, ok := core.(checkedWriter); ok {
+		return w.writeChecked(ent, fields)
+	}
+	if !core.Enabled(ent.Level) {
+		return nil
+	}
+	return core.Write(ent, fields)
+}
+
+// writeReport writes an entry logged by the pipeline itself from a background goroutine,
+// such as a budget alert, reporting a failure on stderr as there is no caller to return
+// it to.
+func writeReport(core zapcore.Core, ent zapcore.Entry, fields []zapcore.Field) {
+	if err := writeChecked(core, ent, fields); err != nil {
+		fmt.Fprintf(os.Stderr, "logger: failed to write %q: %v\n", ent.Message, err)
+	}
+}
+
+// enrichmentFields returns the fields attached to every entry of the pipeline.
+func enrichmentFields(cfg Config) []zapcore.Field {
+	fields := []zapcore.Field{
+		zap.String("service", cfg.ServiceName),
+		zap.String("environment", cfg.Environment),
+	}
+	if cfg.CgroupEnrichment {
+		if field, ok := cgroupLimitsField(); ok {
+			fields = append(fields, field)
+		}
+	}
+	return append(fields, inheritedFields(cfg)...)
+}
+
+// retire waits for in-flight writes (bounded by timeout), flushes, and closes the pipeline.
+//
+// Flushing is best-effort: syncing terminals and pipes commonly fails with EINVAL,
+// which must not be mistaken for lost entries.
+func (p *pipeline) retire(timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for p.inflight.Load() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	p.close()
+}
+
+// close flushes and closes the pipeline, once.
+func (p *pipeline) close() {
+	p.closeOnce.Do(func() {
+		_ = p.core.Sync()
+		closeAll(p.closers)
+	})
+}
+
+// releaseCore is appended to checked entries so that a pipeline knows when
+// an entry it accepted has been fully written.
+type releaseCore struct {
+	p *pipeline
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (r releaseCore) Enabled(zapcore.Level) bool { return true }
+
+// With implements zapcore.Core.
+func (r releaseCore) With([]zapcore.Field) zapcore.Core { return r }
+
+// Check implements zapcore.Core; releaseCore is only ever added explicitly.
+func (r releaseCore) Check(_ zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return ce
+}
+
+// Write marks the entry as no longer in flight.
+func (r releaseCore) Write(zapcore.Entry, []zapcore.Field) error {
+	r.p.inflight.Add(-1)
+	return nil
+}
+
+// Sync implements zapcore.Core.
+func (r releaseCore) Sync() error { return nil }
+
+// reloadableCore delegates to the current pipeline, which can be swapped atomically.
+//
+// Derived cores (created through With) remember their contextual fields and re-apply
+// them lazily to a new pipeline, so loggers derived before a reconfiguration keep
+// working against the new outputs.
+type reloadableCore struct {
+	current *atomic.Pointer[pipeline]
+	fields  []zapcore.Field
+	cache   atomic.Pointer[derivedCore]
+}
+
+// derivedCore caches a pipeline core with contextual fields applied.
+type derivedCore struct {
+	p    *pipeline
+	core zapcore.Core
+}
+
+// newReloadableCore returns a reloadable core initially delegating to p.
+func newReloadableCore(p *pipeline) *reloadableCore {
+	c := &reloadableCore{current: &atomic.Pointer[pipeline]{}}
+	c.current.Store(p)
+	return c
+}
+
+// swap installs p as the current pipeline and returns the previous one.
+func (c *reloadableCore) swap(p *pipeline) *pipeline {
+	return c.current.Swap(p)
+}
+
+// resolve returns the current pipeline and its core with contextual fields applied.
+func (c *reloadableCore) resolve() (*pipeline, zapcore.Core) {
+	p := c.current.Load()
+	if len(c.fields) == 0 {
+		return p, p.core
+	}
+	if d := c.cache.Load(); d != nil && d.p == p {
+		return p, d.core
+	}
+	d := &derivedCore{p: p, core: p.core.With(c.fields)}
+	c.cache.Store(d)
+	return p, d.core
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *reloadableCore) Enabled(level zapcore.Level) bool {
+	_, core := c.resolve()
+	return core.Enabled(level)
+}
+
+// With implements zapcore.Core.
+func (c *reloadableCore) With(fields []zapcore.Field) zapcore.Core {
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &reloadableCore{current: c.current, fields: merged}
+}
+
+// Check implements zapcore.Core, tracking accepted entries as in flight until written.
+func (c *reloadableCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ce != nil {
+		// Entries already accepted by other cores cannot be tracked reliably.
+		_, core := c.resolve()
+		return core.Check(ent, ce)
+	}
+	for {
+		p, core := c.resolve()
+		p.inflight.Add(1)
+		if c.current.Load() != p {
+			// The pipeline was swapped concurrently; retry against the new one.
+			p.inflight.Add(-1)
+			continue
+		}
+		out := core.Check(ent, nil)
+		if out == nil {
+			p.inflight.Add(-1)
+			return nil
+		}
+		return out.AddCore(ent, p.release)
+	}
+}
+
+// Write implements zapcore.Core. It is only reached when this core is registered
+// directly on a checked entry, which Check never does.
+func (c *reloadableCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	_, core := c.resolve()
+	return core.Write(ent, fields)
+}
+
+// exemptions implements exemptionSource.
+func (c *reloadableCore) exemptions() *exemptionSet {
+	return exemptionsOf(c.current.Load().core)
+}
+
+// suppressionReports implements exemptionSource.
+func (c *reloadableCore) suppressionReports() *suppressionReporter {
+	if src, ok := c.current.Load().core.(exemptionSource); ok {
+		return src.suppressionReports()
+	}
+	return nil
+}
+
+// Sync implements zapcore.Core.
+func (c *reloadableCore) Sync() error {
+	_, core := c.resolve()
+	return core.Sync()
+}
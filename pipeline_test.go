@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var errDiskFull = errors.New("disk full")
+
+// failingWriter fails every write with errDiskFull.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errDiskFull }
+func (failingWriter) Sync() error               { return nil }
+
+func TestWriteCheckedReturnsWriteErrors(t *testing.T) {
+	levels := newLevelRegistry(zap.NewAtomicLevelAt(zapcore.InfoLevel), map[string]LogLevel{"quiet": LevelError})
+	out := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), failingWriter{}, zapcore.DebugLevel)
+	core := &nameLevelCore{Core: out, levels: levels}
+
+	tests := []struct {
+		name string
+		ent  zapcore.Entry
+		want error
+	}{
+		{"written", zapcore.Entry{Level: zapcore.InfoLevel, Message: "hello"}, errDiskFull},
+		{"below the logger level", zapcore.Entry{Level: zapcore.DebugLevel, Message: "hello"}, nil},
+		{"below the name level", zapcore.Entry{Level: zapcore.WarnLevel, LoggerName: "quiet", Message: "hello"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := writeChecked(core, tt.ent, nil)
+			if tt.want == nil && err != nil {
+				t.Fatalf("writeChecked() = %v, want nil", err)
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("writeChecked() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package logger
+
+import (
+	"context"
+	"runtime"
+	"runtime/metrics"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of the self-profiling mode.
+const (
+	defaultProfilingInterval   = time.Minute
+	defaultProfilingSampleRate = 100
+)
+
+// profileLabel is the pprof label key set on the goroutines of the logging pipeline.
+const profileLabel = "logger"
+
+// ProfilingConfig makes the logger measure its own cost and report it periodically, to
+// quantify the logging overhead of a service in production.
+//
+// One entry out of SampleRate is timed while it is filtered, encoded and written (or
+// queued, in async mode), and the heap allocations made meanwhile are counted. Every
+// Interval, a "logger profile" entry reports the estimated totals:
+//
+//	{"message":"logger profile","entries":120000,"sampled":1200,"write_time_ms":1843.2,
+//	 "avg_write_us":15.36,"allocs_per_entry":3.1,"alloc_bytes_per_entry":212.4,
+//	 "cpu_share":0.0038}
+//
+// cpu_share is the estimated write time divided by the CPU time available during the
+// interval (Interval × GOMAXPROCS). Allocations are read from process-wide counters, so
+// they are overestimated when other goroutines allocate during a sampled write.
+//
+// Independently of this mode, the background goroutines of the pipeline (async writer,
+// remote sinks, deduplication) always carry a "logger" pprof label, so CPU and heap
+// profiles attribute their cost to the logger.
+type ProfilingConfig struct {
+	// Interval is the period of the reports. Defaults to 1m.
+	Interval time.Duration
+	// SampleRate is the number of entries per timed entry. Defaults to 100.
+	SampleRate int
+}
+
+// labelGoroutine sets the pprof label of a background goroutine of the pipeline.
+func labelGoroutine(role string) {
+	pprof.SetGoroutineLabels(pprof.WithLabels(context.Background(), pprof.Labels(profileLabel, role)))
+}
+
+// allocMetrics are the runtime metrics read around sampled writes.
+var allocMetrics = []string{"/gc/heap/allocs:objects", "/gc/heap/allocs:bytes"}
+
+// readAllocs returns the number and size of heap allocations made by the process so far.
+func readAllocs() (objects, bytes uint64) {
+	var samples [2]metrics.Sample
+	samples[0].Name, samples[1].Name = allocMetrics[0], allocMetrics[1]
+	metrics.Read(samples[:])
+	if samples[0].Value.Kind() != metrics.KindUint64 || samples[1].Value.Kind() != metrics.KindUint64 {
+		return 0, 0
+	}
+	return samples[0].Value.Uint64(), samples[1].Value.Uint64()
+}
+
+// profiler accumulates the measurements of an interval.
+type profiler struct {
+	rate     uint64
+	interval time.Duration
+
+	entries atomic.Uint64
+
+	mu         sync.Mutex
+	sampled    uint64
+	writeTime  time.Duration
+	allocs     uint64
+	allocBytes uint64
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// newProfiler applies the configuration defaults.
+func newProfiler(cfg *ProfilingConfig) *profiler {
+	p := &profiler{
+		rate:     defaultProfilingSampleRate,
+		interval: defaultProfilingInterval,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if cfg.SampleRate > 0 {
+		p.rate = uint64(cfg.SampleRate)
+	}
+	if cfg.Interval > 0 {
+		p.interval = cfg.Interval
+	}
+	return p
+}
+
+// record adds the measurements of a sampled write.
+func (p *profiler) record(d time.Duration, allocs, allocBytes uint64) {
+	p.mu.Lock()
+	p.sampled++
+	p.writeTime += d
+	p.allocs += allocs
+	p.allocBytes += allocBytes
+	p.mu.Unlock()
+}
+
+// run reports the measurements through core every interval.
+func (p *profiler) run(core zapcore.Core) {
+	defer close(p.stopped)
+	labelGoroutine("profiler")
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.report(core)
+		case <-p.done:
+			return
+		}
+	}
+}
+
+// report writes the summary of the interval and starts a new one.
+func (p *profiler) report(core zapcore.Core) {
+	entries := p.entries.Swap(0)
+	p.mu.Lock()
+	sampled, writeTime, allocs, allocBytes := p.sampled, p.writeTime, p.allocs, p.allocBytes
+	p.sampled, p.writeTime, p.allocs, p.allocBytes = 0, 0, 0, 0
+	p.mu.Unlock()
+	if sampled == 0 {
+		return
+	}
+
+	perEntry := writeTime / time.Duration(sampled)
+	total := perEntry * time.Duration(entries)
+	available := p.interval * time.Duration(runtime.GOMAXPROCS(0))
+	writeReport(core, zapcore.Entry{Level: zapcore.InfoLevel, Time: time.Now(), Message: "logger profile"}, []zapcore.Field{
+		zap.Uint64("entries", entries),
+		zap.Uint64("sampled", sampled),
+		DurationMS("write_time_ms", total),
+		zap.Float64("avg_write_us", float64(perEntry)/float64(time.Microsecond)),
+		zap.Float64("allocs_per_entry", float64(allocs)/float64(sampled)),
+		zap.Float64("alloc_bytes_per_entry", float64(allocBytes)/float64(sampled)),
+		zap.Float64("cpu_share", float64(total)/float64(available)),
+	})
+}
+
+// close stops the reports.
+func (p *profiler) close() {
+	p.closeOnce.Do(func() {
+		close(p.done)
+		<-p.stopped
+	})
+}
+
+// profilingCore times a sample of the entries written through the pipeline.
+type profilingCore struct {
+	zapcore.Core
+	p *profiler
+}
+
+// newProfilingCore wraps core with the self-profiling described by cfg and returns the
+// function stopping the reports. The reports carry the given fields.
+func newProfilingCore(core zapcore.Core, cfg *ProfilingConfig, fields []zapcore.Field) (zapcore.Core, func()) {
+	p := newProfiler(cfg)
+	go p.run(core.With(fields))
+	return &profilingCore{Core: core, p: p}, p.close
+}
+
+// With implements zapcore.Core.
+func (c *profilingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &profilingCore{Core: c.Core.With(fields), p: c.p}
+}
+
+// Check counts the entry and registers this core for the sampled ones.
+func (c *profilingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if c.p.entries.Add(1)%c.p.rate != 0 {
+		return c.Core.Check(ent, ce)
+	}
+	return ce.AddCore(ent, c)
+}
+
+// writeChecked implements checkedWriter.
+func (c *profilingCore) writeChecked(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+	if c.p.entries.Add(1)%c.p.rate != 0 {
+		return writeChecked(c.Core, ent, fields)
+	}
+	return c.Write(ent, fields)
+}
+
+// Write measures the processing of a sampled entry by the wrapped core.
+func (c *profilingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	objects, bytes := readAllocs()
+	start := time.Now()
+	err := writeChecked(c.Core, ent, fields)
+	d := time.Since(start)
+	objectsAfter, bytesAfter := readAllocs()
+	c.p.record(d, objectsAfter-objects, bytesAfter-bytes)
+	return err
+}
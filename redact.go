@@ -0,0 +1,262 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces the redacted values.
+const Redacted = "[REDACTED]"
+
+// DefaultRedactedKeys lists the field keys redacted unless RedactionConfig.NoDefaultKeys
+// is set.
+var DefaultRedactedKeys = []string{
+	"password", "passwd", "secret", "token", "access_token", "refresh_token",
+	"api_key", "apikey", "authorization", "cookie", "set-cookie",
+}
+
+// RedactionConfig replaces sensitive values with [REDACTED] before entries are encoded,
+// so secrets and personal data never reach an output even when a call site logs them by
+// mistake.
+//
+// A field whose key is redacted has its whole value replaced, whatever its type. Keys
+// are matched case-insensitively, on top-level fields and on the string fields of nested
+// objects (zap.Object). Patterns are matched against string values (including errors and
+// fmt.Stringer values) and against the message: only the matching part is replaced.
+//
+// Example:
+//
+//	Redaction: &logger.RedactionConfig{
+//	    Keys:     []string{"iban", "ssn"},
+//	    Patterns: []string{`(?i)bearer [a-z0-9._~+/-]+=*`, `\b\d{13,19}\b`},
+//	}
+type RedactionConfig struct {
+	// Keys lists additional field keys whose value is redacted.
+	Keys []string
+	// Patterns lists regular expressions whose matches are redacted in string values
+	// and messages.
+	Patterns []string
+	// NoDefaultKeys disables DefaultRedactedKeys, so that only Keys are redacted.
+	NoDefaultKeys bool
+}
+
+// redactionFromEnv returns the redaction configured by LOG_REDACT and LOG_REDACT_KEYS, or
+// nil when neither is set.
+func redactionFromEnv() *RedactionConfig {
+	keys := parseList(os.Getenv("LOG_REDACT_KEYS"))
+	if len(keys) == 0 && getEnv("LOG_REDACT", "false") != "true" {
+		return nil
+	}
+	return &RedactionConfig{Keys: keys}
+}
+
+// parseList splits a comma-separated list, dropping empty items.
+func parseList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// keys returns the sorted redacted keys, lower-cased.
+func (c *RedactionConfig) keys() []string {
+	if c == nil {
+		return nil
+	}
+	var keys []string
+	if !c.NoDefaultKeys {
+		keys = append(keys, DefaultRedactedKeys...)
+	}
+	for _, key := range c.Keys {
+		keys = append(keys, strings.ToLower(key))
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// redactor is the compiled form of a RedactionConfig.
+type redactor struct {
+	keys     map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+// newRedactor compiles the configuration.
+func newRedactor(cfg *RedactionConfig) (*redactor, error) {
+	r := &redactor{keys: make(map[string]struct{})}
+	for _, key := range cfg.keys() {
+		r.keys[key] = struct{}{}
+	}
+	for _, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redaction: invalid pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	return r, nil
+}
+
+// redactsKey reports whether the value of key is redacted.
+func (r *redactor) redactsKey(key string) bool {
+	if _, ok := r.keys[key]; ok {
+		return true
+	}
+	_, ok := r.keys[strings.ToLower(key)]
+	return ok
+}
+
+// redactString replaces the matches of the patterns in s.
+func (r *redactor) redactString(s string) string {
+	for _, re := range r.patterns {
+		s = re.ReplaceAllLiteralString(s, Redacted)
+	}
+	return s
+}
+
+// field returns the redacted form of f, and whether it differs from f.
+func (r *redactor) field(f zapcore.Field) (zapcore.Field, bool) {
+	if r.redactsKey(f.Key) {
+		return zap.String(f.Key, Redacted), true
+	}
+	switch f.Type {
+	case zapcore.ObjectMarshalerType:
+		return zap.Object(f.Key, redactedObject{r: r, obj: f.Interface.(zapcore.ObjectMarshaler)}), true
+	case zapcore.InlineMarshalerType:
+		return zap.Inline(redactedObject{r: r, obj: f.Interface.(zapcore.ObjectMarshaler)}), true
+	}
+	if len(r.patterns) == 0 {
+		return f, false
+	}
+	var value string
+	switch f.Type {
+	case zapcore.StringType:
+		value = f.String
+	case zapcore.ByteStringType:
+		value = string(f.Interface.([]byte))
+	case zapcore.StringerType:
+		value = f.Interface.(fmt.Stringer).String()
+	case zapcore.ErrorType:
+		value = f.Interface.(error).Error()
+	default:
+		return f, false
+	}
+	redacted := r.redactString(value)
+	if redacted == value && f.Type == zapcore.StringType {
+		return f, false
+	}
+	return zap.String(f.Key, redacted), true
+}
+
+// apply returns the fields with the redactions applied.
+//
+// The input slice is never modified; a copy is made only when a field is changed.
+func (r *redactor) apply(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		redacted, changed := r.field(f)
+		if changed && out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		if out != nil {
+			out = append(out, redacted)
+		}
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// redactedObject redacts the fields of a nested object as it is encoded.
+type redactedObject struct {
+	r   *redactor
+	obj zapcore.ObjectMarshaler
+}
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (o redactedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	return o.obj.MarshalLogObject(redactingEncoder{ObjectEncoder: enc, r: o.r})
+}
+
+// redactingEncoder applies the redactions to the string fields of a nested object.
+type redactingEncoder struct {
+	zapcore.ObjectEncoder
+	r *redactor
+}
+
+// AddString implements zapcore.ObjectEncoder.
+func (e redactingEncoder) AddString(key, value string) {
+	if e.r.redactsKey(key) {
+		value = Redacted
+	} else {
+		value = e.r.redactString(value)
+	}
+	e.ObjectEncoder.AddString(key, value)
+}
+
+// AddByteString implements zapcore.ObjectEncoder.
+func (e redactingEncoder) AddByteString(key string, value []byte) {
+	e.AddString(key, string(value))
+}
+
+// AddReflected implements zapcore.ObjectEncoder.
+func (e redactingEncoder) AddReflected(key string, value any) error {
+	if e.r.redactsKey(key) {
+		e.ObjectEncoder.AddString(key, Redacted)
+		return nil
+	}
+	return e.ObjectEncoder.AddReflected(key, value)
+}
+
+// AddObject implements zapcore.ObjectEncoder.
+func (e redactingEncoder) AddObject(key string, obj zapcore.ObjectMarshaler) error {
+	if e.r.redactsKey(key) {
+		e.ObjectEncoder.AddString(key, Redacted)
+		return nil
+	}
+	return e.ObjectEncoder.AddObject(key, redactedObject{r: e.r, obj: obj})
+}
+
+// redactionCore wraps a core and redacts sensitive values.
+type redactionCore struct {
+	zapcore.Core
+	r *redactor
+}
+
+// newRedactionCore wraps core with the redactions described by cfg.
+func newRedactionCore(core zapcore.Core, cfg *RedactionConfig) (zapcore.Core, error) {
+	r, err := newRedactor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &redactionCore{Core: core, r: r}, nil
+}
+
+// With redacts contextual fields before passing them down.
+func (c *redactionCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactionCore{Core: c.Core.With(c.r.apply(fields)), r: c.r}
+}
+
+// Check registers this core so that Write sees every entry.
+func (c *redactionCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write redacts the entry's message and fields and writes it to the wrapped core.
+func (c *redactionCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.r.redactString(ent.Message)
+	return writeChecked(c.Core, ent, c.r.apply(fields))
+}
@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RetentionClass labels entries with the retention policy they belong to.
+//
+// The class is emitted as the `retention` field and can be promoted to a Loki stream
+// label or used to derive Elasticsearch ILM-aligned index names, so retention can be
+// enforced per log category from the emitter side.
+type RetentionClass string
+
+const (
+	// RetentionHot marks short-lived operational logs (default).
+	RetentionHot RetentionClass = "hot"
+	// RetentionWarm marks logs kept longer for trend analysis.
+	RetentionWarm RetentionClass = "warm"
+	// RetentionAudit marks logs subject to long-term audit retention.
+	RetentionAudit RetentionClass = "audit"
+)
+
+// retentionKey is the field name carrying the retention class.
+const retentionKey = "retention"
+
+// indexKey is the field name carrying the derived index name.
+const indexKey = "index_name"
+
+// Retention constructs the field tagging an entry with a retention class.
+//
+// Example:
+//
+//	log.Info("invoice issued", logger.Retention(logger.RetentionAudit))
+func Retention(class RetentionClass) zap.Field {
+	return zap.String(retentionKey, string(class))
+}
+
+// WithRetention returns a derived logger whose entries are tagged with the given retention class.
+func (l *Logger) WithRetention(class RetentionClass) *Logger {
+	return l.WithContext(Retention(class))
+}
+
+// IndexName returns an ILM-aligned index name for the given prefix, retention class and time,
+// in the form "<prefix>-<class>-YYYY.MM.DD".
+func IndexName(prefix string, class RetentionClass, t time.Time) string {
+	if class == "" {
+		class = RetentionHot
+	}
+	return prefix + "-" + string(class) + "-" + t.UTC().Format("2006.01.02")
+}
+
+// indexCore wraps an output core and attaches the index name derived from each entry's
+// retention class.
+type indexCore struct {
+	zapcore.Core
+	prefix string
+	class  RetentionClass
+}
+
+// newIndexCore wraps core so that every entry carries an index name with the given prefix.
+func newIndexCore(core zapcore.Core, prefix string, class RetentionClass) zapcore.Core {
+	return &indexCore{Core: core, prefix: prefix, class: class}
+}
+
+// With tracks retention classes attached as contextual fields.
+func (c *indexCore) With(fields []zapcore.Field) zapcore.Core {
+	return &indexCore{Core: c.Core.With(fields), prefix: c.prefix, class: retentionOf(fields, c.class)}
+}
+
+// Check registers this core so that Write sees every entry.
+func (c *indexCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write attaches the index name and writes the entry to the wrapped core.
+func (c *indexCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	class := retentionOf(fields, c.class)
+	out := make([]zapcore.Field, len(fields), len(fields)+1)
+	copy(out, fields)
+	out = append(out, zap.String(indexKey, IndexName(c.prefix, class, ent.Time)))
+	return c.Core.Write(ent, out)
+}
+
+// retentionOf returns the last retention class found in fields, or fallback if there is none.
+func retentionOf(fields []zapcore.Field, fallback RetentionClass) RetentionClass {
+	for i := len(fields) - 1; i >= 0; i-- {
+		if fields[i].Key == retentionKey && fields[i].Type == zapcore.StringType {
+			return RetentionClass(fields[i].String)
+		}
+	}
+	return fallback
+}
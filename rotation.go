@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"errors"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// defaultMaxSizeMB is the size at which a rotated file is rolled when MaxSizeMB is unset.
+const defaultMaxSizeMB = 100
+
+// RotationConfig enables size-based rotation for a file output.
+//
+// When the file reaches MaxSizeMB it is renamed with a timestamp suffix (e.g.
+// "app-2025-10-16T12-34-56.000.log") and a new file is started, so binaries running
+// without a log collector don't depend on logrotate.
+type RotationConfig struct {
+	// MaxSizeMB is the maximum size in megabytes of the file before it is rotated.
+	// Defaults to 100.
+	MaxSizeMB int
+	// MaxAge is the maximum age of rotated files, rounded up to whole days. Zero keeps
+	// files regardless of age.
+	MaxAge time.Duration
+	// MaxBackups is the maximum number of rotated files to retain. Zero retains all of
+	// them (subject to MaxAge).
+	MaxBackups int
+	// Compress gzips rotated files.
+	Compress bool
+}
+
+// openRotating opens path as a rotating file according to cfg.
+func openRotating(path string, cfg *RotationConfig) (zapcore.WriteSyncer, func(), error) {
+	if path == "stdout" || path == "stderr" {
+		return nil, nil, errors.New("rotation requires a file path")
+	}
+	maxSize := cfg.MaxSizeMB
+	if maxSize <= 0 {
+		maxSize = defaultMaxSizeMB
+	}
+	day := 24 * time.Hour
+	w := &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSize,
+		MaxAge:     int((cfg.MaxAge + day - 1) / day),
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+	}
+	return zapcore.AddSync(w), func() { _ = w.Close() }, nil
+}
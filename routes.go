@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"sync"
+)
+
+// RouteConfig overrides the middleware settings for the requests of a route.
+type RouteConfig struct {
+	// Level is the level of entries for successful requests of the route. Failed requests
+	// keep WARN (4xx) and ERROR (5xx). For example, LevelDebug hides a chatty endpoint
+	// unless debug logging is enabled. Defaults to INFO.
+	Level LogLevel
+	// DisableBodyCapture stops response bodies of failed requests from being attached,
+	// for endpoints whose payloads must never reach the logs.
+	DisableBodyCapture bool
+	// Rules suppress or sample entries of the route. They are evaluated before the
+	// middleware-wide rules.
+	Rules []AccessRule
+	// Streaming logs successful responses of the route as streams, with connection open
+	// and close entries, for long polls and chunked streams that are not server-sent
+	// events. Server-sent events and upgraded connections are detected without it.
+	Streaming bool
+}
+
+// RouteRegistry maps route patterns to per-route middleware settings.
+//
+// Patterns use the AccessRule.Path syntax and are matched against the request path (or
+// the full method name for gRPC, e.g. "/payments.v1.Payments/*"). When several patterns
+// match, the longest one wins. A RouteRegistry is safe for concurrent use, so routes can
+// be registered while requests are being served.
+//
+// Example:
+//
+//	routes := logger.NewRouteRegistry()
+//	routes.Register("/payments/*", logger.RouteConfig{DisableBodyCapture: true})
+//	routes.Register("/search", logger.RouteConfig{
+//	    Rules: []logger.AccessRule{{Statuses: []int{http.StatusOK}, SampleRate: 0.05}},
+//	})
+//	handler := logger.NewHTTPMiddleware(logger.HTTPConfig{Routes: routes})(mux)
+type RouteRegistry struct {
+	mu     sync.RWMutex
+	routes map[string]RouteConfig
+}
+
+// NewRouteRegistry returns an empty route registry.
+func NewRouteRegistry() *RouteRegistry {
+	return &RouteRegistry{routes: make(map[string]RouteConfig)}
+}
+
+// Register sets the configuration of the routes matching pattern, replacing any
+// configuration previously registered for the same pattern.
+func (r *RouteRegistry) Register(pattern string, cfg RouteConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[pattern] = cfg
+}
+
+// Unregister removes the configuration registered for pattern.
+func (r *RouteRegistry) Unregister(pattern string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.routes, pattern)
+}
+
+// Lookup returns the configuration of the most specific pattern matching route.
+func (r *RouteRegistry) Lookup(route string) (RouteConfig, bool) {
+	if r == nil {
+		return RouteConfig{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var best string
+	var found bool
+	for pattern := range r.routes {
+		if !matchPath(pattern, route) {
+			continue
+		}
+		if !found || len(pattern) > len(best) || (len(pattern) == len(best) && pattern < best) {
+			best, found = pattern, true
+		}
+	}
+	return r.routes[best], found
+}
+
+// successLevel returns the level of entries for successful requests of the route.
+func (c RouteConfig) successLevel() LogLevel {
+	if c.Level == "" {
+		return LevelInfo
+	}
+	return c.Level
+}
@@ -0,0 +1,204 @@
+package logger
+
+import (
+	"cmp"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of the sampling configuration.
+const (
+	defaultSamplingInitial    = 100
+	defaultSamplingThereafter = 100
+	defaultSamplingTick       = time.Second
+	// samplingCountersPerLevel is the number of counters messages are hashed into.
+	samplingCountersPerLevel = 4096
+)
+
+// SamplingConfig caps the volume of repeated entries, following zap's sampling model.
+//
+// Entries are grouped by level and message. Within each Tick, the first Initial entries
+// of a group are logged, then only every Thereafter-th entry. Entries exempt from volume
+// reduction (see SamplingExemptions) are never sampled away.
+//
+// Loggers labelled with a category (see WithCategory) can be sampled with their own
+// rates and counters, so that a chatty category neither floods the outputs nor uses up
+// the allowance of the others.
+//
+// Example (log the first 100 identical lines per second, then 1 in 1000):
+//
+//	Sampling: &logger.SamplingConfig{Initial: 100, Thereafter: 1000}
+type SamplingConfig struct {
+	// Initial is the number of entries per group logged each tick. Defaults to 100.
+	Initial int
+	// Thereafter is the sampling interval after Initial entries. Defaults to 100.
+	Thereafter int
+	// Tick is the sampling period. Defaults to 1s.
+	Tick time.Duration
+	// OnDropped, when set, is called for every entry sampled away, e.g. to count drops
+	// in a metric. It must be fast and must not log through the same logger.
+	OnDropped func(zapcore.Entry)
+	// Categories sets the sampling rates of the loggers labelled with a category through
+	// WithCategory. Zero rates default to Initial and Thereafter.
+	Categories map[string]SamplingRule
+}
+
+// SamplingRule sets the sampling rates of a category.
+type SamplingRule struct {
+	// Initial is the number of entries per group logged each tick.
+	Initial int
+	// Thereafter is the sampling interval after Initial entries.
+	Thereafter int
+}
+
+// samplingFromEnv returns the sampling configured by LOG_SAMPLING_INITIAL and
+// LOG_SAMPLING_THEREAFTER, or nil when neither is set.
+func samplingFromEnv() *SamplingConfig {
+	initial, initialErr := strconv.Atoi(os.Getenv("LOG_SAMPLING_INITIAL"))
+	thereafter, thereafterErr := strconv.Atoi(os.Getenv("LOG_SAMPLING_THEREAFTER"))
+	if initialErr != nil && thereafterErr != nil {
+		return nil
+	}
+	return &SamplingConfig{Initial: initial, Thereafter: thereafter}
+}
+
+// samplingCounter counts the entries of one group during the current tick.
+type samplingCounter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+// inc counts an entry at time t and returns the count within the current tick.
+func (c *samplingCounter) inc(t time.Time, tick time.Duration) uint64 {
+	now := t.UnixNano()
+	resetAt := c.resetAt.Load()
+	if resetAt > now {
+		return c.count.Add(1)
+	}
+	c.count.Store(1)
+	if !c.resetAt.CompareAndSwap(resetAt, now+tick.Nanoseconds()) {
+		// Another goroutine started the new tick concurrently.
+		return c.count.Add(1)
+	}
+	return 1
+}
+
+// sampler holds the counters shared by all cores derived from a sampling core.
+type sampler struct {
+	initial    uint64
+	thereafter uint64
+	tick       time.Duration
+	counters   [zapcore.FatalLevel - zapcore.DebugLevel + 1][samplingCountersPerLevel]samplingCounter
+}
+
+// newSampler applies the configuration defaults.
+func newSampler(cfg *SamplingConfig) *sampler {
+	s := &sampler{initial: defaultSamplingInitial, thereafter: defaultSamplingThereafter, tick: defaultSamplingTick}
+	if cfg.Initial > 0 {
+		s.initial = uint64(cfg.Initial)
+	}
+	if cfg.Thereafter > 0 {
+		s.thereafter = uint64(cfg.Thereafter)
+	}
+	if cfg.Tick > 0 {
+		s.tick = cfg.Tick
+	}
+	return s
+}
+
+// sample reports whether the entry is kept.
+func (s *sampler) sample(ent zapcore.Entry) bool {
+	if ent.Level < zapcore.DebugLevel || ent.Level > zapcore.FatalLevel {
+		return true
+	}
+	n := s.counters[ent.Level-zapcore.DebugLevel][fnv32a(ent.Message)%samplingCountersPerLevel].inc(ent.Time, s.tick)
+	return n <= s.initial || (n-s.initial)%s.thereafter == 0
+}
+
+// fnv32a hashes s with FNV-1a without allocating.
+func fnv32a(s string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= prime32
+	}
+	return h
+}
+
+// samplingCore drops repeated entries, except those exempt from volume reduction.
+type samplingCore struct {
+	zapcore.Core
+	s *sampler
+	// samplers holds the samplers by category, the default one under "".
+	samplers map[string]*sampler
+	// dropped writes the exempt entries among those sampled away.
+	dropped *suppressedCore
+}
+
+// newSamplingCore wraps core with the sampling described by cfg.
+func newSamplingCore(core zapcore.Core, cfg *SamplingConfig, set *exemptionSet) zapcore.Core {
+	s := newSampler(cfg)
+	samplers := map[string]*sampler{"": s}
+	for category, rule := range cfg.Categories {
+		samplers[category] = newSampler(&SamplingConfig{
+			Initial:    cmp.Or(rule.Initial, cfg.Initial),
+			Thereafter: cmp.Or(rule.Thereafter, cfg.Thereafter),
+			Tick:       cfg.Tick,
+		})
+	}
+	return &samplingCore{
+		Core:     core,
+		s:        s,
+		samplers: samplers,
+		dropped:  &suppressedCore{Core: core, set: set, onDrop: cfg.OnDropped},
+	}
+}
+
+// With implements zapcore.Core. Derived cores share the counters, and switch to those of
+// a category attached as a contextual field.
+func (c *samplingCore) With(fields []zapcore.Field) zapcore.Core {
+	core := c.Core.With(fields)
+	s := c.s
+	if category := categoryOf(fields, ""); category != "" {
+		if s = c.samplers[category]; s == nil {
+			s = c.samplers[""]
+		}
+	}
+	return &samplingCore{
+		Core:     core,
+		s:        s,
+		samplers: c.samplers,
+		dropped:  &suppressedCore{Core: core, set: c.dropped.set, onDrop: c.dropped.onDrop},
+	}
+}
+
+// Check implements zapcore.Core.
+func (c *samplingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	if c.s.sample(ent) {
+		return c.Core.Check(ent, ce)
+	}
+	// Exemptions may depend on the fields, which are only known when writing.
+	return ce.AddCore(ent, c.dropped)
+}
+
+// writeChecked implements checkedWriter.
+func (c *samplingCore) writeChecked(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+	if c.s.sample(ent) {
+		return writeChecked(c.Core, ent, fields)
+	}
+	return c.dropped.Write(ent, fields)
+}
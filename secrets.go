@@ -0,0 +1,208 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// SecretCheckMode selects how credentials embedded in the configuration are handled (see
+// Config.SecretCheck).
+type SecretCheckMode string
+
+const (
+	// SecretCheckReject refuses configurations whose static fields carry a secret
+	// (default): such a secret would be attached to every entry.
+	SecretCheckReject SecretCheckMode = ""
+	// SecretCheckMask replaces the secrets found in static fields with [REDACTED].
+	SecretCheckMask SecretCheckMode = "mask"
+	// SecretCheckOff disables the detection in static fields. Credentials in output URLs
+	// are still masked in the banner and in errors.
+	SecretCheckOff SecretCheckMode = "off"
+)
+
+// urlMask replaces credentials in the output URLs shown by the logger, like
+// url.URL.Redacted.
+const urlMask = "xxxxx"
+
+// secretKeyParts are the substrings of keys whose values are treated as secrets, in
+// static fields and in URL query parameters.
+var secretKeyParts = []string{
+	"password", "passwd", "secret", "token", "api_key", "apikey", "api-key",
+	"authorization", "credential", "private_key", "access_key", "signature",
+}
+
+// secretPatterns detect well-known credential formats in static field values.
+var secretPatterns = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"bearer token", regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9._~+/-]{8,}`)},
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+	{"JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]+`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"URL credentials", regexp.MustCompile(`(?i)\b[a-z][a-z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`)},
+}
+
+// secretKey reports whether the values of key are treated as secrets.
+func secretKey(key string) bool {
+	key = strings.ToLower(key)
+	return slices.ContainsFunc(secretKeyParts, func(part string) bool { return strings.Contains(key, part) })
+}
+
+// detectSecret returns the kind of secret the value of a static field carries, or "".
+func detectSecret(key string, value any) string {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return ""
+		}
+		if secretKey(key) {
+			return "secret key " + key
+		}
+		for _, p := range secretPatterns {
+			if p.re.MatchString(v) {
+				return p.kind
+			}
+		}
+	case map[string]any:
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			if kind := detectSecret(k, v[k]); kind != "" {
+				return kind
+			}
+		}
+	case []any:
+		for _, item := range v {
+			if kind := detectSecret(key, item); kind != "" {
+				return kind
+			}
+		}
+	}
+	return ""
+}
+
+// checkSecrets applies the SecretCheck mode to the static fields of the configuration:
+// the service name, the inherited fields, the constants added by field mappings and the
+// Loki labels. It returns the configuration to build, with the secrets masked in mask
+// mode, or an error naming the offending field in reject mode. The caller's maps are not
+// modified.
+func checkSecrets(cfg Config) (Config, error) {
+	switch cfg.SecretCheck {
+	case SecretCheckOff:
+		return cfg, nil
+	case SecretCheckReject, SecretCheckMask:
+	default:
+		return cfg, fmt.Errorf("failed to build logger: unknown secret check mode %q", cfg.SecretCheck)
+	}
+	mask := cfg.SecretCheck == SecretCheckMask
+	var err error
+	// check reports a secret, masking it in mask mode.
+	check := func(where, key string, value any) (any, bool) {
+		kind := detectSecret(key, value)
+		switch {
+		case kind == "":
+			return value, false
+		case mask:
+			return Redacted, true
+		default:
+			if err == nil {
+				err = fmt.Errorf("failed to build logger: %s looks like a secret (%s), which would be attached to every entry; set SecretCheck to %q to mask it", where, kind, SecretCheckMask)
+			}
+			return value, false
+		}
+	}
+	// checkMap checks the values of a map, returning a masked copy if needed.
+	checkMap := func(where string, m map[string]any) (map[string]any, bool) {
+		var masked map[string]any
+		for _, key := range slices.Sorted(maps.Keys(m)) {
+			if value, ok := check(fmt.Sprintf("%s %q", where, key), key, m[key]); ok {
+				if masked == nil {
+					masked = maps.Clone(m)
+				}
+				masked[key] = value
+			}
+		}
+		return masked, masked != nil
+	}
+
+	if value, ok := check("ServiceName", "service", cfg.ServiceName); ok {
+		cfg.ServiceName = value.(string)
+	}
+	if masked, ok := checkMap("inherited field", cfg.Inherited); ok {
+		cfg.Inherited = masked
+	}
+	cfg.Outputs = slices.Clone(cfg.Outputs)
+	for i := range cfg.Outputs {
+		out := &cfg.Outputs[i]
+		if out.Mapping != nil {
+			if masked, ok := checkMap(fmt.Sprintf("output %d: mapping field", i), out.Mapping.Add); ok {
+				m := *out.Mapping
+				m.Add = masked
+				out.Mapping = &m
+			}
+		}
+		if out.Loki != nil {
+			var labels map[string]string
+			for _, key := range slices.Sorted(maps.Keys(out.Loki.Labels)) {
+				if value, ok := check(fmt.Sprintf("output %d: Loki label %q", i, key), key, out.Loki.Labels[key]); ok {
+					if labels == nil {
+						labels = maps.Clone(out.Loki.Labels)
+					}
+					labels[key] = value.(string)
+				}
+			}
+			if labels != nil {
+				l := *out.Loki
+				l.Labels = labels
+				out.Loki = &l
+			}
+		}
+	}
+	return cfg, err
+}
+
+// maskURL masks the password and the secret query parameters of a URL, for the banner
+// and errors. Other strings are returned as is.
+func maskURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return s
+	}
+	masked := false
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), urlMask)
+		masked = true
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for key := range q {
+			if secretKey(key) {
+				q.Set(key, urlMask)
+				masked = true
+			}
+		}
+		if masked {
+			u.RawQuery = q.Encode()
+		}
+	}
+	if !masked {
+		return s
+	}
+	return u.String()
+}
+
+// maskError returns err with the URL s masked in its message, for errors of libraries
+// quoting the URL they failed to open.
+func maskError(err error, s string) error {
+	masked := maskURL(s)
+	if masked == s || !strings.Contains(err.Error(), s) {
+		return err
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), s, masked))
+}
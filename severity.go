@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// SeverityProfile maps levels to the severities of a destination, so that an entry is
+// written with the severity each backend expects without post-processing (see
+// OutputConfig.Severity). Levels missing from the profile keep their name.
+//
+// Example:
+//
+//	cfg.Outputs = []logger.OutputConfig{
+//	    {Path: "stdout"},
+//	    {Path: "/var/log/app/alerts.log", Severity: logger.SeverityPagerDuty},
+//	}
+type SeverityProfile map[LogLevel]string
+
+// Built-in severity profiles.
+var (
+	// SeveritySyslog maps levels to the RFC 5424 severity keywords.
+	SeveritySyslog = SeverityProfile{
+		LevelDebug: "debug",
+		LevelInfo:  "info",
+		LevelWarn:  "warning",
+		LevelError: "err",
+		"DPANIC":   "crit",
+		"PANIC":    "alert",
+		"FATAL":    "emerg",
+	}
+	// SeverityGCP maps levels to Google Cloud Logging severities. It is the profile of
+	// the "gcp" encoding.
+	SeverityGCP = SeverityProfile{
+		LevelDebug: "DEBUG",
+		LevelInfo:  "INFO",
+		LevelWarn:  "WARNING",
+		LevelError: "ERROR",
+		"DPANIC":   "CRITICAL",
+		"PANIC":    "ALERT",
+		"FATAL":    "EMERGENCY",
+	}
+	// SeverityPagerDuty maps levels to the severities of PagerDuty events.
+	SeverityPagerDuty = SeverityProfile{
+		LevelDebug: "info",
+		LevelInfo:  "info",
+		LevelWarn:  "warning",
+		LevelError: "error",
+		"DPANIC":   "critical",
+		"PANIC":    "critical",
+		"FATAL":    "critical",
+	}
+)
+
+// severityProfiles holds the built-in profiles by name, for SeverityProfileByName.
+var severityProfiles = map[string]SeverityProfile{
+	"syslog":    SeveritySyslog,
+	"gcp":       SeverityGCP,
+	"pagerduty": SeverityPagerDuty,
+}
+
+// SeverityProfileByName returns the built-in profile with the given name: "syslog",
+// "gcp" or "pagerduty". It reports false for unknown names.
+func SeverityProfileByName(name string) (SeverityProfile, bool) {
+	p, ok := severityProfiles[name]
+	return p, ok
+}
+
+// Severity returns the severity of a level, or the level name when the profile does not
+// map it. Sinks can use it to honor a profile.
+func (p SeverityProfile) Severity(level LogLevel) string {
+	if severity, ok := p[level]; ok {
+		return severity
+	}
+	return string(level)
+}
+
+// encodeLevel encodes levels with the profile.
+func (p SeverityProfile) encodeLevel(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendString(p.Severity(LogLevel(l.CapitalString())))
+}
+
+// encoderConfig makes an encoder configuration write the severities of the profile.
+func (p SeverityProfile) encoderConfig(cfg zapcore.EncoderConfig) zapcore.EncoderConfig {
+	if p != nil {
+		cfg.EncodeLevel = p.encodeLevel
+	}
+	return cfg
+}
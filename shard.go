@@ -0,0 +1,197 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of sharded output buffers.
+const (
+	defaultShardSize          = 32 << 10
+	defaultShardFlushInterval = 200 * time.Millisecond
+)
+
+// BufferConfig buffers the writes of an output in shards, so that many goroutines
+// logging concurrently at high rates do not contend on a single lock and pay a write
+// system call per entry.
+//
+// Each entry is appended to one of several buffers (one per processor by default), the
+// first one found unlocked starting from a random shard. A background flusher merges
+// the shards into the destination every FlushInterval; a shard is also written as soon
+// as it is full. Entries stay whole lines but entries from different goroutines may be
+// written out of order: rely on their timestamps.
+//
+// Entries still buffered when the process crashes are lost, except those above ERROR,
+// which sync the outputs. Sync writes all shards. The buffer is not used in async mode,
+// where a single goroutine writes the outputs in batches.
+//
+// Example:
+//
+//	{Path: "/var/log/app/app.log", Buffer: &logger.BufferConfig{FlushInterval: time.Second}}
+type BufferConfig struct {
+	// Shards is the number of buffers. Defaults to GOMAXPROCS.
+	Shards int
+	// Size is the capacity of each buffer in bytes. Defaults to 32 KiB.
+	Size int
+	// FlushInterval is the maximum time an entry stays buffered. Defaults to 200ms.
+	FlushInterval time.Duration
+}
+
+// writeShard is one of the buffers of a shardedWriter.
+type writeShard struct {
+	mu  sync.Mutex
+	buf []byte
+	// Pad to a cache line, so that shards locked by different processors do not share one.
+	_ [64 - 8 - 24]byte
+}
+
+// shardedWriter buffers writes in shards and merges them into its destination.
+type shardedWriter struct {
+	name   string
+	ws     zapcore.WriteSyncer
+	size   int
+	shards []writeShard
+
+	// wmu serializes the writes to ws.
+	wmu sync.Mutex
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// newShardedWriter wraps ws with the buffering described by cfg and starts its flusher.
+func newShardedWriter(name string, ws zapcore.WriteSyncer, cfg *BufferConfig) *shardedWriter {
+	shards, size, interval := cfg.Shards, cfg.Size, cfg.FlushInterval
+	if shards <= 0 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+	if size <= 0 {
+		size = defaultShardSize
+	}
+	if interval <= 0 {
+		interval = defaultShardFlushInterval
+	}
+	w := &shardedWriter{
+		name:    name,
+		ws:      ws,
+		size:    size,
+		shards:  make([]writeShard, shards),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go w.run(interval)
+	return w
+}
+
+// Write implements zapcore.WriteSyncer. p is a single encoded entry.
+func (w *shardedWriter) Write(p []byte) (int, error) {
+	s := w.lock()
+	defer s.mu.Unlock()
+	if len(s.buf)+len(p) > w.size && len(s.buf) > 0 {
+		if err := w.writeShard(s); err != nil {
+			return 0, err
+		}
+	}
+	if len(p) >= w.size {
+		// Too large to be buffered.
+		return len(p), w.write(p)
+	}
+	s.buf = append(s.buf, p...)
+	return len(p), nil
+}
+
+// lock locks and returns the first unlocked shard starting from a random one, or waits
+// for the random one when they are all locked.
+func (w *shardedWriter) lock() *writeShard {
+	start := rand.N(len(w.shards))
+	for i := range w.shards {
+		s := &w.shards[(start+i)%len(w.shards)]
+		if s.mu.TryLock() {
+			return s
+		}
+	}
+	s := &w.shards[start]
+	s.mu.Lock()
+	return s
+}
+
+// writeShard writes the content of a locked shard to the destination and empties it.
+func (w *shardedWriter) writeShard(s *writeShard) error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+	err := w.write(s.buf)
+	if cap(s.buf) > 2*w.size {
+		s.buf = nil
+	} else {
+		s.buf = s.buf[:0]
+	}
+	return err
+}
+
+// write writes p to the destination.
+func (w *shardedWriter) write(p []byte) error {
+	w.wmu.Lock()
+	defer w.wmu.Unlock()
+	_, err := w.ws.Write(p)
+	return err
+}
+
+// flush writes every shard to the destination.
+func (w *shardedWriter) flush() error {
+	var errs []error
+	for i := range w.shards {
+		s := &w.shards[i]
+		s.mu.Lock()
+		if err := w.writeShard(s); err != nil {
+			errs = append(errs, err)
+		}
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// run flushes the shards every interval.
+func (w *shardedWriter) run(interval time.Duration) {
+	defer close(w.stopped)
+	labelGoroutine("flusher")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.flush(); err != nil {
+				fmt.Fprintf(os.Stderr, "logger: failed to write buffered entries to %s: %v\n", w.name, err)
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Sync implements zapcore.WriteSyncer. It writes every shard before syncing.
+func (w *shardedWriter) Sync() error {
+	err := w.flush()
+	w.wmu.Lock()
+	defer w.wmu.Unlock()
+	return errors.Join(err, w.ws.Sync())
+}
+
+// close stops the flusher and writes the remaining entries.
+func (w *shardedWriter) close() {
+	w.closeOnce.Do(func() {
+		close(w.done)
+		<-w.stopped
+		if err := w.flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "logger: failed to write buffered entries to %s: %v\n", w.name, err)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Shutdown flushes and closes the outputs of the global logger: it waits for the entries
+// being written, drains the async queue, flushes the batches of remote sinks and closes
+// files and connections. It gives up once ctx is done, returning its error, while
+// closing goes on in the background.
+//
+// Unlike Sync, it also waits for sinks holding in-flight batches, which only flush them
+// on Close. It is meant to be called once, right before the process exits; entries logged
+// after it are lost. A later Reconfigure or InitGlobal installs new outputs.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	if err := logger.Shutdown(ctx); err != nil {
+//	    fmt.Fprintln(os.Stderr, err)
+//	}
+func Shutdown(ctx context.Context) error {
+	g := globalState.Load()
+	if g == nil {
+		return nil
+	}
+	return g.core.shutdown(ctx)
+}
+
+// Shutdown flushes and closes the outputs of the logger, as the package-level Shutdown
+// does for the global logger. Loggers derived from l share its outputs: they must not be
+// used afterwards. For loggers not owning their outputs, such as those returned by
+// NewFromSlog, it only syncs them.
+func (l *Logger) Shutdown(ctx context.Context) error {
+	if l.shutdown == nil {
+		return l.Sync()
+	}
+	return l.shutdown(ctx)
+}
+
+// shutdown shuts down the current pipeline.
+func (c *reloadableCore) shutdown(ctx context.Context) error {
+	return c.current.Load().shutdown(ctx)
+}
+
+// shutdown waits for in-flight writes, then flushes and closes the pipeline, until ctx is
+// done.
+func (p *pipeline) shutdown(ctx context.Context) error {
+	for p.inflight.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to shut down logger: %w", ctx.Err())
+		case <-time.After(time.Millisecond):
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.close()
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to shut down logger: %w", ctx.Err())
+	}
+}
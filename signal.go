@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultShutdownFlushTimeout bounds the final flush of EnableShutdownFlush.
+const defaultShutdownFlushTimeout = 5 * time.Second
+
+// EnableSignalControl installs a signal listener controlling the global logger, for
+// long-running daemons without an HTTP admin port:
+//
+//   - SIGUSR1 flips the level between INFO and DEBUG.
+//   - SIGHUP rebuilds the global logger from the environment (see FromEnv and Reconfigure).
+//
+// It returns a function removing the listener. Signals are not supported on Windows,
+// where EnableSignalControl does nothing.
+//
+// Example:
+//
+//	stop := logger.EnableSignalControl()
+//	defer stop()
+//
+//	// kill -USR1 <pid>
+func EnableSignalControl() (stop func()) {
+	if len(controlSignals) == 0 {
+		return func() {}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, controlSignals...)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case sig := <-ch:
+				handleControlSignal(sig)
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
+
+// toggleDebug flips the global level between INFO and DEBUG.
+func toggleDebug() {
+	level := LevelDebug
+	if CurrentLevel() == LevelDebug {
+		level = LevelInfo
+	}
+	SetLevel(level)
+	Get().Info("log level changed", zap.String("level", string(level)), zap.String("trigger", "signal"))
+}
+
+// reloadFromEnv rebuilds the global logger from the environment.
+func reloadFromEnv() {
+	if err := Reconfigure(FromEnv()); err != nil {
+		Get().Error("logger reconfiguration failed", zap.String("trigger", "signal"), zap.Error(err))
+		return
+	}
+	Get().Info("logger reconfigured", zap.String("trigger", "signal"))
+}
+
+// EnableShutdownFlush installs a listener for SIGTERM and SIGINT which, before the
+// process stops, writes a final "shutting down" entry and shuts the global logger down
+// (see Shutdown), waiting at most timeout (5s when zero or negative) for the queued
+// entries and in-flight batches to be written. The signal is then raised again, so that
+// the process stops as it would have without the listener, or the application's own
+// handler runs. Otherwise, the entries still buffered by async mode, batching or remote
+// sinks are lost when a container is stopped.
+//
+// Entries logged after the signal are lost: applications logging from their own shutdown
+// handler should call Shutdown themselves instead.
+//
+// It returns a function removing the listener.
+//
+// Example:
+//
+//	stop := logger.EnableShutdownFlush(3 * time.Second)
+//	defer stop()
+func EnableShutdownFlush(timeout time.Duration) (stop func()) {
+	if timeout <= 0 {
+		timeout = defaultShutdownFlushTimeout
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGTERM, os.Interrupt)
+	done := make(chan struct{})
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+	go func() {
+		select {
+		case sig := <-ch:
+			flushOnSignal(sig, timeout)
+			stop()
+			raise(sig)
+		case <-done:
+		}
+	}()
+	return stop
+}
+
+// flushOnSignal writes the final entry and shuts the global logger down, waiting at most
+// timeout.
+func flushOnSignal(sig os.Signal, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	logged := make(chan struct{})
+	go func() {
+		Get().Info("shutting down", zap.String("signal", sig.String()), zap.String("trigger", "signal"))
+		close(logged)
+	}()
+	select {
+	case <-logged:
+	case <-ctx.Done():
+	}
+	if err := Shutdown(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "logger: final flush failed: %v\n", err)
+	}
+}
+
+// raise sends sig to the process again, exiting when it cannot be delivered.
+func raise(sig os.Signal) {
+	p, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = p.Signal(sig)
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
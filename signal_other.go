@@ -0,0 +1,11 @@
+//go:build !unix
+
+package logger
+
+import "os"
+
+// controlSignals is empty: SIGUSR1 and SIGHUP are not available on this platform.
+var controlSignals []os.Signal
+
+// handleControlSignal is never called on this platform.
+func handleControlSignal(os.Signal) {}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package logger
+
+import (
+	"os"
+	"syscall"
+)
+
+// controlSignals are the signals handled by EnableSignalControl.
+var controlSignals = []os.Signal{syscall.SIGUSR1, syscall.SIGHUP}
+
+// handleControlSignal applies the action bound to sig.
+func handleControlSignal(sig os.Signal) {
+	switch sig {
+	case syscall.SIGUSR1:
+		toggleDebug()
+	case syscall.SIGHUP:
+		reloadFromEnv()
+	}
+}
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// Sink is a destination implemented outside this package, such as a remote log service
+// provided by an integration subpackage. Integrations with heavy dependencies (gRPC,
+// Kafka, cloud SDKs) live in their own packages so that binaries only logging to the
+// console or to files do not link them. Sink is part of the stable extension interface
+// (see Entry).
+//
+// The logger checks the output's level before calling Write, and calls Close once, after
+// the last Write, when the logger is reconfigured or shut down.
+type Sink interface {
+	// Write writes an entry. encoded holds the entry encoded with the output's Encoding
+	// or Encoder, terminated by a newline; it must not be retained after Write returns.
+	// Write must be safe for concurrent use.
+	Write(e Entry, encoded []byte) error
+	// Sync flushes the buffered entries.
+	Sync() error
+	// Close flushes the buffered entries and releases the resources of the sink.
+	Close() error
+	// String describes the destination in errors and the startup banner.
+	String() string
+}
+
+// SinkFactory opens the sink described by a URL.
+type SinkFactory func(u *url.URL) (Sink, error)
+
+// sinkFactories holds the factories registered by URL scheme.
+var (
+	sinkFactoriesMu sync.RWMutex
+	sinkFactories   = make(map[string]SinkFactory)
+)
+
+// RegisterSink makes outputs whose Path is a URL with the given scheme use the sinks
+// opened by factory, e.g. "kafka://broker:9092/logs". Integration packages call it from
+// an init function, so importing them is enough to enable their URLs. It fails when the
+// scheme is already registered.
+//
+// Example:
+//
+//	func init() {
+//	    _ = logger.RegisterSink("kafka", func(u *url.URL) (logger.Sink, error) {
+//	        return newKafkaSink(u)
+//	    })
+//	}
+func RegisterSink(scheme string, factory SinkFactory) error {
+	sinkFactoriesMu.Lock()
+	defer sinkFactoriesMu.Unlock()
+	if _, ok := sinkFactories[scheme]; ok {
+		return fmt.Errorf("sink scheme %q already registered", scheme)
+	}
+	sinkFactories[scheme] = factory
+	return nil
+}
+
+// sinkFactory returns the factory registered for the scheme of path, if any, and the
+// parsed URL.
+func sinkFactory(path string) (SinkFactory, *url.URL) {
+	u, err := url.Parse(path)
+	if err != nil || u.Scheme == "" {
+		return nil, nil
+	}
+	sinkFactoriesMu.RLock()
+	defer sinkFactoriesMu.RUnlock()
+	return sinkFactories[u.Scheme], u
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by this package.
+const instrumentationName = "github.com/matteocavestri/logger-gath-test"
+
+// SinkInstrumentation records the batch flushes of remote sinks (such as Loki), so slow
+// log shipping shows up in traces and metrics and can be told apart from slow
+// application code.
+//
+// Example:
+//
+//	SinkInstrumentation: &logger.SinkInstrumentation{
+//	    TracerProvider: otel.GetTracerProvider(),
+//	    OnFlush: func(s logger.FlushStats) {
+//	        flushDuration.WithLabelValues(s.Sink).Observe(s.Duration.Seconds())
+//	    },
+//	}
+type SinkInstrumentation struct {
+	// TracerProvider, when set, creates a "logger.flush <sink>" span around every flush.
+	// Flushes run in the background, so each span starts a new trace.
+	TracerProvider trace.TracerProvider
+	// OnFlush, when set, is called after every flush with its statistics.
+	OnFlush func(FlushStats)
+}
+
+// FlushStats describes a single batch flush of a remote sink.
+type FlushStats struct {
+	// Sink names the sink, e.g. "loki".
+	Sink string
+	// Entries is the number of entries in the batch.
+	Entries int
+	// Bytes is the size of the encoded batch.
+	Bytes int
+	// Attempts is the number of delivery attempts, including retries.
+	Attempts int
+	// Duration is the time spent flushing the batch, including retries.
+	Duration time.Duration
+	// Err is the final error when the batch could not be delivered.
+	Err error
+}
+
+// flushRecorder records a single flush. A nil recorder records nothing.
+type flushRecorder struct {
+	si    *SinkInstrumentation
+	span  trace.Span
+	stats FlushStats
+	start time.Time
+}
+
+// startFlush starts recording a flush of the given number of entries and returns the
+// context to use for the delivery requests.
+func (si *SinkInstrumentation) startFlush(sink string, entries int) (context.Context, *flushRecorder) {
+	ctx := context.Background()
+	if si == nil {
+		return ctx, nil
+	}
+	r := &flushRecorder{si: si, stats: FlushStats{Sink: sink, Entries: entries}, start: time.Now()}
+	if si.TracerProvider != nil {
+		ctx, r.span = si.TracerProvider.Tracer(instrumentationName).Start(ctx, "logger.flush "+sink,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				attribute.String("logger.sink", sink),
+				attribute.Int("logger.entries", entries),
+			))
+	}
+	return ctx, r
+}
+
+// attempt records a delivery attempt of the encoded batch.
+func (r *flushRecorder) attempt(bytes int) {
+	if r == nil {
+		return
+	}
+	r.stats.Attempts++
+	r.stats.Bytes = bytes
+}
+
+// end finishes recording the flush.
+func (r *flushRecorder) end(err error) {
+	if r == nil {
+		return
+	}
+	r.stats.Duration = time.Since(r.start)
+	r.stats.Err = err
+	if r.span != nil {
+		r.span.SetAttributes(
+			attribute.Int("logger.bytes", r.stats.Bytes),
+			attribute.Int("logger.attempts", r.stats.Attempts),
+		)
+		if err != nil {
+			r.span.RecordError(err)
+			r.span.SetStatus(codes.Error, err.Error())
+		}
+		r.span.End()
+	}
+	if r.si.OnFlush != nil {
+		r.si.OnFlush(r.stats)
+	}
+}
@@ -0,0 +1,295 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// NewSlogHandler returns a slog.Handler writing through the core of l, so libraries that
+// log with log/slog share the encoders, outputs and enrichment fields of the logger.
+//
+// slog levels are mapped to the closest zap level at or below them. Trace fields are
+// attached when the context passed to the slog call carries a span.
+//
+// Example:
+//
+//	slog.SetDefault(slog.New(logger.NewSlogHandler(logger.Get())))
+func NewSlogHandler(l *Logger) slog.Handler {
+	return &slogHandler{core: l.Core(), name: l.Name()}
+}
+
+// slogHandler implements slog.Handler on top of a zapcore.Core.
+type slogHandler struct {
+	core zapcore.Core
+	name string
+	// groups are opened by WithGroup but not yet applied to core, so that groups
+	// without attributes are omitted as slog requires.
+	groups []string
+}
+
+// Enabled implements slog.Handler.
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.core.Enabled(zapLevelFromSlog(level))
+}
+
+// Handle implements slog.Handler.
+func (h *slogHandler) Handle(ctx context.Context, r slog.Record) error {
+	ent := zapcore.Entry{
+		LoggerName: h.name,
+		Time:       r.Time,
+		Level:      zapLevelFromSlog(r.Level),
+		Message:    r.Message,
+	}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		ent.Caller = zapcore.NewEntryCaller(frame.PC, frame.File, frame.Line, true)
+		ent.Caller.Function = frame.Function
+	}
+	ce := h.core.Check(ent, nil)
+	if ce == nil {
+		return nil
+	}
+
+	fields := make([]zap.Field, 0, r.NumAttrs()+len(h.groups))
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, a)
+		return true
+	})
+	if len(fields) > 0 && len(h.groups) > 0 {
+		fields = append(h.namespaces(), fields...)
+	}
+	// Trace fields belong to the entry, not to the innermost group.
+	fields = append(TraceFields(ctx), fields...)
+	ce.Write(fields...)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var fields []zap.Field
+	for _, a := range attrs {
+		fields = appendSlogAttr(fields, a)
+	}
+	if len(fields) == 0 {
+		return h
+	}
+	return &slogHandler{core: h.core.With(append(h.namespaces(), fields...)), name: h.name}
+}
+
+// WithGroup implements slog.Handler.
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{core: h.core, name: h.name, groups: append(slices.Clip(h.groups), name)}
+}
+
+// namespaces returns the fields opening the pending groups.
+func (h *slogHandler) namespaces() []zap.Field {
+	fields := make([]zap.Field, len(h.groups))
+	for i, g := range h.groups {
+		fields[i] = zap.Namespace(g)
+	}
+	return fields
+}
+
+// appendSlogAttr converts a slog attribute to zap fields. Empty attributes and groups are
+// dropped and groups with an empty key are inlined, following the slog.Handler rules.
+func appendSlogAttr(fields []zap.Field, a slog.Attr) []zap.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindString:
+		return append(fields, zap.String(a.Key, v.String()))
+	case slog.KindInt64:
+		return append(fields, zap.Int64(a.Key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, zap.Uint64(a.Key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, zap.Float64(a.Key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, zap.Bool(a.Key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, zap.Duration(a.Key, v.Duration()))
+	case slog.KindTime:
+		return append(fields, zap.Time(a.Key, v.Time()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		if len(attrs) == 0 {
+			return fields
+		}
+		if a.Key == "" {
+			for _, ga := range attrs {
+				fields = appendSlogAttr(fields, ga)
+			}
+			return fields
+		}
+		return append(fields, zap.Object(a.Key, slogGroup(attrs)))
+	default:
+		return append(fields, zap.Any(a.Key, v.Any()))
+	}
+}
+
+// slogGroup encodes the attributes of a slog group as a nested object.
+type slogGroup []slog.Attr
+
+// MarshalLogObject implements zapcore.ObjectMarshaler.
+func (g slogGroup) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	var fields []zap.Field
+	for _, a := range g {
+		fields = appendSlogAttr(fields, a)
+	}
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return nil
+}
+
+// zapLevelFromSlog maps a slog level to the closest zap level at or below it.
+func zapLevelFromSlog(level slog.Level) zapcore.Level {
+	switch {
+	case level < slog.LevelInfo:
+		return zapcore.DebugLevel
+	case level < slog.LevelWarn:
+		return zapcore.InfoLevel
+	case level < slog.LevelError:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+// slogLevelFromZap maps a zap level to a slog level. Levels above ERROR map to
+// slog.LevelError+1 (DPANIC) through slog.LevelError+3 (FATAL).
+func slogLevelFromZap(level zapcore.Level) slog.Level {
+	switch level {
+	case zapcore.DebugLevel:
+		return slog.LevelDebug
+	case zapcore.InfoLevel:
+		return slog.LevelInfo
+	case zapcore.WarnLevel:
+		return slog.LevelWarn
+	case zapcore.ErrorLevel:
+		return slog.LevelError
+	default:
+		return slog.LevelError + slog.Level(level-zapcore.ErrorLevel)
+	}
+}
+
+// NewFromSlog returns a Logger writing to the handler of sl, for applications that
+// standardized on log/slog but use libraries expecting this package's Logger.
+//
+// Fields are converted to slog attributes and namespaces to groups. The caller, logger
+// name and stacktrace of entries are forwarded as the record PC and the `logger` and
+// `stacktrace` attributes.
+func NewFromSlog(sl *slog.Logger) *Logger {
+	// No caller skip: the returned logger is used directly rather than through the
+	// package-level functions.
+	return &Logger{Logger: zap.New(&slogCore{h: sl.Handler()}, zap.AddCaller())}
+}
+
+// slogCore implements zapcore.Core on top of a slog.Handler.
+type slogCore struct {
+	h slog.Handler
+}
+
+// Enabled implements zapcore.LevelEnabler.
+func (c *slogCore) Enabled(level zapcore.Level) bool {
+	return c.h.Enabled(context.Background(), slogLevelFromZap(level))
+}
+
+// With implements zapcore.Core.
+func (c *slogCore) With(fields []zapcore.Field) zapcore.Core {
+	h := c.h
+	var attrs []slog.Attr
+	for _, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			if len(attrs) > 0 {
+				h = h.WithAttrs(attrs)
+				attrs = nil
+			}
+			h = h.WithGroup(f.Key)
+			continue
+		}
+		attrs = append(attrs, slogAttr(f))
+	}
+	if len(attrs) > 0 {
+		h = h.WithAttrs(attrs)
+	}
+	return &slogCore{h: h}
+}
+
+// Check implements zapcore.Core.
+func (c *slogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *slogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var pc uintptr
+	if ent.Caller.Defined {
+		pc = ent.Caller.PC
+	}
+	r := slog.NewRecord(ent.Time, slogLevelFromZap(ent.Level), ent.Message, pc)
+	if ent.LoggerName != "" {
+		r.AddAttrs(slog.String("logger", ent.LoggerName))
+	}
+	r.AddAttrs(slogAttrs(fields)...)
+	if ent.Stack != "" {
+		r.AddAttrs(slog.String("stacktrace", ent.Stack))
+	}
+	return c.h.Handle(context.Background(), r)
+}
+
+// Sync implements zapcore.Core. slog handlers have no flush operation.
+func (c *slogCore) Sync() error { return nil }
+
+// slogAttrs converts fields to slog attributes, nesting the fields following a namespace
+// in a group.
+func slogAttrs(fields []zapcore.Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			return append(attrs, slog.Attr{Key: f.Key, Value: slog.GroupValue(slogAttrs(fields[i+1:])...)})
+		}
+		attrs = append(attrs, slogAttr(f))
+	}
+	return attrs
+}
+
+// slogAttr converts a single field to a slog attribute.
+func slogAttr(f zapcore.Field) slog.Attr {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return slog.Any(f.Key, slogValue(enc.Fields[f.Key]))
+}
+
+// slogValue converts a value produced by zapcore.MapObjectEncoder, turning nested
+// objects into groups.
+func slogValue(v any) slog.Value {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return slog.AnyValue(v)
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	attrs := make([]slog.Attr, len(keys))
+	for i, k := range keys {
+		attrs[i] = slog.Attr{Key: k, Value: slogValue(m[k])}
+	}
+	return slog.GroupValue(attrs...)
+}
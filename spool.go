@@ -0,0 +1,321 @@
+package logger
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Defaults of the disk spool.
+const (
+	defaultSpoolMaxBytes = 256 << 20
+	// spoolSegments is the number of segment files the spool is split into at most, so
+	// that the oldest entries can be dropped a segment at a time.
+	spoolSegments = 8
+	// minSpoolSegmentSize bounds the size of segments from below.
+	minSpoolSegmentSize = 1 << 20
+	// spoolExt is the extension of segment files.
+	spoolExt = ".spool"
+)
+
+// SpoolConfig makes a remote sink write the entries it fails to send to a bounded
+// write-ahead log on disk, and send them again once the destination is reachable, so
+// that an outage of the log backend does not lose the entries logged meanwhile.
+//
+// Entries are spooled once the sink gave up sending them, after its retries. While the
+// spool holds entries, the sink first tries to send them, oldest first, and spools new
+// batches behind them as long as that fails, so that entries keep their order. When the
+// spool is full, its oldest entries are dropped and the drop is reported on stderr.
+//
+// Entries are delivered at least once: those being sent again when the process stops
+// are sent once more after a restart. Each sink needs its own directory.
+//
+// With Encryption, every spooled entry is sealed with AES-GCM, so that the entries
+// waiting for the backend don't leak from a compromised host. Files spooled before
+// encryption was enabled are still sent; encrypted files are left on disk, and reported
+// on stderr, when the key is missing or different.
+//
+// Example:
+//
+//	Loki: &logger.LokiConfig{
+//	    URL:   "http://loki:3100",
+//	    Spool: &logger.SpoolConfig{Dir: "/var/spool/orders/loki", MaxBytes: 1 << 30},
+//	}
+type SpoolConfig struct {
+	// Dir is the directory of the spool files, created if needed.
+	Dir string
+	// MaxBytes bounds the size of the spool. Defaults to 256 MiB.
+	MaxBytes int64
+	// Encryption, when set, encrypts the spooled entries at rest.
+	Encryption *EncryptionConfig
+}
+
+// Spool is a bounded on-disk write-ahead log of entries a sink failed to send. Remote
+// sinks implemented in other packages use it to support SpoolConfig. It is safe for
+// concurrent use.
+type Spool struct {
+	dir         string
+	maxBytes    int64
+	segmentSize int64
+	// aead seals the records, nil without SpoolConfig.Encryption.
+	aead cipher.AEAD
+
+	mu sync.Mutex
+	// segments lists the sequence numbers of the segment files, oldest first.
+	segments []uint64
+	// sizes holds the size of each segment.
+	sizes map[uint64]int64
+	// next is the sequence number of the next segment.
+	next uint64
+	// current is the segment being appended to, nil when a new one must be started.
+	current *os.File
+	// offset is the offset of the first record of the oldest segment not sent yet.
+	offset int64
+}
+
+// OpenSpool opens the spool described by cfg, keeping the entries spooled by a previous
+// run so that they are sent again.
+func OpenSpool(cfg SpoolConfig) (*Spool, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("spool: directory is required")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("spool: %w", err)
+	}
+	s := &Spool{dir: cfg.Dir, maxBytes: cfg.MaxBytes, sizes: make(map[uint64]int64)}
+	if s.maxBytes <= 0 {
+		s.maxBytes = defaultSpoolMaxBytes
+	}
+	s.segmentSize = max(s.maxBytes/spoolSegments, minSpoolSegmentSize)
+	if cfg.Encryption != nil {
+		aead, err := cfg.Encryption.aead()
+		if err != nil {
+			return nil, fmt.Errorf("spool: %w", err)
+		}
+		s.aead = aead
+	}
+	entries, err := os.ReadDir(cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("spool: %w", err)
+	}
+	for _, entry := range entries {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), spoolExt), 10, 64)
+		if err != nil || !strings.HasSuffix(entry.Name(), spoolExt) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		s.segments = append(s.segments, seq)
+		s.sizes[seq] = info.Size()
+		s.next = max(s.next, seq+1)
+	}
+	slices.Sort(s.segments)
+	return s, nil
+}
+
+// Empty reports whether the spool holds no entries.
+func (s *Spool) Empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.segments) == 0
+}
+
+// Append writes records at the end of the spool, dropping the oldest entries when it
+// exceeds its size.
+func (s *Spool) Append(records []SpoolRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var b []byte
+	for _, rec := range records {
+		if s.aead == nil {
+			b = appendSpoolRecord(b, rec)
+			continue
+		}
+		var err error
+		if b, err = appendSealedSpoolRecord(b, rec, s.aead); err != nil {
+			return fmt.Errorf("spool: %w", err)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.current == nil || s.sizes[s.segments[len(s.segments)-1]] >= s.segmentSize {
+		if err := s.startSegment(); err != nil {
+			return err
+		}
+	}
+	seq := s.segments[len(s.segments)-1]
+	n, err := s.current.Write(b)
+	s.sizes[seq] += int64(n)
+	if err == nil {
+		err = s.current.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("spool: %w", err)
+	}
+	s.trim()
+	return nil
+}
+
+// startSegment closes the current segment and starts a new one. The caller holds mu.
+func (s *Spool) startSegment() error {
+	s.closeCurrent()
+	seq := s.next
+	s.next++
+	f, err := os.OpenFile(s.path(seq), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("spool: %w", err)
+	}
+	header := appendSpoolHeader(nil, s.aead != nil)
+	if _, err := f.Write(header); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("spool: %w", err)
+	}
+	s.current = f
+	s.segments = append(s.segments, seq)
+	s.sizes[seq] = int64(len(header))
+	return nil
+}
+
+// trim removes the oldest segments while the spool exceeds its size, keeping the one
+// being appended to. The caller holds mu.
+func (s *Spool) trim() {
+	var size int64
+	for _, seq := range s.segments {
+		size += s.sizes[seq]
+	}
+	var dropped int64
+	for size > s.maxBytes && len(s.segments) > 1 {
+		seq := s.segments[0]
+		size -= s.sizes[seq]
+		dropped += s.sizes[seq]
+		s.remove(seq)
+	}
+	if dropped > 0 {
+		fmt.Fprintf(os.Stderr, "logger: spool %s full, dropped %d bytes of the oldest entries\n", s.dir, dropped)
+	}
+}
+
+// remove deletes the oldest segment. The caller holds mu.
+func (s *Spool) remove(seq uint64) {
+	if err := os.Remove(s.path(seq)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "logger: failed to remove spool file: %v\n", err)
+	}
+	s.segments = s.segments[1:]
+	delete(s.sizes, seq)
+	s.offset = 0
+}
+
+// Replay sends the spooled entries, oldest first, in batches of at most batchSize
+// records. It stops at the first batch send fails to send, returning its error; that
+// batch and the following ones are sent by the next call. Entries sent are removed from
+// the spool.
+func (s *Spool) Replay(batchSize int, send func([]SpoolRecord) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.segments) > 0 {
+		seq := s.segments[0]
+		if len(s.segments) == 1 && s.current != nil {
+			// New entries go to a new segment while this one is sent.
+			s.closeCurrent()
+		}
+		done, err := s.replaySegment(seq, max(batchSize, 1), send)
+		if err != nil {
+			return err
+		}
+		if !done {
+			// Written by a newer binary, or with another key: leave it alone.
+			fmt.Fprintf(os.Stderr, "logger: skipping spool file %s\n", s.path(seq))
+			s.segments = s.segments[1:]
+			delete(s.sizes, seq)
+			s.offset = 0
+			continue
+		}
+		s.remove(seq)
+	}
+	return nil
+}
+
+// replaySegment sends the records of a segment from the current offset. It reports
+// false when the segment cannot be read by this binary or with this key. The caller
+// holds mu.
+func (s *Spool) replaySegment(seq uint64, batchSize int, send func([]SpoolRecord) error) (bool, error) {
+	f, err := os.Open(s.path(seq))
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil {
+		return true, fmt.Errorf("spool: %w", err)
+	}
+	defer f.Close()
+	r, err := newSpoolReader(f, s.aead)
+	if errors.Is(err, errSpoolTruncated) || errors.Is(err, io.EOF) {
+		// Crashed right after creating the file.
+		return true, nil
+	}
+	if err != nil {
+		return false, nil
+	}
+	batch := make([]SpoolRecord, 0, batchSize)
+	sent := r.offset
+	for {
+		rec, err := r.next()
+		if errors.Is(err, errSpoolAuth) {
+			return false, nil
+		}
+		if err == nil && r.offset <= s.offset {
+			// Sent before the previous failure.
+			continue
+		}
+		if err == nil {
+			batch = append(batch, rec)
+			if len(batch) < batchSize {
+				continue
+			}
+		} else if !errors.Is(err, io.EOF) {
+			fmt.Fprintf(os.Stderr, "logger: spool file %s: %v, dropping the rest of it\n", s.path(seq), err)
+		}
+		if len(batch) > 0 {
+			if serr := send(batch); serr != nil {
+				s.offset = max(s.offset, sent)
+				return true, serr
+			}
+			sent = r.offset
+			batch = batch[:0]
+		}
+		if err != nil {
+			return true, nil
+		}
+	}
+}
+
+// closeCurrent closes the segment being appended to. The caller holds mu.
+func (s *Spool) closeCurrent() {
+	if s.current != nil {
+		_ = s.current.Close()
+		s.current = nil
+	}
+}
+
+// path returns the path of a segment.
+func (s *Spool) path(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, spoolExt))
+}
+
+// Close closes the spool. The entries it holds are kept for the next run.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeCurrent()
+	return nil
+}
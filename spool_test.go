@@ -0,0 +1,224 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// openTestSpool opens the spool described by cfg, failing the test on error.
+func openTestSpool(t *testing.T, cfg SpoolConfig) *Spool {
+	t.Helper()
+	s, err := OpenSpool(cfg)
+	if err != nil {
+		t.Fatalf("OpenSpool() = %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+// spoolEntries returns records whose entries are the given strings.
+func spoolEntries(entries ...string) []SpoolRecord {
+	records := make([]SpoolRecord, len(entries))
+	for i, e := range entries {
+		records[i] = SpoolRecord{Time: spoolTestTime, Level: LevelError, Attributes: map[string]string{"n": e}, Entry: []byte(e)}
+	}
+	return records
+}
+
+// replayAll replays the spool, returning the entries sent.
+func replayAll(t *testing.T, s *Spool, batchSize int) []string {
+	t.Helper()
+	var sent []string
+	err := s.Replay(batchSize, func(records []SpoolRecord) error {
+		if len(records) > batchSize {
+			t.Errorf("batch of %d records, want at most %d", len(records), batchSize)
+		}
+		for _, rec := range records {
+			if rec.Attributes["n"] != string(rec.Entry) || rec.Level != LevelError {
+				t.Errorf("record = %+v, want the one appended", rec)
+			}
+			sent = append(sent, string(rec.Entry))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay() = %v", err)
+	}
+	return sent
+}
+
+func TestSpoolAppendReplay(t *testing.T) {
+	dir := t.TempDir()
+	s := openTestSpool(t, SpoolConfig{Dir: dir})
+	if !s.Empty() {
+		t.Fatal("Empty() = false for a new spool")
+	}
+	if err := s.Append(spoolEntries("a", "b", "c")); err != nil {
+		t.Fatalf("Append() = %v", err)
+	}
+	if err := s.Append(spoolEntries("d", "e")); err != nil {
+		t.Fatalf("Append() = %v", err)
+	}
+	if s.Empty() {
+		t.Fatal("Empty() = true after Append")
+	}
+
+	// The entries survive a restart.
+	_ = s.Close()
+	s = openTestSpool(t, SpoolConfig{Dir: dir})
+	if got, want := replayAll(t, s, 2), []string{"a", "b", "c", "d", "e"}; !slices.Equal(got, want) {
+		t.Fatalf("replayed %q, want %q", got, want)
+	}
+	if !s.Empty() {
+		t.Fatal("Empty() = false after Replay")
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*"+spoolExt)); len(files) != 0 {
+		t.Fatalf("spool files left after Replay: %v", files)
+	}
+}
+
+func TestSpoolReplayResumesAfterFailure(t *testing.T) {
+	s := openTestSpool(t, SpoolConfig{Dir: t.TempDir()})
+	if err := s.Append(spoolEntries("a", "b", "c", "d", "e")); err != nil {
+		t.Fatalf("Append() = %v", err)
+	}
+
+	errDown := errors.New("backend down")
+	var sent []string
+	err := s.Replay(2, func(records []SpoolRecord) error {
+		if len(sent) > 0 {
+			return errDown
+		}
+		for _, rec := range records {
+			sent = append(sent, string(rec.Entry))
+		}
+		return nil
+	})
+	if !errors.Is(err, errDown) {
+		t.Fatalf("Replay() = %v, want %v", err, errDown)
+	}
+	if want := []string{"a", "b"}; !slices.Equal(sent, want) {
+		t.Fatalf("sent %q before the failure, want %q", sent, want)
+	}
+
+	// Entries spooled meanwhile go behind the unsent ones, and the partially sent
+	// segment resumes after the last batch sent.
+	if err := s.Append(spoolEntries("f")); err != nil {
+		t.Fatalf("Append() = %v", err)
+	}
+	if got, want := replayAll(t, s, 2), []string{"c", "d", "e", "f"}; !slices.Equal(got, want) {
+		t.Fatalf("replayed %q, want %q", got, want)
+	}
+}
+
+func TestSpoolTrimDropsOldestEntries(t *testing.T) {
+	const maxBytes = 2 << 20
+	dir := t.TempDir()
+	s := openTestSpool(t, SpoolConfig{Dir: dir, MaxBytes: maxBytes})
+	padding := bytes.Repeat([]byte("x"), 64<<10)
+	const n = 64
+	for i := range n {
+		rec := SpoolRecord{Time: spoolTestTime, Attributes: map[string]string{"i": fmt.Sprint(i)}, Entry: padding}
+		if err := s.Append([]SpoolRecord{rec}); err != nil {
+			t.Fatalf("Append() = %v", err)
+		}
+	}
+
+	var size int64
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+spoolExt))
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += info.Size()
+	}
+	if size > maxBytes {
+		t.Fatalf("spool holds %d bytes, want at most %d", size, maxBytes)
+	}
+
+	var kept []string
+	err := s.Replay(n, func(records []SpoolRecord) error {
+		for _, rec := range records {
+			kept = append(kept, rec.Attributes["i"])
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay() = %v", err)
+	}
+	if len(kept) == 0 || len(kept) == n {
+		t.Fatalf("kept %d of %d entries, want the oldest dropped", len(kept), n)
+	}
+	// The newest entries are kept, in order.
+	first := n - len(kept)
+	for i, got := range kept {
+		if want := fmt.Sprint(first + i); got != want {
+			t.Fatalf("kept entries %q, want %d to %d", kept, first, n-1)
+		}
+	}
+}
+
+func TestSpoolEncryption(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	otherKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+	dir := t.TempDir()
+
+	// Entries spooled before encryption was enabled are still sent.
+	plain := openTestSpool(t, SpoolConfig{Dir: dir})
+	if err := plain.Append(spoolEntries("plain")); err != nil {
+		t.Fatalf("Append() = %v", err)
+	}
+	_ = plain.Close()
+
+	s := openTestSpool(t, SpoolConfig{Dir: dir, Encryption: &EncryptionConfig{Key: key}})
+	if err := s.Append(spoolEntries("secret")); err != nil {
+		t.Fatalf("Append() = %v", err)
+	}
+	_ = s.Close()
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+spoolExt))
+	if len(files) != 2 {
+		t.Fatalf("spool files = %v, want 2", files)
+	}
+	raw, err := os.ReadFile(files[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("secret")) {
+		t.Fatal("encrypted spool file holds the entry in plaintext")
+	}
+
+	// Without the key, or with another one, the encrypted file is left alone.
+	tests := []struct {
+		enc  *EncryptionConfig
+		want []string
+	}{
+		{nil, []string{"plain"}},
+		{&EncryptionConfig{Key: otherKey}, nil},
+	}
+	for _, tt := range tests {
+		s := openTestSpool(t, SpoolConfig{Dir: dir, Encryption: tt.enc})
+		if got := replayAll(t, s, 10); !slices.Equal(got, tt.want) {
+			t.Fatalf("replayed %q with %+v, want %q", got, tt.enc, tt.want)
+		}
+		_ = s.Close()
+		if _, err := os.Stat(files[1]); err != nil {
+			t.Fatalf("encrypted spool file removed: %v", err)
+		}
+	}
+
+	s = openTestSpool(t, SpoolConfig{Dir: dir, Encryption: &EncryptionConfig{Key: key}})
+	if got, want := replayAll(t, s, 10), []string{"secret"}; !slices.Equal(got, want) {
+		t.Fatalf("replayed %q with the key, want %q", got, want)
+	}
+
+	if _, err := OpenSpool(SpoolConfig{Dir: dir, Encryption: &EncryptionConfig{KeyEnv: "LOGGER_TEST_NO_SUCH_KEY"}}); err == nil {
+		t.Fatal("OpenSpool() with a missing key = nil, want an error")
+	}
+}
@@ -0,0 +1,273 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// The on-disk spool format.
+//
+// A spool file starts with a header: the magic "LGSP" and the format version as a
+// big-endian uint16. It is followed by records, each made of the payload length and the
+// CRC-32C of the payload as big-endian uint32s, then the payload. The layout of the
+// payload depends on the version of the file:
+//
+//	version 1: unix time in nanoseconds (int64) | encoded entry
+//	version 2: unix time in nanoseconds (int64) | level (int8) | attribute count (uint16) |
+//	           attributes | encoded entry
+//
+// In version 2, each attribute is its key, prefixed by its length as a uint16, followed
+// by its value, prefixed by its length as a uint32.
+//
+// Encrypted spools use the magic "LGSE" instead. Their payloads are sealed with AES-GCM
+// as nonce|ciphertext, the ciphertext holding the payload of the version, and the CRC
+// covers the sealed payload, so truncation is told apart from a wrong key.
+//
+// Files are never rewritten in place: a binary writes new files in spoolVersion and
+// reads older files through the decoder of their version, so entries spooled by an old
+// binary are replayed after an upgrade. Files written by a newer binary are left alone.
+const (
+	spoolMagic       = "LGSP"
+	spoolSealedMagic = "LGSE"
+	spoolVersion     = 2
+	spoolHeaderSize  = len(spoolMagic) + 2
+	spoolRecordHead  = 8
+	// maxSpoolRecord bounds the payload length, so that a corrupted length is not
+	// mistaken for a huge record.
+	maxSpoolRecord = 16 << 20
+)
+
+// Errors reading spool files.
+var (
+	// errSpoolTruncated reports a record cut short, typically by a crash while writing it.
+	errSpoolTruncated = errors.New("truncated spool record")
+	// errSpoolCorrupt reports a record whose length or checksum is invalid.
+	errSpoolCorrupt = errors.New("corrupt spool record")
+	// errSpoolAuth reports a sealed record that fails authentication, typically because
+	// the spool was written with another key.
+	errSpoolAuth = errors.New("spool record fails authentication")
+)
+
+// spoolCRC is the CRC-32C table of spool records.
+var spoolCRC = crc32.MakeTable(crc32.Castagnoli)
+
+// SpoolRecord is an entry stored in a spool.
+type SpoolRecord struct {
+	// Time is the time the entry was logged.
+	Time time.Time
+	// Level is the level of the entry. Entries spooled in version 1 have none.
+	Level LogLevel
+	// Attributes holds what the sink needs besides the encoded entry to send it again,
+	// e.g. the structured metadata of a Loki entry or the key of a Kafka message.
+	Attributes map[string]string
+	// Entry is the entry as encoded for its output.
+	Entry []byte
+}
+
+// spoolDecoders decode the payloads of each format version into records. Supporting a
+// new version means adding its decoder here, and keeping those of the older ones.
+var spoolDecoders = map[uint16]func(payload []byte) (SpoolRecord, error){
+	1: decodeSpoolV1,
+	2: decodeSpoolV2,
+}
+
+// decodeSpoolV1 decodes a version 1 payload.
+func decodeSpoolV1(payload []byte) (SpoolRecord, error) {
+	if len(payload) < 8 {
+		return SpoolRecord{}, errSpoolCorrupt
+	}
+	nanos := int64(binary.BigEndian.Uint64(payload))
+	return SpoolRecord{Time: time.Unix(0, nanos), Entry: payload[8:]}, nil
+}
+
+// decodeSpoolV2 decodes a version 2 payload.
+func decodeSpoolV2(payload []byte) (SpoolRecord, error) {
+	if len(payload) < 11 {
+		return SpoolRecord{}, errSpoolCorrupt
+	}
+	rec := SpoolRecord{
+		Time:  time.Unix(0, int64(binary.BigEndian.Uint64(payload))),
+		Level: LogLevel(zapcore.Level(int8(payload[8])).CapitalString()),
+	}
+	n := int(binary.BigEndian.Uint16(payload[9:]))
+	payload = payload[11:]
+	if n > 0 {
+		rec.Attributes = make(map[string]string, n)
+	}
+	for range n {
+		if len(payload) < 2 {
+			return SpoolRecord{}, errSpoolCorrupt
+		}
+		klen := int(binary.BigEndian.Uint16(payload))
+		if len(payload) < 2+klen+4 {
+			return SpoolRecord{}, errSpoolCorrupt
+		}
+		key := string(payload[2 : 2+klen])
+		payload = payload[2+klen:]
+		vlen := int(binary.BigEndian.Uint32(payload))
+		if len(payload) < 4+vlen {
+			return SpoolRecord{}, errSpoolCorrupt
+		}
+		rec.Attributes[key] = string(payload[4 : 4+vlen])
+		payload = payload[4+vlen:]
+	}
+	rec.Entry = payload
+	return rec, nil
+}
+
+// zapLevel returns the zap level of the record, INFO when it has none.
+func (rec SpoolRecord) zapLevel() zapcore.Level {
+	level, err := zapcore.ParseLevel(strings.ToLower(string(rec.Level)))
+	if err != nil {
+		return zapcore.InfoLevel
+	}
+	return level
+}
+
+// appendSpoolHeader appends the header of a spool file in the current version to b,
+// with the magic of encrypted spools when sealed is set.
+func appendSpoolHeader(b []byte, sealed bool) []byte {
+	if sealed {
+		b = append(b, spoolSealedMagic...)
+	} else {
+		b = append(b, spoolMagic...)
+	}
+	return binary.BigEndian.AppendUint16(b, spoolVersion)
+}
+
+// appendSpoolRecord appends rec, framed in the current version, to b. Attributes are
+// written in key order.
+func appendSpoolRecord(b []byte, rec SpoolRecord) []byte {
+	start := len(b)
+	b = appendSpoolPayload(append(b, make([]byte, spoolRecordHead)...), rec)
+	return frameSpoolRecord(b, start)
+}
+
+// appendSealedSpoolRecord is appendSpoolRecord for encrypted spools: the payload is
+// sealed with aead.
+func appendSealedSpoolRecord(b []byte, rec SpoolRecord, aead cipher.AEAD) ([]byte, error) {
+	start := len(b)
+	b, err := seal(aead, append(b, make([]byte, spoolRecordHead)...), appendSpoolPayload(nil, rec))
+	if err != nil {
+		return nil, err
+	}
+	return frameSpoolRecord(b, start), nil
+}
+
+// appendSpoolPayload appends the payload of rec in the current version to b.
+func appendSpoolPayload(b []byte, rec SpoolRecord) []byte {
+	b = binary.BigEndian.AppendUint64(b, uint64(rec.Time.UnixNano()))
+	b = append(b, byte(int8(rec.zapLevel())))
+	b = binary.BigEndian.AppendUint16(b, uint16(len(rec.Attributes)))
+	for _, k := range slices.Sorted(maps.Keys(rec.Attributes)) {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(k)))
+		b = append(b, k...)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(rec.Attributes[k])))
+		b = append(b, rec.Attributes[k]...)
+	}
+	return append(b, rec.Entry...)
+}
+
+// frameSpoolRecord fills in the length and checksum of the record starting at start,
+// whose payload extends to the end of b.
+func frameSpoolRecord(b []byte, start int) []byte {
+	payload := b[start+spoolRecordHead:]
+	binary.BigEndian.PutUint32(b[start:], uint32(len(payload)))
+	binary.BigEndian.PutUint32(b[start+4:], crc32.Checksum(payload, spoolCRC))
+	return b
+}
+
+// spoolReader reads the records of a spool file.
+type spoolReader struct {
+	r       *bufio.Reader
+	version uint16
+	decode  func([]byte) (SpoolRecord, error)
+	// aead opens the payloads of an encrypted spool, nil for a plain one.
+	aead cipher.AEAD
+	// offset is the end of the last valid record, where a writer can resume after a
+	// truncated or corrupt one.
+	offset int64
+}
+
+// newSpoolReader reads the header of a spool file. It fails for files that are not
+// spools or were written in a version this binary does not know, and for encrypted
+// spools when aead is nil. Plain spools are read whether aead is set or not, so entries
+// spooled before encryption was enabled are still sent.
+func newSpoolReader(r io.Reader, aead cipher.AEAD) (*spoolReader, error) {
+	br := bufio.NewReader(r)
+	var header [spoolHeaderSize]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errSpoolTruncated
+		}
+		return nil, err
+	}
+	switch string(header[:len(spoolMagic)]) {
+	case spoolMagic:
+		aead = nil
+	case spoolSealedMagic:
+		if aead == nil {
+			return nil, errors.New("encrypted spool file, no encryption key is configured")
+		}
+	default:
+		return nil, errors.New("not a spool file")
+	}
+	version := binary.BigEndian.Uint16(header[len(spoolMagic):])
+	decode, ok := spoolDecoders[version]
+	if !ok {
+		return nil, fmt.Errorf("unsupported spool version %d (this binary writes version %d)", version, spoolVersion)
+	}
+	return &spoolReader{r: br, version: version, decode: decode, aead: aead, offset: int64(spoolHeaderSize)}, nil
+}
+
+// next returns the next record. It returns io.EOF at the end of the file, and
+// errSpoolTruncated or errSpoolCorrupt when the remaining data is not a valid record,
+// and errSpoolAuth when a sealed record cannot be opened; the records read before
+// remain valid. The returned Entry is only valid until the next
+// call.
+func (s *spoolReader) next() (SpoolRecord, error) {
+	var head [spoolRecordHead]byte
+	if _, err := io.ReadFull(s.r, head[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return SpoolRecord{}, errSpoolTruncated
+		}
+		return SpoolRecord{}, err
+	}
+	n := binary.BigEndian.Uint32(head[:])
+	if n > maxSpoolRecord {
+		return SpoolRecord{}, errSpoolCorrupt
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(s.r, payload); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return SpoolRecord{}, errSpoolTruncated
+		}
+		return SpoolRecord{}, err
+	}
+	if crc32.Checksum(payload, spoolCRC) != binary.BigEndian.Uint32(head[4:]) {
+		return SpoolRecord{}, errSpoolCorrupt
+	}
+	if s.aead != nil {
+		var err error
+		if payload, err = open(s.aead, payload); err != nil {
+			return SpoolRecord{}, errSpoolAuth
+		}
+	}
+	rec, err := s.decode(payload)
+	if err != nil {
+		return SpoolRecord{}, err
+	}
+	s.offset += int64(spoolRecordHead) + int64(n)
+	return rec, nil
+}
@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+	"maps"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// readSpool reads every record of a spool file, returning the records read before the
+// first error other than io.EOF.
+func readSpool(t *testing.T, b []byte) ([]SpoolRecord, *spoolReader, error) {
+	t.Helper()
+	r, err := newSpoolReader(bytes.NewReader(b), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var recs []SpoolRecord
+	for {
+		rec, err := r.next()
+		if err == io.EOF {
+			return recs, r, nil
+		}
+		if err != nil {
+			return recs, r, err
+		}
+		rec.Entry = bytes.Clone(rec.Entry)
+		recs = append(recs, rec)
+	}
+}
+
+// spoolV1 builds a version 1 spool file, as written by the binaries preceding version 2.
+func spoolV1(recs ...SpoolRecord) []byte {
+	b := append([]byte(spoolMagic), 0, 1)
+	for _, rec := range recs {
+		payload := binary.BigEndian.AppendUint64(nil, uint64(rec.Time.UnixNano()))
+		payload = append(payload, rec.Entry...)
+		b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+		b = binary.BigEndian.AppendUint32(b, crc32.Checksum(payload, spoolCRC))
+		b = append(b, payload...)
+	}
+	return b
+}
+
+// equalRecords reports whether two records hold the same entry.
+func equalRecords(a, b SpoolRecord) bool {
+	return a.Time.Equal(b.Time) && a.Level == b.Level && maps.Equal(a.Attributes, b.Attributes) && bytes.Equal(a.Entry, b.Entry)
+}
+
+var spoolTestTime = time.Date(2026, 3, 14, 15, 9, 26, 535897932, time.UTC)
+
+func TestSpoolRecordRoundTrip(t *testing.T) {
+	want := []SpoolRecord{
+		{Time: spoolTestTime, Level: LevelError, Attributes: map[string]string{"trace_id": "4bf92f35", "key": "order-1"}, Entry: []byte(`{"msg":"charge failed"}` + "\n")},
+		{Time: spoolTestTime.Add(time.Second), Level: LevelDebug, Entry: []byte("plain entry\n")},
+		{Time: spoolTestTime.Add(2 * time.Second), Level: LevelInfo, Attributes: map[string]string{"empty": ""}, Entry: []byte{}},
+	}
+	b := appendSpoolHeader(nil, false)
+	for _, rec := range want {
+		b = appendSpoolRecord(b, rec)
+	}
+	got, r, err := readSpool(t, b)
+	if err != nil {
+		t.Fatalf("reading the spool: %v", err)
+	}
+	if r.version != spoolVersion {
+		t.Errorf("version = %d, want %d", r.version, spoolVersion)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("read %d records, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !equalRecords(got[i], want[i]) {
+			t.Errorf("record %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if r.offset != int64(len(b)) {
+		t.Errorf("offset = %d, want %d", r.offset, len(b))
+	}
+}
+
+func TestSpoolReadsVersion1(t *testing.T) {
+	b := spoolV1(
+		SpoolRecord{Time: spoolTestTime, Entry: []byte(`{"msg":"first"}` + "\n")},
+		SpoolRecord{Time: spoolTestTime.Add(time.Minute), Entry: []byte(`{"msg":"second"}` + "\n")},
+	)
+	got, r, err := readSpool(t, b)
+	if err != nil {
+		t.Fatalf("reading the version 1 spool: %v", err)
+	}
+	if r.version != 1 {
+		t.Errorf("version = %d, want 1", r.version)
+	}
+	if len(got) != 2 {
+		t.Fatalf("read %d records, want 2", len(got))
+	}
+	if !got[1].Time.Equal(spoolTestTime.Add(time.Minute)) || string(got[1].Entry) != `{"msg":"second"}`+"\n" {
+		t.Errorf("second record = %+v", got[1])
+	}
+	// Version 1 records carry no level or attributes; they are sent again at INFO.
+	if got[0].Level != "" || got[0].Attributes != nil || got[0].zapLevel() != zapcore.InfoLevel {
+		t.Errorf("version 1 record has level %q and attributes %v", got[0].Level, got[0].Attributes)
+	}
+}
+
+func TestSpoolReadErrors(t *testing.T) {
+	valid := appendSpoolRecord(appendSpoolHeader(nil, false), SpoolRecord{Time: spoolTestTime, Level: LevelWarn, Entry: []byte("first\n")})
+	second := appendSpoolRecord(nil, SpoolRecord{Time: spoolTestTime, Level: LevelWarn, Attributes: map[string]string{"k": "v"}, Entry: []byte("second\n")})
+	withSecond := func(edit func(rec []byte) []byte) []byte {
+		return append(bytes.Clone(valid), edit(bytes.Clone(second))...)
+	}
+	// reframe replaces the payload of a record, with a valid length and checksum.
+	reframe := func(payload []byte) []byte {
+		b := binary.BigEndian.AppendUint32(nil, uint32(len(payload)))
+		b = binary.BigEndian.AppendUint32(b, crc32.Checksum(payload, spoolCRC))
+		return append(b, payload...)
+	}
+
+	tests := []struct {
+		name string
+		b    []byte
+		want error
+	}{
+		{"record head cut short", withSecond(func(rec []byte) []byte { return rec[:5] }), errSpoolTruncated},
+		{"payload cut short", withSecond(func(rec []byte) []byte { return rec[:len(rec)-3] }), errSpoolTruncated},
+		{"payload missing", withSecond(func(rec []byte) []byte { return rec[:spoolRecordHead] }), errSpoolTruncated},
+		{"checksum mismatch", withSecond(func(rec []byte) []byte { rec[len(rec)-1] ^= 0xff; return rec }), errSpoolCorrupt},
+		{"length shorter than the payload", withSecond(func(rec []byte) []byte {
+			binary.BigEndian.PutUint32(rec, binary.BigEndian.Uint32(rec)-1)
+			return rec
+		}), errSpoolCorrupt},
+		{"length beyond the maximum", withSecond(func(rec []byte) []byte {
+			binary.BigEndian.PutUint32(rec, maxSpoolRecord+1)
+			return rec
+		}), errSpoolCorrupt},
+		{"payload shorter than its fixed part", withSecond(func([]byte) []byte { return reframe([]byte{1, 2, 3}) }), errSpoolCorrupt},
+		{"attribute beyond the payload", withSecond(func(rec []byte) []byte {
+			payload := rec[spoolRecordHead:]
+			// The length of the value of the only attribute, "k".
+			binary.BigEndian.PutUint32(payload[8+1+2+2+1:], 1<<20)
+			return reframe(payload)
+		}), errSpoolCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, r, err := readSpool(t, tt.b)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("reading the spool: got %v, want %v", err, tt.want)
+			}
+			// The records before the damaged one remain readable, and a writer resumes
+			// after them.
+			if len(got) != 1 || string(got[0].Entry) != "first\n" {
+				t.Errorf("records before the error = %+v, want the first one", got)
+			}
+			if r.offset != int64(len(valid)) {
+				t.Errorf("offset = %d, want %d", r.offset, len(valid))
+			}
+		})
+	}
+}
+
+func TestSpoolHeaderErrors(t *testing.T) {
+	newer := append([]byte(spoolMagic), 0, 0)
+	binary.BigEndian.PutUint16(newer[len(spoolMagic):], spoolVersion+1)
+
+	tests := []struct {
+		name string
+		b    []byte
+		want string
+	}{
+		{"header cut short", []byte("LGS"), errSpoolTruncated.Error()},
+		{"empty file", nil, io.EOF.Error()},
+		{"not a spool", []byte("{\"msg\":\"hello\"}\n"), "not a spool file"},
+		{"newer version", newer, "unsupported spool version"},
+		{"version 0", append([]byte(spoolMagic), 0, 0), "unsupported spool version 0"},
+		{"encrypted without a key", appendSpoolHeader(nil, true), "no encryption key is configured"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newSpoolReader(bytes.NewReader(tt.b), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("newSpoolReader() = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
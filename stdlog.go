@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"log"
+
+	"go.uber.org/zap"
+)
+
+// StdLogAt returns a standard library *log.Logger writing each line as an entry at the
+// given level, for APIs that only accept a *log.Logger such as http.Server.ErrorLog.
+//
+// The trailing newline is trimmed and the caller is reported as the code calling the
+// *log.Logger, not the adapter.
+//
+// Example:
+//
+//	srv := &http.Server{
+//	    Addr:     ":8080",
+//	    ErrorLog: log.StdLogAt(logger.LevelWarn),
+//	}
+func (l *Logger) StdLogAt(level LogLevel) *log.Logger {
+	std, _ := zap.NewStdLogAt(l.Logger, parseLevel(level))
+	return std
+}
@@ -0,0 +1,212 @@
+package logger
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// badKey is the key of values passed to the key-value methods without a string key.
+const badKey = "!BADKEY"
+
+// The printf-style and key-value methods below follow zap.SugaredLogger, for codebases
+// migrating from loosely typed loggers such as logrus. They format the message only when
+// the level is enabled.
+
+// Debugf formats a message with fmt.Sprintf and logs it at the DEBUG level.
+func (l *Logger) Debugf(template string, args ...any) {
+	if l.Core().Enabled(zapcore.DebugLevel) {
+		if ce := l.skipped().Check(zapcore.DebugLevel, sprintf(template, args)); ce != nil {
+			ce.Write()
+		}
+	}
+}
+
+// Infof formats a message with fmt.Sprintf and logs it at the INFO level.
+func (l *Logger) Infof(template string, args ...any) {
+	if l.Core().Enabled(zapcore.InfoLevel) {
+		if ce := l.skipped().Check(zapcore.InfoLevel, sprintf(template, args)); ce != nil {
+			ce.Write()
+		}
+	}
+}
+
+// Warnf formats a message with fmt.Sprintf and logs it at the WARN level.
+func (l *Logger) Warnf(template string, args ...any) {
+	if l.Core().Enabled(zapcore.WarnLevel) {
+		if ce := l.skipped().Check(zapcore.WarnLevel, sprintf(template, args)); ce != nil {
+			ce.Write()
+		}
+	}
+}
+
+// Errorf formats a message with fmt.Sprintf and logs it at the ERROR level.
+func (l *Logger) Errorf(template string, args ...any) {
+	if l.Core().Enabled(zapcore.ErrorLevel) {
+		if ce := l.skipped().Check(zapcore.ErrorLevel, sprintf(template, args)); ce != nil {
+			ce.Write()
+		}
+	}
+}
+
+// Fatalf formats a message with fmt.Sprintf and logs it at the FATAL level and terminates the application.
+//
+// The application terminates even when the logger is disabled, e.g. by NewNop.
+func (l *Logger) Fatalf(template string, args ...any) {
+	if ce := l.skipped().Check(zapcore.FatalLevel, sprintf(template, args)); ce != nil {
+		ce.Write()
+	}
+}
+
+// Debugw logs a message at the DEBUG level, with fields given as alternating keys
+// and values. zap.Field values are accepted in place of a pair.
+func (l *Logger) Debugw(msg string, keysAndValues ...any) {
+	if l.Core().Enabled(zapcore.DebugLevel) {
+		if ce := l.skipped().Check(zapcore.DebugLevel, msg); ce != nil {
+			ce.Write(sweeten(keysAndValues)...)
+		}
+	}
+}
+
+// Infow logs a message at the INFO level, with fields given as alternating keys
+// and values. zap.Field values are accepted in place of a pair.
+//
+// Example:
+//
+//	log.Infow("order created", "order_id", id, "amount", 12.5)
+func (l *Logger) Infow(msg string, keysAndValues ...any) {
+	if l.Core().Enabled(zapcore.InfoLevel) {
+		if ce := l.skipped().Check(zapcore.InfoLevel, msg); ce != nil {
+			ce.Write(sweeten(keysAndValues)...)
+		}
+	}
+}
+
+// Warnw logs a message at the WARN level, with fields given as alternating keys
+// and values. zap.Field values are accepted in place of a pair.
+func (l *Logger) Warnw(msg string, keysAndValues ...any) {
+	if l.Core().Enabled(zapcore.WarnLevel) {
+		if ce := l.skipped().Check(zapcore.WarnLevel, msg); ce != nil {
+			ce.Write(sweeten(keysAndValues)...)
+		}
+	}
+}
+
+// Errorw logs a message at the ERROR level, with fields given as alternating keys
+// and values. zap.Field values are accepted in place of a pair.
+func (l *Logger) Errorw(msg string, keysAndValues ...any) {
+	if l.Core().Enabled(zapcore.ErrorLevel) {
+		if ce := l.skipped().Check(zapcore.ErrorLevel, msg); ce != nil {
+			ce.Write(sweeten(keysAndValues)...)
+		}
+	}
+}
+
+// Fatalw logs a message at the FATAL level and terminates the application, with fields given as alternating keys
+// and values. zap.Field values are accepted in place of a pair.
+//
+// The application terminates even when the logger is disabled, e.g. by NewNop.
+func (l *Logger) Fatalw(msg string, keysAndValues ...any) {
+	if ce := l.skipped().Check(zapcore.FatalLevel, msg); ce != nil {
+		ce.Write(sweeten(keysAndValues)...)
+	}
+}
+
+// Debugf formats a message with fmt.Sprintf and logs it at the DEBUG level, using
+// the global logger.
+func Debugf(template string, args ...any) {
+	packageLogger().Debugf(template, args...)
+}
+
+// Infof formats a message with fmt.Sprintf and logs it at the INFO level, using
+// the global logger.
+func Infof(template string, args ...any) {
+	packageLogger().Infof(template, args...)
+}
+
+// Warnf formats a message with fmt.Sprintf and logs it at the WARN level, using
+// the global logger.
+func Warnf(template string, args ...any) {
+	packageLogger().Warnf(template, args...)
+}
+
+// Errorf formats a message with fmt.Sprintf and logs it at the ERROR level, using
+// the global logger.
+func Errorf(template string, args ...any) {
+	packageLogger().Errorf(template, args...)
+}
+
+// Fatalf formats a message with fmt.Sprintf and logs it at the FATAL level and terminates the application, using
+// the global logger.
+func Fatalf(template string, args ...any) {
+	packageLogger().Fatalf(template, args...)
+}
+
+// Debugw logs a message with alternating keys and values at the DEBUG level, using
+// the global logger.
+func Debugw(msg string, keysAndValues ...any) {
+	packageLogger().Debugw(msg, keysAndValues...)
+}
+
+// Infow logs a message with alternating keys and values at the INFO level, using
+// the global logger.
+func Infow(msg string, keysAndValues ...any) {
+	packageLogger().Infow(msg, keysAndValues...)
+}
+
+// Warnw logs a message with alternating keys and values at the WARN level, using
+// the global logger.
+func Warnw(msg string, keysAndValues ...any) {
+	packageLogger().Warnw(msg, keysAndValues...)
+}
+
+// Errorw logs a message with alternating keys and values at the ERROR level, using
+// the global logger.
+func Errorw(msg string, keysAndValues ...any) {
+	packageLogger().Errorw(msg, keysAndValues...)
+}
+
+// Fatalw logs a message with alternating keys and values at the FATAL level and terminates the application, using
+// the global logger.
+func Fatalw(msg string, keysAndValues ...any) {
+	packageLogger().Fatalw(msg, keysAndValues...)
+}
+
+// skipped returns the logger reporting the caller of the method calling Check.
+func (l *Logger) skipped() *zap.Logger {
+	return l.WithOptions(zap.AddCallerSkip(1))
+}
+
+// sprintf formats a message like zap.SugaredLogger: the template alone without args, and
+// the args alone without a template.
+func sprintf(template string, args []any) string {
+	switch {
+	case len(args) == 0:
+		return template
+	case template == "":
+		return fmt.Sprint(args...)
+	default:
+		return fmt.Sprintf(template, args...)
+	}
+}
+
+// sweeten converts alternating keys and values into fields. Values without a string key
+// are logged under !BADKEY, rather than dropped.
+func sweeten(keysAndValues []any) []zap.Field {
+	fields := make([]zap.Field, 0, (len(keysAndValues)+1)/2)
+	for i := 0; i < len(keysAndValues); i++ {
+		if f, ok := keysAndValues[i].(zap.Field); ok {
+			fields = append(fields, f)
+			continue
+		}
+		key, ok := keysAndValues[i].(string)
+		if !ok || i == len(keysAndValues)-1 {
+			fields = append(fields, zap.Any(badKey, keysAndValues[i]))
+			continue
+		}
+		fields = append(fields, zap.Any(key, keysAndValues[i+1]))
+		i++
+	}
+	return fields
+}
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+// fatalVariantEnv selects the fatal call made by the subprocess of
+// TestFatalExitsWhenDisabled.
+const fatalVariantEnv = "LOGGER_TEST_FATAL_VARIANT"
+
+func TestFatalExitsWhenDisabled(t *testing.T) {
+	variants := map[string]func(){
+		"nop/Fatalf":      func() { NewNop().Fatalf("cannot start: %d", 1) },
+		"nop/Fatalw":      func() { NewNop().Fatalw("cannot start", "attempt", 1) },
+		"disabled/Fatalf": func() { Disable(); Fatalf("cannot start: %d", 1) },
+		"disabled/Fatalw": func() { Disable(); Fatalw("cannot start", "attempt", 1) },
+	}
+	if name := os.Getenv(fatalVariantEnv); name != "" {
+		variants[name]()
+		// Reached only when the fatal call returned.
+		os.Exit(0)
+	}
+	for name := range variants {
+		t.Run(name, func(t *testing.T) {
+			cmd := exec.Command(os.Args[0], "-test.run=^TestFatalExitsWhenDisabled$")
+			cmd.Env = append(os.Environ(), fatalVariantEnv+"="+name)
+			err := cmd.Run()
+			var exit *exec.ExitError
+			if !errors.As(err, &exit) || exit.ExitCode() != 1 {
+				t.Fatalf("%s returned: got %v, want exit status 1", name, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Defaults of the suppression reports.
+const (
+	defaultSuppressionReportInterval = time.Minute
+	// maxSuppressionKeys bounds the number of distinct messages counted in a report.
+	// Entries with new messages are counted together while the limit is reached.
+	maxSuppressionKeys = 1000
+)
+
+// Reasons of suppressed entries, reported in the suppression_reason field.
+const (
+	suppressionSampled    = "sampled"
+	suppressionRateLimit  = "rate_limited"
+	suppressionAccessRule = "access_rule"
+	suppressionShed       = "shed"
+)
+
+// SuppressionReportConfig makes the logger report the entries dropped by volume
+// reduction, so that operators can tell "nothing happened" from "logs were suppressed".
+//
+// Every Interval, a WARN "log entries suppressed" entry is logged per level, logger name,
+// message and reason with entries dropped during the interval, carrying the message in
+// suppressed_message, its level in suppressed_level, the count in suppressed_count and
+// the reason in suppression_reason: "sampled" (Sampling), "rate_limited" (Once and
+// EveryN), "access_rule" (access log rules) or "shed" (async queue full). Deduplicated
+// entries are left out: Dedup already summarizes them.
+//
+// Reports are not subject to sampling and deduplication themselves. Past 1000 distinct
+// messages in an interval, the others are counted in a single report with an empty
+// suppressed_message.
+//
+// Example:
+//
+//	SuppressionReports: &logger.SuppressionReportConfig{Interval: 5 * time.Minute}
+type SuppressionReportConfig struct {
+	// Interval is the period of the reports. Defaults to 1m.
+	Interval time.Duration
+}
+
+// suppressionReportsFromEnv returns the reports configured by LOG_SUPPRESSION_REPORTS,
+// or nil when it is unset or invalid.
+func suppressionReportsFromEnv() *SuppressionReportConfig {
+	interval, err := time.ParseDuration(getEnv("LOG_SUPPRESSION_REPORTS", ""))
+	if err != nil || interval <= 0 {
+		return nil
+	}
+	return &SuppressionReportConfig{Interval: interval}
+}
+
+// suppressionKey identifies the suppressed entries of a report.
+type suppressionKey struct {
+	reason  string
+	level   zapcore.Level
+	name    string
+	message string
+}
+
+// suppressionReporter counts the suppressed entries of a pipeline and logs the reports.
+type suppressionReporter struct {
+	interval time.Duration
+	core     zapcore.Core
+
+	mu     sync.Mutex
+	counts map[suppressionKey]int
+	since  time.Time
+
+	once    sync.Once
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// newSuppressionReporter returns a reporter for cfg, or nil when cfg is nil.
+func newSuppressionReporter(cfg *SuppressionReportConfig) *suppressionReporter {
+	if cfg == nil {
+		return nil
+	}
+	r := &suppressionReporter{
+		interval: defaultSuppressionReportInterval,
+		counts:   make(map[suppressionKey]int),
+		since:    time.Now(),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	if cfg.Interval > 0 {
+		r.interval = cfg.Interval
+	}
+	return r
+}
+
+// hook returns the callback counting the entries dropped for reason, calling next as
+// well when set. It returns next when r is nil.
+func (r *suppressionReporter) hook(reason string, next func(zapcore.Entry)) func(zapcore.Entry) {
+	if r == nil {
+		return next
+	}
+	return func(ent zapcore.Entry) {
+		r.record(reason, ent)
+		if next != nil {
+			next(ent)
+		}
+	}
+}
+
+// record counts a suppressed entry.
+func (r *suppressionReporter) record(reason string, ent zapcore.Entry) {
+	key := suppressionKey{reason: reason, level: ent.Level, name: ent.LoggerName, message: ent.Message}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.counts[key]; !ok && len(r.counts) >= maxSuppressionKeys {
+		key = suppressionKey{reason: reason, level: ent.Level}
+	}
+	r.counts[key]++
+}
+
+// start logs the reports through core until close is called.
+func (r *suppressionReporter) start(core zapcore.Core) {
+	r.core = core
+	go r.run()
+}
+
+// run logs a report every interval.
+func (r *suppressionReporter) run() {
+	defer close(r.stopped)
+	labelGoroutine("suppression")
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.report()
+		case <-r.done:
+			r.report()
+			return
+		}
+	}
+}
+
+// report logs the entries suppressed since the last report and resets the counts.
+func (r *suppressionReporter) report() {
+	r.mu.Lock()
+	counts, since := r.counts, r.since
+	r.counts, r.since = make(map[suppressionKey]int), time.Now()
+	r.mu.Unlock()
+	period := time.Since(since)
+	for key, n := range counts {
+		writeReport(r.core, zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Now(), LoggerName: key.name, Message: "log entries suppressed"}, []zapcore.Field{
+			zap.String("suppressed_message", key.message),
+			zap.String("suppressed_level", key.level.CapitalString()),
+			zap.String("suppression_reason", key.reason),
+			zap.Int("suppressed_count", n),
+			DurationMS("report_period_ms", period),
+		})
+	}
+}
+
+// close logs the last report and stops the reporter.
+func (r *suppressionReporter) close() {
+	r.once.Do(func() {
+		close(r.done)
+		<-r.stopped
+	})
+}
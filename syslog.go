@@ -0,0 +1,59 @@
+package logger
+
+import "crypto/tls"
+
+// Syslog network values.
+const (
+	// SyslogLocal writes to the local syslog socket (/dev/log and common alternatives).
+	SyslogLocal = ""
+	// SyslogUDP sends one datagram per entry.
+	SyslogUDP = "udp"
+	// SyslogTCP sends octet-counted frames (RFC 6587) over TCP.
+	SyslogTCP = "tcp"
+	// SyslogTLS sends octet-counted frames over TLS (RFC 5425).
+	SyslogTLS = "tcp+tls"
+)
+
+// Syslog facilities (RFC 5424 section 6.2.1).
+const (
+	FacilityUser   = 1
+	FacilityDaemon = 3
+	FacilityAuth   = 4
+	FacilityLocal0 = 16
+	FacilityLocal1 = 17
+	FacilityLocal2 = 18
+	FacilityLocal3 = 19
+	FacilityLocal4 = 20
+	FacilityLocal5 = 21
+	FacilityLocal6 = 22
+	FacilityLocal7 = 23
+)
+
+// SyslogConfig makes an output send entries to syslog using RFC 5424 framing.
+//
+// The entry, encoded with the output's encoding, becomes the MSG part; the header carries
+// the priority derived from the facility and the entry level, the entry timestamp, the
+// hostname, the application name and the process ID.
+type SyslogConfig struct {
+	// Network is "" for the local syslog socket, "udp", "tcp", or "tcp+tls".
+	Network string
+	// Address is the remote "host:port", or the socket path overriding the local default.
+	Address string
+	// Facility is the syslog facility. Defaults to FacilityUser.
+	Facility int
+	// AppName is the APP-NAME header field. Defaults to the service name.
+	AppName string
+	// Hostname is the HOSTNAME header field. Defaults to os.Hostname.
+	Hostname string
+	// TLSConfig configures the "tcp+tls" network.
+	TLSConfig *tls.Config
+}
+
+// description returns a human-readable destination for the startup banner.
+func (c *SyslogConfig) description() string {
+	network := c.Network
+	if network == SyslogLocal {
+		network = "unix"
+	}
+	return "syslog+" + network + "://" + c.Address
+}
@@ -0,0 +1,14 @@
+//go:build logger_nosyslog
+
+package logger
+
+import (
+	"errors"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// newSyslogCore reports that the syslog sink was left out of the build.
+func newSyslogCore(Config, *SyslogConfig, zapcore.Encoder, zapcore.LevelEnabler) (zapcore.Core, func(), error) {
+	return nil, nil, errors.New("syslog sink not available: built with the logger_nosyslog tag")
+}
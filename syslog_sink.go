@@ -0,0 +1,220 @@
+//go:build !logger_nosyslog
+
+package logger
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// syslogTimeLayout is the RFC 5424 timestamp with microsecond precision.
+const syslogTimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// localSyslogPaths lists the usual locations of the local syslog socket.
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogSeverity maps a zap level to a syslog severity.
+func syslogSeverity(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 7 // debug
+	case zapcore.InfoLevel:
+		return 6 // informational
+	case zapcore.WarnLevel:
+		return 4 // warning
+	case zapcore.ErrorLevel:
+		return 3 // error
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return 2 // critical
+	case zapcore.FatalLevel:
+		return 1 // alert
+	default:
+		return 6
+	}
+}
+
+// syslogWriter sends formatted messages, reconnecting once after a write failure.
+type syslogWriter struct {
+	cfg    SyslogConfig
+	framed bool
+	header string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// newSyslogWriter connects to the syslog destination described by sc.
+func newSyslogWriter(cfg Config, sc *SyslogConfig) (*syslogWriter, error) {
+	switch sc.Network {
+	case SyslogLocal, SyslogUDP, SyslogTCP, SyslogTLS:
+	default:
+		return nil, fmt.Errorf("syslog: unknown network %q", sc.Network)
+	}
+	if sc.Network != SyslogLocal && sc.Address == "" {
+		return nil, errors.New("syslog: address is required")
+	}
+
+	w := &syslogWriter{cfg: *sc, framed: sc.Network == SyslogTCP || sc.Network == SyslogTLS}
+	if w.cfg.Facility == 0 {
+		w.cfg.Facility = FacilityUser
+	}
+	hostname := sc.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	appName := sc.AppName
+	if appName == "" {
+		appName = cfg.ServiceName
+	}
+	// HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA
+	w.header = " " + syslogField(hostname) + " " + syslogField(appName) + " " + strconv.Itoa(os.Getpid()) + " - - "
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// syslogField returns a header field value, or the NILVALUE "-" when empty.
+func syslogField(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+		return r
+	}, s)
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// connect opens the connection to the destination.
+func (w *syslogWriter) connect() error {
+	var conn net.Conn
+	var err error
+	switch w.cfg.Network {
+	case SyslogLocal:
+		conn, err = dialLocalSyslog(w.cfg.Address)
+	case SyslogTLS:
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", w.cfg.Address, w.cfg.TLSConfig)
+	default:
+		conn, err = net.DialTimeout(w.cfg.Network, w.cfg.Address, 5*time.Second)
+	}
+	if err != nil {
+		return fmt.Errorf("syslog: %w", err)
+	}
+	w.conn = conn
+	return nil
+}
+
+// dialLocalSyslog connects to the local syslog socket, trying datagram then stream sockets.
+func dialLocalSyslog(path string) (net.Conn, error) {
+	paths := localSyslogPaths
+	if path != "" {
+		paths = []string{path}
+	}
+	for _, p := range paths {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, p); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, errors.New("no local syslog socket found")
+}
+
+// write sends a single message with the given level and time.
+func (w *syslogWriter) write(level zapcore.Level, t time.Time, msg []byte) error {
+	pri := w.cfg.Facility*8 + syslogSeverity(level)
+	line := "<" + strconv.Itoa(pri) + ">1 " + t.Format(syslogTimeLayout) + w.header + string(msg)
+	if w.framed {
+		line = strconv.Itoa(len(line)) + " " + line
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		if _, err := w.conn.Write([]byte(line)); err == nil {
+			return nil
+		}
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	// Reconnect once, e.g. after the syslog daemon restarted.
+	if err := w.connect(); err != nil {
+		return err
+	}
+	_, err := w.conn.Write([]byte(line))
+	return err
+}
+
+// close closes the connection.
+func (w *syslogWriter) close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// syslogCore encodes entries with the output's encoder and sends them to syslog.
+type syslogCore struct {
+	zapcore.LevelEnabler
+	enc zapcore.Encoder
+	w   *syslogWriter
+}
+
+// newSyslogCore returns a core sending to the syslog destination described by sc, and a
+// function closing the connection.
+func newSyslogCore(cfg Config, sc *SyslogConfig, enc zapcore.Encoder, level zapcore.LevelEnabler) (zapcore.Core, func(), error) {
+	w, err := newSyslogWriter(cfg, sc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &syslogCore{LevelEnabler: level, enc: enc, w: w}, w.close, nil
+}
+
+// With implements zapcore.Core.
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return &syslogCore{LevelEnabler: c.LevelEnabler, enc: enc, w: c.w}
+}
+
+// Check implements zapcore.Core.
+func (c *syslogCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *syslogCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	msg := buf.Bytes()
+	for len(msg) > 0 && (msg[len(msg)-1] == '\n' || msg[len(msg)-1] == '\r') {
+		msg = msg[:len(msg)-1]
+	}
+	return c.w.write(ent.Level, ent.Time, msg)
+}
+
+// Sync implements zapcore.Core. Messages are sent synchronously.
+func (c *syslogCore) Sync() error { return nil }
@@ -0,0 +1,178 @@
+package logger
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// TestObserver records the entries logged during a test. Filters return a new observer
+// holding the matching entries only.
+type TestObserver struct {
+	t    testing.TB
+	logs *observer.ObservedLogs
+}
+
+// NewTest returns a logger recording every entry, at every level, and installs it as the
+// global logger until the end of the test, so that code calling the package-level
+// functions (logger.Info, logger.Get, ...) is observed as well. The previous global
+// logger is restored when the test and its subtests complete.
+//
+// Entries are also written to the test log, shown for failed tests or with go test -v.
+// Since the global logger is shared, tests calling NewTest must not run in parallel.
+//
+// Example:
+//
+//	func TestCharge(t *testing.T) {
+//	    _, logs := logger.NewTest(t)
+//
+//	    charge(ctx, order)
+//
+//	    logs.AssertLogged(logger.LevelInfo, "payment captured")
+//	    if n := logs.FilterField(zap.String("order_id", order.ID)).Len(); n != 1 {
+//	        t.Errorf("got %d entries for the order, want 1", n)
+//	    }
+//	}
+func NewTest(t testing.TB) (*Logger, *TestObserver) {
+	t.Helper()
+	level := zap.NewAtomicLevelAt(zapcore.DebugLevel)
+	levels := newLevelRegistry(level, nil)
+	observed, logs := observer.New(levels)
+	tw := &testWriter{t: t}
+	testLog := zapcore.NewCore(zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig()), tw, levels)
+	p := newPipeline(zapcore.NewTee(observed, testLog), nil, levels, defaultExemptions, nil)
+
+	cfg := Config{Level: LevelDebug, Environment: "development", ServiceName: t.Name()}
+	g, err := newGlobalWithPipeline(cfg, p, newOptions(nil))
+	if err != nil {
+		t.Fatalf("logger: %v", err)
+	}
+	previous := globalState.Swap(g)
+	t.Cleanup(func() {
+		globalState.CompareAndSwap(g, previous)
+		tw.close()
+	})
+	return g.logger, &TestObserver{t: t, logs: logs}
+}
+
+// testWriter writes entries to the test log until the test completes: logging to a
+// completed test panics.
+type testWriter struct {
+	t      testing.TB
+	mu     sync.Mutex
+	closed bool
+}
+
+// Write implements zapcore.WriteSyncer.
+func (w *testWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed {
+		w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer.
+func (w *testWriter) Sync() error { return nil }
+
+// close stops writing to the test log.
+func (w *testWriter) close() {
+	w.mu.Lock()
+	w.closed = true
+	w.mu.Unlock()
+}
+
+// Entries returns the recorded entries, in logging order.
+func (o *TestObserver) Entries() []observer.LoggedEntry {
+	return o.logs.All()
+}
+
+// Len returns the number of recorded entries.
+func (o *TestObserver) Len() int {
+	return o.logs.Len()
+}
+
+// Messages returns the messages of the recorded entries, in logging order.
+func (o *TestObserver) Messages() []string {
+	entries := o.logs.All()
+	messages := make([]string, len(entries))
+	for i, e := range entries {
+		messages[i] = e.Message
+	}
+	return messages
+}
+
+// Reset discards the recorded entries.
+func (o *TestObserver) Reset() {
+	o.logs.TakeAll()
+}
+
+// FilterMessage returns the entries with the given message.
+func (o *TestObserver) FilterMessage(msg string) *TestObserver {
+	return &TestObserver{t: o.t, logs: o.logs.FilterMessage(msg)}
+}
+
+// FilterMessageSnippet returns the entries whose message contains snippet.
+func (o *TestObserver) FilterMessageSnippet(snippet string) *TestObserver {
+	return &TestObserver{t: o.t, logs: o.logs.FilterMessageSnippet(snippet)}
+}
+
+// FilterLevel returns the entries of the given level.
+func (o *TestObserver) FilterLevel(level LogLevel) *TestObserver {
+	return &TestObserver{t: o.t, logs: o.logs.FilterLevelExact(parseLevel(level))}
+}
+
+// FilterField returns the entries carrying the given field, attached with With or
+// passed to the logging call.
+func (o *TestObserver) FilterField(field zap.Field) *TestObserver {
+	return &TestObserver{t: o.t, logs: o.logs.FilterField(field)}
+}
+
+// FilterFieldKey returns the entries carrying a field with the given key.
+func (o *TestObserver) FilterFieldKey(key string) *TestObserver {
+	return &TestObserver{t: o.t, logs: o.logs.FilterFieldKey(key)}
+}
+
+// AssertLogged marks the test as failed unless an entry with the given level and message
+// was recorded, and reports whether it was.
+func (o *TestObserver) AssertLogged(level LogLevel, msg string) bool {
+	o.t.Helper()
+	if o.FilterLevel(level).FilterMessage(msg).Len() > 0 {
+		return true
+	}
+	o.t.Errorf("logger: no %s entry %q was logged; got %s", parseLevel(level).CapitalString(), msg, o.describe())
+	return false
+}
+
+// AssertNotLogged marks the test as failed if an entry with the given level and message
+// was recorded, and reports whether none was.
+func (o *TestObserver) AssertNotLogged(level LogLevel, msg string) bool {
+	o.t.Helper()
+	if o.FilterLevel(level).FilterMessage(msg).Len() == 0 {
+		return true
+	}
+	o.t.Errorf("logger: unexpected %s entry %q was logged", parseLevel(level).CapitalString(), msg)
+	return false
+}
+
+// describe lists the recorded entries for failure messages.
+func (o *TestObserver) describe() string {
+	entries := o.logs.All()
+	if len(entries) == 0 {
+		return "no entries"
+	}
+	var b strings.Builder
+	for i, e := range entries {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(e.Level.CapitalString() + " " + strconv.Quote(e.Message))
+	}
+	return b.String()
+}
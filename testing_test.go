@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// recordingTB records the failures reported through it, without failing the test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestNewTest(t *testing.T) {
+	log, logs := NewTest(t)
+
+	log.Debug("cart loaded")
+	log.Info("payment captured", zap.String("order_id", "42"))
+	Get().Named("billing").Warn("slow gateway")
+	Warn("retrying", zap.Int("attempt", 2))
+
+	if got, want := strings.Join(logs.Messages(), ","), "cart loaded,payment captured,slow gateway,retrying"; got != want {
+		t.Fatalf("Messages() = %s, want %s", got, want)
+	}
+	logs.AssertLogged(LevelInfo, "payment captured")
+	logs.AssertNotLogged(LevelError, "payment captured")
+	if n := logs.FilterField(zap.String("order_id", "42")).Len(); n != 1 {
+		t.Errorf("FilterField(order_id) = %d entries, want 1", n)
+	}
+	if n := logs.FilterLevel(LevelWarn).Len(); n != 2 {
+		t.Errorf("FilterLevel(WARN) = %d entries, want 2", n)
+	}
+	if n := logs.FilterMessageSnippet("pay").FilterFieldKey("order_id").Len(); n != 1 {
+		t.Errorf("FilterMessageSnippet(pay).FilterFieldKey(order_id) = %d entries, want 1", n)
+	}
+	if e := logs.FilterMessage("slow gateway").Entries(); len(e) != 1 || e[0].LoggerName != "billing" {
+		t.Errorf("FilterMessage(slow gateway) = %+v, want the billing entry", e)
+	}
+
+	logs.Reset()
+	if n := logs.Len(); n != 0 {
+		t.Errorf("Len() after Reset = %d, want 0", n)
+	}
+}
+
+func TestNewTestRestoresGlobal(t *testing.T) {
+	previous := globalState.Load()
+	var installed *global
+	t.Run("test", func(t *testing.T) {
+		log, _ := NewTest(t)
+		installed = globalState.Load()
+		if Get() != log {
+			t.Fatal("Get() is not the test logger")
+		}
+	})
+	if installed == previous {
+		t.Fatal("NewTest did not install the test logger")
+	}
+	if globalState.Load() != previous {
+		t.Fatal("the previous global logger was not restored")
+	}
+}
+
+func TestObserverAssertions(t *testing.T) {
+	rec := &recordingTB{TB: t}
+	log, logs := NewTest(rec)
+	log.Info("payment captured")
+
+	if !logs.AssertLogged(LevelInfo, "payment captured") || !logs.AssertNotLogged(LevelError, "payment failed") {
+		t.Fatalf("assertions failed: %q", rec.errors)
+	}
+	if logs.AssertLogged(LevelWarn, "payment captured") {
+		t.Error("AssertLogged(WARN) = true for an INFO entry")
+	}
+	if logs.AssertNotLogged(LevelInfo, "payment captured") {
+		t.Error("AssertNotLogged(INFO) = true for a logged entry")
+	}
+	want := []string{
+		`logger: no WARN entry "payment captured" was logged; got INFO "payment captured"`,
+		`logger: unexpected INFO entry "payment captured" was logged`,
+	}
+	if strings.Join(rec.errors, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("reported %q, want %q", rec.errors, want)
+	}
+}
@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ValidationMode controls how well-known field type violations are reported.
+type ValidationMode string
+
+const (
+	// ValidationOff disables well-known field validation (default).
+	ValidationOff ValidationMode = ""
+	// ValidationWarn emits a self-warning entry the first time a given field and message
+	// combination carries an unexpected type. The original entry is still written.
+	ValidationWarn ValidationMode = "warn"
+	// ValidationStrict panics on any violation. Intended for tests and CI, where a
+	// mistyped field should fail the build rather than reach production.
+	ValidationStrict ValidationMode = "strict"
+)
+
+// fieldKind groups zap field types into the categories downstream aggregations care about.
+type fieldKind int
+
+const (
+	kindString fieldKind = iota + 1
+	kindInteger
+	kindNumber
+)
+
+// String returns a human-readable name of the kind.
+func (k fieldKind) String() string {
+	switch k {
+	case kindString:
+		return "string"
+	case kindInteger:
+		return "integer"
+	case kindNumber:
+		return "number"
+	default:
+		return "unknown"
+	}
+}
+
+// accepts reports whether a field of the given zap type satisfies the kind.
+func (k fieldKind) accepts(t zapcore.FieldType) bool {
+	switch t {
+	case zapcore.StringType, zapcore.StringerType:
+		return k == kindString
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type,
+		zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type:
+		return k == kindInteger || k == kindNumber
+	case zapcore.Float64Type, zapcore.Float32Type:
+		return k == kindNumber
+	default:
+		return false
+	}
+}
+
+// wellKnownFields lists reserved keys and the type downstream consumers expect them to carry.
+var wellKnownFields = map[string]fieldKind{
+	"trace_id":        kindString,
+	"span_id":         kindString,
+	"trace_flags":     kindString,
+	"request_id":      kindString,
+	"status":          kindInteger,
+	"duration_ms":     kindNumber,
+	"ingest_delay_ms": kindNumber,
+	"repeated":        kindInteger,
+}
+
+// validationCore wraps a core and checks well-known fields for their expected types.
+type validationCore struct {
+	zapcore.Core
+	mode     ValidationMode
+	reported *sync.Map
+}
+
+// newValidationCore wraps core with well-known field validation in the given mode.
+func newValidationCore(core zapcore.Core, mode ValidationMode) zapcore.Core {
+	return &validationCore{Core: core, mode: mode, reported: &sync.Map{}}
+}
+
+// With validates contextual fields before passing them down.
+func (c *validationCore) With(fields []zapcore.Field) zapcore.Core {
+	c.validate(zapcore.Entry{Time: time.Now()}, fields)
+	return &validationCore{Core: c.Core.With(fields), mode: c.mode, reported: c.reported}
+}
+
+// Check registers this core so that Write sees every entry.
+func (c *validationCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write validates the entry's fields and writes it to the wrapped core.
+func (c *validationCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	c.validate(ent, fields)
+	return writeChecked(c.Core, ent, fields)
+}
+
+// validate reports every well-known field whose type does not match expectations.
+func (c *validationCore) validate(ent zapcore.Entry, fields []zapcore.Field) {
+	for _, f := range fields {
+		want, ok := wellKnownFields[f.Key]
+		if !ok || want.accepts(f.Type) {
+			continue
+		}
+		if c.mode == ValidationStrict {
+			panic(fmt.Sprintf("logger: field %q must be a %s (message %q)", f.Key, want, ent.Message))
+		}
+		if !c.Enabled(zapcore.WarnLevel) {
+			continue
+		}
+		if _, seen := c.reported.LoadOrStore(f.Key+"\x00"+ent.Message, struct{}{}); seen {
+			continue
+		}
+		_ = writeChecked(c.Core, zapcore.Entry{
+			Level:      zapcore.WarnLevel,
+			Time:       ent.Time,
+			LoggerName: ent.LoggerName,
+			Message:    "well-known field has unexpected type",
+			Caller:     ent.Caller,
+		}, []zapcore.Field{
+			zap.String("field", f.Key),
+			zap.String("expected_type", want.String()),
+			zap.String("original_message", ent.Message),
+		})
+	}
+}